@@ -11,5 +11,10 @@ do
         OUTPUT="${OUTPUT}.exe"
     fi
     echo "Building for $GOOS/$GOARCH..."
-    GOOS=$GOOS GOARCH=$GOARCH go build -o "build/$OUTPUT" cmd/main.go
+    GOOS=$GOOS GOARCH=$GOARCH go build -o "build/$OUTPUT" ./cmd
+    PLUGIN_OUTPUT="age-plugin-eccfrog512ck2-${GOOS}-${GOARCH}"
+    if [ "$GOOS" = "windows" ]; then
+        PLUGIN_OUTPUT="${PLUGIN_OUTPUT}.exe"
+    fi
+    GOOS=$GOOS GOARCH=$GOARCH go build -o "build/$PLUGIN_OUTPUT" ./cmd/age-plugin-eccfrog512ck2
 done
\ No newline at end of file
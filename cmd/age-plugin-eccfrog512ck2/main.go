@@ -0,0 +1,43 @@
+// Command age-plugin-eccfrog512ck2 is an age plugin that lets age
+// implementations encrypt files to EccFrog512ck2 recipients and decrypt them
+// with EccFrog512ck2 identities.
+//
+// Install it in $PATH, and age will run it for recipients starting with
+// "age1eccfrog512ck2" and identities starting with
+// "AGE-PLUGIN-ECCFROG512CK2-". Run it with --generate to create an identity.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/age"
+)
+
+func main() {
+	stateMachine := flag.String("age-plugin", "", "run the given age plugin state machine")
+	generate := flag.Bool("generate", false, "generate a new identity")
+	flag.Parse()
+
+	switch {
+	case *stateMachine != "":
+		if err := age.RunPlugin(*stateMachine, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "age-plugin-eccfrog512ck2: %v\n", err)
+			os.Exit(1)
+		}
+	case *generate:
+		id, err := age.GenerateEccFrog512ck2Identity()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "age-plugin-eccfrog512ck2: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("# created: %s\n", time.Now().Format(time.RFC3339))
+		fmt.Printf("# recipient: %s\n", id.Recipient())
+		fmt.Println(id)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/armor"
+	"github.com/spf13/cobra"
+)
+
+// armorChecksum returns the checksum named by --armor-checksum, and whether
+// --armor was given.
+func armorChecksum(cmd *cobra.Command) (bool, armor.Checksum, error) {
+	armored, _ := cmd.Flags().GetBool("armor")
+	name, _ := cmd.Flags().GetString("armor-checksum")
+	switch name {
+	case "crc24":
+		return armored, armor.CRC24, nil
+	case "sha256":
+		return armored, armor.SHA256, nil
+	}
+	return false, 0, fmt.Errorf("invalid --armor-checksum %q: must be crc24 or sha256", name)
+}
+
+// armorBytes armors data as blockType if --armor was given, and returns it
+// unchanged otherwise.
+func armorBytes(cmd *cobra.Command, blockType string, data []byte) ([]byte, error) {
+	armored, checksum, err := armorChecksum(cmd)
+	if err != nil || !armored {
+		return data, err
+	}
+	return armor.Encode(&armor.Block{Type: blockType, Bytes: data}, checksum)
+}
+
+// armorWriter wraps w in an armor encoder for blockType if --armor was
+// given. The returned function must be called once everything has been
+// written, to finish the armored block.
+func armorWriter(cmd *cobra.Command, w io.Writer, blockType string) (io.Writer, func() error, error) {
+	armored, checksum, err := armorChecksum(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !armored {
+		return w, func() error { return nil }, nil
+	}
+	enc, err := armor.NewEncoder(w, blockType, nil, checksum)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, enc.Close, nil
+}
+
+// dearmor decodes data if it is armored as blockType, and returns it
+// unchanged if it is binary.
+func dearmor(data []byte, blockType string) ([]byte, error) {
+	data, err := armor.DecodeType(data, blockType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode armor: %v", err)
+	}
+	return data, nil
+}
+
+// rejectArmor fails if --armor or --armor-checksum was given to a command
+// whose output is PEM, text or a format of its own, so that the flags are
+// never silently ignored.
+func rejectArmor(cmd *cobra.Command, args []string) error {
+	for _, name := range []string{"armor", "armor-checksum"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be used with %s", name, cmd.Name())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/armor"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecies"
+	"github.com/spf13/cobra"
+)
+
+// encryptEnvelope encrypts the input once for the public keys in
+// recipientFiles.
+func encryptEnvelope(cmd *cobra.Command, recipientFiles []string, inFile, outFile string, opts *ecies.EnvelopeOptions) error {
+	var recipients []eccfrog512ck2.CurvePoint
+	for _, name := range recipientFiles {
+		keyBytes, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to read public key: %v", err)
+		}
+		publicKey, err := ecc.UnmarshalPublicPEM(keyBytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse public key %s: %v", name, err)
+		}
+		recipients = append(recipients, publicKey)
+	}
+
+	in, err := openInput(inFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := createOutput(outFile)
+	if err != nil {
+		return err
+	}
+	dst, finishArmor, err := armorWriter(cmd, out, armor.TypeMessage)
+	if err != nil {
+		discardOutput(out, outFile)
+		return err
+	}
+	w, err := ecies.NewEnvelopeWriter(dst, recipients, opts)
+	if err != nil {
+		discardOutput(out, outFile)
+		return fmt.Errorf("failed to encrypt message: %v", err)
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		discardOutput(out, outFile)
+		return fmt.Errorf("failed to encrypt message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		discardOutput(out, outFile)
+		return fmt.Errorf("failed to encrypt message: %v", err)
+	}
+	if err := finishArmor(); err != nil {
+		discardOutput(out, outFile)
+		return fmt.Errorf("failed to write encrypted file: %v", err)
+	}
+	return closeOutput(out, outFile, "Encrypted")
+}
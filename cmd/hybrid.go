@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecies"
+	"github.com/shovon/go-eccfrog512ck2/ecc/hybrid"
+)
+
+// generateHybridKey writes a new hybrid private key to outFile.
+func generateHybridKey(outFile string) error {
+	privateKey, err := hybrid.GenerateKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate private key: %v", err)
+	}
+	pemBytes, err := privateKey.MarshalPEM()
+	if err != nil {
+		return fmt.Errorf("failed to marshal private key: %v", err)
+	}
+	if err := os.WriteFile(outFile, pemBytes, 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %v", err)
+	}
+	fmt.Printf("Private key written to %s\n", outFile)
+	return nil
+}
+
+// writeHybridPublicKey writes the public key of a PEM-encoded hybrid private
+// key to outFile.
+func writeHybridPublicKey(pemBytes []byte, outFile string) error {
+	privateKey, err := hybrid.UnmarshalPEM(pemBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse private key: %v", err)
+	}
+	publicKey, err := privateKey.PublicKey()
+	if err != nil {
+		return fmt.Errorf("failed to derive public key: %v", err)
+	}
+	pemBytes, err = hybrid.MarshalPublicPEM(publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to marshal public key: %v", err)
+	}
+	if err := os.WriteFile(outFile, pemBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %v", err)
+	}
+	fmt.Printf("Public key written to %s\n", outFile)
+	return nil
+}
+
+// hybridEncrypt seals message to the PEM-encoded hybrid public key with the
+// given cipher, returning the marshaled container.
+func hybridEncrypt(keyBytes, message []byte, suite cryptohelpers.CipherSuite) ([]byte, error) {
+	publicKey, err := hybrid.UnmarshalPublicPEM(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+	container, err := ecies.SealHybridContainer(publicKey, message, ecies.KDFHKDFSHA256, suite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %v", err)
+	}
+	return container.MarshalBinary()
+}
+
+// hybridDecrypt decrypts input, either a container or a file written by
+// earlier versions, with the PEM-encoded hybrid private key.
+func hybridDecrypt(keyBytes, input []byte) ([]byte, error) {
+	privateKey, err := hybrid.UnmarshalPEM(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	if bytes.HasPrefix(input, []byte(ecies.ContainerMagic)) {
+		var container ecies.Container
+		if err := container.UnmarshalBinary(input); err != nil {
+			return nil, fmt.Errorf("invalid input file format: %v", err)
+		}
+		plaintext, err := container.OpenHybrid(privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt message: %v", err)
+		}
+		return plaintext, nil
+	}
+
+	kemCiphertext, ciphertext, err := readLegacyEnvelope(input)
+	if err != nil {
+		return nil, err
+	}
+	sharedKey, err := privateKey.Decapsulate(kemCiphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decapsulate key: %v", err)
+	}
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	plaintext, err := cryptohelpers.AES256GCMDecrypt(kdf)(sharedKey, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt message: %v", err)
+	}
+	return plaintext, nil
+}
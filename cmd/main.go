@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/sha256"
-	"encoding/binary"
 	"fmt"
+	"io"
 	"math/big"
 	"os"
 
 	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/armor"
 	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
 	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
 	"github.com/shovon/go-eccfrog512ck2/ecc/ecdsa"
@@ -23,14 +26,19 @@ It provides functionality for key generation, signing, verification, encryption,
 }
 
 var genpkeyCmd = &cobra.Command{
-	Use:   "genpkey",
-	Short: "Generate a private key",
-	Long:  `Generate a new private key and save it to a file in PEM format.`,
+	Use:     "genpkey",
+	Short:   "Generate a private key",
+	Long:    `Generate a new private key and save it to a file in PEM format.`,
+	PreRunE: rejectArmor,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		outFile, _ := cmd.Flags().GetString("out")
+		hybrid, _ := cmd.Flags().GetBool("hybrid")
 		if outFile == "" {
 			return fmt.Errorf("output file is required")
 		}
+		if hybrid {
+			return generateHybridKey(outFile)
+		}
 
 		// Generate private key
 		privateKey, err := ecc.GeneratePrivateKey()
@@ -54,13 +62,15 @@ var genpkeyCmd = &cobra.Command{
 }
 
 var pkeyCmd = &cobra.Command{
-	Use:   "pkey",
-	Short: "Public key operations",
-	Long:  `Operations on private/public keys including deriving public keys from private keys.`,
+	Use:     "pkey",
+	Short:   "Public key operations",
+	Long:    `Operations on private/public keys including deriving public keys from private keys.`,
+	PreRunE: rejectArmor,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		inFile, _ := cmd.Flags().GetString("in")
 		outFile, _ := cmd.Flags().GetString("out")
 		pubout, _ := cmd.Flags().GetBool("pubout")
+		hybrid, _ := cmd.Flags().GetBool("hybrid")
 
 		if inFile == "" {
 			return fmt.Errorf("input file is required")
@@ -77,6 +87,9 @@ var pkeyCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to read private key: %v", err)
 		}
+		if hybrid {
+			return writeHybridPublicKey(pemBytes, outFile)
+		}
 
 		// Parse private key
 		privateKey, err := ecc.UnmarshalPEM(pemBytes)
@@ -150,7 +163,10 @@ var signCmd = &cobra.Command{
 		}
 
 		// Write signature to output file
-		signature := append(r.Bytes(), s.Bytes()...)
+		signature, err := armorBytes(cmd, armor.TypeSignature, append(r.Bytes(), s.Bytes()...))
+		if err != nil {
+			return err
+		}
 		if err := os.WriteFile(outFile, signature, 0644); err != nil {
 			return fmt.Errorf("failed to write signature: %v", err)
 		}
@@ -202,6 +218,9 @@ var verifyCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("failed to read signature file: %v", err)
 		}
+		if sigBytes, err = dearmor(sigBytes, armor.TypeSignature); err != nil {
+			return err
+		}
 
 		// Split signature into r and s components
 		if len(sigBytes) != 128 { // 64 bytes for r + 64 bytes for s
@@ -230,17 +249,84 @@ var verifyCmd = &cobra.Command{
 var encryptCmd = &cobra.Command{
 	Use:   "encrypt",
 	Short: "Encrypt a file",
-	Long:  `Encrypt a file using ECIES with AES-GCM-256.`,
+	Long: `Encrypt a file with ECIES to an EccFrog512ck2 public key.
+
+The output is a versioned container: magic bytes and a format version, the
+IDs of the KEM, KDF and AEAD, the ephemeral public key as a compressed point,
+the nonce and the ciphertext. The input is read whole into memory. Input is
+read from stdin and output written to stdout if --in or --out is omitted or
+"-".
+
+With --recipient or --pad, the output is a multi-recipient envelope instead.
+It starts with magic bytes, a format version and the IDs of the KEM, KDF and
+AEAD, followed by the data key wrapped for each recipient. The input is then
+encrypted as it is read, in authenticated 64 KiB chunks, so files of any size
+can be encrypted. --recipient may be repeated, and the file is encrypted for
+every recipient together with --inkey if it is given. --hints lets recipients
+find their key quickly at the cost of revealing who they are, and
+--shared-ephemeral shortens the output by using one ephemeral key for all
+recipients.
+
+--cipher picks the AEAD: aes-256-gcm (the default), aes-256-gcm-siv,
+chacha20-poly1305 or xchacha20-poly1305. The ChaCha20 ciphers are faster on
+processors without AES instructions; AES-256-GCM-SIV stays secure if a nonce
+is ever repeated. Append -commit to any of them, as in aes-256-gcm-commit, to
+add a key commitment so that the file decrypts to the same plaintext for
+every recipient. The output records the cipher, so decrypt needs no flag.
+
+--pad hides the exact length of the input by padding it before encryption:
+padme adds at most 12%, power-of-two pads to the next power of two, and
+block:SIZE pads to a multiple of SIZE bytes. decrypt removes the padding.
+
+With --hybrid, the key is encapsulated with the EccFrog512ck2 and ML-KEM-1024
+hybrid KEM to a hybrid public key instead, and the container records that
+KEM.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		inFile, _ := cmd.Flags().GetString("in")
 		outFile, _ := cmd.Flags().GetString("out")
 		keyFile, _ := cmd.Flags().GetString("inkey")
-
-		if inFile == "" {
-			return fmt.Errorf("input file is required")
-		}
-		if outFile == "" {
-			return fmt.Errorf("output file is required")
+		hybrid, _ := cmd.Flags().GetBool("hybrid")
+		recipientFiles, _ := cmd.Flags().GetStringArray("recipient")
+		cipherName, _ := cmd.Flags().GetString("cipher")
+		padName, _ := cmd.Flags().GetString("pad")
+		hints, _ := cmd.Flags().GetBool("hints")
+		shared, _ := cmd.Flags().GetBool("shared-ephemeral")
+
+		suite, err := cryptohelpers.ParseCipherSuite(cipherName)
+		if err != nil {
+			return fmt.Errorf("invalid --cipher: %v", err)
+		}
+		var padding ecies.Padding
+		if padName != "" {
+			if padding, err = ecies.ParsePadding(padName); err != nil {
+				return fmt.Errorf("invalid --pad: %v", err)
+			}
+		}
+
+		// Containers have one recipient and no padding, so these need the
+		// multi-recipient envelope
+		if len(recipientFiles) > 0 || padding != nil {
+			if hybrid && padding != nil {
+				return fmt.Errorf("--hybrid cannot be used with --pad")
+			}
+			if hybrid {
+				return fmt.Errorf("--hybrid cannot be used with --recipient")
+			}
+			if keyFile != "" {
+				recipientFiles = append([]string{keyFile}, recipientFiles...)
+			}
+			if len(recipientFiles) == 0 {
+				return fmt.Errorf("public key file is required")
+			}
+			return encryptEnvelope(cmd, recipientFiles, inFile, outFile, &ecies.EnvelopeOptions{
+				Cipher:             suite,
+				SharedEphemeralKey: shared,
+				Hints:              hints,
+				Padding:            padding,
+			})
+		}
+		if hints || shared {
+			return fmt.Errorf("--hints and --shared-ephemeral need --recipient or --pad")
 		}
 		if keyFile == "" {
 			return fmt.Errorf("public key file is required")
@@ -252,70 +338,67 @@ var encryptCmd = &cobra.Command{
 			return fmt.Errorf("failed to read public key: %v", err)
 		}
 
-		// Parse public key
-		publicKey, err := ecc.UnmarshalPublicPEM(keyBytes)
+		in, err := openInput(inFile)
 		if err != nil {
-			return fmt.Errorf("failed to parse public key: %v", err)
+			return err
 		}
-
-		// Read input file
-		message, err := os.ReadFile(inFile)
+		defer in.Close()
+		message, err := io.ReadAll(in)
 		if err != nil {
 			return fmt.Errorf("failed to read input file: %v", err)
 		}
 
-		// Generate ephemeral key pair
-		ephemeralKey, err := ecc.GeneratePrivateKey()
-		if err != nil {
-			return fmt.Errorf("failed to generate ephemeral key: %v", err)
-		}
-
-		// Encrypt message
-		kdf := cryptohelpers.HKDF256(sha256.New)
-		rG, ciphertext, err := ecies.
-			NewEncryptor(cryptohelpers.AES256GCMEncrypt(kdf)).
-			Encrypt(ephemeralKey, publicKey, message)
-		if err != nil {
-			return fmt.Errorf("failed to encrypt message: %v", err)
-		}
-
-		// Write output file
-		// Format: [rG length (4 bytes)][rG bytes][ciphertext length (4 bytes)][ciphertext bytes]
-		rGBytes := rG.MarshalSEC1(false)
-		rGLength := make([]byte, 4)
-		binary.BigEndian.PutUint32(rGLength, uint32(len(rGBytes)))
-		ciphertextLength := make([]byte, 4)
-		binary.BigEndian.PutUint32(ciphertextLength, uint32(len(ciphertext.CipherText)))
-
-		output := append(rGLength, rGBytes...)
-		output = append(output, ciphertextLength...)
-		output = append(output, ciphertext.CipherText...)
-		output = append(output, ciphertext.Nonce...)
-
-		if err := os.WriteFile(outFile, output, 0644); err != nil {
-			return fmt.Errorf("failed to write encrypted file: %v", err)
-		}
-
-		fmt.Printf("Encrypted file written to %s\n", outFile)
-		return nil
+		var container []byte
+		if hybrid {
+			if container, err = hybridEncrypt(keyBytes, message, suite); err != nil {
+				return err
+			}
+		} else {
+			publicKey, err := ecc.UnmarshalPublicPEM(keyBytes)
+			if err != nil {
+				return fmt.Errorf("failed to parse public key: %v", err)
+			}
+			sealed, err := ecies.SealContainer(publicKey, message, ecies.KDFHKDFSHA256, suite)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt message: %v", err)
+			}
+			if container, err = sealed.MarshalBinary(); err != nil {
+				return fmt.Errorf("failed to encrypt message: %v", err)
+			}
+		}
+		if container, err = armorBytes(cmd, armor.TypeMessage, container); err != nil {
+			return err
+		}
+		return writeOutput(outFile, container, "Encrypted")
 	},
 }
 
 var decryptCmd = &cobra.Command{
 	Use:   "decrypt",
 	Short: "Decrypt a file",
-	Long:  `Decrypt a file using ECIES with AES-GCM-256.`,
+	Long: `Decrypt a file written by encrypt.
+
+The format is recognized by the magic bytes the file starts with. Containers
+are read whole and decrypted with the KEM, KDF and AEAD they name.
+Multi-recipient envelopes are decrypted with the private key of any one of
+their recipients, in 64 KiB chunks as they are read, with the cipher their
+header names; padding is removed. Files written by the first versions of this
+tool, laid out as [rG length][rG][ciphertext length][ciphertext][nonce] and
+encrypted with AES-256-GCM, are also accepted. Armored input is decoded
+first.
+
+Input is read from stdin and output written to stdout if --in or --out is
+omitted or "-". A partially written output file is removed if decryption
+fails.
+
+With --hybrid, the file must have been encrypted with --hybrid and the key
+must be a hybrid private key.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		inFile, _ := cmd.Flags().GetString("in")
 		outFile, _ := cmd.Flags().GetString("out")
 		keyFile, _ := cmd.Flags().GetString("inkey")
+		hybrid, _ := cmd.Flags().GetBool("hybrid")
 
-		if inFile == "" {
-			return fmt.Errorf("input file is required")
-		}
-		if outFile == "" {
-			return fmt.Errorf("output file is required")
-		}
 		if keyFile == "" {
 			return fmt.Errorf("private key file is required")
 		}
@@ -326,60 +409,82 @@ var decryptCmd = &cobra.Command{
 			return fmt.Errorf("failed to read private key: %v", err)
 		}
 
+		f, err := openInput(inFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in := bufio.NewReader(f)
+
+		// Armored input is read whole and decoded
+		if prefix, _ := in.Peek(64); armor.IsArmored(prefix) {
+			data, err := io.ReadAll(in)
+			if err != nil {
+				return fmt.Errorf("failed to read input file: %v", err)
+			}
+			if data, err = dearmor(data, armor.TypeMessage); err != nil {
+				return err
+			}
+			in = bufio.NewReader(bytes.NewReader(data))
+		}
+
+		if hybrid {
+			input, err := io.ReadAll(in)
+			if err != nil {
+				return fmt.Errorf("failed to read input file: %v", err)
+			}
+			plaintext, err := hybridDecrypt(keyBytes, input)
+			if err != nil {
+				return err
+			}
+			return writeOutput(outFile, plaintext, "Decrypted")
+		}
+
 		// Parse private key
 		privateKey, err := ecc.UnmarshalPEM(keyBytes)
 		if err != nil {
 			return fmt.Errorf("failed to parse private key: %v", err)
 		}
 
-		// Read input file
-		input, err := os.ReadFile(inFile)
+		if prefix, _ := in.Peek(len(ecies.EnvelopeMagic)); string(prefix) == ecies.EnvelopeMagic {
+			r, err := ecies.NewEnvelopeReader(in, privateKey)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt message: %v", err)
+			}
+			return copyOutput(outFile, r)
+		}
+
+		input, err := io.ReadAll(in)
 		if err != nil {
 			return fmt.Errorf("failed to read input file: %v", err)
 		}
-
-		// Parse input file
-		// Format: [rG length (4 bytes)][rG bytes][ciphertext length (4 bytes)][ciphertext bytes][nonce]
-		if len(input) < 8 { // At least 4 bytes for each length
-			return fmt.Errorf("invalid input file format")
+		if bytes.HasPrefix(input, []byte(ecies.ContainerMagic)) {
+			var container ecies.Container
+			if err := container.UnmarshalBinary(input); err != nil {
+				return fmt.Errorf("invalid input file format: %v", err)
+			}
+			plaintext, err := container.Open(privateKey)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt message: %v", err)
+			}
+			return writeOutput(outFile, plaintext, "Decrypted")
 		}
 
-		rGLength := binary.BigEndian.Uint32(input[:4])
-		if len(input) < int(4+rGLength+4) {
-			return fmt.Errorf("invalid input file format")
+		rGBytes, ciphertext, err := readLegacyEnvelope(input)
+		if err != nil {
+			return err
 		}
-
-		rGBytes := input[4 : 4+rGLength]
 		rG, err := ecc.ParsePublicKeySEC1(rGBytes)
 		if err != nil {
 			return fmt.Errorf("failed to parse ephemeral public key: %v", err)
 		}
-
-		ciphertextLength := binary.BigEndian.Uint32(input[4+rGLength : 8+rGLength])
-		if len(input) < int(8+rGLength+ciphertextLength+12) { // 12 bytes for nonce
-			return fmt.Errorf("invalid input file format")
-		}
-
-		ciphertext := cryptohelpers.AES256GCMResults{
-			CipherText: input[8+rGLength : 8+rGLength+ciphertextLength],
-			Nonce:      input[8+rGLength+ciphertextLength:],
-		}
-
-		// Decrypt message
-		kdf := cryptohelpers.HKDF256(sha256.New)
 		plaintext, err := ecies.
-			NewDecryptor(cryptohelpers.AES256GCMDecrypt(kdf)).
-			Decrypt(privateKey, rG, ciphertext)
+			NewContextDecryptor(cryptohelpers.AES256GCMDecryptWithAAD(cryptohelpers.HKDF256(sha256.New))).
+			DecryptLegacy(privateKey, rG, ciphertext)
 		if err != nil {
 			return fmt.Errorf("failed to decrypt message: %v", err)
 		}
-
-		if err := os.WriteFile(outFile, plaintext, 0644); err != nil {
-			return fmt.Errorf("failed to write decrypted file: %v", err)
-		}
-
-		fmt.Printf("Decrypted file written to %s\n", outFile)
-		return nil
+		return writeOutput(outFile, plaintext, "Decrypted")
 	},
 }
 
@@ -434,6 +539,9 @@ var ecdhCmd = &cobra.Command{
 		}
 
 		// Write shared secret to output file
+		if sharedSecret, err = armorBytes(cmd, armor.TypeSharedSecret, sharedSecret); err != nil {
+			return err
+		}
 		if err := os.WriteFile(outFile, sharedSecret, 0600); err != nil {
 			return fmt.Errorf("failed to write shared secret: %v", err)
 		}
@@ -444,6 +552,9 @@ var ecdhCmd = &cobra.Command{
 }
 
 func init() {
+	rootCmd.PersistentFlags().Bool("armor", false, "ASCII-armor binary output: signatures, ciphertexts and shared secrets")
+	rootCmd.PersistentFlags().String("armor-checksum", "crc24", "Checksum of armored output: crc24 or sha256")
+
 	// Add commands to root
 	rootCmd.AddCommand(genpkeyCmd)
 	rootCmd.AddCommand(pkeyCmd)
@@ -455,11 +566,13 @@ func init() {
 
 	// Add flags
 	genpkeyCmd.Flags().StringP("out", "o", "", "Output file for private key")
+	genpkeyCmd.Flags().Bool("hybrid", false, "Generate an EccFrog512ck2 and ML-KEM-1024 hybrid key")
 	genpkeyCmd.MarkFlagRequired("out")
 
 	pkeyCmd.Flags().StringP("in", "i", "", "Input file containing private key")
 	pkeyCmd.Flags().StringP("out", "o", "", "Output file for public key")
 	pkeyCmd.Flags().Bool("pubout", false, "Output public key")
+	pkeyCmd.Flags().Bool("hybrid", false, "The private key is a hybrid key")
 	pkeyCmd.MarkFlagRequired("in")
 	pkeyCmd.MarkFlagRequired("out")
 
@@ -477,18 +590,20 @@ func init() {
 	verifyCmd.MarkFlagRequired("sigfile")
 	verifyCmd.MarkFlagRequired("inkey")
 
-	encryptCmd.Flags().StringP("in", "i", "", "Input file to encrypt")
-	encryptCmd.Flags().StringP("out", "o", "", "Output file for encrypted data")
+	encryptCmd.Flags().StringP("in", "i", "", "Input file to encrypt (default stdin)")
+	encryptCmd.Flags().StringP("out", "o", "", "Output file for encrypted data (default stdout)")
 	encryptCmd.Flags().StringP("inkey", "k", "", "Public key file")
-	encryptCmd.MarkFlagRequired("in")
-	encryptCmd.MarkFlagRequired("out")
-	encryptCmd.MarkFlagRequired("inkey")
-
-	decryptCmd.Flags().StringP("in", "i", "", "Input file to decrypt")
-	decryptCmd.Flags().StringP("out", "o", "", "Output file for decrypted data")
+	encryptCmd.Flags().Bool("hybrid", false, "Encrypt to a hybrid public key with ML-KEM-1024")
+	encryptCmd.Flags().StringArrayP("recipient", "r", nil, "Recipient public key file (can be repeated)")
+	encryptCmd.Flags().Bool("hints", false, "Include recipient key hints")
+	encryptCmd.Flags().Bool("shared-ephemeral", false, "Use one ephemeral key for all recipients")
+	encryptCmd.Flags().String("pad", "", "Pad the input to hide its length: padme, power-of-two or block:SIZE")
+	encryptCmd.Flags().String("cipher", cryptohelpers.CipherAES256GCM.String(), "AEAD cipher: aes-256-gcm, aes-256-gcm-siv, chacha20-poly1305 or xchacha20-poly1305, optionally with a -commit suffix")
+
+	decryptCmd.Flags().StringP("in", "i", "", "Input file to decrypt (default stdin)")
+	decryptCmd.Flags().StringP("out", "o", "", "Output file for decrypted data (default stdout)")
 	decryptCmd.Flags().StringP("inkey", "k", "", "Private key file")
-	decryptCmd.MarkFlagRequired("in")
-	decryptCmd.MarkFlagRequired("out")
+	decryptCmd.Flags().Bool("hybrid", false, "Decrypt with a hybrid private key")
 	decryptCmd.MarkFlagRequired("inkey")
 
 	ecdhCmd.Flags().StringP("inkey", "k", "", "Private key file")
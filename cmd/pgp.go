@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/openpgp"
+	"github.com/spf13/cobra"
+)
+
+var pgpCmd = &cobra.Command{
+	Use:   "pgp",
+	Short: "OpenPGP key import and export",
+	Long: `Convert keys between PEM files and ASCII-armored OpenPGP v6 keys.
+
+With --export, the signing key in --key and the encryption key in --subkey
+are combined into an OpenPGP key with the user ID in --uid. The public key is
+written unless --secret is given, in which case the secret key is written,
+protected with --passphrase if one is given.
+With --import, the armored key in --in is read and its primary key is written
+to --out as a PEM file. The encryption subkey is written to --subkey-out if
+given. Secret keys are decrypted with --passphrase.
+
+The keys use private/experimental OpenPGP algorithm IDs, so only tools that
+know about EccFrog512ck2 can use them.`,
+	PreRunE: rejectArmor,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		export, _ := cmd.Flags().GetBool("export")
+		importKey, _ := cmd.Flags().GetBool("import")
+
+		if export {
+			return pgpExport(cmd)
+		}
+		if importKey {
+			return pgpImport(cmd)
+		}
+		return fmt.Errorf("either --export or --import is required")
+	},
+}
+
+func pgpExport(cmd *cobra.Command) error {
+	keyFile, _ := cmd.Flags().GetString("key")
+	subkeyFile, _ := cmd.Flags().GetString("subkey")
+	userId, _ := cmd.Flags().GetString("uid")
+	outFile, _ := cmd.Flags().GetString("out")
+	secret, _ := cmd.Flags().GetBool("secret")
+	passphrase, _ := cmd.Flags().GetString("passphrase")
+	created, _ := cmd.Flags().GetString("created")
+
+	if keyFile == "" || subkeyFile == "" {
+		return fmt.Errorf("signing and encryption key files are required")
+	}
+	if userId == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if outFile == "" {
+		return fmt.Errorf("output file is required")
+	}
+
+	// The creation time is part of the fingerprint, so it can be given
+	// explicitly to export the same key again.
+	creationTime := time.Now()
+	if created != "" {
+		var err error
+		creationTime, err = time.Parse(time.RFC3339, created)
+		if err != nil {
+			return fmt.Errorf("invalid creation time: %v", err)
+		}
+	}
+
+	signingKey, err := readPrivateKey(keyFile)
+	if err != nil {
+		return err
+	}
+	encryptionKey, err := readPrivateKey(subkeyFile)
+	if err != nil {
+		return err
+	}
+
+	entity, err := openpgp.NewEntity(userId, signingKey, encryptionKey, creationTime)
+	if err != nil {
+		return fmt.Errorf("failed to create OpenPGP key: %v", err)
+	}
+
+	var b bytes.Buffer
+	blockType := openpgp.ArmorPublicKey
+	if secret {
+		if passphrase != "" {
+			if err := entity.EncryptPrivateKeys([]byte(passphrase)); err != nil {
+				return fmt.Errorf("failed to encrypt secret key: %v", err)
+			}
+		}
+		blockType = openpgp.ArmorPrivateKey
+		err = entity.SerializePrivate(&b)
+	} else {
+		err = entity.Serialize(&b)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to serialize OpenPGP key: %v", err)
+	}
+
+	if err := os.WriteFile(outFile, openpgp.Armor(blockType, b.Bytes()), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+
+	fmt.Printf("Fingerprint: %X\n", entity.PrimaryKey.Fingerprint())
+	fmt.Printf("OpenPGP key written to %s\n", outFile)
+	return nil
+}
+
+func pgpImport(cmd *cobra.Command) error {
+	inFile, _ := cmd.Flags().GetString("in")
+	outFile, _ := cmd.Flags().GetString("out")
+	subkeyOutFile, _ := cmd.Flags().GetString("subkey-out")
+	passphrase, _ := cmd.Flags().GetString("passphrase")
+
+	if inFile == "" {
+		return fmt.Errorf("input file is required")
+	}
+	if outFile == "" {
+		return fmt.Errorf("output file is required")
+	}
+
+	armored, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %v", err)
+	}
+	blockType, data, err := openpgp.Dearmor(armored)
+	if err != nil {
+		return fmt.Errorf("failed to decode armor: %v", err)
+	}
+	if blockType != openpgp.ArmorPublicKey && blockType != openpgp.ArmorPrivateKey {
+		return fmt.Errorf("unexpected armor block type: %s", blockType)
+	}
+
+	keyring, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to read OpenPGP key: %v", err)
+	}
+	if len(keyring) != 1 {
+		return fmt.Errorf("expected a single key, found %d", len(keyring))
+	}
+	entity := keyring[0]
+
+	fmt.Printf("Fingerprint: %X\n", entity.PrimaryKey.Fingerprint())
+	for _, identity := range entity.Identities {
+		fmt.Printf("User ID: %s\n", identity.UserId.Id)
+	}
+
+	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+		if passphrase == "" {
+			return fmt.Errorf("secret key is protected; a passphrase is required")
+		}
+		if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+			return fmt.Errorf("failed to decrypt secret key: %v", err)
+		}
+	}
+
+	if err := writeOpenPGPKey(outFile, entity.PrimaryKey, entity.PrivateKey); err != nil {
+		return err
+	}
+	fmt.Printf("Primary key written to %s\n", outFile)
+
+	if subkeyOutFile != "" {
+		if len(entity.Subkeys) == 0 {
+			return fmt.Errorf("key has no subkey")
+		}
+		subkey := entity.Subkeys[0]
+		if err := writeOpenPGPKey(subkeyOutFile, subkey.PublicKey, subkey.PrivateKey); err != nil {
+			return err
+		}
+		fmt.Printf("Subkey written to %s\n", subkeyOutFile)
+	}
+	return nil
+}
+
+// writeOpenPGPKey writes the private key as PEM if there is one, and the
+// public key otherwise.
+func writeOpenPGPKey(filename string, publicKey *openpgp.PublicKey, privateKey *openpgp.PrivateKey) error {
+	var pemBytes []byte
+	var err error
+	if privateKey != nil {
+		pemBytes, err = privateKey.PrivateKey.MarshalPEM()
+	} else {
+		pemBytes, err = ecc.MarshalPublicPEM(publicKey.PublicKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode key: %v", err)
+	}
+	if err := os.WriteFile(filename, pemBytes, 0600); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pgpCmd)
+
+	pgpCmd.Flags().Bool("export", false, "Export PEM keys as an armored OpenPGP key")
+	pgpCmd.Flags().Bool("import", false, "Import an armored OpenPGP key as PEM keys")
+	pgpCmd.Flags().StringP("key", "k", "", "Signing (primary) private key file")
+	pgpCmd.Flags().String("subkey", "", "Encryption subkey private key file")
+	pgpCmd.Flags().String("uid", "", "User ID, e.g. \"Alice <alice@example.com>\"")
+	pgpCmd.Flags().Bool("secret", false, "Export the secret key instead of the public key")
+	pgpCmd.Flags().String("passphrase", "", "Passphrase protecting the secret key")
+	pgpCmd.Flags().String("created", "", "Key creation time in RFC 3339 format (defaults to now)")
+	pgpCmd.Flags().StringP("in", "i", "", "Input file containing an armored OpenPGP key")
+	pgpCmd.Flags().StringP("out", "o", "", "Output file")
+	pgpCmd.Flags().String("subkey-out", "", "Output file for the encryption subkey when importing")
+}
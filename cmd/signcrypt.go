@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/armor"
+	"github.com/shovon/go-eccfrog512ck2/ecc/signcrypt"
+	"github.com/spf13/cobra"
+)
+
+var sealCmd = &cobra.Command{
+	Use:   "seal",
+	Short: "Sign and encrypt a file",
+	Long: `Sign a file with the private key in --inkey and encrypt it, together with the
+signature, to the public key in --recipient. The signature covers the sender's
+and the recipient's public keys, so the recipient cannot pass the message off
+as signed for someone else. The result is a single file that unseal decrypts
+and verifies.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inFile, _ := cmd.Flags().GetString("in")
+		outFile, _ := cmd.Flags().GetString("out")
+		keyFile, _ := cmd.Flags().GetString("inkey")
+		recipientFile, _ := cmd.Flags().GetString("recipient")
+
+		privateKey, err := readPrivateKey(keyFile)
+		if err != nil {
+			return err
+		}
+		recipient, err := readPublicKey(recipientFile)
+		if err != nil {
+			return err
+		}
+		message, err := readInput(inFile)
+		if err != nil {
+			return err
+		}
+
+		sealed, err := signcrypt.Seal(privateKey, recipient, message)
+		if err != nil {
+			return fmt.Errorf("failed to seal message: %v", err)
+		}
+		if sealed, err = armorBytes(cmd, armor.TypeSigncryptedMessage, sealed); err != nil {
+			return err
+		}
+		return writeOutput(outFile, sealed, "Sealed")
+	},
+}
+
+var unsealCmd = &cobra.Command{
+	Use:   "unseal",
+	Short: "Decrypt and verify a sealed file",
+	Long: `Decrypt a file written by seal with the private key in --inkey, and verify that
+it was signed for that key by the sender whose public key is in --sender. Nothing
+is written unless the signature is valid.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inFile, _ := cmd.Flags().GetString("in")
+		outFile, _ := cmd.Flags().GetString("out")
+		keyFile, _ := cmd.Flags().GetString("inkey")
+		senderFile, _ := cmd.Flags().GetString("sender")
+
+		privateKey, err := readPrivateKey(keyFile)
+		if err != nil {
+			return err
+		}
+		sender, err := readPublicKey(senderFile)
+		if err != nil {
+			return err
+		}
+		sealed, err := readInput(inFile)
+		if err != nil {
+			return err
+		}
+		if armor.IsArmored(sealed) {
+			if sealed, err = dearmor(sealed, armor.TypeSigncryptedMessage); err != nil {
+				return err
+			}
+		}
+
+		message, err := signcrypt.Open(privateKey, sender, sealed)
+		if err != nil {
+			return fmt.Errorf("failed to unseal message: %v", err)
+		}
+		return writeOutput(outFile, message, "Unsealed")
+	},
+}
+
+// readInput reads the whole named file, or stdin if the name is empty or
+// "-".
+func readInput(name string) ([]byte, error) {
+	in, err := openInput(name)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	data, err := io.ReadAll(bufio.NewReader(in))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %v", err)
+	}
+	return data, nil
+}
+
+func readPublicKey(filename string) (eccfrog512ck2.CurvePoint, error) {
+	keyBytes, err := os.ReadFile(filename)
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, fmt.Errorf("failed to read public key: %v", err)
+	}
+	publicKey, err := ecc.UnmarshalPublicPEM(keyBytes)
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, fmt.Errorf("failed to parse public key %s: %v", filename, err)
+	}
+	return publicKey, nil
+}
+
+func init() {
+	rootCmd.AddCommand(sealCmd)
+	rootCmd.AddCommand(unsealCmd)
+
+	sealCmd.Flags().StringP("in", "i", "", "Input file to seal (default stdin)")
+	sealCmd.Flags().StringP("out", "o", "", "Output file for the sealed message (default stdout)")
+	sealCmd.Flags().StringP("inkey", "k", "", "Sender's private key file")
+	sealCmd.Flags().StringP("recipient", "r", "", "Recipient's public key file")
+	sealCmd.MarkFlagRequired("inkey")
+	sealCmd.MarkFlagRequired("recipient")
+
+	unsealCmd.Flags().StringP("in", "i", "", "Input file to unseal (default stdin)")
+	unsealCmd.Flags().StringP("out", "o", "", "Output file for the message (default stdout)")
+	unsealCmd.Flags().StringP("inkey", "k", "", "Recipient's private key file")
+	unsealCmd.Flags().StringP("sender", "s", "", "Sender's public key file")
+	unsealCmd.MarkFlagRequired("inkey")
+	unsealCmd.MarkFlagRequired("sender")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+)
+
+// openInput opens the named file for reading, or stdin if the name is empty
+// or "-".
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "" || name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %v", err)
+	}
+	return f, nil
+}
+
+// createOutput creates the named file for writing, or returns stdout if the
+// name is empty or "-".
+func createOutput(name string) (*os.File, error) {
+	if name == "" || name == "-" {
+		return os.Stdout, nil
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %v", err)
+	}
+	return f, nil
+}
+
+// closeOutput closes an output created by createOutput and, if it is a
+// file, reports that it was written.
+func closeOutput(out *os.File, name, what string) error {
+	if out == os.Stdout {
+		return nil
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "%s file written to %s\n", what, name)
+	return nil
+}
+
+// discardOutput closes and removes a partially written output file.
+func discardOutput(out *os.File, name string) {
+	if out == os.Stdout {
+		return
+	}
+	out.Close()
+	os.Remove(name)
+}
+
+// writeOutput writes data to the named file, or stdout, reporting it as
+// what.
+func writeOutput(name string, data []byte, what string) error {
+	out, err := createOutput(name)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(data); err != nil {
+		discardOutput(out, name)
+		return fmt.Errorf("failed to write %s file: %v", strings.ToLower(what), err)
+	}
+	return closeOutput(out, name, what)
+}
+
+// readLegacyEnvelope parses a message encrypted in one piece by earlier
+// versions, which is laid out as:
+// [rG length (4 bytes)][rG bytes][ciphertext length (4 bytes)][ciphertext bytes][nonce]
+//
+// In hybrid mode, the KEM ciphertext takes the place of rG. The cipher is
+// always AES-256-GCM, whose nonce is 12 bytes. Current versions write an
+// ecies.Container instead, or an envelope with ecies.NewEnvelopeWriter for
+// --recipient and --pad.
+func readLegacyEnvelope(input []byte) ([]byte, cryptohelpers.AES256GCMResults, error) {
+	if len(input) < 8 { // At least 4 bytes for each length
+		return nil, cryptohelpers.AES256GCMResults{}, fmt.Errorf("invalid input file format")
+	}
+
+	rGLength := uint64(binary.BigEndian.Uint32(input[:4]))
+	if uint64(len(input)) < 4+rGLength+4 {
+		return nil, cryptohelpers.AES256GCMResults{}, fmt.Errorf("invalid input file format")
+	}
+	rGBytes := input[4 : 4+rGLength]
+
+	ciphertextLength := uint64(binary.BigEndian.Uint32(input[4+rGLength : 8+rGLength]))
+	if uint64(len(input)) != 8+rGLength+ciphertextLength+12 { // 12 bytes for nonce
+		return nil, cryptohelpers.AES256GCMResults{}, fmt.Errorf("invalid input file format")
+	}
+
+	return rGBytes, cryptohelpers.AES256GCMResults{
+		CipherText: input[8+rGLength : 8+rGLength+ciphertextLength],
+		Nonce:      input[8+rGLength+ciphertextLength:],
+	}, nil
+}
+
+// copyOutput decrypts r to the named file, or stdout, removing a partially
+// written file if decryption fails.
+func copyOutput(name string, r io.Reader) error {
+	out, err := createOutput(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		// Do not leave partially decrypted, unauthenticated output behind
+		discardOutput(out, name)
+		return fmt.Errorf("failed to decrypt message: %v", err)
+	}
+	return closeOutput(out, name, "Decrypted")
+}
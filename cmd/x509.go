@@ -0,0 +1,308 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+	"github.com/spf13/cobra"
+)
+
+var reqCmd = &cobra.Command{
+	Use:   "req",
+	Short: "Certificate signing request operations",
+	Long: `Create and verify PKCS#10 certificate signing requests.
+
+With --new, a request for the key in --key is created for the subject given in
+--subj (e.g. "/C=US/O=Example/CN=alice"). Adding --x509 outputs a self-signed
+certificate instead of a request, which is useful for creating a CA.
+With --verify, the proof-of-possession signature of the request in --in is
+checked.`,
+	PreRunE: rejectArmor,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newRequest, _ := cmd.Flags().GetBool("new")
+		verify, _ := cmd.Flags().GetBool("verify")
+
+		if newRequest {
+			return newCertificateRequest(cmd)
+		}
+		if verify {
+			return verifyCertificateRequest(cmd)
+		}
+		return fmt.Errorf("either --new or --verify is required")
+	},
+}
+
+func newCertificateRequest(cmd *cobra.Command) error {
+	keyFile, _ := cmd.Flags().GetString("key")
+	subj, _ := cmd.Flags().GetString("subj")
+	outFile, _ := cmd.Flags().GetString("out")
+	selfSigned, _ := cmd.Flags().GetBool("x509")
+	days, _ := cmd.Flags().GetInt("days")
+
+	if keyFile == "" {
+		return fmt.Errorf("private key file is required")
+	}
+	if outFile == "" {
+		return fmt.Errorf("output file is required")
+	}
+
+	subject, err := parseSubject(subj)
+	if err != nil {
+		return err
+	}
+
+	// Read private key
+	keyBytes, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %v", err)
+	}
+
+	// Parse private key
+	privateKey, err := ecc.UnmarshalPEM(keyBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	var pemBytes []byte
+	if selfSigned {
+		publicKey, err := privateKey.DerivePublicKey()
+		if err != nil {
+			return fmt.Errorf("failed to derive public key: %v", err)
+		}
+
+		serialNumber, err := randomSerialNumber()
+		if err != nil {
+			return err
+		}
+
+		template := &x509.Certificate{
+			SerialNumber:          serialNumber,
+			Subject:               subject,
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().AddDate(0, 0, days),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		der, err := x509.CreateCertificate(template, template, publicKey, privateKey)
+		if err != nil {
+			return fmt.Errorf("failed to create certificate: %v", err)
+		}
+		pemBytes = (&x509.Certificate{Raw: der}).MarshalPEM()
+	} else {
+		der, err := x509.CreateCertificateRequest(&x509.CertificateRequest{Subject: subject}, privateKey)
+		if err != nil {
+			return fmt.Errorf("failed to create certificate request: %v", err)
+		}
+		pemBytes = (&x509.CertificateRequest{Raw: der}).MarshalPEM()
+	}
+
+	if err := os.WriteFile(outFile, pemBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+
+	if selfSigned {
+		fmt.Printf("Certificate written to %s\n", outFile)
+	} else {
+		fmt.Printf("Certificate request written to %s\n", outFile)
+	}
+	return nil
+}
+
+func verifyCertificateRequest(cmd *cobra.Command) error {
+	inFile, _ := cmd.Flags().GetString("in")
+	if inFile == "" {
+		return fmt.Errorf("input file is required")
+	}
+
+	pemBytes, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate request: %v", err)
+	}
+
+	csr, err := x509.ParseCertificateRequestPEM(pemBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate request: %v", err)
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		fmt.Println("Certificate request signature is invalid")
+		os.Exit(1)
+	}
+
+	fmt.Println("Certificate request signature is valid")
+	return nil
+}
+
+var x509Cmd = &cobra.Command{
+	Use:   "x509",
+	Short: "Certificate operations",
+	Long: `Issue certificates.
+
+With --req, the certificate request in --in is checked for proof of possession
+and signed by the CA certificate in --CA using the CA private key in --CAkey.`,
+	PreRunE: rejectArmor,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromRequest, _ := cmd.Flags().GetBool("req")
+		inFile, _ := cmd.Flags().GetString("in")
+		outFile, _ := cmd.Flags().GetString("out")
+		caFile, _ := cmd.Flags().GetString("CA")
+		caKeyFile, _ := cmd.Flags().GetString("CAkey")
+		days, _ := cmd.Flags().GetInt("days")
+		serial, _ := cmd.Flags().GetString("set_serial")
+
+		if !fromRequest {
+			return fmt.Errorf("currently only --req is supported")
+		}
+		if inFile == "" {
+			return fmt.Errorf("input file is required")
+		}
+		if outFile == "" {
+			return fmt.Errorf("output file is required")
+		}
+		if caFile == "" {
+			return fmt.Errorf("CA certificate file is required")
+		}
+		if caKeyFile == "" {
+			return fmt.Errorf("CA private key file is required")
+		}
+
+		// Read and check certificate request
+		csrBytes, err := os.ReadFile(inFile)
+		if err != nil {
+			return fmt.Errorf("failed to read certificate request: %v", err)
+		}
+		csr, err := x509.ParseCertificateRequestPEM(csrBytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate request: %v", err)
+		}
+		if err := csr.CheckSignature(); err != nil {
+			return fmt.Errorf("certificate request signature is invalid: %v", err)
+		}
+
+		// Read CA certificate
+		caBytes, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificate: %v", err)
+		}
+		ca, err := x509.ParseCertificatePEM(caBytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse CA certificate: %v", err)
+		}
+
+		// Read CA private key
+		caKeyBytes, err := os.ReadFile(caKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA private key: %v", err)
+		}
+		caKey, err := ecc.UnmarshalPEM(caKeyBytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse CA private key: %v", err)
+		}
+
+		var serialNumber *big.Int
+		if serial != "" {
+			var ok bool
+			serialNumber, ok = new(big.Int).SetString(serial, 0)
+			if !ok {
+				return fmt.Errorf("invalid serial number: %s", serial)
+			}
+		} else if serialNumber, err = randomSerialNumber(); err != nil {
+			return err
+		}
+
+		template := &x509.Certificate{
+			SerialNumber: serialNumber,
+			RawSubject:   csr.RawSubject,
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().AddDate(0, 0, days),
+			KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+		}
+		der, err := x509.CreateCertificate(template, ca, csr.PublicKey, caKey)
+		if err != nil {
+			return fmt.Errorf("failed to create certificate: %v", err)
+		}
+
+		if err := os.WriteFile(outFile, (&x509.Certificate{Raw: der}).MarshalPEM(), 0644); err != nil {
+			return fmt.Errorf("failed to write certificate: %v", err)
+		}
+
+		fmt.Printf("Certificate written to %s\n", outFile)
+		return nil
+	},
+}
+
+// parseSubject parses an OpenSSL-style subject such as
+// "/C=US/O=Example/CN=alice" into a pkix.Name.
+func parseSubject(subj string) (pkix.Name, error) {
+	var name pkix.Name
+	for _, component := range strings.Split(subj, "/") {
+		if component == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(component, "=")
+		if !ok {
+			return pkix.Name{}, fmt.Errorf("invalid subject component: %s", component)
+		}
+		switch key {
+		case "C":
+			name.Country = append(name.Country, value)
+		case "ST":
+			name.Province = append(name.Province, value)
+		case "L":
+			name.Locality = append(name.Locality, value)
+		case "O":
+			name.Organization = append(name.Organization, value)
+		case "OU":
+			name.OrganizationalUnit = append(name.OrganizationalUnit, value)
+		case "CN":
+			name.CommonName = value
+		case "serialNumber":
+			name.SerialNumber = value
+		default:
+			return pkix.Name{}, fmt.Errorf("unsupported subject attribute: %s", key)
+		}
+	}
+	return name, nil
+}
+
+func randomSerialNumber() (*big.Int, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+	return serialNumber, nil
+}
+
+func init() {
+	rootCmd.AddCommand(reqCmd)
+	rootCmd.AddCommand(x509Cmd)
+
+	reqCmd.Flags().Bool("new", false, "Create a new certificate request")
+	reqCmd.Flags().Bool("verify", false, "Verify the signature of a certificate request")
+	reqCmd.Flags().Bool("x509", false, "Output a self-signed certificate instead of a request")
+	reqCmd.Flags().StringP("key", "k", "", "Private key file")
+	reqCmd.Flags().String("subj", "", "Subject name, e.g. /C=US/O=Example/CN=alice")
+	reqCmd.Flags().StringP("in", "i", "", "Input file containing a certificate request")
+	reqCmd.Flags().StringP("out", "o", "", "Output file for the request or certificate")
+	reqCmd.Flags().Int("days", 30, "Number of days a self-signed certificate is valid for")
+
+	x509Cmd.Flags().Bool("req", false, "Input is a certificate request")
+	x509Cmd.Flags().StringP("in", "i", "", "Input file containing a certificate request")
+	x509Cmd.Flags().StringP("out", "o", "", "Output file for certificate")
+	x509Cmd.Flags().String("CA", "", "CA certificate file")
+	x509Cmd.Flags().String("CAkey", "", "CA private key file")
+	x509Cmd.Flags().Int("days", 30, "Number of days the certificate is valid for")
+	x509Cmd.Flags().String("set_serial", "", "Serial number to use instead of a random one")
+	x509Cmd.MarkFlagRequired("in")
+	x509Cmd.MarkFlagRequired("out")
+	x509Cmd.MarkFlagRequired("CA")
+	x509Cmd.MarkFlagRequired("CAkey")
+}
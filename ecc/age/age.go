@@ -0,0 +1,163 @@
+package age
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const fileKeySize = 16
+
+// ErrIncorrectIdentity is returned by Identity.Unwrap when none of the
+// stanzas are addressed to the identity.
+var ErrIncorrectIdentity = errors.New("age: incorrect identity for recipient block")
+
+// NoIdentityMatchError is returned by Decrypt when none of the identities
+// can unwrap the file key.
+type NoIdentityMatchError struct {
+	// Errors are the errors returned by each identity, other than
+	// ErrIncorrectIdentity.
+	Errors []error
+}
+
+func (e *NoIdentityMatchError) Error() string {
+	if len(e.Errors) == 1 {
+		return "age: no identity matched any of the recipients: " + e.Errors[0].Error()
+	}
+	return "age: no identity matched any of the recipients"
+}
+
+// Recipient is the public side of an age key pair: something a file key can
+// be wrapped for.
+type Recipient interface {
+	Wrap(fileKey []byte) ([]*Stanza, error)
+}
+
+// Identity is the private side of an age key pair: something that can
+// unwrap a file key from the header stanzas addressed to it.
+//
+// Unwrap must return ErrIncorrectIdentity if none of the stanzas are
+// addressed to the identity, and another error if one of them is but cannot
+// be unwrapped.
+type Identity interface {
+	Unwrap(stanzas []*Stanza) ([]byte, error)
+}
+
+func streamKey(fileKey, nonce []byte) []byte {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, fileKey, nonce, []byte("payload")), key); err != nil {
+		panic("age: internal error: failed to derive payload key: " + err.Error())
+	}
+	return key
+}
+
+func headerMAC(fileKey []byte, h *header) ([]byte, error) {
+	hmacKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, fileKey, nil, []byte("header")), hmacKey); err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, hmacKey)
+	if err := h.marshalWithoutMAC(mac); err != nil {
+		return nil, err
+	}
+	return mac.Sum(nil), nil
+}
+
+// Encrypt encrypts a file to one or more recipients. Writes to the returned
+// WriteCloser are encrypted and written to dst as an age file. Every
+// recipient can decrypt the file.
+//
+// The caller must call Close on the WriteCloser when done for the last chunk
+// to be encrypted and flushed to dst.
+func Encrypt(dst io.Writer, recipients ...Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("age: no recipients specified")
+	}
+
+	fileKey := make([]byte, fileKeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, err
+	}
+
+	h := &header{}
+	for i, r := range recipients {
+		stanzas, err := r.Wrap(fileKey)
+		if err != nil {
+			return nil, fmt.Errorf("age: failed to wrap key for recipient #%d: %v", i, err)
+		}
+		h.recipients = append(h.recipients, stanzas...)
+	}
+
+	mac, err := headerMAC(fileKey, h)
+	if err != nil {
+		return nil, err
+	}
+	h.mac = mac
+	if err := h.marshal(dst); err != nil {
+		return nil, fmt.Errorf("age: failed to write header: %v", err)
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(nonce); err != nil {
+		return nil, fmt.Errorf("age: failed to write nonce: %v", err)
+	}
+
+	return newStreamWriter(streamKey(fileKey, nonce), dst)
+}
+
+// Decrypt decrypts a file encrypted to one or more identities. It returns a
+// Reader reading the decrypted plaintext of the age file read from src.
+//
+// The header is authenticated before Decrypt returns, and the payload is
+// authenticated chunk by chunk as it is read; a Read error means the file
+// was truncated or modified.
+func Decrypt(src io.Reader, identities ...Identity) (io.Reader, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("age: no identities specified")
+	}
+
+	h, payload, err := parseHeader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileKey []byte
+	var errs []error
+	for _, id := range identities {
+		fileKey, err = id.Unwrap(h.recipients)
+		if errors.Is(err, ErrIncorrectIdentity) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		break
+	}
+	if fileKey == nil {
+		return nil, &NoIdentityMatchError{Errors: errs}
+	}
+
+	mac, err := headerMAC(fileKey, h)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, h.mac) {
+		return nil, errors.New("age: bad header MAC")
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := io.ReadFull(payload, nonce); err != nil {
+		return nil, fmt.Errorf("age: failed to read nonce: %v", err)
+	}
+
+	return newStreamReader(streamKey(fileKey, nonce), payload)
+}
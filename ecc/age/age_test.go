@@ -0,0 +1,293 @@
+package age_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/age"
+)
+
+func generateIdentity(t *testing.T) *age.EccFrog512ck2Identity {
+	t.Helper()
+	id, err := age.GenerateEccFrog512ck2Identity()
+	if err != nil {
+		t.Fatalf("Failed to generate identity: %v", err)
+	}
+	return id
+}
+
+func encrypt(t *testing.T, plaintext []byte, recipients ...age.Recipient) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w, err := age.Encrypt(&b, recipients...)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatalf("Failed to write plaintext: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	return b.Bytes()
+}
+
+func decrypt(ciphertext []byte, identities ...age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	alice := generateIdentity(t)
+	bob := generateIdentity(t)
+
+	sizes := []int{0, 1, 1000, 64 * 1024, 64*1024 + 1, 3*64*1024 + 17}
+	for _, size := range sizes {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+		ciphertext := encrypt(t, plaintext, alice.Recipient(), bob.Recipient())
+
+		for _, id := range []*age.EccFrog512ck2Identity{alice, bob} {
+			decrypted, err := decrypt(ciphertext, id)
+			if err != nil {
+				t.Fatalf("Failed to decrypt %d bytes: %v", size, err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Decrypted %d bytes do not match plaintext", size)
+			}
+		}
+	}
+}
+
+func TestDecryptWrongIdentity(t *testing.T) {
+	ciphertext := encrypt(t, []byte("secret"), generateIdentity(t).Recipient())
+
+	_, err := decrypt(ciphertext, generateIdentity(t))
+	var noMatch *age.NoIdentityMatchError
+	if !errors.As(err, &noMatch) {
+		t.Errorf("Expected NoIdentityMatchError, got %v", err)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	id := generateIdentity(t)
+	ciphertext := encrypt(t, []byte("attack at dawn"), id.Recipient())
+
+	// Changing the last character of the header MAC, before the newline
+	headerEnd := bytes.Index(ciphertext, []byte("\n---")) + 1
+	macEnd := headerEnd + bytes.IndexByte(ciphertext[headerEnd:], '\n')
+	tampered := append([]byte{}, ciphertext...)
+	if tampered[macEnd-1] == 'A' {
+		tampered[macEnd-1] = 'Q'
+	} else {
+		tampered[macEnd-1] = 'A'
+	}
+	if _, err := decrypt(tampered, id); err == nil {
+		t.Error("Expected error for tampered header MAC")
+	}
+
+	tampered = append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := decrypt(tampered, id); err == nil {
+		t.Error("Expected error for tampered payload")
+	}
+
+	if _, err := decrypt(ciphertext[:len(ciphertext)-1], id); err == nil {
+		t.Error("Expected error for truncated payload")
+	}
+}
+
+func TestDecryptTruncatedAtChunkBoundary(t *testing.T) {
+	id := generateIdentity(t)
+	plaintext := make([]byte, 2*64*1024)
+	ciphertext := encrypt(t, plaintext, id.Recipient())
+
+	// Dropping the last full chunk leaves a valid-looking first chunk that
+	// was not encrypted as the last one.
+	if _, err := decrypt(ciphertext[:len(ciphertext)-(64*1024+16)], id); err == nil {
+		t.Error("Expected error for payload truncated at a chunk boundary")
+	}
+}
+
+func TestRecipientAndIdentityEncoding(t *testing.T) {
+	id := generateIdentity(t)
+
+	s := id.String()
+	if !strings.HasPrefix(s, "AGE-PLUGIN-ECCFROG512CK2-1") {
+		t.Errorf("Unexpected identity prefix: %s", s)
+	}
+	parsedId, err := age.ParseEccFrog512ck2Identity(s)
+	if err != nil {
+		t.Fatalf("Failed to parse identity: %v", err)
+	}
+	if parsedId.String() != s {
+		t.Error("Identity does not round trip")
+	}
+
+	r := id.Recipient().String()
+	if !strings.HasPrefix(r, "age1eccfrog512ck21") {
+		t.Errorf("Unexpected recipient prefix: %s", r)
+	}
+	parsedRecipient, err := age.ParseEccFrog512ck2Recipient(r)
+	if err != nil {
+		t.Fatalf("Failed to parse recipient: %v", err)
+	}
+	if !parsedRecipient.PublicKey().Equal(id.Recipient().PublicKey()) {
+		t.Error("Recipient does not round trip")
+	}
+
+	// A single changed character breaks the checksum
+	corrupted := r[:len(r)-1] + "q"
+	if corrupted == r {
+		corrupted = r[:len(r)-1] + "p"
+	}
+	if _, err := age.ParseEccFrog512ck2Recipient(corrupted); err == nil {
+		t.Error("Expected error for corrupted recipient")
+	}
+	if _, err := age.ParseEccFrog512ck2Recipient(s); err == nil {
+		t.Error("Expected error for identity parsed as recipient")
+	}
+}
+
+func TestParseIdentities(t *testing.T) {
+	alice := generateIdentity(t)
+	bob := generateIdentity(t)
+	file := "# created: today\n" + alice.String() + "\n\n" + bob.String() + "\n"
+
+	ids, err := age.ParseIdentities(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Failed to parse identities: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 identities, got %d", len(ids))
+	}
+
+	if _, err := age.ParseIdentities(strings.NewReader("# nothing here\n")); err == nil {
+		t.Error("Expected error for empty identity file")
+	}
+}
+
+// pluginSession runs a plugin state machine on the given client commands,
+// answering every phase two command with "ok", and returns the stanzas the
+// plugin sent.
+func pluginSession(t *testing.T, stateMachine, commands string) []*age.Stanza {
+	t.Helper()
+
+	clientIn, pluginOut := io.Pipe()
+	pluginIn, clientOut := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- age.RunPlugin(stateMachine, pluginIn, pluginOut)
+		pluginOut.Close()
+	}()
+
+	go func() {
+		io.WriteString(clientOut, commands)
+	}()
+
+	var stanzas []*age.Stanza
+	r := bufio.NewReader(clientIn)
+	for {
+		s := readPluginStanza(t, r)
+		if s.Type == "done" {
+			break
+		}
+		stanzas = append(stanzas, s)
+		io.WriteString(clientOut, "-> ok\n\n")
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Plugin failed: %v", err)
+	}
+	return stanzas
+}
+
+func readPluginStanza(t *testing.T, r *bufio.Reader) *age.Stanza {
+	t.Helper()
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read from plugin: %v", err)
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "-> "))
+	s := &age.Stanza{Type: fields[0], Args: fields[1:]}
+	var body strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read from plugin: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		body.WriteString(line)
+		if len(line) < 64 {
+			break
+		}
+	}
+	s.Body = []byte(body.String())
+	return s
+}
+
+func TestPluginProtocol(t *testing.T) {
+	id := generateIdentity(t)
+	fileKey := "AAECAwQFBgcICQoLDA0ODw" // 00 01 ... 0f
+
+	stanzas := pluginSession(t, "recipient-v1",
+		"-> add-recipient "+id.Recipient().String()+"\n\n"+
+			"-> grease-x y\nAAAA\n"+
+			"-> wrap-file-key\n"+fileKey+"\n"+
+			"-> done\n\n")
+	if len(stanzas) != 1 || stanzas[0].Type != "recipient-stanza" {
+		t.Fatalf("Expected one recipient-stanza, got %v", stanzas)
+	}
+	args := stanzas[0].Args
+	if len(args) != 3 || args[0] != "0" || args[1] != age.StanzaType {
+		t.Fatalf("Unexpected recipient-stanza arguments %v", args)
+	}
+
+	stanzas = pluginSession(t, "identity-v1",
+		"-> add-identity "+id.String()+"\n\n"+
+			"-> recipient-stanza 0 "+args[1]+" "+args[2]+"\n"+string(stanzas[0].Body)+"\n"+
+			"-> done\n\n")
+	if len(stanzas) != 1 || stanzas[0].Type != "file-key" {
+		t.Fatalf("Expected one file-key, got %v", stanzas)
+	}
+	if string(stanzas[0].Body) != fileKey {
+		t.Errorf("Unwrapped file key %s, expected %s", stanzas[0].Body, fileKey)
+	}
+
+	stanzas = pluginSession(t, "recipient-v1",
+		"-> add-recipient age1eccfrog512ck21invalid\n\n"+
+			"-> wrap-file-key\n"+fileKey+"\n"+
+			"-> done\n\n")
+	if len(stanzas) != 1 || stanzas[0].Type != "error" || stanzas[0].Args[0] != "recipient" {
+		t.Errorf("Expected a recipient error, got %v", stanzas)
+	}
+
+	// Recipients and identities are indexed separately
+	stanzas = pluginSession(t, "recipient-v1",
+		"-> add-recipient "+id.Recipient().String()+"\n\n"+
+			"-> add-identity "+id.String()+"\n\n"+
+			"-> add-recipient "+id.Recipient().String()+"\n\n"+
+			"-> add-identity AGE-PLUGIN-ECCFROG512CK2-1INVALID\n\n"+
+			"-> wrap-file-key\n"+fileKey+"\n"+
+			"-> done\n\n")
+	if len(stanzas) != 1 || stanzas[0].Type != "error" || len(stanzas[0].Args) != 2 ||
+		stanzas[0].Args[0] != "identity" || stanzas[0].Args[1] != "1" {
+		t.Errorf("Expected an error for identity 1, got %v", stanzas)
+	}
+	stanzas = pluginSession(t, "recipient-v1",
+		"-> add-identity "+id.String()+"\n\n"+
+			"-> add-recipient age1eccfrog512ck21invalid\n\n"+
+			"-> wrap-file-key\n"+fileKey+"\n"+
+			"-> done\n\n")
+	if len(stanzas) != 1 || stanzas[0].Type != "error" || len(stanzas[0].Args) != 2 ||
+		stanzas[0].Args[0] != "recipient" || stanzas[0].Args[1] != "0" {
+		t.Errorf("Expected an error for recipient 0, got %v", stanzas)
+	}
+}
@@ -0,0 +1,144 @@
+package age
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Bech32 encoding as specified in BIP 173, which age uses for recipients and
+// identities. Unlike BIP 173, strings are not limited to 90 characters.
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= bech32Generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	h := []byte(strings.ToLower(hrp))
+	var ret []byte
+	for _, c := range h {
+		ret = append(ret, c>>5)
+	}
+	ret = append(ret, 0)
+	for _, c := range h {
+		ret = append(ret, c&31)
+	}
+	return ret
+}
+
+// convertBits regroups data from frombits-bit groups into tobits-bit
+// groups.
+func convertBits(data []byte, frombits, tobits byte, pad bool) ([]byte, error) {
+	var ret []byte
+	acc, bits := uint32(0), byte(0)
+	maxv := byte(1<<tobits - 1)
+	for _, value := range data {
+		if value>>frombits != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<frombits | uint32(value)
+		bits += frombits
+		for bits >= tobits {
+			bits -= tobits
+			ret = append(ret, byte(acc>>bits)&maxv)
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte(acc<<(tobits-bits))&maxv)
+		}
+	} else if bits >= frombits {
+		return nil, errors.New("illegal zero padding")
+	} else if byte(acc<<(tobits-bits))&maxv != 0 {
+		return nil, errors.New("non-zero padding")
+	}
+	return ret, nil
+}
+
+// bech32Encode encodes data with the human-readable part hrp. The case of
+// the result follows the case of hrp.
+func bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	if len(hrp) < 1 {
+		return "", errors.New("bech32: empty human-readable part")
+	}
+	for _, c := range hrp {
+		if c < 33 || c > 126 {
+			return "", fmt.Errorf("bech32: invalid human-readable part character %q", c)
+		}
+	}
+	if strings.ToLower(hrp) != hrp && strings.ToUpper(hrp) != hrp {
+		return "", errors.New("bech32: mixed case human-readable part")
+	}
+	lower := strings.ToLower(hrp) == hrp
+
+	polymod := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := 0; i < 6; i++ {
+		values = append(values, byte(polymod>>uint(5*(5-i)))&31)
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.ToLower(hrp))
+	b.WriteByte('1')
+	for _, v := range values {
+		b.WriteByte(bech32Charset[v])
+	}
+	if lower {
+		return b.String(), nil
+	}
+	return strings.ToUpper(b.String()), nil
+}
+
+// bech32Decode decodes a Bech32 string, returning its human-readable part in
+// the case it was written in.
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errors.New("bech32: mixed case")
+	}
+	pos := strings.LastIndex(s, "1")
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, errors.New("bech32: separator '1' at invalid position")
+	}
+	hrp := s[:pos]
+	for _, c := range hrp {
+		if c < 33 || c > 126 {
+			return "", nil, fmt.Errorf("bech32: invalid human-readable part character %q", c)
+		}
+	}
+
+	lower := strings.ToLower(s)
+	var values []byte
+	for _, c := range lower[pos+1:] {
+		d := strings.IndexRune(bech32Charset, c)
+		if d == -1 {
+			return "", nil, fmt.Errorf("bech32: invalid character %q", c)
+		}
+		values = append(values, byte(d))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, errors.New("bech32: invalid checksum")
+	}
+
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, fmt.Errorf("bech32: %v", err)
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,17 @@
+// Package age implements the age v1 file encryption format
+// (https://age-encryption.org/v1) with an EccFrog512ck2 recipient type.
+//
+// An age file starts with a text header holding one stanza per recipient,
+// each wrapping the random file key, followed by an HMAC-SHA256 of the header.
+// The payload is encrypted with ChaCha20-Poly1305 in 64 KiB chunks using the
+// STREAM construction, so files of any size can be encrypted and decrypted
+// without holding them in memory.
+//
+// EccFrog512ck2 recipients wrap the file key with an ephemeral ECDH exchange,
+// in the same way age's native X25519 recipients do. Recipients are Bech32
+// strings starting with "age1eccfrog512ck2" and identities are Bech32
+// strings starting with "AGE-PLUGIN-ECCFROG512CK2-", which are the formats
+// age uses for plugins. Other age implementations can therefore encrypt and
+// decrypt these files through the age-plugin-eccfrog512ck2 binary, whose
+// protocol is implemented by RunPlugin.
+package age
@@ -0,0 +1,265 @@
+package age
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// StanzaType is the type of the header stanzas that wrap a file key for
+	// an EccFrog512ck2 recipient.
+	StanzaType = "eccfrog512ck2"
+
+	// PluginName is the name of the age plugin, which determines the
+	// prefixes of recipients and identities and the name of the plugin
+	// binary, age-plugin-eccfrog512ck2.
+	PluginName = "eccfrog512ck2"
+
+	recipientHRP = "age1" + PluginName
+	identityHRP  = "AGE-PLUGIN-ECCFROG512CK2-"
+
+	wrapLabel = "age-encryption.org/v1/" + StanzaType
+
+	compressedPointSize = 65
+)
+
+// EccFrog512ck2Recipient is an EccFrog512ck2 public key that files can be encrypted to.
+//
+// Its stanza is modelled on age's X25519 stanza:
+//
+//	-> eccfrog512ck2 <ephemeral public key>
+//	<wrapped file key>
+//
+// where the ephemeral public key is a compressed SEC1 point, and the file
+// key is encrypted with ChaCha20-Poly1305 and an all-zero nonce, under a key
+// derived with HKDF-SHA256 from the ECDH shared secret, salted with the
+// ephemeral and recipient public keys.
+type EccFrog512ck2Recipient struct {
+	publicKey eccfrog512ck2.CurvePoint
+}
+
+var _ Recipient = &EccFrog512ck2Recipient{}
+
+// NewEccFrog512ck2Recipient returns a recipient for publicKey.
+func NewEccFrog512ck2Recipient(publicKey eccfrog512ck2.CurvePoint) (*EccFrog512ck2Recipient, error) {
+	if _, _, ok := publicKey.CoordinateIfNotInfinity(); !ok {
+		return nil, errors.New("age: recipient public key is the point at infinity")
+	}
+	return &EccFrog512ck2Recipient{publicKey: publicKey}, nil
+}
+
+// ParseEccFrog512ck2Recipient parses a Bech32 recipient string such as
+// "age1eccfrog512ck21...".
+func ParseEccFrog512ck2Recipient(s string) (*EccFrog512ck2Recipient, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("age: malformed recipient %q: %v", s, err)
+	}
+	if hrp != recipientHRP {
+		return nil, fmt.Errorf("age: malformed recipient %q: unexpected type %q", s, hrp)
+	}
+	publicKey, err := ecc.ParsePublicKeySEC1(data)
+	if err != nil || len(data) != compressedPointSize {
+		return nil, fmt.Errorf("age: malformed recipient %q: invalid public key", s)
+	}
+	return NewEccFrog512ck2Recipient(publicKey)
+}
+
+// String returns the Bech32 encoding of the recipient.
+func (r *EccFrog512ck2Recipient) String() string {
+	s, _ := bech32Encode(recipientHRP, r.publicKey.MarshalSEC1(true))
+	return s
+}
+
+// PublicKey returns the public key of the recipient.
+func (r *EccFrog512ck2Recipient) PublicKey() eccfrog512ck2.CurvePoint {
+	return r.publicKey
+}
+
+func wrappingKey(sharedSecret, ephemeralKey, recipientKey []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeralKey...), recipientKey...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, salt, []byte(wrapLabel)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Wrap wraps fileKey for the recipient, using a fresh ephemeral key.
+func (r *EccFrog512ck2Recipient) Wrap(fileKey []byte) ([]*Stanza, error) {
+	ephemeralPrivateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPublicKey, err := ephemeralPrivateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := ecdh.ECDHPrivateKey(ephemeralPrivateKey).DeriveFixedSizeSharedSecret(r.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralKey := ephemeralPublicKey.MarshalSEC1(true)
+	key, err := wrappingKey(sharedSecret, ephemeralKey, r.publicKey.MarshalSEC1(true))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Stanza{{
+		Type: StanzaType,
+		Args: []string{b64.EncodeToString(ephemeralKey)},
+		Body: aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil),
+	}}, nil
+}
+
+// EccFrog512ck2Identity is an EccFrog512ck2 private key that files can be
+// decrypted with.
+type EccFrog512ck2Identity struct {
+	privateKey ecc.PrivateKey
+	publicKey  eccfrog512ck2.CurvePoint
+}
+
+var _ Identity = &EccFrog512ck2Identity{}
+
+// NewEccFrog512ck2Identity returns an identity for privateKey.
+func NewEccFrog512ck2Identity(privateKey ecc.PrivateKey) (*EccFrog512ck2Identity, error) {
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &EccFrog512ck2Identity{privateKey: privateKey, publicKey: publicKey}, nil
+}
+
+// GenerateEccFrog512ck2Identity generates a new random identity.
+func GenerateEccFrog512ck2Identity() (*EccFrog512ck2Identity, error) {
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return NewEccFrog512ck2Identity(privateKey)
+}
+
+// ParseEccFrog512ck2Identity parses a Bech32 identity string such as
+// "AGE-PLUGIN-ECCFROG512CK2-1...".
+func ParseEccFrog512ck2Identity(s string) (*EccFrog512ck2Identity, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("age: malformed identity: %v", err)
+	}
+	if !strings.EqualFold(hrp, identityHRP) {
+		return nil, fmt.Errorf("age: malformed identity: unexpected type %q", hrp)
+	}
+	if len(data) != 64 {
+		return nil, errors.New("age: malformed identity: invalid private key length")
+	}
+	privateKey, err := ecc.ParsePrivateKeySEC1(data)
+	if err != nil {
+		return nil, fmt.Errorf("age: malformed identity: %v", err)
+	}
+	return NewEccFrog512ck2Identity(privateKey)
+}
+
+// String returns the Bech32 encoding of the identity, which is how it is
+// stored in identity files.
+func (i *EccFrog512ck2Identity) String() string {
+	scalar := i.privateKey.MarshalSEC1(false)
+	s, _ := bech32Encode(identityHRP, append(make([]byte, 64-len(scalar)), scalar...))
+	return s
+}
+
+// Recipient returns the recipient that files for this identity are
+// encrypted to.
+func (i *EccFrog512ck2Identity) Recipient() *EccFrog512ck2Recipient {
+	return &EccFrog512ck2Recipient{publicKey: i.publicKey}
+}
+
+// Unwrap returns the file key from the first stanza addressed to the
+// identity.
+func (i *EccFrog512ck2Identity) Unwrap(stanzas []*Stanza) ([]byte, error) {
+	for _, s := range stanzas {
+		fileKey, err := i.unwrap(s)
+		if errors.Is(err, ErrIncorrectIdentity) {
+			continue
+		}
+		return fileKey, err
+	}
+	return nil, ErrIncorrectIdentity
+}
+
+func (i *EccFrog512ck2Identity) unwrap(s *Stanza) ([]byte, error) {
+	if s.Type != StanzaType {
+		return nil, ErrIncorrectIdentity
+	}
+	if len(s.Args) != 1 {
+		return nil, errors.New("age: invalid eccfrog512ck2 recipient block")
+	}
+	ephemeralKey, err := b64.DecodeString(s.Args[0])
+	if err != nil || len(ephemeralKey) != compressedPointSize {
+		return nil, errors.New("age: invalid eccfrog512ck2 recipient block")
+	}
+	ephemeralPublicKey, err := ecc.ParsePublicKeySEC1(ephemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("age: invalid eccfrog512ck2 recipient block: %v", err)
+	}
+	if len(s.Body) != fileKeySize+chacha20poly1305.Overhead {
+		return nil, errors.New("age: invalid eccfrog512ck2 recipient block")
+	}
+
+	sharedSecret, err := ecdh.ECDHPrivateKey(i.privateKey).DeriveFixedSizeSharedSecret(ephemeralPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("age: invalid eccfrog512ck2 recipient block: %v", err)
+	}
+	key, err := wrappingKey(sharedSecret, ephemeralKey, i.publicKey.MarshalSEC1(true))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), s.Body, nil)
+	if err != nil {
+		// The stanza is for a different key.
+		return nil, ErrIncorrectIdentity
+	}
+	return fileKey, nil
+}
+
+// ParseIdentities parses an identity file: one identity per line, with
+// blank lines and lines starting with "#" ignored.
+func ParseIdentities(f io.Reader) ([]*EccFrog512ck2Identity, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	var ids []*EccFrog512ck2Identity
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := ParseEccFrog512ck2Identity(line)
+		if err != nil {
+			return nil, fmt.Errorf("age: error at line %d: %v", n+1, err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("age: no identities found")
+	}
+	return ids, nil
+}
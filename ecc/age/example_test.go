@@ -0,0 +1,53 @@
+package age_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/age"
+)
+
+func ExampleEncrypt() {
+	// Alice generates an identity and shares its recipient
+	identity, err := age.GenerateEccFrog512ck2Identity()
+	if err != nil {
+		fmt.Println("Failed to generate identity:", err)
+		return
+	}
+	recipient, err := age.ParseEccFrog512ck2Recipient(identity.Recipient().String())
+	if err != nil {
+		fmt.Println("Failed to parse recipient:", err)
+		return
+	}
+
+	// Bob encrypts a file to Alice
+	var file bytes.Buffer
+	w, err := age.Encrypt(&file, recipient)
+	if err != nil {
+		fmt.Println("Failed to encrypt:", err)
+		return
+	}
+	if _, err := io.WriteString(w, "Hello, Alice!"); err != nil {
+		fmt.Println("Failed to write:", err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		fmt.Println("Failed to close:", err)
+		return
+	}
+
+	// Alice decrypts it
+	r, err := age.Decrypt(&file, identity)
+	if err != nil {
+		fmt.Println("Failed to decrypt:", err)
+		return
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		fmt.Println("Failed to read:", err)
+		return
+	}
+	fmt.Println(string(plaintext))
+	// Output: Hello, Alice!
+}
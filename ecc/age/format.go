@@ -0,0 +1,187 @@
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	intro        = "age-encryption.org/v1\n"
+	stanzaPrefix = "-> "
+	footerPrefix = "---"
+
+	// columnsPerLine is the width of wrapped stanza bodies.
+	columnsPerLine = 64
+	bytesPerLine   = columnsPerLine / 4 * 3
+)
+
+var b64 = base64.RawStdEncoding.Strict()
+
+// Stanza is a section of the age header that wraps the file key for one
+// recipient, or a message of the plugin protocol, which uses the same
+// encoding.
+type Stanza struct {
+	Type string
+	Args []string
+	Body []byte
+}
+
+// marshal writes the stanza. The body is always terminated by a line shorter
+// than 64 columns, which is empty if the body is a multiple of 48 bytes.
+func (s *Stanza) marshal(w io.Writer) error {
+	if _, err := io.WriteString(w, stanzaPrefix+s.Type); err != nil {
+		return err
+	}
+	for _, arg := range s.Args {
+		if _, err := io.WriteString(w, " "+arg); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return err
+	}
+	body := b64.EncodeToString(s.Body)
+	for len(body) >= columnsPerLine {
+		if _, err := io.WriteString(w, body[:columnsPerLine]+"\n"); err != nil {
+			return err
+		}
+		body = body[columnsPerLine:]
+	}
+	_, err := io.WriteString(w, body+"\n")
+	return err
+}
+
+func isValidString(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range []byte(s) {
+		if c < 33 || c > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+// readStanza reads a stanza whose "-> " line has already been read.
+func readStanza(r *bufio.Reader, line string) (*Stanza, error) {
+	args := strings.Split(strings.TrimPrefix(line, stanzaPrefix), " ")
+	for _, arg := range args {
+		if !isValidString(arg) {
+			return nil, fmt.Errorf("age: malformed stanza line %q", line)
+		}
+	}
+	s := &Stanza{Type: args[0], Args: args[1:]}
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		b, err := b64.DecodeString(line)
+		if err != nil || len(line) > columnsPerLine {
+			return nil, fmt.Errorf("age: malformed stanza body line %q", line)
+		}
+		s.Body = append(s.Body, b...)
+		if len(line) < columnsPerLine {
+			return s, nil
+		}
+	}
+}
+
+// readLine reads a line terminated by a line feed, and returns it without
+// the line feed.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+type header struct {
+	recipients []*Stanza
+	mac        []byte
+}
+
+// marshalWithoutMAC writes the header up to and including the "---" that
+// starts the footer, which is the part covered by the MAC.
+func (h *header) marshalWithoutMAC(w io.Writer) error {
+	if _, err := io.WriteString(w, intro); err != nil {
+		return err
+	}
+	for _, s := range h.recipients {
+		if err := s.marshal(w); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, footerPrefix)
+	return err
+}
+
+func (h *header) marshal(w io.Writer) error {
+	if err := h.marshalWithoutMAC(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, " "+b64.EncodeToString(h.mac)+"\n")
+	return err
+}
+
+// parseHeader reads the header from r, returning it and a reader for the
+// payload that follows.
+func parseHeader(input io.Reader) (*header, io.Reader, error) {
+	r := bufio.NewReader(input)
+	h := &header{}
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, nil, fmt.Errorf("age: failed to read intro: %v", err)
+	}
+	if line != intro {
+		return nil, nil, errors.New("age: unexpected intro, the file is not an age v1 file")
+	}
+
+	for {
+		peek, err := r.Peek(len(footerPrefix))
+		if err != nil {
+			return nil, nil, fmt.Errorf("age: failed to read header: %v", err)
+		}
+		if bytes.Equal(peek, []byte(footerPrefix)) {
+			break
+		}
+		line, err := readLine(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("age: failed to read header: %v", err)
+		}
+		if !strings.HasPrefix(line, stanzaPrefix) {
+			return nil, nil, fmt.Errorf("age: malformed stanza opening line %q", line)
+		}
+		s, err := readStanza(r, line)
+		if err != nil {
+			return nil, nil, err
+		}
+		h.recipients = append(h.recipients, s)
+	}
+
+	line, err = readLine(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("age: failed to read header: %v", err)
+	}
+	mac, ok := strings.CutPrefix(line, footerPrefix+" ")
+	if !ok {
+		return nil, nil, fmt.Errorf("age: malformed closing line %q", line)
+	}
+	h.mac, err = b64.DecodeString(mac)
+	if err != nil || len(h.mac) != 32 {
+		return nil, nil, fmt.Errorf("age: malformed closing line %q", line)
+	}
+	return h, r, nil
+}
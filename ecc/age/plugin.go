@@ -0,0 +1,238 @@
+package age
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The age plugin protocol (https://c2sp.org/age-plugin) runs a plugin
+// binary with a --age-plugin=<state machine> flag and exchanges stanzas with
+// it over stdin and stdout. In the first phase the client sends commands
+// until "done"; in the second phase the plugin sends commands, each answered
+// by the client with "ok" or "fail", until the plugin sends "done".
+
+type pluginConn struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+func (c *pluginConn) readStanza() (*Stanza, error) {
+	line, err := readLine(c.r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, stanzaPrefix) {
+		return nil, fmt.Errorf("age: malformed plugin protocol line %q", line)
+	}
+	return readStanza(c.r, line)
+}
+
+// send writes a phase two command and waits for the client's response.
+func (c *pluginConn) send(s *Stanza) error {
+	if err := s.marshal(c.w); err != nil {
+		return err
+	}
+	response, err := c.readStanza()
+	if err != nil {
+		return err
+	}
+	if response.Type != "ok" {
+		return fmt.Errorf("age: client responded %q to %q", response.Type, s.Type)
+	}
+	return nil
+}
+
+// sendError reports an error to the client. args are the kind of error
+// ("recipient", "identity", "stanza" or "internal") followed by the indexes
+// it applies to.
+func (c *pluginConn) sendError(err error, args ...string) error {
+	return c.send(&Stanza{Type: "error", Args: args, Body: []byte(err.Error())})
+}
+
+func (c *pluginConn) done() error {
+	return (&Stanza{Type: "done"}).marshal(c.w)
+}
+
+// RunPlugin runs the plugin side of the given age plugin state machine,
+// "recipient-v1" or "identity-v1", over stdin and stdout. It is the main
+// loop of the age-plugin-eccfrog512ck2 binary.
+func RunPlugin(stateMachine string, stdin io.Reader, stdout io.Writer) error {
+	c := &pluginConn{r: bufio.NewReader(stdin), w: stdout}
+	switch stateMachine {
+	case "recipient-v1":
+		return runRecipientV1(c)
+	case "identity-v1":
+		return runIdentityV1(c)
+	}
+	return fmt.Errorf("age: unknown plugin state machine %q", stateMachine)
+}
+
+func runRecipientV1(c *pluginConn) error {
+	var recipients []*EccFrog512ck2Recipient
+	var fileKeys [][]byte
+	var recipientErr, identityErr error
+	recipientErrIndex, identityErrIndex := -1, -1
+	// Errors index recipients and identities separately, each in the order
+	// of its own commands.
+	var recipientCount, identityCount int
+
+phase1:
+	for {
+		s, err := c.readStanza()
+		if err != nil {
+			return err
+		}
+		switch s.Type {
+		case "add-recipient":
+			if len(s.Args) != 1 {
+				return errors.New("age: malformed add-recipient command")
+			}
+			r, err := ParseEccFrog512ck2Recipient(s.Args[0])
+			if err != nil && recipientErr == nil {
+				recipientErr, recipientErrIndex = err, recipientCount
+			}
+			recipientCount++
+			recipients = append(recipients, r)
+		case "add-identity":
+			// Identities can be used as recipients, encrypting to their
+			// public key.
+			if len(s.Args) != 1 {
+				return errors.New("age: malformed add-identity command")
+			}
+			id, err := ParseEccFrog512ck2Identity(s.Args[0])
+			identityCount++
+			if err != nil {
+				if identityErr == nil {
+					identityErr, identityErrIndex = err, identityCount-1
+				}
+				recipients = append(recipients, nil)
+				continue
+			}
+			recipients = append(recipients, id.Recipient())
+		case "wrap-file-key":
+			fileKeys = append(fileKeys, s.Body)
+		case "done":
+			break phase1
+		default:
+			// Unknown commands, including grease, are ignored.
+		}
+	}
+
+	switch {
+	case recipientErr != nil:
+		if err := c.sendError(recipientErr, "recipient", strconv.Itoa(recipientErrIndex)); err != nil {
+			return err
+		}
+		return c.done()
+	case identityErr != nil:
+		if err := c.sendError(identityErr, "identity", strconv.Itoa(identityErrIndex)); err != nil {
+			return err
+		}
+		return c.done()
+	}
+
+	for fileIndex, fileKey := range fileKeys {
+		for _, r := range recipients {
+			stanzas, err := r.Wrap(fileKey)
+			if err != nil {
+				if err := c.sendError(err, "internal"); err != nil {
+					return err
+				}
+				return c.done()
+			}
+			for _, stanza := range stanzas {
+				args := append([]string{strconv.Itoa(fileIndex), stanza.Type}, stanza.Args...)
+				if err := c.send(&Stanza{Type: "recipient-stanza", Args: args, Body: stanza.Body}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return c.done()
+}
+
+func runIdentityV1(c *pluginConn) error {
+	var identities []*EccFrog512ck2Identity
+	files := map[int][]*Stanza{}
+	var fileIndexes []int
+
+phase1:
+	for {
+		s, err := c.readStanza()
+		if err != nil {
+			return err
+		}
+		switch s.Type {
+		case "add-identity":
+			if len(s.Args) != 1 {
+				return errors.New("age: malformed add-identity command")
+			}
+			id, parseErr := ParseEccFrog512ck2Identity(s.Args[0])
+			if parseErr != nil {
+				// Report the first bad identity and give up, as the
+				// protocol requires.
+				if err := c.readUntilDone(); err != nil {
+					return err
+				}
+				if err := c.sendError(parseErr, "identity", strconv.Itoa(len(identities))); err != nil {
+					return err
+				}
+				return c.done()
+			}
+			identities = append(identities, id)
+		case "recipient-stanza":
+			if len(s.Args) < 2 {
+				return errors.New("age: malformed recipient-stanza command")
+			}
+			fileIndex, err := strconv.Atoi(s.Args[0])
+			if err != nil {
+				return errors.New("age: malformed recipient-stanza command")
+			}
+			if _, ok := files[fileIndex]; !ok {
+				fileIndexes = append(fileIndexes, fileIndex)
+			}
+			files[fileIndex] = append(files[fileIndex], &Stanza{Type: s.Args[1], Args: s.Args[2:], Body: s.Body})
+		case "done":
+			break phase1
+		default:
+		}
+	}
+
+files:
+	for _, fileIndex := range fileIndexes {
+		for stanzaIndex, stanza := range files[fileIndex] {
+			for _, id := range identities {
+				fileKey, err := id.unwrap(stanza)
+				if errors.Is(err, ErrIncorrectIdentity) {
+					continue
+				}
+				if err != nil {
+					err = c.sendError(err, "stanza", strconv.Itoa(fileIndex), strconv.Itoa(stanzaIndex))
+				} else {
+					err = c.send(&Stanza{Type: "file-key", Args: []string{strconv.Itoa(fileIndex)}, Body: fileKey})
+				}
+				if err != nil {
+					return err
+				}
+				continue files
+			}
+		}
+	}
+	return c.done()
+}
+
+func (c *pluginConn) readUntilDone() error {
+	for {
+		s, err := c.readStanza()
+		if err != nil {
+			return err
+		}
+		if s.Type == "done" {
+			return nil
+		}
+	}
+}
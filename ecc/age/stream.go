@@ -0,0 +1,188 @@
+package age
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The payload is encrypted with the STREAM construction: ChaCha20-Poly1305
+// over 64 KiB chunks, each with a nonce made of an 11-byte big-endian chunk
+// counter and a final byte that is 1 for the last chunk and 0 otherwise.
+
+const (
+	chunkSize          = 64 * 1024
+	encryptedChunkSize = chunkSize + chacha20poly1305.Overhead
+)
+
+type streamNonce [chacha20poly1305.NonceSize]byte
+
+func (n *streamNonce) increment() {
+	for i := len(n) - 2; i >= 0; i-- {
+		n[i]++
+		if n[i] != 0 {
+			return
+		}
+	}
+	// The counter wrapped around, which takes 2^88 chunks.
+	panic("age: stream nonce overflow")
+}
+
+func (n *streamNonce) setLastChunk() {
+	n[len(n)-1] = 1
+}
+
+type streamWriter struct {
+	aead  cipher.AEAD
+	dst   io.Writer
+	nonce streamNonce
+	buf   []byte
+	err   error
+}
+
+func newStreamWriter(key []byte, dst io.Writer) (*streamWriter, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &streamWriter{aead: aead, dst: dst, buf: make([]byte, 0, encryptedChunkSize)}, nil
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only flushed once more data arrives, since the
+		// last chunk is encrypted differently and may be full.
+		if len(w.buf) == chunkSize {
+			if err := w.flushChunk(false); err != nil {
+				w.err = err
+				return written, err
+			}
+		}
+		n := min(chunkSize-len(w.buf), len(p))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *streamWriter) flushChunk(last bool) error {
+	if last {
+		w.nonce.setLastChunk()
+	}
+	w.buf = w.aead.Seal(w.buf[:0], w.nonce[:], w.buf, nil)
+	_, err := w.dst.Write(w.buf)
+	w.buf = w.buf[:0]
+	w.nonce.increment()
+	return err
+}
+
+// Close encrypts and writes the last chunk. It does not close the
+// underlying writer.
+func (w *streamWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.flushChunk(true)
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errors.New("age: write to closed stream")
+	return nil
+}
+
+type streamReader struct {
+	aead  cipher.AEAD
+	src   io.Reader
+	nonce streamNonce
+
+	// unread is the decrypted data not yet returned by Read, and buf holds
+	// the chunk being read.
+	unread    []byte
+	buf       []byte
+	lookahead []byte
+	done      bool
+	err       error
+}
+
+func newStreamReader(key []byte, src io.Reader) (*streamReader, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &streamReader{aead: aead, src: src, buf: make([]byte, encryptedChunkSize+1)}, nil
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.unread) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.unread, r.err = r.readChunk()
+	}
+	n := copy(p, r.unread)
+	r.unread = r.unread[n:]
+	return n, nil
+}
+
+// readChunk reads and decrypts the next chunk. One byte beyond the chunk is
+// read to find out whether it is the last one; it is kept for the next call.
+func (r *streamReader) readChunk() ([]byte, error) {
+	n := copy(r.buf, r.lookahead)
+	m, err := io.ReadFull(r.src, r.buf[n:])
+	n += m
+	r.lookahead = nil
+
+	var chunk []byte
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		if n < chacha20poly1305.Overhead {
+			return nil, errors.New("age: truncated payload")
+		}
+		chunk, last = r.buf[:n], true
+	case err != nil:
+		return nil, err
+	default:
+		chunk = r.buf[:encryptedChunkSize]
+		r.lookahead = append([]byte{}, r.buf[encryptedChunkSize:n]...)
+	}
+
+	if last {
+		r.nonce.setLastChunk()
+	}
+	plaintext, err := r.aead.Open(chunk[:0], r.nonce[:], chunk, nil)
+	if err != nil {
+		return nil, errors.New("age: failed to decrypt and authenticate payload chunk")
+	}
+	r.nonce.increment()
+
+	if last {
+		r.done = true
+		// An empty last chunk is only allowed if it is the only chunk.
+		if len(plaintext) == 0 && !r.isFirstChunk() {
+			return nil, errors.New("age: last chunk is empty")
+		}
+	}
+	return plaintext, nil
+}
+
+// isFirstChunk reports whether the chunk that was just read was the first,
+// that is, whether the counter is now one.
+func (r *streamReader) isFirstChunk() bool {
+	for i := 0; i < len(r.nonce)-2; i++ {
+		if r.nonce[i] != 0 {
+			return false
+		}
+	}
+	return r.nonce[len(r.nonce)-2] == 1
+}
@@ -0,0 +1,289 @@
+package armor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Block types used by this module.
+const (
+	TypeMessage            = "ECCFROG512CK2 MESSAGE"
+	TypeSigncryptedMessage = "ECCFROG512CK2 SIGNCRYPTED MESSAGE"
+	TypeSignature          = "ECCFROG512CK2 SIGNATURE"
+	TypePublicKey          = "ECCFROG512CK2 PUBLIC KEY"
+	TypePrivateKey         = "ECCFROG512CK2 PRIVATE KEY"
+	TypeSharedSecret       = "ECCFROG512CK2 SHARED SECRET"
+)
+
+const lineLength = 64
+
+// Checksum selects the checksum written after the data of a block.
+type Checksum int
+
+const (
+	// CRC24 is the 24-bit CRC of OpenPGP armor, which catches accidental
+	// corruption in 4 characters.
+	CRC24 Checksum = iota
+	// SHA256 is a SHA-256 hash of the data, in 44 characters.
+	SHA256
+)
+
+func (c Checksum) new() (hash.Hash, error) {
+	switch c {
+	case CRC24:
+		return newCRC24(), nil
+	case SHA256:
+		return sha256.New(), nil
+	}
+	return nil, errors.New("armor: unknown checksum")
+}
+
+// Block is an armored block.
+type Block struct {
+	// Type is the text after BEGIN, such as TypeMessage.
+	Type string
+	// Headers are written as "Key: Value" lines, sorted by key.
+	Headers map[string]string
+	// Bytes is the decoded contents of the block.
+	Bytes []byte
+}
+
+// IsArmored reports whether data, after any leading whitespace, starts with
+// an armor BEGIN line.
+func IsArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("-----BEGIN "))
+}
+
+// Encode returns the armored encoding of b.
+func Encode(b *Block, checksum Checksum) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewEncoder(&buf, b.Type, b.Headers, checksum)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b.Bytes); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encoder armors the data written to it.
+type encoder struct {
+	dst       io.Writer
+	blockType string
+	checksum  hash.Hash
+	lines     *lineWriter
+	b64       io.WriteCloser
+}
+
+// NewEncoder writes the BEGIN line and headers of a block to w, and returns
+// a writer that armors the data written to it. Closing it writes the
+// checksum and the END line.
+func NewEncoder(w io.Writer, blockType string, headers map[string]string, checksum Checksum) (io.WriteCloser, error) {
+	if blockType == "" || strings.ContainsAny(blockType, "-\r\n") {
+		return nil, errors.New("armor: invalid block type")
+	}
+	h, err := checksum.new()
+	if err != nil {
+		return nil, err
+	}
+
+	var head strings.Builder
+	head.WriteString("-----BEGIN " + blockType + "-----\n")
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := headers[k]
+		if k == "" || strings.ContainsAny(k, ":\r\n") || strings.ContainsAny(v, "\r\n") {
+			return nil, errors.New("armor: invalid header")
+		}
+		head.WriteString(k + ": " + v + "\n")
+	}
+	if len(keys) > 0 {
+		head.WriteString("\n")
+	}
+	if _, err := io.WriteString(w, head.String()); err != nil {
+		return nil, err
+	}
+
+	lines := &lineWriter{dst: w}
+	return &encoder{
+		dst:       w,
+		blockType: blockType,
+		checksum:  h,
+		lines:     lines,
+		b64:       base64.NewEncoder(base64.StdEncoding, lines),
+	}, nil
+}
+
+func (e *encoder) Write(p []byte) (int, error) {
+	e.checksum.Write(p)
+	return e.b64.Write(p)
+}
+
+func (e *encoder) Close() error {
+	if err := e.b64.Close(); err != nil {
+		return err
+	}
+	if err := e.lines.flush(); err != nil {
+		return err
+	}
+	tail := "=" + base64.StdEncoding.EncodeToString(e.checksum.Sum(nil)) + "\n" +
+		"-----END " + e.blockType + "-----\n"
+	_, err := io.WriteString(e.dst, tail)
+	return err
+}
+
+// lineWriter breaks what is written to it into lines of lineLength.
+type lineWriter struct {
+	dst  io.Writer
+	line []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		m := min(lineLength-len(l.line), len(p))
+		l.line = append(l.line, p[:m]...)
+		p = p[m:]
+		if len(l.line) == lineLength {
+			if err := l.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (l *lineWriter) flush() error {
+	if len(l.line) == 0 {
+		return nil
+	}
+	l.line = append(l.line, '\n')
+	_, err := l.dst.Write(l.line)
+	l.line = l.line[:0]
+	return err
+}
+
+// Decode decodes the first armored block in data and returns it with the
+// data that follows it. Text before the block is skipped. The checksum,
+// CRC-24 or SHA-256 as told by its length, must be present and match.
+func Decode(data []byte) (*Block, []byte, error) {
+	start := bytes.Index(data, []byte("-----BEGIN "))
+	if start < 0 {
+		return nil, data, errors.New("armor: no armored data found")
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data[start:]))
+	scanner.Buffer(nil, len(data)+1)
+	nextLine := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		return strings.TrimRight(scanner.Text(), " \t\r"), true
+	}
+
+	line, _ := nextLine()
+	if !strings.HasSuffix(line, "-----") {
+		return nil, data, errors.New("armor: invalid BEGIN line")
+	}
+	block := &Block{Type: strings.TrimSuffix(strings.TrimPrefix(line, "-----BEGIN "), "-----")}
+
+	// Headers, if any, end at a blank line. Base64 has no colons.
+	var encoded strings.Builder
+	inHeaders := true
+	var sum string
+	for {
+		line, ok := nextLine()
+		if !ok {
+			return nil, data, errors.New("armor: armored data has no END line")
+		}
+		if line == "-----END "+block.Type+"-----" {
+			break
+		}
+		if inHeaders {
+			if line == "" {
+				inHeaders = false
+				continue
+			}
+			if k, v, ok := strings.Cut(line, ":"); ok {
+				if block.Headers == nil {
+					block.Headers = make(map[string]string)
+				}
+				block.Headers[k] = strings.TrimSpace(v)
+				continue
+			}
+			inHeaders = false
+		}
+		if sum != "" {
+			return nil, data, errors.New("armor: data after the checksum")
+		}
+		if strings.HasPrefix(line, "=") {
+			sum = line[1:]
+			continue
+		}
+		encoded.WriteString(line)
+	}
+
+	contents, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, data, errors.New("armor: invalid armored data")
+	}
+	var h hash.Hash
+	switch len(sum) {
+	case 4:
+		h = newCRC24()
+	case 44:
+		h = sha256.New()
+	case 0:
+		return nil, data, errors.New("armor: missing checksum")
+	default:
+		return nil, data, errors.New("armor: invalid checksum")
+	}
+	want, err := base64.StdEncoding.DecodeString(sum)
+	if err != nil {
+		return nil, data, errors.New("armor: invalid checksum")
+	}
+	h.Write(contents)
+	if subtle.ConstantTimeCompare(h.Sum(nil), want) != 1 {
+		return nil, data, errors.New("armor: checksum mismatch")
+	}
+	block.Bytes = contents
+
+	end := []byte("-----END " + block.Type + "-----")
+	rest := data[start+bytes.Index(data[start:], end)+len(end):]
+	rest = bytes.TrimLeft(rest, " \t")
+	rest = bytes.TrimPrefix(rest, []byte("\r"))
+	rest = bytes.TrimPrefix(rest, []byte("\n"))
+	return block, rest, nil
+}
+
+// DecodeType is like Decode, but also checks the block type and drops the
+// remaining data. It returns data unchanged if it is not armored, so that
+// callers can accept armored and binary input alike.
+func DecodeType(data []byte, blockType string) ([]byte, error) {
+	if !IsArmored(data) {
+		return data, nil
+	}
+	block, _, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != blockType {
+		return nil, errors.New("armor: unexpected block type " + block.Type)
+	}
+	return block.Bytes, nil
+}
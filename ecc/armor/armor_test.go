@@ -0,0 +1,128 @@
+package armor_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/armor"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	data := make([]byte, 1000)
+	rand.Read(data)
+
+	for _, checksum := range []armor.Checksum{armor.CRC24, armor.SHA256} {
+		for _, headers := range []map[string]string{nil, {"Comment": "test", "Version": "1"}} {
+			armored, err := armor.Encode(&armor.Block{Type: armor.TypeMessage, Headers: headers, Bytes: data}, checksum)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(armored, []byte("-----BEGIN ECCFROG512CK2 MESSAGE-----\n")) ||
+				!bytes.HasSuffix(armored, []byte("\n-----END ECCFROG512CK2 MESSAGE-----\n")) {
+				t.Errorf("Unexpected armor:\n%s", armored)
+			}
+			for _, line := range strings.Split(string(armored), "\n") {
+				if len(line) > 64 {
+					t.Errorf("Line is %d characters long", len(line))
+				}
+			}
+			if !armor.IsArmored(armored) {
+				t.Error("Expected IsArmored to be true")
+			}
+
+			// Surrounding text and CRLF line endings are tolerated
+			pasted := append([]byte("See below.\r\n"), bytes.ReplaceAll(armored, []byte("\n"), []byte("\r\n"))...)
+			pasted = append(pasted, "Thanks"...)
+			block, rest, err := armor.Decode(pasted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if block.Type != armor.TypeMessage || !bytes.Equal(block.Bytes, data) {
+				t.Error("Decode did not return the original block")
+			}
+			if len(block.Headers) != len(headers) || block.Headers["Comment"] != headers["Comment"] {
+				t.Errorf("Headers = %v, want %v", block.Headers, headers)
+			}
+			if string(rest) != "Thanks" {
+				t.Errorf("rest = %q", rest)
+			}
+		}
+	}
+}
+
+func TestEncoderMatchesEncode(t *testing.T) {
+	data := make([]byte, 300)
+	rand.Read(data)
+	armored, err := armor.Encode(&armor.Block{Type: armor.TypeSignature, Bytes: data}, armor.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	w, err := armor.NewEncoder(&b, armor.TypeSignature, nil, armor.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(data); i += 7 {
+		w.Write(data[i:min(i+7, len(data))])
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), armored) {
+		t.Error("Streaming encoder output differs from Encode")
+	}
+}
+
+func TestCRC24(t *testing.T) {
+	// The check value of CRC-24/OPENPGP is 0x21CF02
+	armored, err := armor.Encode(&armor.Block{Type: armor.TypeMessage, Bytes: []byte("123456789")}, armor.CRC24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(armored, []byte("\n=Ic8C\n")) {
+		t.Errorf("Unexpected checksum in:\n%s", armored)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	armored, err := armor.Encode(&armor.Block{Type: armor.TypeSignature, Bytes: []byte("signature bytes")}, armor.CRC24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(armored), "\n")
+
+	for name, input := range map[string]string{
+		"no armor":         "just text",
+		"no END line":      strings.Join(lines[:3], "\n"),
+		"missing checksum": strings.Join([]string{lines[0], lines[1], lines[3]}, "\n"),
+		"corrupted data":   strings.Replace(string(armored), "c2ln", "c2lm", 1),
+		"bad checksum":     strings.Replace(string(armored), lines[2], "=AAAA", 1),
+		"mismatched END":   strings.Replace(string(armored), "END ECCFROG512CK2 SIGNATURE", "END ECCFROG512CK2 MESSAGE", 1),
+	} {
+		if _, _, err := armor.Decode([]byte(input)); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+
+	if _, err := armor.DecodeType(armored, armor.TypeMessage); err == nil {
+		t.Error("Expected error for the wrong block type")
+	}
+	data, err := armor.DecodeType(armored, armor.TypeSignature)
+	if err != nil || string(data) != "signature bytes" {
+		t.Errorf("DecodeType = %q, %v", data, err)
+	}
+	binary := []byte{0x30, 0x82}
+	if data, err := armor.DecodeType(binary, armor.TypeSignature); err != nil || !bytes.Equal(data, binary) {
+		t.Error("Expected binary input to be returned unchanged")
+	}
+
+	if _, err := armor.Encode(&armor.Block{Type: "BAD-TYPE"}, armor.CRC24); err == nil {
+		t.Error("Expected error for an invalid block type")
+	}
+	if _, err := armor.Encode(&armor.Block{Type: armor.TypeMessage, Headers: map[string]string{"A": "b\nc"}}, armor.CRC24); err == nil {
+		t.Error("Expected error for an invalid header")
+	}
+}
@@ -0,0 +1,39 @@
+package armor
+
+import "hash"
+
+// The CRC-24 of RFC 4880, section 6.1.
+const (
+	crc24Init = 0xB704CE
+	crc24Poly = 0x1864CFB
+)
+
+type crc24 uint32
+
+func newCRC24() hash.Hash {
+	c := crc24(crc24Init)
+	return &c
+}
+
+func (c *crc24) Write(p []byte) (int, error) {
+	crc := uint32(*c)
+	for _, b := range p {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	*c = crc24(crc & 0xFFFFFF)
+	return len(p), nil
+}
+
+func (c *crc24) Sum(b []byte) []byte {
+	return append(b, byte(*c>>16), byte(*c>>8), byte(*c))
+}
+
+func (c *crc24) Reset()         { *c = crc24Init }
+func (c *crc24) Size() int      { return 3 }
+func (c *crc24) BlockSize() int { return 1 }
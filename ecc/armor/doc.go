@@ -0,0 +1,18 @@
+// Package armor encodes binary data, such as ciphertexts and signatures, as
+// text that survives being pasted into tickets, email and chat.
+//
+// A block looks like PEM or OpenPGP armor:
+//
+//	-----BEGIN ECCFROG512CK2 MESSAGE-----
+//	Comment: optional headers
+//
+//	base64 data, wrapped at 64 characters
+//	=checksum
+//	-----END ECCFROG512CK2 MESSAGE-----
+//
+// The blank line is only written when there are headers. The checksum is the
+// base64 encoding of either the CRC-24 of OpenPGP, in 4 characters, or a
+// SHA-256 hash of the data, in 44 characters, and is always checked when
+// decoding. It guards against accidental corruption only; anything that
+// needs integrity against an attacker must be signed or authenticated.
+package armor
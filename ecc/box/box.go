@@ -0,0 +1,195 @@
+package box
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// NonceSize is the size of the nonce of Seal and Open.
+	NonceSize = 24
+	// SharedKeySize is the size of a key computed by Precompute.
+	SharedKeySize = 32
+	// Overhead is the number of bytes Seal adds to a message.
+	Overhead = 16
+	// AnonymousOverhead is the number of bytes SealAnonymous adds to a
+	// message: the compressed ephemeral public key and the tag.
+	AnonymousOverhead = ephemeralKeySize + Overhead
+
+	// ephemeralKeySize is the size of a compressed SEC1 point.
+	ephemeralKeySize = 65
+)
+
+const (
+	boxInfo       = "eccfrog512ck2 box"
+	sealedBoxInfo = "eccfrog512ck2 sealed box"
+)
+
+// GenerateKey generates a key pair from random, which is crypto/rand's
+// Reader if nil.
+func GenerateKey(random io.Reader) (eccfrog512ck2.CurvePoint, ecc.PrivateKey, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+	privateKey, err := ecc.GeneratePrivateKeyFrom(random)
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, ecc.PrivateKey{}, err
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, ecc.PrivateKey{}, err
+	}
+	return publicKey, privateKey, nil
+}
+
+// agree returns the ECDH shared secret between privateKey and
+// peersPublicKey, after checking that peersPublicKey is a valid public key.
+func agree(privateKey ecc.PrivateKey, peersPublicKey eccfrog512ck2.CurvePoint) ([]byte, error) {
+	if err := ecc.ValidatePublicKey(peersPublicKey); err != nil {
+		return nil, err
+	}
+	return ecdh.ECDHPrivateKey(privateKey).DeriveFixedSizeSharedSecret(peersPublicKey)
+}
+
+// newAEAD returns the XChaCha20-Poly1305 AEAD keyed with key.
+func newAEAD(key []byte) cipher.AEAD {
+	aead, err := cryptohelpers.CipherXChaCha20Poly1305.NewAEAD(key)
+	if err != nil {
+		// Only a key of the wrong length is rejected.
+		panic(err)
+	}
+	return aead
+}
+
+// Precompute computes the key that privateKey shares with peersPublicKey
+// into sharedKey, for use with SealAfterPrecomputation and
+// OpenAfterPrecomputation. Both parties compute the same key.
+func Precompute(
+	sharedKey *[SharedKeySize]byte,
+	peersPublicKey eccfrog512ck2.CurvePoint,
+	privateKey ecc.PrivateKey,
+) error {
+	z, err := agree(privateKey, peersPublicKey)
+	if err != nil {
+		return err
+	}
+	_, err = io.ReadFull(hkdf.New(sha256.New, z, nil, []byte(boxInfo)), sharedKey[:])
+	return err
+}
+
+// Seal appends an encrypted and authenticated copy of message to out, from
+// the holder of privateKey to the holder of the private key for
+// peersPublicKey, and returns the result. The nonce must be unique for each
+// message between the same keys.
+func Seal(
+	out, message []byte,
+	nonce *[NonceSize]byte,
+	peersPublicKey eccfrog512ck2.CurvePoint,
+	privateKey ecc.PrivateKey,
+) ([]byte, error) {
+	var sharedKey [SharedKeySize]byte
+	if err := Precompute(&sharedKey, peersPublicKey, privateKey); err != nil {
+		return nil, err
+	}
+	return SealAfterPrecomputation(out, message, nonce, &sharedKey), nil
+}
+
+// SealAfterPrecomputation performs the same actions as Seal, but takes a
+// shared key computed by Precompute.
+func SealAfterPrecomputation(out, message []byte, nonce *[NonceSize]byte, sharedKey *[SharedKeySize]byte) []byte {
+	return newAEAD(sharedKey[:]).Seal(out, nonce[:], message, nil)
+}
+
+// Open authenticates and decrypts a box produced by Seal, appends the
+// message to out, and returns the result. It reports false if the box was
+// not sealed by the holder of the private key for peersPublicKey to
+// privateKey with nonce, or if peersPublicKey is not a valid public key.
+func Open(
+	out, box []byte,
+	nonce *[NonceSize]byte,
+	peersPublicKey eccfrog512ck2.CurvePoint,
+	privateKey ecc.PrivateKey,
+) ([]byte, bool) {
+	var sharedKey [SharedKeySize]byte
+	if err := Precompute(&sharedKey, peersPublicKey, privateKey); err != nil {
+		return nil, false
+	}
+	return OpenAfterPrecomputation(out, box, nonce, &sharedKey)
+}
+
+// OpenAfterPrecomputation performs the same actions as Open, but takes a
+// shared key computed by Precompute.
+func OpenAfterPrecomputation(out, box []byte, nonce *[NonceSize]byte, sharedKey *[SharedKeySize]byte) ([]byte, bool) {
+	message, err := newAEAD(sharedKey[:]).Open(out, nonce[:], box, nil)
+	if err != nil {
+		return nil, false
+	}
+	return message, true
+}
+
+// sealedBoxKey derives the key and nonce of a sealed box from the shared
+// secret z, binding both the ephemeral and the recipient's public key.
+func sealedBoxKey(z []byte, ephemeralKey, recipient eccfrog512ck2.CurvePoint) ([]byte, []byte, error) {
+	salt := append(ephemeralKey.MarshalSEC1(true), recipient.MarshalSEC1(true)...)
+	keyAndNonce := make([]byte, SharedKeySize+NonceSize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, z, salt, []byte(sealedBoxInfo)), keyAndNonce); err != nil {
+		return nil, nil, err
+	}
+	return keyAndNonce[:SharedKeySize], keyAndNonce[SharedKeySize:], nil
+}
+
+// SealAnonymous appends an encrypted copy of message to out, which only the
+// holder of the private key for recipient can open, and returns the result.
+// The ephemeral key is generated from random, which is crypto/rand's Reader
+// if nil. The box does not reveal or authenticate the sender.
+func SealAnonymous(out, message []byte, recipient eccfrog512ck2.CurvePoint, random io.Reader) ([]byte, error) {
+	ephemeralPublicKey, ephemeralPrivateKey, err := GenerateKey(random)
+	if err != nil {
+		return nil, err
+	}
+	z, err := agree(ephemeralPrivateKey, recipient)
+	if err != nil {
+		return nil, err
+	}
+	key, nonce, err := sealedBoxKey(z, ephemeralPublicKey, recipient)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, ephemeralPublicKey.MarshalSEC1(true)...)
+	return newAEAD(key).Seal(out, nonce, message, nil), nil
+}
+
+// OpenAnonymous decrypts a box produced by SealAnonymous, appends the
+// message to out, and returns the result. publicKey must be the public key
+// of privateKey. It reports false if the box was not sealed to publicKey
+// or was modified.
+func OpenAnonymous(out, box []byte, publicKey eccfrog512ck2.CurvePoint, privateKey ecc.PrivateKey) ([]byte, bool) {
+	if len(box) < AnonymousOverhead {
+		return nil, false
+	}
+	ephemeralPublicKey, err := ecc.ParsePublicKeySEC1(box[:ephemeralKeySize])
+	if err != nil {
+		return nil, false
+	}
+	z, err := agree(privateKey, ephemeralPublicKey)
+	if err != nil {
+		return nil, false
+	}
+	key, nonce, err := sealedBoxKey(z, ephemeralPublicKey, publicKey)
+	if err != nil {
+		return nil, false
+	}
+	message, err := newAEAD(key).Open(out, nonce, box[ephemeralKeySize:], nil)
+	if err != nil {
+		return nil, false
+	}
+	return message, true
+}
@@ -0,0 +1,138 @@
+package box_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc/box"
+)
+
+func TestSealOpen(t *testing.T) {
+	alicePublicKey, alicePrivateKey, err := box.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPublicKey, bobPrivateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evePublicKey, evePrivateKey, _ := box.GenerateKey(nil)
+	message := []byte("Hello, World!")
+	var nonce [box.NonceSize]byte
+	rand.Read(nonce[:])
+
+	prefix := []byte("prefix")
+	sealed, err := box.Seal(prefix, message, &nonce, bobPublicKey, alicePrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(sealed, prefix) || len(sealed) != len(prefix)+len(message)+box.Overhead {
+		t.Fatalf("Unexpected box of %d bytes", len(sealed))
+	}
+	sealed = sealed[len(prefix):]
+	opened, ok := box.Open(nil, sealed, &nonce, alicePublicKey, bobPrivateKey)
+	if !ok || !bytes.Equal(opened, message) {
+		t.Fatal("Failed to open box")
+	}
+
+	// Both parties compute the same shared key
+	var aliceKey, bobKey [box.SharedKeySize]byte
+	if err := box.Precompute(&aliceKey, bobPublicKey, alicePrivateKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := box.Precompute(&bobKey, alicePublicKey, bobPrivateKey); err != nil {
+		t.Fatal(err)
+	}
+	if aliceKey != bobKey {
+		t.Fatal("Precomputed keys do not match")
+	}
+	if !bytes.Equal(box.SealAfterPrecomputation(nil, message, &nonce, &aliceKey), sealed) {
+		t.Error("SealAfterPrecomputation does not match Seal")
+	}
+	opened, ok = box.OpenAfterPrecomputation(nil, sealed, &nonce, &bobKey)
+	if !ok || !bytes.Equal(opened, message) {
+		t.Error("Failed to open box with the precomputed key")
+	}
+
+	if _, ok := box.Open(nil, sealed, &nonce, evePublicKey, bobPrivateKey); ok {
+		t.Error("Opened a box from the wrong sender")
+	}
+	if _, ok := box.Open(nil, sealed, &nonce, alicePublicKey, evePrivateKey); ok {
+		t.Error("Opened a box with the wrong private key")
+	}
+	otherNonce := nonce
+	otherNonce[0] ^= 1
+	if _, ok := box.Open(nil, sealed, &otherNonce, alicePublicKey, bobPrivateKey); ok {
+		t.Error("Opened a box with the wrong nonce")
+	}
+	for i := range sealed {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 1
+		if _, ok := box.Open(nil, tampered, &nonce, alicePublicKey, bobPrivateKey); ok {
+			t.Fatalf("Opened a box modified at byte %d", i)
+		}
+	}
+
+	if _, err := box.Seal(nil, message, &nonce, eccfrog512ck2.PointAtInfinity(), alicePrivateKey); err == nil {
+		t.Error("Expected error for the point at infinity")
+	}
+	if _, ok := box.Open(nil, sealed, &nonce, eccfrog512ck2.CurvePoint{}, bobPrivateKey); ok {
+		t.Error("Opened a box from the point at infinity")
+	}
+}
+
+func TestSealOpenAnonymous(t *testing.T) {
+	publicKey, privateKey, err := box.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublicKey, otherPrivateKey, _ := box.GenerateKey(nil)
+	message := []byte("Hello, World!")
+
+	sealed, err := box.SealAnonymous(nil, message, publicKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sealed) != len(message)+box.AnonymousOverhead {
+		t.Fatalf("Unexpected box of %d bytes", len(sealed))
+	}
+	opened, ok := box.OpenAnonymous(nil, sealed, publicKey, privateKey)
+	if !ok || !bytes.Equal(opened, message) {
+		t.Fatal("Failed to open sealed box")
+	}
+
+	again, _ := box.SealAnonymous(nil, message, publicKey, nil)
+	if bytes.Equal(sealed, again) {
+		t.Error("Expected a fresh ephemeral key for each box")
+	}
+	if _, ok := box.OpenAnonymous(nil, sealed, otherPublicKey, otherPrivateKey); ok {
+		t.Error("Opened a sealed box with the wrong key")
+	}
+	for i := range sealed {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 1
+		if _, ok := box.OpenAnonymous(nil, tampered, publicKey, privateKey); ok {
+			t.Fatalf("Opened a sealed box modified at byte %d", i)
+		}
+	}
+	if _, ok := box.OpenAnonymous(nil, sealed[:box.AnonymousOverhead-1], publicKey, privateKey); ok {
+		t.Error("Opened a truncated sealed box")
+	}
+
+	// The same randomness gives the same box
+	seed := bytes.Repeat([]byte{0x42}, 128)
+	a, err := box.SealAnonymous(nil, message, publicKey, bytes.NewReader(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := box.SealAnonymous(nil, message, publicKey, bytes.NewReader(seed))
+	if !bytes.Equal(a, b) {
+		t.Error("Expected the same box from the same randomness")
+	}
+
+	if _, err := box.SealAnonymous(nil, message, eccfrog512ck2.PointAtInfinity(), nil); err == nil {
+		t.Error("Expected error for the point at infinity")
+	}
+}
@@ -0,0 +1,21 @@
+// Package box encrypts small messages to EccFrog512ck2 public keys without
+// asking the caller to pick a KDF or a cipher. Its API is modelled on
+// golang.org/x/crypto/nacl/box.
+//
+// Seal and Open authenticate the sender as well as encrypting: a box opens
+// only under the recipient's private key and the sender's public key, and
+// either party could have produced it. The key they share can be computed
+// once with Precompute and reused with SealAfterPrecomputation and
+// OpenAfterPrecomputation. The nonce must be unique for each message
+// between the same pair of keys; it is 24 bytes long, so random nonces are
+// safe.
+//
+// SealAnonymous and OpenAnonymous are sealed boxes: the sender uses a fresh
+// ephemeral key, so the box is anonymous and needs no nonce, and only the
+// recipient can open it.
+//
+// Shared keys are derived from the ECDH shared secret with HKDF-SHA256, and
+// messages are encrypted with XChaCha20-Poly1305. A box is a single opaque
+// []byte, Overhead bytes longer than the message for Seal, and
+// AnonymousOverhead bytes longer for SealAnonymous.
+package box
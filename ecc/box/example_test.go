@@ -0,0 +1,29 @@
+package box_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/box"
+)
+
+func ExampleSealAnonymous() {
+	publicKey, privateKey, err := box.GenerateKey(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Anyone can seal a box to the recipient's public key
+	sealed, err := box.SealAnonymous(nil, []byte("Hello, World!"), publicKey, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Only the recipient can open it
+	message, ok := box.OpenAnonymous(nil, sealed, publicKey, privateKey)
+	if !ok {
+		log.Fatal("failed to open box")
+	}
+	fmt.Println(string(message))
+	// Output: Hello, World!
+}
@@ -0,0 +1,446 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	simpleFloat16   = 25
+	simpleFloat32   = 26
+	simpleFloat64   = 27
+	simpleBreak     = 31
+
+	additionalIndefinite = 31
+
+	// maxDepth bounds the nesting of arrays, maps and tags that Unmarshal
+	// accepts, so that hostile input cannot exhaust the stack.
+	maxDepth = 64
+)
+
+// Tag is a tagged data item.
+type Tag struct {
+	Number  uint64
+	Content any
+}
+
+// RawMessage is an encoded CBOR data item. It is written as is by Marshal.
+type RawMessage []byte
+
+// Marshal returns the deterministic encoding of v.
+func Marshal(v any) ([]byte, error) {
+	var b bytes.Buffer
+	if err := encode(&b, v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeHead(b *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		b.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		b.WriteByte(major<<5 | 24)
+		b.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		b.WriteByte(major<<5 | 25)
+		b.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		b.WriteByte(major<<5 | 26)
+		b.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		b.WriteByte(major<<5 | 27)
+		b.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func writeInt(b *bytes.Buffer, n int64) {
+	if n >= 0 {
+		writeHead(b, majorUnsigned, uint64(n))
+	} else {
+		writeHead(b, majorNegative, uint64(-(n + 1)))
+	}
+}
+
+func encode(b *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		b.WriteByte(majorSimple<<5 | simpleNull)
+	case bool:
+		if v {
+			b.WriteByte(majorSimple<<5 | simpleTrue)
+		} else {
+			b.WriteByte(majorSimple<<5 | simpleFalse)
+		}
+	case int:
+		writeInt(b, int64(v))
+	case int8:
+		writeInt(b, int64(v))
+	case int16:
+		writeInt(b, int64(v))
+	case int32:
+		writeInt(b, int64(v))
+	case int64:
+		writeInt(b, v)
+	case uint:
+		writeHead(b, majorUnsigned, uint64(v))
+	case uint8:
+		writeHead(b, majorUnsigned, uint64(v))
+	case uint16:
+		writeHead(b, majorUnsigned, uint64(v))
+	case uint32:
+		writeHead(b, majorUnsigned, uint64(v))
+	case uint64:
+		writeHead(b, majorUnsigned, v)
+	case float64:
+		b.WriteByte(majorSimple<<5 | simpleFloat64)
+		b.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+	case []byte:
+		writeHead(b, majorBytes, uint64(len(v)))
+		b.Write(v)
+	case string:
+		writeHead(b, majorText, uint64(len(v)))
+		b.WriteString(v)
+	case RawMessage:
+		if _, err := Unmarshal(v); err != nil {
+			return fmt.Errorf("cbor: invalid RawMessage: %v", err)
+		}
+		b.Write(v)
+	case Tag:
+		writeHead(b, majorTag, v.Number)
+		return encode(b, v.Content)
+	case []any:
+		writeHead(b, majorArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encode(b, item); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		return encodeMap(b, v)
+	default:
+		return encodeReflect(b, reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// encodeReflect encodes values of named integer, string and byte slice
+// types, such as enumerations.
+func encodeReflect(b *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeInt(b, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		writeHead(b, majorUnsigned, v.Uint())
+	case reflect.String:
+		writeHead(b, majorText, uint64(v.Len()))
+		b.WriteString(v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("cbor: unsupported type %s", v.Type())
+		}
+		writeHead(b, majorBytes, uint64(v.Len()))
+		b.Write(v.Bytes())
+	default:
+		if !v.IsValid() {
+			return errors.New("cbor: unsupported value")
+		}
+		return fmt.Errorf("cbor: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// encodeMap writes a map with its keys sorted bytewise by their encoding, as
+// the deterministic encoding requires.
+func encodeMap(b *bytes.Buffer, m map[any]any) error {
+	type entry struct {
+		key   []byte
+		value any
+	}
+	entries := make([]entry, 0, len(m))
+	for k, v := range m {
+		key, err := Marshal(k)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key, v})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	writeHead(b, majorMap, uint64(len(entries)))
+	for i, e := range entries {
+		if i > 0 && bytes.Equal(e.key, entries[i-1].key) {
+			return errors.New("cbor: duplicate map key")
+		}
+		b.Write(e.key)
+		if err := encode(b, e.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unmarshal decodes a single data item, which must make up all of data.
+//
+// Map keys must be integers or text strings, so that they can be used as Go
+// map keys, and must not repeat.
+func Unmarshal(data []byte) (any, error) {
+	d := &decoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.data) {
+		return nil, errors.New("cbor: trailing data")
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	off  int
+}
+
+var errUnexpectedEnd = errors.New("cbor: unexpected end of data")
+
+func (d *decoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, errUnexpectedEnd
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// readHead reads the initial byte and argument of a data item. The
+// additional information is 31 for indefinite-length items.
+func (d *decoder) readHead() (major, additional byte, n uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, additional = b[0]>>5, b[0]&0x1f
+	switch {
+	case additional < 24:
+		n = uint64(additional)
+	case additional == 24:
+		b, err = d.read(1)
+		if err == nil {
+			n = uint64(b[0])
+		}
+	case additional == 25:
+		b, err = d.read(2)
+		if err == nil {
+			n = uint64(binary.BigEndian.Uint16(b))
+		}
+	case additional == 26:
+		b, err = d.read(4)
+		if err == nil {
+			n = uint64(binary.BigEndian.Uint32(b))
+		}
+	case additional == 27:
+		b, err = d.read(8)
+		if err == nil {
+			n = binary.BigEndian.Uint64(b)
+		}
+	case additional == additionalIndefinite:
+		if major == majorUnsigned || major == majorNegative || major == majorTag {
+			err = fmt.Errorf("cbor: invalid indefinite length for major type %d", major)
+		}
+	default:
+		err = fmt.Errorf("cbor: reserved additional information %d", additional)
+	}
+	return major, additional, n, err
+}
+
+// isBreak consumes a break stop code if it is next.
+func (d *decoder) isBreak() (bool, error) {
+	if d.off >= len(d.data) {
+		return false, errUnexpectedEnd
+	}
+	if d.data[d.off] == majorSimple<<5|simpleBreak {
+		d.off++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (d *decoder) decode(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, errors.New("cbor: maximum nesting depth exceeded")
+	}
+	major, additional, n, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := additional == additionalIndefinite
+
+	switch major {
+	case majorUnsigned:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case majorNegative:
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer out of range")
+		}
+		return -1 - int64(n), nil
+	case majorBytes, majorText:
+		var s []byte
+		if indefinite {
+			s, err = d.decodeChunks(major)
+		} else {
+			s, err = d.read(n)
+			s = append([]byte{}, s...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if major == majorBytes {
+			return s, nil
+		}
+		return string(s), nil
+	case majorArray:
+		array := []any{}
+		for i := uint64(0); indefinite || i < n; i++ {
+			if indefinite {
+				if done, err := d.isBreak(); err != nil {
+					return nil, err
+				} else if done {
+					break
+				}
+			}
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		return array, nil
+	case majorMap:
+		m := map[any]any{}
+		for i := uint64(0); indefinite || i < n; i++ {
+			if indefinite {
+				if done, err := d.isBreak(); err != nil {
+					return nil, err
+				} else if done {
+					break
+				}
+			}
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, uint64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, ok := m[key]; ok {
+				return nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case majorTag:
+		content, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Tag{Number: n, Content: content}, nil
+	default:
+		return d.decodeSimple(additional, n)
+	}
+}
+
+// decodeChunks reads the definite-length chunks of an indefinite-length
+// byte or text string.
+func (d *decoder) decodeChunks(major byte) ([]byte, error) {
+	s := []byte{}
+	for {
+		done, err := d.isBreak()
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return s, nil
+		}
+		chunkMajor, additional, n, err := d.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || additional == additionalIndefinite {
+			return nil, errors.New("cbor: invalid indefinite-length string chunk")
+		}
+		chunk, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+}
+
+func (d *decoder) decodeSimple(additional byte, n uint64) (any, error) {
+	switch additional {
+	case simpleFalse:
+		return false, nil
+	case simpleTrue:
+		return true, nil
+	case simpleNull, simpleUndefined:
+		return nil, nil
+	case simpleFloat16:
+		return float16ToFloat64(uint16(n)), nil
+	case simpleFloat32:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case simpleFloat64:
+		return math.Float64frombits(n), nil
+	case additionalIndefinite:
+		return nil, errors.New("cbor: unexpected break stop code")
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+}
+
+func float16ToFloat64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exponent := int(h>>10) & 0x1f
+	mantissa := float64(h & 0x3ff)
+	switch exponent {
+	case 0:
+		return sign * math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(mantissa+1024, exponent-25)
+}
@@ -0,0 +1,190 @@
+package cbor_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+)
+
+// Examples from RFC 8949, Appendix A.
+var vectors = []struct {
+	value   any
+	encoded string
+}{
+	{int64(0), "00"},
+	{int64(1), "01"},
+	{int64(10), "0a"},
+	{int64(23), "17"},
+	{int64(24), "1818"},
+	{int64(100), "1864"},
+	{int64(1000), "1903e8"},
+	{int64(1000000), "1a000f4240"},
+	{int64(1000000000000), "1b000000e8d4a51000"},
+	{uint64(18446744073709551615), "1bffffffffffffffff"},
+	{int64(-1), "20"},
+	{int64(-10), "29"},
+	{int64(-100), "3863"},
+	{int64(-1000), "3903e7"},
+	{false, "f4"},
+	{true, "f5"},
+	{nil, "f6"},
+	{[]byte{}, "40"},
+	{[]byte{1, 2, 3, 4}, "4401020304"},
+	{"", "60"},
+	{"a", "6161"},
+	{"IETF", "6449455446"},
+	{"ü", "62c3bc"},
+	{[]any{}, "80"},
+	{[]any{int64(1), int64(2), int64(3)}, "83010203"},
+	{[]any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}, "8301820203820405"},
+	{map[any]any{}, "a0"},
+	{map[any]any{int64(1): int64(2), int64(3): int64(4)}, "a201020304"},
+	{map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}, "a26161016162820203"},
+	{cbor.Tag{Number: 1, Content: int64(1363896240)}, "c11a514b67b0"},
+	{cbor.Tag{Number: 32, Content: "http://www.example.com"}, "d82076687474703a2f2f7777772e6578616d706c652e636f6d"},
+	{1.1, "fb3ff199999999999a"},
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range vectors {
+		encoded, err := cbor.Marshal(v.value)
+		if err != nil {
+			t.Errorf("Failed to marshal %v: %v", v.value, err)
+			continue
+		}
+		if hex.EncodeToString(encoded) != v.encoded {
+			t.Errorf("Marshal(%v) = %x, expected %s", v.value, encoded, v.encoded)
+		}
+
+		data, _ := hex.DecodeString(v.encoded)
+		decoded, err := cbor.Unmarshal(data)
+		if err != nil {
+			t.Errorf("Failed to unmarshal %s: %v", v.encoded, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, v.value) {
+			t.Errorf("Unmarshal(%s) = %#v, expected %#v", v.encoded, decoded, v.value)
+		}
+	}
+}
+
+func TestUnmarshalNonDeterministic(t *testing.T) {
+	tests := []struct {
+		encoded string
+		value   any
+	}{
+		// Indefinite-length items
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		// Integers and floats not in their shortest form
+		{"1800", int64(0)},
+		{"f93c00", 1.0},
+		{"f97bff", 65504.0},
+		{"fa47c35000", 100000.0},
+		{"f90001", 5.960464477539063e-8},
+		{"f7", nil},
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(test.encoded)
+		decoded, err := cbor.Unmarshal(data)
+		if err != nil {
+			t.Errorf("Failed to unmarshal %s: %v", test.encoded, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, test.value) {
+			t.Errorf("Unmarshal(%s) = %#v, expected %#v", test.encoded, decoded, test.value)
+		}
+	}
+
+	data, _ := hex.DecodeString("f97c00")
+	if v, err := cbor.Unmarshal(data); err != nil || !math.IsInf(v.(float64), 1) {
+		t.Errorf("Unmarshal(f97c00) = %v, %v, expected +Inf", v, err)
+	}
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	malformed := []string{
+		"",
+		"18",                 // missing argument
+		"62c3",               // truncated text string
+		"8301",               // truncated array
+		"0102",               // trailing data
+		"1c",                 // reserved additional information
+		"1f",                 // indefinite-length integer
+		"ff",                 // lone break
+		"5f01ff",             // integer chunk in a byte string
+		"9f01",               // unterminated indefinite-length array
+		"a2010201",           // truncated map
+		"a201020103",         // duplicate map key
+		"a1410102",           // byte string map key
+		"3bffffffffffffffff", // negative integer out of range
+		"f8ff",               // unsupported simple value
+	}
+	for _, m := range malformed {
+		data, _ := hex.DecodeString(m)
+		if _, err := cbor.Unmarshal(data); err == nil {
+			t.Errorf("Expected error for %q", m)
+		}
+	}
+
+	if _, err := cbor.Unmarshal(bytes.Repeat([]byte{0x81}, 1000)); err == nil {
+		t.Error("Expected error for deeply nested arrays")
+	}
+}
+
+func TestMarshalDeterministic(t *testing.T) {
+	// Keys are sorted by their encoding, so shorter keys come first and
+	// positive integers before negative ones.
+	m := map[any]any{
+		"aa":     int64(4),
+		"b":      int64(3),
+		-1:       int64(2),
+		10:       int64(1),
+		int64(1): int64(0),
+	}
+	encoded, err := cbor.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "a501000a01200261620362616104" {
+		t.Errorf("Unexpected encoding %x", encoded)
+	}
+
+	if _, err := cbor.Marshal(map[any]any{1: 1, int64(1): 2}); err == nil {
+		t.Error("Expected error for duplicate map keys")
+	}
+	if _, err := cbor.Marshal(struct{}{}); err == nil {
+		t.Error("Expected error for unsupported type")
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	encoded, err := cbor.Marshal([]any{cbor.RawMessage{0x01}, int64(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "820102" {
+		t.Errorf("Unexpected encoding %x", encoded)
+	}
+	if _, err := cbor.Marshal(cbor.RawMessage{0x18}); err == nil {
+		t.Error("Expected error for malformed RawMessage")
+	}
+}
+
+func TestMarshalNamedTypes(t *testing.T) {
+	type label int
+	type name string
+	encoded, err := cbor.Marshal(map[any]any{label(-1): name("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != "a1206178" {
+		t.Errorf("Unexpected encoding %x", encoded)
+	}
+}
@@ -0,0 +1,10 @@
+// Package cbor implements the Concise Binary Object Representation
+// (RFC 8949), as needed by the cose package.
+//
+// Values are represented with plain Go types rather than struct tags:
+// integers, []byte, string, []any, map[any]any, bool, nil, float64 and Tag.
+// Marshal produces the core deterministic encoding of RFC 8949 section 4.2:
+// shortest-form lengths and integers, and map keys sorted by their encoding.
+// Unmarshal accepts any well-formed encoding, including indefinite-length
+// items, and returns integers as int64, or uint64 when they do not fit.
+package cbor
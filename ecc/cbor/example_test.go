@@ -0,0 +1,30 @@
+package cbor_test
+
+import (
+	"fmt"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+)
+
+func ExampleMarshal() {
+	encoded, err := cbor.Marshal(map[any]any{
+		1:   "issuer",
+		"a": []any{1, 2, 3},
+		-1:  []byte{0xde, 0xad},
+	})
+	if err != nil {
+		fmt.Println("Failed to marshal:", err)
+		return
+	}
+	fmt.Printf("%x\n", encoded)
+
+	decoded, err := cbor.Unmarshal(encoded)
+	if err != nil {
+		fmt.Println("Failed to unmarshal:", err)
+		return
+	}
+	fmt.Println(decoded.(map[any]any)[int64(1)])
+	// Output:
+	// a301666973737565722042dead616183010203
+	// issuer
+}
@@ -0,0 +1,102 @@
+package cms
+
+import (
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+var (
+	OIDData              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	OIDSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDEnvelopedData     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	OIDAuthEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 23}
+
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+
+	oidDigestSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidAES256Wrap = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 45}
+	oidAES256GCM  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 46}
+
+	// oidDHSinglePassHKDFSHA512 is dhSinglePass-stdDH-hkdf-sha512-scheme
+	// from RFC 8418.
+	oidDHSinglePassHKDFSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 3, 21}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type issuerAndSerial struct {
+	IssuerName   asn1.RawValue
+	SerialNumber *big.Int
+}
+
+func newIssuerAndSerial(cert *x509.Certificate) issuerAndSerial {
+	return issuerAndSerial{
+		IssuerName:   asn1.RawValue{FullBytes: cert.RawIssuer},
+		SerialNumber: cert.SerialNumber,
+	}
+}
+
+func (ias issuerAndSerial) matches(cert *x509.Certificate) bool {
+	return string(ias.IssuerName.FullBytes) == string(cert.RawIssuer) &&
+		ias.SerialNumber.Cmp(cert.SerialNumber) == 0
+}
+
+// marshalContentInfo wraps the DER encoding of content in a ContentInfo of
+// the given type.
+func marshalContentInfo(contentType asn1.ObjectIdentifier, content []byte) ([]byte, error) {
+	return asn1.Marshal(contentInfo{
+		ContentType: contentType,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      content,
+		},
+	})
+}
+
+// parseContentInfo returns the content type and DER-encoded content of a
+// ContentInfo.
+func parseContentInfo(der []byte) (asn1.ObjectIdentifier, []byte, error) {
+	var info contentInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, errors.New("cms: trailing data after ContentInfo")
+	}
+	if len(info.Content.Bytes) == 0 {
+		return nil, nil, errors.New("cms: ContentInfo has no content")
+	}
+	return info.ContentType, info.Content.Bytes, nil
+}
+
+// retag replaces the identifier octet of a DER encoding, which is how
+// IMPLICIT tags on SEQUENCE types are applied and removed.
+func retag(der []byte, identifier byte) []byte {
+	out := append([]byte{}, der...)
+	out[0] = identifier
+	return out
+}
+
+const (
+	identifierSequence = 0x30
+	identifierSet      = 0x31
+)
+
+// contextConstructed returns the identifier octet of a constructed,
+// context-specific tag.
+func contextConstructed(tag int) byte {
+	return 0xa0 | byte(tag)
+}
@@ -0,0 +1,186 @@
+package cms_test
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cms"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+// newCertificate creates a certificate for name signed by parent, or a
+// self-signed CA certificate when parent is nil.
+func newCertificate(t *testing.T, name string, serial int64, parent *x509.Certificate, parentKey ecc.PrivateKey) (*x509.Certificate, ecc.PrivateKey) {
+	t.Helper()
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	if parent == nil {
+		template.KeyUsage = x509.KeyUsageCertSign
+		template.BasicConstraintsValid = true
+		template.IsCA = true
+		parent, parentKey = template, privateKey
+	}
+	der, err := x509.CreateCertificate(template, parent, publicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert, privateKey
+}
+
+func TestSignedData(t *testing.T) {
+	ca, caKey := newCertificate(t, "root", 1, nil, ecc.PrivateKey{})
+	alice, aliceKey := newCertificate(t, "alice", 2, ca, caKey)
+	bob, bobKey := newCertificate(t, "bob", 3, ca, caKey)
+	content := []byte("Hello, CMS!")
+
+	sd := cms.NewSignedData(content)
+	if err := sd.AddSigner(alice, aliceKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := sd.AddSigner(bob, bobKey); err != nil {
+		t.Fatal(err)
+	}
+	der, err := sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := cms.ParseSignedData(der)
+	if err != nil {
+		t.Fatalf("Failed to parse SignedData: %v", err)
+	}
+	if !bytes.Equal(message.Content, content) {
+		t.Errorf("Content = %q, want %q", message.Content, content)
+	}
+
+	signers, err := message.Verify(&x509.VerifyOptions{Roots: []*x509.Certificate{ca}})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	// SignerInfos is a DER SET OF, so the signers come back sorted by
+	// their encoding rather than in the order they were added
+	if len(signers) != 2 ||
+		!(signers[0].Equal(alice) && signers[1].Equal(bob) || signers[0].Equal(bob) && signers[1].Equal(alice)) {
+		t.Error("Verify returned the wrong signers")
+	}
+
+	other, _ := newCertificate(t, "other", 1, nil, ecc.PrivateKey{})
+	if _, err := message.Verify(&x509.VerifyOptions{Roots: []*x509.Certificate{other}}); err == nil {
+		t.Error("Verify succeeded with an untrusted root")
+	}
+
+	t.Run("tampered content", func(t *testing.T) {
+		tampered := bytes.Replace(der, content, []byte("Hello, XMS!"), 1)
+		message, err := cms.ParseSignedData(tampered)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := message.Verify(nil); err == nil {
+			t.Error("Verify succeeded on tampered content")
+		}
+	})
+}
+
+func TestSignedDataDetached(t *testing.T) {
+	ca, caKey := newCertificate(t, "root", 1, nil, ecc.PrivateKey{})
+	alice, aliceKey := newCertificate(t, "alice", 2, ca, caKey)
+	content := []byte("detached content")
+
+	sd := cms.NewSignedData(content)
+	if err := sd.AddSigner(alice, aliceKey); err != nil {
+		t.Fatal(err)
+	}
+	sd.Detach()
+	der, err := sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(der, content) {
+		t.Error("Detached signature contains the content")
+	}
+
+	message, err := cms.ParseSignedData(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Content != nil {
+		t.Error("Detached message has content")
+	}
+	if _, err := message.Verify(nil); err == nil {
+		t.Error("Verify succeeded without content")
+	}
+	if _, err := message.VerifyDetached(content, &x509.VerifyOptions{Roots: []*x509.Certificate{ca}}); err != nil {
+		t.Errorf("VerifyDetached failed: %v", err)
+	}
+	if _, err := message.VerifyDetached([]byte("other content"), nil); err == nil {
+		t.Error("VerifyDetached succeeded with the wrong content")
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	ca, caKey := newCertificate(t, "root", 1, nil, ecc.PrivateKey{})
+	alice, aliceKey := newCertificate(t, "alice", 2, ca, caKey)
+	bob, bobKey := newCertificate(t, "bob", 3, ca, caKey)
+	carol, carolKey := newCertificate(t, "carol", 4, ca, caKey)
+
+	for _, test := range []struct {
+		name      string
+		algorithm cms.ContentEncryptionAlgorithm
+	}{
+		{"EnvelopedData", cms.AES256CBC},
+		{"AuthEnvelopedData", cms.AES256GCM},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, content := range [][]byte{{}, []byte("0123456789abcdef"), []byte("Hello, recipients!")} {
+				der, err := cms.Encrypt(content, []*x509.Certificate{alice, bob}, test.algorithm)
+				if err != nil {
+					t.Fatalf("Encrypt failed: %v", err)
+				}
+
+				for _, recipient := range []struct {
+					cert *x509.Certificate
+					key  ecc.PrivateKey
+				}{{alice, aliceKey}, {bob, bobKey}} {
+					plaintext, err := cms.Decrypt(der, recipient.cert, recipient.key)
+					if err != nil {
+						t.Fatalf("Decrypt failed for %s: %v", recipient.cert.Subject.CommonName, err)
+					}
+					if !bytes.Equal(plaintext, content) {
+						t.Errorf("Decrypt = %q, want %q", plaintext, content)
+					}
+				}
+
+				if _, err := cms.Decrypt(der, carol, carolKey); err == nil {
+					t.Error("Decrypt succeeded for a non-recipient")
+				}
+				if _, err := cms.Decrypt(der, alice, bobKey); err == nil {
+					t.Error("Decrypt succeeded with the wrong private key")
+				}
+			}
+		})
+	}
+
+	if _, err := cms.Encrypt([]byte("x"), nil, cms.AES256GCM); err == nil {
+		t.Error("Encrypt succeeded without recipients")
+	}
+}
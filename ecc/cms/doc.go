@@ -0,0 +1,15 @@
+// Package cms implements the subset of the Cryptographic Message Syntax (RFC
+// 5652) needed to exchange signed and encrypted documents using
+// EccFrog512ck2 certificates.
+//
+// SignedData messages are signed with ECDSA over SHA-512, always carry signed
+// attributes (content type, message digest and signing time), and may either
+// encapsulate the content or be detached from it.
+//
+// EnvelopedData and AuthEnvelopedData (RFC 5083) messages are encrypted for
+// any number of recipients using a KeyAgreeRecipientInfo: a single ephemeral
+// EccFrog512ck2 key is agreed with each recipient's public key, the shared
+// secret is turned into a key-encryption key with HKDF-SHA512 as in RFC 8418,
+// and the content-encryption key is wrapped with AES-256 key wrap. Content is
+// encrypted with AES-256-CBC or, for AuthEnvelopedData, AES-256-GCM.
+package cms
@@ -0,0 +1,430 @@
+package cms
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ContentEncryptionAlgorithm selects how the content of an encrypted message
+// is protected.
+type ContentEncryptionAlgorithm int
+
+const (
+	// AES256CBC produces an EnvelopedData message with AES-256-CBC content
+	// encryption.
+	AES256CBC ContentEncryptionAlgorithm = iota
+	// AES256GCM produces an AuthEnvelopedData message with AES-256-GCM
+	// content encryption.
+	AES256GCM
+)
+
+const (
+	contentKeySize = 32
+	gcmNonceSize   = 12
+	gcmTagSize     = 16
+)
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"optional,tag:0"`
+}
+
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []asn1.RawValue `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type authEnvelopedData struct {
+	Version                  int
+	RecipientInfos           []asn1.RawValue `asn1:"set"`
+	AuthEncryptedContentInfo encryptedContentInfo
+	MAC                      []byte
+}
+
+type recipientEncryptedKey struct {
+	RID          issuerAndSerial
+	EncryptedKey []byte
+}
+
+type keyAgreeRecipientInfo struct {
+	Version                int
+	Originator             asn1.RawValue
+	UKM                    []byte `asn1:"explicit,optional,tag:1"`
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	RecipientEncryptedKeys []recipientEncryptedKey
+}
+
+type eccCMSSharedInfo struct {
+	KeyInfo     pkix.AlgorithmIdentifier
+	EntityUInfo []byte `asn1:"explicit,optional,tag:0"`
+	SuppPubInfo []byte `asn1:"explicit,tag:2"`
+}
+
+type gcmParameters struct {
+	Nonce  []byte
+	ICVLen int
+}
+
+// keyWrapAlgorithm is the AlgorithmIdentifier of the AES-256 key wrap used to
+// protect the content-encryption key.
+func keyWrapAlgorithm() pkix.AlgorithmIdentifier {
+	return pkix.AlgorithmIdentifier{Algorithm: oidAES256Wrap}
+}
+
+// deriveKEK derives the key-encryption key from the shared secret as
+// described in RFC 8418, section 2.2, using HKDF-SHA512 with the DER encoding
+// of the ECC-CMS-SharedInfo as the info parameter.
+func deriveKEK(sharedSecret, ukm []byte) ([]byte, error) {
+	suppPubInfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(suppPubInfo, 8*contentKeySize)
+	info, err := asn1.Marshal(eccCMSSharedInfo{
+		KeyInfo:     keyWrapAlgorithm(),
+		EntityUInfo: ukm,
+		SuppPubInfo: suppPubInfo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kek := make([]byte, contentKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha512.New, sharedSecret, nil, info), kek); err != nil {
+		return nil, err
+	}
+	return kek, nil
+}
+
+// Encrypt encrypts content for each of the recipients and returns a
+// DER-encoded ContentInfo. With AES256CBC the message is an EnvelopedData,
+// and with AES256GCM it is an AuthEnvelopedData.
+//
+// A single ephemeral key is generated for the message and agreed with every
+// recipient's public key, so adding recipients only adds a wrapped key per
+// recipient.
+func Encrypt(content []byte, recipients []*x509.Certificate, algorithm ContentEncryptionAlgorithm) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("cms: no recipients")
+	}
+
+	contentKey := make([]byte, contentKeySize)
+	if _, err := io.ReadFull(rand.Reader, contentKey); err != nil {
+		return nil, err
+	}
+
+	recipientInfo, err := newKeyAgreeRecipientInfo(contentKey, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case AES256CBC:
+		iv := make([]byte, aes.BlockSize)
+		if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+			return nil, err
+		}
+		params, err := asn1.Marshal(iv)
+		if err != nil {
+			return nil, err
+		}
+		padded := pad(content, aes.BlockSize)
+		ciphertext := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+		der, err := asn1.Marshal(envelopedData{
+			Version:        2,
+			RecipientInfos: []asn1.RawValue{recipientInfo},
+			EncryptedContentInfo: encryptedContentInfo{
+				ContentType: OIDData,
+				ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+					Algorithm:  oidAES256CBC,
+					Parameters: asn1.RawValue{FullBytes: params},
+				},
+				EncryptedContent: ciphertext,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return marshalContentInfo(OIDEnvelopedData, der)
+
+	case AES256GCM:
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, gcmNonceSize)
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+		params, err := asn1.Marshal(gcmParameters{Nonce: nonce, ICVLen: gcmTagSize})
+		if err != nil {
+			return nil, err
+		}
+		sealed := aead.Seal(nil, nonce, content, nil)
+
+		der, err := asn1.Marshal(authEnvelopedData{
+			Version:        0,
+			RecipientInfos: []asn1.RawValue{recipientInfo},
+			AuthEncryptedContentInfo: encryptedContentInfo{
+				ContentType: OIDData,
+				ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+					Algorithm:  oidAES256GCM,
+					Parameters: asn1.RawValue{FullBytes: params},
+				},
+				EncryptedContent: sealed[:len(sealed)-gcmTagSize],
+			},
+			MAC: sealed[len(sealed)-gcmTagSize:],
+		})
+		if err != nil {
+			return nil, err
+		}
+		return marshalContentInfo(OIDAuthEnvelopedData, der)
+
+	default:
+		return nil, fmt.Errorf("cms: unsupported content encryption algorithm %d", algorithm)
+	}
+}
+
+// newKeyAgreeRecipientInfo wraps contentKey for every recipient, and returns
+// the DER encoding of the resulting RecipientInfo.
+func newKeyAgreeRecipientInfo(contentKey []byte, recipients []*x509.Certificate) (asn1.RawValue, error) {
+	ephemeralKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	ephemeralPublicKey, err := ephemeralKey.DerivePublicKey()
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	originatorKey, err := x509.MarshalPKIXPublicKey(ephemeralPublicKey)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+
+	ukm := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, ukm); err != nil {
+		return asn1.RawValue{}, err
+	}
+	kek := ecdh.ECDHPrivateKey(ephemeralKey)
+
+	var encryptedKeys []recipientEncryptedKey
+	for _, recipient := range recipients {
+		sharedSecret, err := kek.DeriveFixedSizeSharedSecret(recipient.PublicKey)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		wrappingKey, err := deriveKEK(sharedSecret, ukm)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		wrapped, err := cryptohelpers.AESKeyWrap(wrappingKey, contentKey)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		encryptedKeys = append(encryptedKeys, recipientEncryptedKey{
+			RID:          newIssuerAndSerial(recipient),
+			EncryptedKey: wrapped,
+		})
+	}
+
+	keyWrap, err := asn1.Marshal(keyWrapAlgorithm())
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+
+	der, err := asn1.Marshal(keyAgreeRecipientInfo{
+		Version: 3,
+		// originator [0] EXPLICIT OriginatorIdentifierOrKey, choosing
+		// originatorKey [1] IMPLICIT OriginatorPublicKey.
+		Originator: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      retag(originatorKey, contextConstructed(1)),
+		},
+		UKM: ukm,
+		KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidDHSinglePassHKDFSHA512,
+			Parameters: asn1.RawValue{FullBytes: keyWrap},
+		},
+		RecipientEncryptedKeys: encryptedKeys,
+	})
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+
+	// kari [1] IMPLICIT KeyAgreeRecipientInfo
+	return asn1.RawValue{FullBytes: retag(der, contextConstructed(1))}, nil
+}
+
+// Decrypt decrypts a DER-encoded EnvelopedData or AuthEnvelopedData message
+// for the recipient identified by cert, using its private key.
+func Decrypt(der []byte, cert *x509.Certificate, priv ecc.PrivateKey) ([]byte, error) {
+	contentType, content, err := parseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case contentType.Equal(OIDEnvelopedData):
+		var data envelopedData
+		if rest, err := asn1.Unmarshal(content, &data); err != nil {
+			return nil, err
+		} else if len(rest) != 0 {
+			return nil, errors.New("cms: trailing data after EnvelopedData")
+		}
+		contentKey, err := unwrapContentKey(data.RecipientInfos, cert, priv)
+		if err != nil {
+			return nil, err
+		}
+		return decryptCBC(contentKey, data.EncryptedContentInfo)
+
+	case contentType.Equal(OIDAuthEnvelopedData):
+		var data authEnvelopedData
+		if rest, err := asn1.Unmarshal(content, &data); err != nil {
+			return nil, err
+		} else if len(rest) != 0 {
+			return nil, errors.New("cms: trailing data after AuthEnvelopedData")
+		}
+		contentKey, err := unwrapContentKey(data.RecipientInfos, cert, priv)
+		if err != nil {
+			return nil, err
+		}
+		return decryptGCM(contentKey, data.AuthEncryptedContentInfo, data.MAC)
+
+	default:
+		return nil, fmt.Errorf("cms: content type %v is not an encrypted message", contentType)
+	}
+}
+
+func unwrapContentKey(recipientInfos []asn1.RawValue, cert *x509.Certificate, priv ecc.PrivateKey) ([]byte, error) {
+	for _, recipientInfo := range recipientInfos {
+		if recipientInfo.Class != asn1.ClassContextSpecific || recipientInfo.Tag != 1 {
+			continue
+		}
+
+		var kari keyAgreeRecipientInfo
+		if _, err := asn1.Unmarshal(retag(recipientInfo.FullBytes, identifierSequence), &kari); err != nil {
+			return nil, err
+		}
+		if !kari.KeyEncryptionAlgorithm.Algorithm.Equal(oidDHSinglePassHKDFSHA512) {
+			continue
+		}
+
+		for _, encryptedKey := range kari.RecipientEncryptedKeys {
+			if !encryptedKey.RID.matches(cert) {
+				continue
+			}
+
+			var originator asn1.RawValue
+			if _, err := asn1.Unmarshal(kari.Originator.Bytes, &originator); err != nil {
+				return nil, err
+			}
+			if originator.Class != asn1.ClassContextSpecific || originator.Tag != 1 {
+				return nil, errors.New("cms: originator is not a public key")
+			}
+			originatorKey, err := x509.ParsePKIXPublicKey(retag(originator.FullBytes, identifierSequence))
+			if err != nil {
+				return nil, err
+			}
+
+			sharedSecret, err := ecdh.ECDHPrivateKey(priv).DeriveFixedSizeSharedSecret(originatorKey)
+			if err != nil {
+				return nil, err
+			}
+			wrappingKey, err := deriveKEK(sharedSecret, kari.UKM)
+			if err != nil {
+				return nil, err
+			}
+			return cryptohelpers.AESKeyUnwrap(wrappingKey, encryptedKey.EncryptedKey)
+		}
+	}
+	return nil, errors.New("cms: no recipient matches the certificate")
+}
+
+func decryptCBC(contentKey []byte, info encryptedContentInfo) ([]byte, error) {
+	if !info.ContentEncryptionAlgorithm.Algorithm.Equal(oidAES256CBC) {
+		return nil, fmt.Errorf("cms: unsupported content encryption algorithm %v", info.ContentEncryptionAlgorithm.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(info.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("cms: invalid AES-CBC IV")
+	}
+	if len(info.EncryptedContent) == 0 || len(info.EncryptedContent)%aes.BlockSize != 0 {
+		return nil, errors.New("cms: invalid encrypted content length")
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(info.EncryptedContent))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, info.EncryptedContent)
+	return unpad(plaintext, aes.BlockSize)
+}
+
+func decryptGCM(contentKey []byte, info encryptedContentInfo, mac []byte) ([]byte, error) {
+	if !info.ContentEncryptionAlgorithm.Algorithm.Equal(oidAES256GCM) {
+		return nil, fmt.Errorf("cms: unsupported content encryption algorithm %v", info.ContentEncryptionAlgorithm.Algorithm)
+	}
+	params := gcmParameters{ICVLen: 12}
+	if _, err := asn1.Unmarshal(info.ContentEncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+	if len(params.Nonce) != gcmNonceSize || params.ICVLen != gcmTagSize || len(mac) != gcmTagSize {
+		return nil, errors.New("cms: unsupported AES-GCM parameters")
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, params.Nonce, append(append([]byte{}, info.EncryptedContent...), mac...), nil)
+}
+
+// pad applies PKCS #7 padding, as required by RFC 5652, section 6.3.
+func pad(data []byte, blockSize int) []byte {
+	padding := blockSize - len(data)%blockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > blockSize || padding > len(data) {
+		return nil, errors.New("cms: invalid padding")
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("cms: invalid padding")
+		}
+	}
+	return data[:len(data)-padding], nil
+}
@@ -0,0 +1,59 @@
+package cms_test
+
+import (
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cms"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+func ExampleEncrypt() {
+	// The recipient has a self-signed certificate for their key
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate private key:", err)
+		return
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		fmt.Println("Failed to derive public key:", err)
+		return
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "alice"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageKeyAgreement,
+	}
+	der, err := x509.CreateCertificate(template, template, publicKey, privateKey)
+	if err != nil {
+		fmt.Println("Failed to create certificate:", err)
+		return
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		fmt.Println("Failed to parse certificate:", err)
+		return
+	}
+
+	// Encrypt a message for the certificate
+	message, err := cms.Encrypt([]byte("Hello, Alice!"), []*x509.Certificate{cert}, cms.AES256GCM)
+	if err != nil {
+		fmt.Println("Failed to encrypt:", err)
+		return
+	}
+
+	// The recipient decrypts it with their private key
+	plaintext, err := cms.Decrypt(message, cert, privateKey)
+	if err != nil {
+		fmt.Println("Failed to decrypt:", err)
+		return
+	}
+	fmt.Println(string(plaintext))
+	// Output: Hello, Alice!
+}
@@ -0,0 +1,371 @@
+package cms
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdsa"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerial
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+// newAttribute creates an attribute with a single, already DER-encoded,
+// value.
+func newAttribute(attributeType asn1.ObjectIdentifier, value []byte) attribute {
+	return attribute{
+		Type: attributeType,
+		Values: asn1.RawValue{
+			Class:      asn1.ClassUniversal,
+			Tag:        asn1.TagSet,
+			IsCompound: true,
+			Bytes:      value,
+		},
+	}
+}
+
+// marshalAttributes encodes attributes as a DER SET OF, which requires the
+// encoded elements to be sorted.
+func marshalAttributes(attributes []attribute) ([]byte, error) {
+	var encoded [][]byte
+	for _, attr := range attributes {
+		der, err := asn1.Marshal(attr)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, der)
+	}
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+	return asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      bytes.Join(encoded, nil),
+	})
+}
+
+// SignedData builds a CMS SignedData message. Create one with NewSignedData,
+// add one or more signers, and call Finish to obtain the DER encoding.
+type SignedData struct {
+	content      []byte
+	detached     bool
+	certificates []*x509.Certificate
+	signerInfos  []signerInfo
+}
+
+// NewSignedData creates a SignedData message that will sign content, with the
+// id-data content type.
+func NewSignedData(content []byte) *SignedData {
+	return &SignedData{content: content}
+}
+
+// AddSigner signs the content with priv using ECDSA with SHA-512 and adds the
+// signature to the message. The signer is identified by the issuer and serial
+// number of cert, which is also included in the message.
+//
+// The signature covers the content type, the SHA-512 digest of the content
+// and the signing time.
+func (sd *SignedData) AddSigner(cert *x509.Certificate, priv ecc.PrivateKey) error {
+	digest := sha512.Sum512(sd.content)
+
+	contentType, err := asn1.Marshal(OIDData)
+	if err != nil {
+		return err
+	}
+	messageDigest, err := asn1.Marshal(digest[:])
+	if err != nil {
+		return err
+	}
+	signingTime, err := asn1.MarshalWithParams(time.Now().UTC().Truncate(time.Second), "utc")
+	if err != nil {
+		return err
+	}
+
+	signedAttrs, err := marshalAttributes([]attribute{
+		newAttribute(oidAttributeContentType, contentType),
+		newAttribute(oidAttributeMessageDigest, messageDigest),
+		newAttribute(oidAttributeSigningTime, signingTime),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The signature is computed over the DER encoding of the attributes with
+	// the SET OF tag, not the IMPLICIT [0] tag they are transmitted with.
+	signature, err := ecdsa.NewSign(sha512.New, priv).SignASN1(signedAttrs)
+	if err != nil {
+		return err
+	}
+
+	sd.signerInfos = append(sd.signerInfos, signerInfo{
+		Version:            1,
+		SID:                newIssuerAndSerial(cert),
+		DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA512},
+		SignedAttrs:        asn1.RawValue{FullBytes: retag(signedAttrs, contextConstructed(0))},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: x509.OIDSignatureECDSAWithSHA512},
+		Signature:          signature,
+	})
+	sd.AddCertificate(cert)
+	return nil
+}
+
+// AddCertificate includes cert in the message, for instance an intermediate
+// CA certificate needed to verify a signer's certificate.
+func (sd *SignedData) AddCertificate(cert *x509.Certificate) {
+	for _, c := range sd.certificates {
+		if c.Equal(cert) {
+			return
+		}
+	}
+	sd.certificates = append(sd.certificates, cert)
+}
+
+// Detach removes the content from the message, producing a detached
+// signature that must be verified together with the original content.
+func (sd *SignedData) Detach() {
+	sd.detached = true
+}
+
+// Finish returns the DER encoding of the SignedData message, wrapped in a
+// ContentInfo.
+func (sd *SignedData) Finish() ([]byte, error) {
+	if len(sd.signerInfos) == 0 {
+		return nil, errors.New("cms: SignedData has no signers")
+	}
+
+	data := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidDigestSHA512}},
+		ContentInfo:      encapsulatedContentInfo{EContentType: OIDData},
+		SignerInfos:      sd.signerInfos,
+	}
+
+	if !sd.detached {
+		content, err := asn1.Marshal(sd.content)
+		if err != nil {
+			return nil, err
+		}
+		data.ContentInfo.EContent = asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      content,
+		}
+	}
+
+	var certificates []byte
+	for _, cert := range sd.certificates {
+		certificates = append(certificates, cert.Raw...)
+	}
+	if len(certificates) > 0 {
+		data.Certificates = asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      certificates,
+		}
+	}
+
+	der, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return marshalContentInfo(OIDSignedData, der)
+}
+
+// SignedMessage is a parsed CMS SignedData message.
+type SignedMessage struct {
+	// Content is the encapsulated content, or nil if the signature is
+	// detached.
+	Content []byte
+
+	// Certificates are the certificates included in the message.
+	Certificates []*x509.Certificate
+
+	contentType asn1.ObjectIdentifier
+	signerInfos []signerInfo
+}
+
+// ParseSignedData parses a DER-encoded ContentInfo containing a SignedData
+// message. Signatures are not checked until Verify or VerifyDetached is
+// called.
+func ParseSignedData(der []byte) (*SignedMessage, error) {
+	contentType, content, err := parseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+	if !contentType.Equal(OIDSignedData) {
+		return nil, fmt.Errorf("cms: content type %v is not SignedData", contentType)
+	}
+
+	var data signedData
+	rest, err := asn1.Unmarshal(content, &data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("cms: trailing data after SignedData")
+	}
+
+	message := &SignedMessage{
+		contentType: data.ContentInfo.EContentType,
+		signerInfos: data.SignerInfos,
+	}
+
+	if len(data.ContentInfo.EContent.Bytes) > 0 {
+		if _, err := asn1.Unmarshal(data.ContentInfo.EContent.Bytes, &message.Content); err != nil {
+			return nil, fmt.Errorf("cms: failed to parse encapsulated content: %v", err)
+		}
+		if message.Content == nil {
+			message.Content = []byte{}
+		}
+	}
+
+	for remaining := data.Certificates.Bytes; len(remaining) > 0; {
+		var raw asn1.RawValue
+		remaining, err = asn1.Unmarshal(remaining, &raw)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		message.Certificates = append(message.Certificates, cert)
+	}
+
+	return message, nil
+}
+
+// Verify checks every signature on a message with encapsulated content, and
+// returns the certificates of the signers.
+//
+// If opts is not nil, each signer's certificate must also chain to one of
+// opts.Roots; the certificates included in the message are used as
+// intermediates. If opts is nil, only the signatures are checked, and the
+// caller is responsible for deciding whether to trust the returned
+// certificates.
+func (m *SignedMessage) Verify(opts *x509.VerifyOptions) ([]*x509.Certificate, error) {
+	if m.Content == nil {
+		return nil, errors.New("cms: message has detached content; use VerifyDetached")
+	}
+	return m.verify(m.Content, opts)
+}
+
+// VerifyDetached is like Verify, but checks the signatures against content
+// supplied by the caller, as needed for detached signatures.
+func (m *SignedMessage) VerifyDetached(content []byte, opts *x509.VerifyOptions) ([]*x509.Certificate, error) {
+	return m.verify(content, opts)
+}
+
+func (m *SignedMessage) verify(content []byte, opts *x509.VerifyOptions) ([]*x509.Certificate, error) {
+	if len(m.signerInfos) == 0 {
+		return nil, errors.New("cms: message has no signers")
+	}
+
+	var signers []*x509.Certificate
+	for _, si := range m.signerInfos {
+		cert, err := m.verifySigner(si, content)
+		if err != nil {
+			return nil, err
+		}
+		if opts != nil {
+			chainOpts := *opts
+			chainOpts.Intermediates = append(append([]*x509.Certificate{}, opts.Intermediates...), m.Certificates...)
+			if _, err := cert.Verify(chainOpts); err != nil {
+				return nil, err
+			}
+		}
+		signers = append(signers, cert)
+	}
+	return signers, nil
+}
+
+func (m *SignedMessage) verifySigner(si signerInfo, content []byte) (*x509.Certificate, error) {
+	var cert *x509.Certificate
+	for _, c := range m.Certificates {
+		if si.SID.matches(c) {
+			cert = c
+			break
+		}
+	}
+	if cert == nil {
+		return nil, errors.New("cms: signer certificate not found in message")
+	}
+
+	if !si.DigestAlgorithm.Algorithm.Equal(oidDigestSHA512) {
+		return nil, fmt.Errorf("cms: unsupported digest algorithm %v", si.DigestAlgorithm.Algorithm)
+	}
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return nil, errors.New("cms: signer has no signed attributes")
+	}
+
+	signedAttrs := retag(si.SignedAttrs.FullBytes, identifierSet)
+	var attributes []attribute
+	if _, err := asn1.UnmarshalWithParams(signedAttrs, &attributes, "set"); err != nil {
+		return nil, err
+	}
+
+	var contentType asn1.ObjectIdentifier
+	var messageDigest []byte
+	for _, attr := range attributes {
+		switch {
+		case attr.Type.Equal(oidAttributeContentType):
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &contentType); err != nil {
+				return nil, err
+			}
+		case attr.Type.Equal(oidAttributeMessageDigest):
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &messageDigest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !contentType.Equal(m.contentType) {
+		return nil, errors.New("cms: content type attribute does not match the content")
+	}
+	digest := sha512.Sum512(content)
+	if subtle.ConstantTimeCompare(messageDigest, digest[:]) != 1 {
+		return nil, errors.New("cms: message digest does not match the content")
+	}
+
+	if err := cert.CheckSignature(si.SignatureAlgorithm.Algorithm, signedAttrs, si.Signature); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
@@ -0,0 +1,244 @@
+package cose
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+)
+
+// Algorithm is a COSE algorithm identifier.
+type Algorithm int64
+
+const (
+	// AlgEF512 is ECDSA over EccFrog512ck2 with SHA-512. Signatures are the
+	// 64-byte big-endian r and s concatenated. The value is from the
+	// private-use range.
+	AlgEF512 Algorithm = -65537
+
+	// AlgECDHESHKDF512 is ephemeral-static ECDH with the content key derived
+	// by HKDF-SHA512.
+	AlgECDHESHKDF512 Algorithm = -26
+	// AlgECDHESA256KW is ephemeral-static ECDH with a key encryption key
+	// derived by HKDF-SHA256, which wraps the content key with AES key wrap.
+	AlgECDHESA256KW Algorithm = -31
+
+	// AlgA256GCM is AES-256-GCM content encryption.
+	AlgA256GCM Algorithm = 3
+	// AlgHMAC256 is HMAC-SHA256 with a 256-bit tag.
+	AlgHMAC256 Algorithm = 5
+
+	algA256KW Algorithm = -5
+)
+
+// Common header parameter labels.
+const (
+	HeaderAlgorithm    int64 = 1
+	HeaderCritical     int64 = 2
+	HeaderContentType  int64 = 3
+	HeaderKeyID        int64 = 4
+	HeaderIV           int64 = 5
+	HeaderEphemeralKey int64 = -1
+)
+
+// CBOR tags of the COSE and CWT structures.
+const (
+	tagSign1   = 18
+	tagMac0    = 17
+	tagEncrypt = 96
+	tagSign    = 98
+	tagCWT     = 61
+)
+
+// Headers are the header parameters of a message, signature or recipient.
+// Labels are int64 or string, and values are of the types used by the cbor
+// package.
+type Headers struct {
+	Protected   map[any]any
+	Unprotected map[any]any
+
+	// rawProtected is the protected header as it was signed or parsed, so
+	// that it can be verified even if it is not in deterministic encoding.
+	rawProtected []byte
+}
+
+func (h *Headers) setProtected(label int64, value any) {
+	if h.Protected == nil {
+		h.Protected = map[any]any{}
+	}
+	h.Protected[label] = value
+	h.rawProtected = nil
+}
+
+func (h *Headers) setUnprotected(label int64, value any) {
+	if h.Unprotected == nil {
+		h.Unprotected = map[any]any{}
+	}
+	h.Unprotected[label] = value
+}
+
+// get returns a header parameter, looking at the protected header first.
+func (h *Headers) get(label int64) (any, bool) {
+	if v, ok := h.Protected[label]; ok {
+		return v, true
+	}
+	v, ok := h.Unprotected[label]
+	return v, ok
+}
+
+// Algorithm returns the algorithm header parameter, or 0 if there is none.
+func (h *Headers) Algorithm() Algorithm {
+	v, _ := h.get(HeaderAlgorithm)
+	switch v := v.(type) {
+	case Algorithm:
+		return v
+	case int64:
+		return Algorithm(v)
+	case int:
+		return Algorithm(v)
+	}
+	return 0
+}
+
+// KeyID returns the key ID header parameter, or nil if there is none.
+func (h *Headers) KeyID() []byte {
+	v, _ := h.get(HeaderKeyID)
+	kid, _ := v.([]byte)
+	return kid
+}
+
+// checkAlgorithm sets the algorithm header parameter to alg if there is
+// none, and otherwise checks that it is alg.
+func (h *Headers) checkAlgorithm(alg Algorithm) error {
+	if _, ok := h.get(HeaderAlgorithm); !ok {
+		h.setProtected(HeaderAlgorithm, alg)
+		return nil
+	}
+	if got := h.Algorithm(); got != alg {
+		return fmt.Errorf("cose: unexpected algorithm %d, expected %d", got, alg)
+	}
+	return nil
+}
+
+func (h *Headers) protectedBytes() ([]byte, error) {
+	if h.rawProtected != nil {
+		return h.rawProtected, nil
+	}
+	if len(h.Protected) == 0 {
+		return []byte{}, nil
+	}
+	return cbor.Marshal(h.Protected)
+}
+
+// seal fixes the encoding of the protected header before it is signed.
+func (h *Headers) seal() ([]byte, error) {
+	protected, err := h.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	h.rawProtected = protected
+	return protected, nil
+}
+
+func (h *Headers) unprotected() map[any]any {
+	if h.Unprotected == nil {
+		return map[any]any{}
+	}
+	return h.Unprotected
+}
+
+func (h *Headers) checkCritical() error {
+	crit, ok := h.Protected[HeaderCritical]
+	if !ok {
+		return nil
+	}
+	labels, ok := crit.([]any)
+	if !ok || len(labels) == 0 {
+		return errors.New("cose: malformed critical header parameter")
+	}
+	for _, label := range labels {
+		switch label {
+		case HeaderAlgorithm, HeaderCritical, HeaderContentType, HeaderKeyID, HeaderIV:
+		default:
+			return fmt.Errorf("cose: unsupported critical header parameter %v", label)
+		}
+	}
+	return nil
+}
+
+// parseHeaders parses the protected and unprotected headers at the start of
+// a COSE structure.
+func parseHeaders(protected, unprotected any) (Headers, error) {
+	rawProtected, ok := protected.([]byte)
+	if !ok {
+		return Headers{}, errors.New("cose: protected header is not a byte string")
+	}
+	h := Headers{rawProtected: rawProtected}
+	if len(rawProtected) > 0 {
+		v, err := cbor.Unmarshal(rawProtected)
+		if err != nil {
+			return Headers{}, fmt.Errorf("cose: malformed protected header: %v", err)
+		}
+		if h.Protected, ok = v.(map[any]any); !ok {
+			return Headers{}, errors.New("cose: protected header is not a map")
+		}
+	}
+	if h.Unprotected, ok = unprotected.(map[any]any); !ok {
+		return Headers{}, errors.New("cose: unprotected header is not a map")
+	}
+	for label := range h.Unprotected {
+		if _, ok := h.Protected[label]; ok {
+			return Headers{}, fmt.Errorf("cose: header parameter %v is both protected and unprotected", label)
+		}
+	}
+	if _, ok := h.Unprotected[HeaderCritical]; ok {
+		return Headers{}, errors.New("cose: critical header parameter is not protected")
+	}
+	if err := h.checkCritical(); err != nil {
+		return Headers{}, err
+	}
+	return h, nil
+}
+
+// parseMessage decodes a COSE structure, which may carry its tag, into an
+// array of n items.
+func parseMessage(data []byte, tag uint64, n int) ([]any, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("cose: %v", err)
+	}
+	if t, ok := v.(cbor.Tag); ok {
+		if t.Number != tag {
+			return nil, fmt.Errorf("cose: unexpected tag %d, expected %d", t.Number, tag)
+		}
+		v = t.Content
+	}
+	items, ok := v.([]any)
+	if !ok || len(items) != n {
+		return nil, errors.New("cose: malformed message")
+	}
+	return items, nil
+}
+
+// payloadValue encodes a payload, with nil for a detached payload.
+func payloadValue(payload []byte) any {
+	if payload == nil {
+		return nil
+	}
+	return payload
+}
+
+func parsePayload(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	}
+	return nil, errors.New("cose: payload is not a byte string")
+}
+
+func equalKeyID(a, b []byte) bool {
+	return a == nil || b == nil || bytes.Equal(a, b)
+}
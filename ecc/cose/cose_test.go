@@ -0,0 +1,325 @@
+package cose_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cose"
+)
+
+func generateKey(t *testing.T, keyID string) *cose.Key {
+	t.Helper()
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kid []byte
+	if keyID != "" {
+		kid = []byte(keyID)
+	}
+	key, err := cose.NewPrivateKey(privateKey, kid)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	return key
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	key := generateKey(t, "device-1")
+	key.Algorithm = cose.AlgEF512
+
+	for _, k := range []*cose.Key{key, key.Public()} {
+		encoded, err := k.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("Failed to marshal key: %v", err)
+		}
+		parsed, err := cose.ParseKey(encoded)
+		if err != nil {
+			t.Fatalf("Failed to parse key: %v", err)
+		}
+		if !bytes.Equal(parsed.KeyID, k.KeyID) || parsed.Algorithm != k.Algorithm || !parsed.PublicKey.Equal(k.PublicKey) {
+			t.Error("Parsed key does not match")
+		}
+		if (parsed.PrivateKey == nil) != (k.PrivateKey == nil) {
+			t.Error("Parsed key private part does not match")
+		}
+		if parsed.PrivateKey != nil && parsed.PrivateKey.GetKey().Cmp(k.PrivateKey.GetKey()) != 0 {
+			t.Error("Parsed private key does not match")
+		}
+	}
+}
+
+func TestParseKeyCompressed(t *testing.T) {
+	key := generateKey(t, "")
+	encoded, err := key.Public().MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := cbor.Unmarshal(encoded)
+	m := v.(map[any]any)
+	y := m[int64(-3)].([]byte)
+	m[int64(-3)] = y[len(y)-1]&1 == 1
+	compressed, err := cbor.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := cose.ParseKey(compressed)
+	if err != nil {
+		t.Fatalf("Failed to parse compressed key: %v", err)
+	}
+	if !parsed.PublicKey.Equal(key.PublicKey) {
+		t.Error("Decompressed key does not match")
+	}
+
+	m[int64(-1)] = int64(1) // P-256
+	wrongCurve, _ := cbor.Marshal(m)
+	if _, err := cose.ParseKey(wrongCurve); err == nil {
+		t.Error("Expected error for unsupported curve")
+	}
+}
+
+func TestSign1(t *testing.T) {
+	key := generateKey(t, "signer")
+	m := &cose.Sign1Message{Payload: []byte("This is the content.")}
+	m.Headers.Protected = map[any]any{cose.HeaderContentType: int64(0)}
+	if err := m.Sign([]byte("external"), key); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	encoded, err := m.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	parsed, err := cose.ParseSign1Message(encoded)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if !bytes.Equal(parsed.Headers.KeyID(), []byte("signer")) {
+		t.Errorf("Unexpected key ID %q", parsed.Headers.KeyID())
+	}
+	if err := parsed.Verify([]byte("external"), key.Public()); err != nil {
+		t.Errorf("Failed to verify: %v", err)
+	}
+	if err := parsed.Verify(nil, key.Public()); !errors.Is(err, cose.ErrVerification) {
+		t.Errorf("Expected verification failure for wrong external data, got %v", err)
+	}
+	if err := parsed.Verify([]byte("external"), generateKey(t, "").Public()); !errors.Is(err, cose.ErrVerification) {
+		t.Errorf("Expected verification failure for wrong key, got %v", err)
+	}
+
+	parsed.Payload = []byte("This is not the content.")
+	if err := parsed.Verify([]byte("external"), key.Public()); !errors.Is(err, cose.ErrVerification) {
+		t.Errorf("Expected verification failure for modified payload, got %v", err)
+	}
+}
+
+func TestSign1Detached(t *testing.T) {
+	key := generateKey(t, "")
+	payload := []byte("detached")
+	m := &cose.Sign1Message{Payload: payload}
+	if err := m.Sign(nil, key); err != nil {
+		t.Fatal(err)
+	}
+	m.Payload = nil
+	encoded, err := m.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := cose.ParseSign1Message(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Payload != nil {
+		t.Fatal("Expected detached payload")
+	}
+	parsed.Payload = payload
+	if err := parsed.Verify(nil, key); err != nil {
+		t.Errorf("Failed to verify detached payload: %v", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	alice := generateKey(t, "alice")
+	bob := generateKey(t, "bob")
+
+	m := &cose.SignMessage{Payload: []byte("jointly signed")}
+	for _, key := range []*cose.Key{alice, bob} {
+		if err := m.AddSignature(nil, key); err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+	}
+	encoded, err := m.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := cose.ParseSignMessage(encoded)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if len(parsed.Signatures) != 2 {
+		t.Fatalf("Expected 2 signatures, got %d", len(parsed.Signatures))
+	}
+	for _, key := range []*cose.Key{alice, bob} {
+		if err := parsed.Verify(nil, key.Public()); err != nil {
+			t.Errorf("Failed to verify signature by %s: %v", key.KeyID, err)
+		}
+	}
+
+	// Carol has Alice's key ID, so only Alice's signature is tried
+	carol := generateKey(t, "alice")
+	if err := parsed.Verify(nil, carol.Public()); !errors.Is(err, cose.ErrVerification) {
+		t.Errorf("Expected verification failure, got %v", err)
+	}
+}
+
+func TestMac0(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	m := &cose.Mac0Message{Payload: []byte("sensor reading")}
+	if err := m.Authenticate(nil, key); err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	encoded, err := m.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := cose.ParseMac0Message(encoded)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if err := parsed.Verify(nil, key); err != nil {
+		t.Errorf("Failed to verify: %v", err)
+	}
+	wrongKey := bytes.Repeat([]byte{0x43}, 32)
+	if err := parsed.Verify(nil, wrongKey); !errors.Is(err, cose.ErrVerification) {
+		t.Errorf("Expected verification failure, got %v", err)
+	}
+	if err := m.Authenticate(nil, key[:16]); err == nil {
+		t.Error("Expected error for short key")
+	}
+}
+
+func TestEncryptDirect(t *testing.T) {
+	recipient := generateKey(t, "device")
+	plaintext := []byte("firmware update")
+
+	encrypted, err := cose.Encrypt(plaintext, []byte("aad"), cose.AlgECDHESHKDF512, recipient.Public())
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	decrypted, err := cose.Decrypt(encrypted, []byte("aad"), recipient)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Error("Decrypted plaintext does not match")
+	}
+
+	if _, err := cose.Decrypt(encrypted, nil, recipient); err == nil {
+		t.Error("Expected error for wrong external data")
+	}
+	if _, err := cose.Decrypt(encrypted, []byte("aad"), generateKey(t, "")); err == nil {
+		t.Error("Expected error for wrong key")
+	}
+	if _, err := cose.Encrypt(plaintext, nil, cose.AlgECDHESHKDF512, recipient, generateKey(t, "")); err == nil {
+		t.Error("Expected error for direct key agreement with two recipients")
+	}
+}
+
+func TestEncryptKeyWrap(t *testing.T) {
+	alice := generateKey(t, "alice")
+	bob := generateKey(t, "")
+	plaintext := []byte("for both of you")
+
+	encrypted, err := cose.Encrypt(plaintext, nil, cose.AlgECDHESA256KW, alice.Public(), bob.Public())
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	for _, key := range []*cose.Key{alice, bob} {
+		decrypted, err := cose.Decrypt(encrypted, nil, key)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Error("Decrypted plaintext does not match")
+		}
+	}
+	if _, err := cose.Decrypt(encrypted, nil, generateKey(t, "carol")); err == nil {
+		t.Error("Expected error for wrong key")
+	}
+	if _, err := cose.Decrypt(encrypted, nil, alice.Public()); err == nil {
+		t.Error("Expected error for public key")
+	}
+}
+
+func TestCWT(t *testing.T) {
+	key := generateKey(t, "issuer-key")
+	now := time.Unix(1700000000, 0)
+	claims := &cose.Claims{
+		Issuer:         "coap://as.example.com",
+		Subject:        "erikw",
+		Audience:       "coap://light.example.com",
+		ExpirationTime: now.Add(time.Hour),
+		NotBefore:      now.Add(-time.Minute),
+		IssuedAt:       now,
+		CWTID:          []byte{0x0b, 0x71},
+	}
+	token, err := cose.SignCWT(claims, key)
+	if err != nil {
+		t.Fatalf("Failed to sign CWT: %v", err)
+	}
+	if token[0] != 0xd8 || token[1] != 61 {
+		t.Errorf("Expected CWT tag, got %x", token[:2])
+	}
+
+	parsed, err := cose.VerifyCWT(token, key.Public(), now)
+	if err != nil {
+		t.Fatalf("Failed to verify CWT: %v", err)
+	}
+	if parsed.Issuer != claims.Issuer || parsed.Subject != claims.Subject || parsed.Audience != claims.Audience ||
+		!parsed.ExpirationTime.Equal(claims.ExpirationTime) || !parsed.NotBefore.Equal(claims.NotBefore) ||
+		!parsed.IssuedAt.Equal(claims.IssuedAt) || !bytes.Equal(parsed.CWTID, claims.CWTID) {
+		t.Errorf("Parsed claims %+v do not match %+v", parsed, claims)
+	}
+
+	if _, err := cose.VerifyCWT(token, key.Public(), now.Add(2*time.Hour)); !errors.Is(err, cose.ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired, got %v", err)
+	}
+	if _, err := cose.VerifyCWT(token, key.Public(), now.Add(-time.Hour)); !errors.Is(err, cose.ErrTokenNotValidYet) {
+		t.Errorf("Expected ErrTokenNotValidYet, got %v", err)
+	}
+	if _, err := cose.VerifyCWT(token, generateKey(t, "").Public(), now); !errors.Is(err, cose.ErrVerification) {
+		t.Errorf("Expected ErrVerification, got %v", err)
+	}
+}
+
+// TestParseClaimsRFC8392 parses the claims set of RFC 8392, Appendix A.1.
+func TestParseClaimsRFC8392(t *testing.T) {
+	data := []byte{
+		0xa7, 0x01, 0x75, 0x63, 0x6f, 0x61, 0x70, 0x3a, 0x2f, 0x2f, 0x61, 0x73, 0x2e, 0x65, 0x78, 0x61,
+		0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x02, 0x65, 0x65, 0x72, 0x69, 0x6b, 0x77, 0x03,
+		0x78, 0x18, 0x63, 0x6f, 0x61, 0x70, 0x3a, 0x2f, 0x2f, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x2e, 0x65,
+		0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x04, 0x1a, 0x56, 0x12, 0xae, 0xb0,
+		0x05, 0x1a, 0x56, 0x10, 0xd9, 0xf0, 0x06, 0x1a, 0x56, 0x10, 0xd9, 0xf0, 0x07, 0x42, 0x0b, 0x71,
+	}
+	claims, err := cose.ParseClaims(data)
+	if err != nil {
+		t.Fatalf("Failed to parse claims: %v", err)
+	}
+	if claims.Issuer != "coap://as.example.com" || claims.Subject != "erikw" ||
+		claims.Audience != "coap://light.example.com" || claims.ExpirationTime.Unix() != 1444064944 ||
+		claims.NotBefore.Unix() != 1443944944 || claims.IssuedAt.Unix() != 1443944944 ||
+		!bytes.Equal(claims.CWTID, []byte{0x0b, 0x71}) {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	encoded, err := claims.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("Claims do not re-encode to the RFC 8392 example:\n%x\n%x", encoded, data)
+	}
+}
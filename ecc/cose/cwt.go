@@ -0,0 +1,190 @@
+package cose
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+)
+
+// CWT claim keys.
+const (
+	claimIssuer         int64 = 1
+	claimSubject        int64 = 2
+	claimAudience       int64 = 3
+	claimExpirationTime int64 = 4
+	claimNotBefore      int64 = 5
+	claimIssuedAt       int64 = 6
+	claimCWTID          int64 = 7
+)
+
+var (
+	// ErrTokenExpired is returned when a CWT's expiration time has passed.
+	ErrTokenExpired = errors.New("cose: token has expired")
+	// ErrTokenNotValidYet is returned when a CWT's not-before time has not
+	// been reached.
+	ErrTokenNotValidYet = errors.New("cose: token is not valid yet")
+)
+
+// Claims are the registered claims of a CBOR Web Token (RFC 8392). Empty
+// strings, zero times and a nil CWTID are left out.
+type Claims struct {
+	Issuer         string
+	Subject        string
+	Audience       string
+	ExpirationTime time.Time
+	NotBefore      time.Time
+	IssuedAt       time.Time
+	CWTID          []byte
+}
+
+// MarshalCBOR returns the claims set encoded as a CBOR map.
+func (c *Claims) MarshalCBOR() ([]byte, error) {
+	m := map[any]any{}
+	for key, value := range map[int64]string{
+		claimIssuer:   c.Issuer,
+		claimSubject:  c.Subject,
+		claimAudience: c.Audience,
+	} {
+		if value != "" {
+			m[key] = value
+		}
+	}
+	for key, value := range map[int64]time.Time{
+		claimExpirationTime: c.ExpirationTime,
+		claimNotBefore:      c.NotBefore,
+		claimIssuedAt:       c.IssuedAt,
+	} {
+		if !value.IsZero() {
+			m[key] = value.Unix()
+		}
+	}
+	if c.CWTID != nil {
+		m[claimCWTID] = c.CWTID
+	}
+	return cbor.Marshal(m)
+}
+
+// ParseClaims parses a claims set. Claims other than the registered ones are
+// ignored.
+func ParseClaims(data []byte) (*Claims, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("cose: %v", err)
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, errors.New("cose: claims set is not a map")
+	}
+
+	c := &Claims{}
+	for key, field := range map[int64]*string{
+		claimIssuer:   &c.Issuer,
+		claimSubject:  &c.Subject,
+		claimAudience: &c.Audience,
+	} {
+		if v, ok := m[key]; ok {
+			if *field, ok = v.(string); !ok {
+				return nil, fmt.Errorf("cose: claim %d is not a text string", key)
+			}
+		}
+	}
+	for key, field := range map[int64]*time.Time{
+		claimExpirationTime: &c.ExpirationTime,
+		claimNotBefore:      &c.NotBefore,
+		claimIssuedAt:       &c.IssuedAt,
+	} {
+		if v, ok := m[key]; ok {
+			if *field, err = numericDate(v); err != nil {
+				return nil, fmt.Errorf("cose: claim %d: %v", key, err)
+			}
+		}
+	}
+	if v, ok := m[claimCWTID]; ok {
+		if c.CWTID, ok = v.([]byte); !ok {
+			return nil, errors.New("cose: CWT ID is not a byte string")
+		}
+	}
+	return c, nil
+}
+
+// numericDate converts a NumericDate, which may carry the epoch time tag 1,
+// to a time.
+func numericDate(v any) (time.Time, error) {
+	if t, ok := v.(cbor.Tag); ok && t.Number == 1 {
+		v = t.Content
+	}
+	switch v := v.(type) {
+	case int64:
+		return time.Unix(v, 0), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return time.Time{}, errors.New("invalid date")
+		}
+		seconds, fraction := math.Modf(v)
+		return time.Unix(int64(seconds), int64(fraction*1e9)), nil
+	}
+	return time.Time{}, errors.New("date is not a number")
+}
+
+// Validate checks the expiration and not-before times against now.
+func (c *Claims) Validate(now time.Time) error {
+	if !c.ExpirationTime.IsZero() && !now.Before(c.ExpirationTime) {
+		return ErrTokenExpired
+	}
+	if !c.NotBefore.IsZero() && now.Before(c.NotBefore) {
+		return ErrTokenNotValidYet
+	}
+	return nil
+}
+
+// SignCWT returns a CWT carrying claims, signed by key as a COSE_Sign1
+// message with the CWT tag.
+func SignCWT(claims *Claims, key *Key) ([]byte, error) {
+	payload, err := claims.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+	m := &Sign1Message{Payload: payload}
+	if err := m.Sign(nil, key); err != nil {
+		return nil, err
+	}
+	signed, err := m.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(cbor.Tag{Number: tagCWT, Content: cbor.RawMessage(signed)})
+}
+
+// VerifyCWT verifies a CWT signed by key, with or without the CWT tag, and
+// validates its claims at now.
+func VerifyCWT(token []byte, key *Key, now time.Time) (*Claims, error) {
+	v, err := cbor.Unmarshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("cose: %v", err)
+	}
+	if t, ok := v.(cbor.Tag); ok && t.Number == tagCWT {
+		// The protected header is a byte string, so it survives
+		// re-encoding unchanged.
+		if token, err = cbor.Marshal(t.Content); err != nil {
+			return nil, err
+		}
+	}
+	m, err := ParseSign1Message(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Verify(nil, key); err != nil {
+		return nil, err
+	}
+	claims, err := ParseClaims(m.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := claims.Validate(now); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
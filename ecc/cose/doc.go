@@ -0,0 +1,15 @@
+// Package cose implements CBOR Object Signing and Encryption (RFC 9052) and
+// CBOR Web Tokens (RFC 8392) for EccFrog512ck2 keys.
+//
+// The supported structures are COSE_Sign1 and COSE_Sign, signed with
+// AlgEF512; COSE_Mac0, authenticated with HMAC-SHA256; and COSE_Encrypt,
+// with AES-256-GCM content encryption and a key agreed with ephemeral-static
+// ECDH, either used directly (AlgECDHESHKDF512) or to wrap the content key
+// for each recipient (AlgECDHESA256KW). Keys are COSE_Key EC2 keys.
+//
+// EccFrog512ck2 is not a registered COSE curve, so keys use
+// CurveEccFrog512ck2 and signatures use AlgEF512, both from the private-use
+// ranges of the IANA registries. The ECDH-ES algorithms are defined for any
+// EC2 curve and use their registered identifiers. Other implementations
+// will only interoperate if they are taught the same private-use values.
+package cose
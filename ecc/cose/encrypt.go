@@ -0,0 +1,272 @@
+package cose
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	contentKeySize = 32
+	gcmNonceSize   = 12
+)
+
+// kdfContext returns the COSE_KDF_Context for a key of alg derived for a
+// recipient with the given protected header, with no party information.
+func kdfContext(alg Algorithm, recipientProtected []byte) ([]byte, error) {
+	party := []any{nil, nil, nil}
+	return cbor.Marshal([]any{alg, party, party, []any{contentKeySize * 8, recipientProtected}})
+}
+
+// deriveKey derives the key for a recipient with the given algorithm and
+// protected header from the ECDH shared secret.
+func deriveKey(alg Algorithm, sharedSecret, recipientProtected []byte) ([]byte, error) {
+	var h func() hash.Hash
+	var keyAlg Algorithm
+	switch alg {
+	case AlgECDHESHKDF512:
+		h, keyAlg = sha512.New, AlgA256GCM
+	case AlgECDHESA256KW:
+		h, keyAlg = sha256.New, algA256KW
+	default:
+		return nil, fmt.Errorf("cose: unsupported key management algorithm %d", alg)
+	}
+	context, err := kdfContext(keyAlg, recipientProtected)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, contentKeySize)
+	if _, err := io.ReadFull(hkdf.New(h, sharedSecret, nil, context), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func encStructure(protected, external []byte) ([]byte, error) {
+	return cbor.Marshal([]any{"Encrypt", protected, nonNil(external)})
+}
+
+// Encrypt encrypts plaintext for the recipients and returns a tagged
+// COSE_Encrypt message. The content is encrypted with AES-256-GCM, and
+// external is additional data it is bound to but which is not carried in
+// the message.
+//
+// With AlgECDHESHKDF512 the content key is derived directly from an ECDH
+// exchange with the single recipient. With AlgECDHESA256KW a random content
+// key is wrapped for each recipient.
+func Encrypt(plaintext, external []byte, alg Algorithm, recipients ...*Key) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("cose: no recipients")
+	}
+
+	var contentKey []byte
+	switch alg {
+	case AlgECDHESHKDF512:
+		if len(recipients) != 1 {
+			return nil, errors.New("cose: direct key agreement supports a single recipient")
+		}
+	case AlgECDHESA256KW:
+		contentKey = make([]byte, contentKeySize)
+		if _, err := rand.Read(contentKey); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cose: unsupported key management algorithm %d", alg)
+	}
+
+	recipientInfos := make([]any, len(recipients))
+	for i, recipient := range recipients {
+		ephemeralPrivateKey, err := ecc.GeneratePrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		ephemeralKey, err := NewPrivateKey(ephemeralPrivateKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		sharedSecret, err := ecdh.ECDHPrivateKey(ephemeralPrivateKey).DeriveFixedSizeSharedSecret(recipient.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("cose: key agreement with recipient #%d failed: %v", i, err)
+		}
+
+		var h Headers
+		h.setProtected(HeaderAlgorithm, alg)
+		ephemeralMap, err := ephemeralKey.Public().toMap()
+		if err != nil {
+			return nil, err
+		}
+		h.setUnprotected(HeaderEphemeralKey, ephemeralMap)
+		if recipient.KeyID != nil {
+			h.setUnprotected(HeaderKeyID, recipient.KeyID)
+		}
+		protected, err := h.seal()
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := deriveKey(alg, sharedSecret, protected)
+		if err != nil {
+			return nil, err
+		}
+		var wrapped []byte
+		if alg == AlgECDHESHKDF512 {
+			contentKey = key
+		} else if wrapped, err = cryptohelpers.AESKeyWrap(key, contentKey); err != nil {
+			return nil, err
+		}
+		recipientInfos[i] = []any{protected, h.unprotected(), nonNil(wrapped)}
+	}
+
+	var h Headers
+	h.setProtected(HeaderAlgorithm, AlgA256GCM)
+	iv := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	h.setUnprotected(HeaderIV, iv)
+	protected, err := h.seal()
+	if err != nil {
+		return nil, err
+	}
+	aad, err := encStructure(protected, external)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nil, iv, plaintext, aad)
+
+	return cbor.Marshal(cbor.Tag{Number: tagEncrypt, Content: []any{
+		protected, h.unprotected(), ciphertext, recipientInfos,
+	}})
+}
+
+// Decrypt decrypts a tagged or untagged COSE_Encrypt message with key, which
+// must have a private part. If both the key and a recipient have a key ID,
+// the recipient is only tried if they match.
+func Decrypt(data, external []byte, key *Key) ([]byte, error) {
+	privateKey, err := key.privateKey()
+	if err != nil {
+		return nil, err
+	}
+	items, err := parseMessage(data, tagEncrypt, 4)
+	if err != nil {
+		return nil, err
+	}
+	h, err := parseHeaders(items[0], items[1])
+	if err != nil {
+		return nil, err
+	}
+	if h.Algorithm() != AlgA256GCM {
+		return nil, fmt.Errorf("cose: unsupported content encryption algorithm %d", h.Algorithm())
+	}
+	iv, _ := h.Unprotected[HeaderIV].([]byte)
+	if len(iv) != gcmNonceSize {
+		return nil, errors.New("cose: invalid IV")
+	}
+	ciphertext, ok := items[2].([]byte)
+	if !ok {
+		return nil, errors.New("cose: ciphertext is not a byte string")
+	}
+	recipients, ok := items[3].([]any)
+	if !ok || len(recipients) == 0 {
+		return nil, errors.New("cose: malformed recipients")
+	}
+	aad, err := encStructure(h.rawProtected, external)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range recipients {
+		contentKey, err := recipientContentKey(item, key, privateKey)
+		if err != nil {
+			return nil, err
+		}
+		if contentKey == nil {
+			continue
+		}
+		aead, err := newGCM(contentKey)
+		if err != nil {
+			return nil, err
+		}
+		if plaintext, err := aead.Open(nil, iv, ciphertext, aad); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, errors.New("cose: message could not be decrypted with the key")
+}
+
+// recipientContentKey returns the content key from a recipient structure, or
+// nil if the recipient is not for key.
+func recipientContentKey(item any, key *Key, privateKey ecc.PrivateKey) ([]byte, error) {
+	fields, ok := item.([]any)
+	if !ok || len(fields) != 3 {
+		return nil, errors.New("cose: malformed recipient")
+	}
+	h, err := parseHeaders(fields[0], fields[1])
+	if err != nil {
+		return nil, err
+	}
+	if !equalKeyID(key.KeyID, h.KeyID()) {
+		return nil, nil
+	}
+	alg := h.Algorithm()
+	if alg != AlgECDHESHKDF512 && alg != AlgECDHESA256KW {
+		return nil, nil
+	}
+	ephemeralMap, ok := h.Unprotected[HeaderEphemeralKey].(map[any]any)
+	if !ok {
+		return nil, errors.New("cose: missing ephemeral key")
+	}
+	ephemeralKey, err := keyFromMap(ephemeralMap)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := ecdh.ECDHPrivateKey(privateKey).DeriveFixedSizeSharedSecret(ephemeralKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("cose: key agreement failed: %v", err)
+	}
+	derived, err := deriveKey(alg, sharedSecret, h.rawProtected)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, ok := fields[2].([]byte)
+	if !ok {
+		return nil, errors.New("cose: recipient ciphertext is not a byte string")
+	}
+	if alg == AlgECDHESHKDF512 {
+		if len(wrapped) != 0 {
+			return nil, errors.New("cose: unexpected recipient ciphertext for direct key agreement")
+		}
+		return derived, nil
+	}
+	contentKey, err := cryptohelpers.AESKeyUnwrap(derived, wrapped)
+	if err != nil || len(contentKey) != contentKeySize {
+		// The recipient is for a different key.
+		return nil, nil
+	}
+	return contentKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,71 @@
+package cose_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cose"
+)
+
+func ExampleSignCWT() {
+	// The authorization server has an EccFrog512ck2 key
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate key:", err)
+		return
+	}
+	key, err := cose.NewPrivateKey(privateKey, []byte("as-key-1"))
+	if err != nil {
+		fmt.Println("Failed to create key:", err)
+		return
+	}
+
+	// It issues a token for a device
+	now := time.Now()
+	token, err := cose.SignCWT(&cose.Claims{
+		Issuer:         "coap://as.example.com",
+		Subject:        "device-42",
+		ExpirationTime: now.Add(time.Hour),
+		IssuedAt:       now,
+	}, key)
+	if err != nil {
+		fmt.Println("Failed to sign token:", err)
+		return
+	}
+
+	// The resource server verifies it with the public key
+	claims, err := cose.VerifyCWT(token, key.Public(), now)
+	if err != nil {
+		fmt.Println("Failed to verify token:", err)
+		return
+	}
+	fmt.Println(claims.Subject)
+	// Output: device-42
+}
+
+func ExampleEncrypt() {
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate key:", err)
+		return
+	}
+	device, err := cose.NewPrivateKey(privateKey, []byte("device-42"))
+	if err != nil {
+		fmt.Println("Failed to create key:", err)
+		return
+	}
+
+	message, err := cose.Encrypt([]byte("turn on"), nil, cose.AlgECDHESHKDF512, device.Public())
+	if err != nil {
+		fmt.Println("Failed to encrypt:", err)
+		return
+	}
+	plaintext, err := cose.Decrypt(message, nil, device)
+	if err != nil {
+		fmt.Println("Failed to decrypt:", err)
+		return
+	}
+	fmt.Println(string(plaintext))
+	// Output: turn on
+}
@@ -0,0 +1,191 @@
+package cose
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+)
+
+const (
+	// KeyTypeEC2 is the COSE key type of elliptic curve keys with x and y
+	// coordinates.
+	KeyTypeEC2 int64 = 2
+
+	// CurveEccFrog512ck2 is the COSE elliptic curve identifier used for
+	// EccFrog512ck2 keys. The value is from the private-use range.
+	CurveEccFrog512ck2 int64 = -65537
+
+	coordinateSize = 64
+)
+
+// COSE_Key parameter labels.
+const (
+	keyLabelType      int64 = 1
+	keyLabelID        int64 = 2
+	keyLabelAlgorithm int64 = 3
+	keyLabelCurve     int64 = -1
+	keyLabelX         int64 = -2
+	keyLabelY         int64 = -3
+	keyLabelD         int64 = -4
+)
+
+// Key is an EccFrog512ck2 COSE_Key. PrivateKey is nil for public keys.
+type Key struct {
+	KeyID      []byte
+	Algorithm  Algorithm
+	PublicKey  eccfrog512ck2.CurvePoint
+	PrivateKey *ecc.PrivateKey
+}
+
+// NewPrivateKey returns a key for privateKey, with the given key ID, which
+// may be nil.
+func NewPrivateKey(privateKey ecc.PrivateKey, keyID []byte) (*Key, error) {
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Key{KeyID: keyID, PublicKey: publicKey, PrivateKey: &privateKey}, nil
+}
+
+// NewPublicKey returns a key for publicKey, with the given key ID, which may
+// be nil.
+func NewPublicKey(publicKey eccfrog512ck2.CurvePoint, keyID []byte) *Key {
+	return &Key{KeyID: keyID, PublicKey: publicKey}
+}
+
+// Public returns the public part of the key.
+func (k *Key) Public() *Key {
+	return &Key{KeyID: k.KeyID, Algorithm: k.Algorithm, PublicKey: k.PublicKey}
+}
+
+func (k *Key) toMap() (map[any]any, error) {
+	x, y, ok := k.PublicKey.CoordinateIfNotInfinity()
+	if !ok {
+		return nil, errors.New("cose: public key is the point at infinity")
+	}
+	m := map[any]any{
+		keyLabelType:  KeyTypeEC2,
+		keyLabelCurve: CurveEccFrog512ck2,
+		keyLabelX:     x.FillBytes(make([]byte, coordinateSize)),
+		keyLabelY:     y.FillBytes(make([]byte, coordinateSize)),
+	}
+	if k.KeyID != nil {
+		m[keyLabelID] = k.KeyID
+	}
+	if k.Algorithm != 0 {
+		m[keyLabelAlgorithm] = k.Algorithm
+	}
+	if k.PrivateKey != nil {
+		m[keyLabelD] = k.PrivateKey.GetKey().FillBytes(make([]byte, coordinateSize))
+	}
+	return m, nil
+}
+
+// MarshalCBOR returns the COSE_Key encoding of the key.
+func (k *Key) MarshalCBOR() ([]byte, error) {
+	m, err := k.toMap()
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(m)
+}
+
+// ParseKey parses a COSE_Key. The y coordinate may be given as a sign bit,
+// in which case the point is decompressed.
+func ParseKey(data []byte) (*Key, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("cose: %v", err)
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, errors.New("cose: key is not a map")
+	}
+	return keyFromMap(m)
+}
+
+func keyFromMap(m map[any]any) (*Key, error) {
+	if kty, _ := m[keyLabelType].(int64); kty != KeyTypeEC2 {
+		return nil, fmt.Errorf("cose: unsupported key type %v", m[keyLabelType])
+	}
+	if crv, _ := m[keyLabelCurve].(int64); crv != CurveEccFrog512ck2 {
+		return nil, fmt.Errorf("cose: unsupported curve %v", m[keyLabelCurve])
+	}
+
+	k := &Key{}
+	if kid, ok := m[keyLabelID]; ok {
+		if k.KeyID, ok = kid.([]byte); !ok {
+			return nil, errors.New("cose: key ID is not a byte string")
+		}
+	}
+	if alg, ok := m[keyLabelAlgorithm]; ok {
+		a, ok := alg.(int64)
+		if !ok {
+			return nil, errors.New("cose: key algorithm is not an integer")
+		}
+		k.Algorithm = Algorithm(a)
+	}
+
+	x, ok := m[keyLabelX].([]byte)
+	if !ok || len(x) != coordinateSize {
+		return nil, errors.New("cose: invalid x coordinate")
+	}
+	var sec1 []byte
+	switch y := m[keyLabelY].(type) {
+	case []byte:
+		if len(y) != coordinateSize {
+			return nil, errors.New("cose: invalid y coordinate")
+		}
+		sec1 = append(append([]byte{0x04}, x...), y...)
+	case bool:
+		prefix := byte(0x02)
+		if y {
+			prefix = 0x03
+		}
+		sec1 = append([]byte{prefix}, x...)
+	default:
+		return nil, errors.New("cose: invalid y coordinate")
+	}
+	publicKey, err := ecc.ParsePublicKeySEC1(sec1)
+	if err != nil {
+		return nil, fmt.Errorf("cose: invalid public key: %v", err)
+	}
+	k.PublicKey = publicKey
+
+	if d, ok := m[keyLabelD]; ok {
+		b, ok := d.([]byte)
+		if !ok || len(b) != coordinateSize {
+			return nil, errors.New("cose: invalid private key")
+		}
+		privateKey, err := ecc.ParsePrivateKeySEC1(b)
+		if err != nil {
+			return nil, fmt.Errorf("cose: invalid private key: %v", err)
+		}
+		derived, err := privateKey.DerivePublicKey()
+		if err != nil || !derived.Equal(publicKey) {
+			return nil, errors.New("cose: private key does not match public key")
+		}
+		k.PrivateKey = &privateKey
+	}
+	return k, nil
+}
+
+func (k *Key) privateKey() (ecc.PrivateKey, error) {
+	if k.PrivateKey == nil {
+		return ecc.PrivateKey{}, errors.New("cose: key has no private part")
+	}
+	return *k.PrivateKey, nil
+}
+
+// scalarsToSignature encodes r and s as the fixed-width signature of
+// AlgEF512.
+func scalarsToSignature(r, s *big.Int) []byte {
+	signature := make([]byte, 2*coordinateSize)
+	r.FillBytes(signature[:coordinateSize])
+	s.FillBytes(signature[coordinateSize:])
+	return signature
+}
@@ -0,0 +1,97 @@
+package cose
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+)
+
+// Mac0Message is a COSE_Mac0 message: a payload authenticated with HMAC
+// under a key shared by the sender and recipient.
+type Mac0Message struct {
+	Headers Headers
+	Payload []byte
+	Tag     []byte
+}
+
+func (m *Mac0Message) computeTag(external, key []byte) ([]byte, error) {
+	if len(key) < sha256.Size {
+		return nil, errors.New("cose: MAC key must be at least 32 bytes")
+	}
+	protected, err := m.Headers.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	toBeMACed, err := cbor.Marshal([]any{"MAC0", protected, nonNil(external), nonNil(m.Payload)})
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(toBeMACed)
+	return mac.Sum(nil), nil
+}
+
+// Authenticate computes the tag of the message with AlgHMAC256 under key.
+func (m *Mac0Message) Authenticate(external, key []byte) error {
+	if err := m.Headers.checkAlgorithm(AlgHMAC256); err != nil {
+		return err
+	}
+	if _, err := m.Headers.seal(); err != nil {
+		return err
+	}
+	tag, err := m.computeTag(external, key)
+	if err != nil {
+		return err
+	}
+	m.Tag = tag
+	return nil
+}
+
+// Verify checks the tag of the message under key.
+func (m *Mac0Message) Verify(external, key []byte) error {
+	if m.Headers.Algorithm() != AlgHMAC256 {
+		return errors.New("cose: unsupported MAC algorithm")
+	}
+	tag, err := m.computeTag(external, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(tag, m.Tag) {
+		return ErrVerification
+	}
+	return nil
+}
+
+// MarshalCBOR returns the tagged COSE_Mac0 encoding of the message.
+func (m *Mac0Message) MarshalCBOR() ([]byte, error) {
+	protected, err := m.Headers.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(cbor.Tag{Number: tagMac0, Content: []any{
+		protected, m.Headers.unprotected(), payloadValue(m.Payload), nonNil(m.Tag),
+	}})
+}
+
+// ParseMac0Message parses a tagged or untagged COSE_Mac0 message.
+func ParseMac0Message(data []byte) (*Mac0Message, error) {
+	items, err := parseMessage(data, tagMac0, 4)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := parseHeaders(items[0], items[1])
+	if err != nil {
+		return nil, err
+	}
+	payload, err := parsePayload(items[2])
+	if err != nil {
+		return nil, err
+	}
+	tag, ok := items[3].([]byte)
+	if !ok {
+		return nil, errors.New("cose: tag is not a byte string")
+	}
+	return &Mac0Message{Headers: headers, Payload: payload, Tag: tag}, nil
+}
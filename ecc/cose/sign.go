@@ -0,0 +1,272 @@
+package cose
+
+import (
+	"crypto/sha512"
+	"errors"
+	"math/big"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cbor"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdsa"
+)
+
+// ErrVerification is returned when a signature or MAC does not verify.
+var ErrVerification = errors.New("cose: verification failed")
+
+func sign(key *Key, toBeSigned []byte) ([]byte, error) {
+	privateKey, err := key.privateKey()
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := ecdsa.NewSign(sha512.New, privateKey).Sign(toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+	return scalarsToSignature(r, s), nil
+}
+
+func verify(key *Key, toBeSigned, signature []byte) error {
+	if len(signature) != 2*coordinateSize {
+		return ErrVerification
+	}
+	r := new(big.Int).SetBytes(signature[:coordinateSize])
+	s := new(big.Int).SetBytes(signature[coordinateSize:])
+	n := eccfrog512ck2.GeneratorOrder()
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return ErrVerification
+	}
+	ok, err := ecdsa.NewVerification(sha512.New, key.PublicKey).Verify([2]*big.Int{r, s}, toBeSigned)
+	if err != nil || !ok {
+		return ErrVerification
+	}
+	return nil
+}
+
+// prepareSigner sets the algorithm and key ID header parameters for a
+// signature by key.
+func prepareSigner(h *Headers, key *Key) error {
+	if err := h.checkAlgorithm(AlgEF512); err != nil {
+		return err
+	}
+	if key.KeyID != nil {
+		if _, ok := h.get(HeaderKeyID); !ok {
+			h.setUnprotected(HeaderKeyID, key.KeyID)
+		}
+	}
+	return nil
+}
+
+// Sign1Message is a COSE_Sign1 message: a payload with a single signature.
+//
+// A nil Payload is detached: it is encoded as null and must be set again
+// before the parsed message is verified.
+type Sign1Message struct {
+	Headers   Headers
+	Payload   []byte
+	Signature []byte
+}
+
+func (m *Sign1Message) toBeSigned(external []byte) ([]byte, error) {
+	protected, err := m.Headers.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal([]any{"Signature1", protected, nonNil(external), nonNil(m.Payload)})
+}
+
+// Sign signs the message with key. The algorithm is added to the protected
+// header and the key ID, if any, to the unprotected header. external is
+// additional data covered by the signature but not carried in the message.
+func (m *Sign1Message) Sign(external []byte, key *Key) error {
+	if err := prepareSigner(&m.Headers, key); err != nil {
+		return err
+	}
+	if _, err := m.Headers.seal(); err != nil {
+		return err
+	}
+	tbs, err := m.toBeSigned(external)
+	if err != nil {
+		return err
+	}
+	m.Signature, err = sign(key, tbs)
+	return err
+}
+
+// Verify verifies the signature with key, which may be a public key.
+func (m *Sign1Message) Verify(external []byte, key *Key) error {
+	if m.Headers.Algorithm() != AlgEF512 {
+		return errors.New("cose: unsupported signature algorithm")
+	}
+	tbs, err := m.toBeSigned(external)
+	if err != nil {
+		return err
+	}
+	return verify(key, tbs, m.Signature)
+}
+
+// MarshalCBOR returns the tagged COSE_Sign1 encoding of the message.
+func (m *Sign1Message) MarshalCBOR() ([]byte, error) {
+	protected, err := m.Headers.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(cbor.Tag{Number: tagSign1, Content: []any{
+		protected, m.Headers.unprotected(), payloadValue(m.Payload), nonNil(m.Signature),
+	}})
+}
+
+// ParseSign1Message parses a tagged or untagged COSE_Sign1 message.
+func ParseSign1Message(data []byte) (*Sign1Message, error) {
+	items, err := parseMessage(data, tagSign1, 4)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := parseHeaders(items[0], items[1])
+	if err != nil {
+		return nil, err
+	}
+	payload, err := parsePayload(items[2])
+	if err != nil {
+		return nil, err
+	}
+	signature, ok := items[3].([]byte)
+	if !ok {
+		return nil, errors.New("cose: signature is not a byte string")
+	}
+	return &Sign1Message{Headers: headers, Payload: payload, Signature: signature}, nil
+}
+
+// Signature is one of the signatures of a COSE_Sign message.
+type Signature struct {
+	Headers   Headers
+	Signature []byte
+}
+
+// SignMessage is a COSE_Sign message: a payload with any number of
+// signatures.
+//
+// As for Sign1Message, a nil Payload is detached.
+type SignMessage struct {
+	Headers    Headers
+	Payload    []byte
+	Signatures []*Signature
+}
+
+func (m *SignMessage) toBeSigned(external []byte, s *Signature) ([]byte, error) {
+	bodyProtected, err := m.Headers.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	signProtected, err := s.Headers.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal([]any{"Signature", bodyProtected, signProtected, nonNil(external), nonNil(m.Payload)})
+}
+
+// AddSignature adds a signature by key to the message. The headers of the
+// message must not change after the first signature is added.
+func (m *SignMessage) AddSignature(external []byte, key *Key) error {
+	if _, err := m.Headers.seal(); err != nil {
+		return err
+	}
+	s := &Signature{}
+	if err := prepareSigner(&s.Headers, key); err != nil {
+		return err
+	}
+	if _, err := s.Headers.seal(); err != nil {
+		return err
+	}
+	tbs, err := m.toBeSigned(external, s)
+	if err != nil {
+		return err
+	}
+	if s.Signature, err = sign(key, tbs); err != nil {
+		return err
+	}
+	m.Signatures = append(m.Signatures, s)
+	return nil
+}
+
+// Verify checks that one of the signatures was made by key. If both the key
+// and a signature have a key ID, the signature is only tried if they match.
+func (m *SignMessage) Verify(external []byte, key *Key) error {
+	for _, s := range m.Signatures {
+		if s.Headers.Algorithm() != AlgEF512 || !equalKeyID(key.KeyID, s.Headers.KeyID()) {
+			continue
+		}
+		tbs, err := m.toBeSigned(external, s)
+		if err != nil {
+			return err
+		}
+		if verify(key, tbs, s.Signature) == nil {
+			return nil
+		}
+	}
+	return ErrVerification
+}
+
+// MarshalCBOR returns the tagged COSE_Sign encoding of the message.
+func (m *SignMessage) MarshalCBOR() ([]byte, error) {
+	protected, err := m.Headers.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	signatures := make([]any, len(m.Signatures))
+	for i, s := range m.Signatures {
+		signProtected, err := s.Headers.protectedBytes()
+		if err != nil {
+			return nil, err
+		}
+		signatures[i] = []any{signProtected, s.Headers.unprotected(), nonNil(s.Signature)}
+	}
+	return cbor.Marshal(cbor.Tag{Number: tagSign, Content: []any{
+		protected, m.Headers.unprotected(), payloadValue(m.Payload), signatures,
+	}})
+}
+
+// ParseSignMessage parses a tagged or untagged COSE_Sign message.
+func ParseSignMessage(data []byte) (*SignMessage, error) {
+	items, err := parseMessage(data, tagSign, 4)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := parseHeaders(items[0], items[1])
+	if err != nil {
+		return nil, err
+	}
+	payload, err := parsePayload(items[2])
+	if err != nil {
+		return nil, err
+	}
+	signatures, ok := items[3].([]any)
+	if !ok || len(signatures) == 0 {
+		return nil, errors.New("cose: malformed signatures")
+	}
+	m := &SignMessage{Headers: headers, Payload: payload}
+	for _, item := range signatures {
+		fields, ok := item.([]any)
+		if !ok || len(fields) != 3 {
+			return nil, errors.New("cose: malformed signature")
+		}
+		h, err := parseHeaders(fields[0], fields[1])
+		if err != nil {
+			return nil, err
+		}
+		signature, ok := fields[2].([]byte)
+		if !ok {
+			return nil, errors.New("cose: signature is not a byte string")
+		}
+		m.Signatures = append(m.Signatures, &Signature{Headers: h, Signature: signature})
+	}
+	return m, nil
+}
+
+// nonNil returns b, or an empty byte string if b is nil, since nil would be
+// encoded as null.
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+package cryptohelpers
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// AEADResults holds a ciphertext and the random nonce it was encrypted with.
+type AEADResults struct {
+	CipherText []byte
+	Nonce      []byte
+}
+
+// aeadEncrypt returns an encryption function that derives a key from the
+// secret with kdf and encrypts under a random nonce with the AEAD that
+// newAEAD creates.
+func aeadEncrypt(
+	kdf func(SecretKey) ([32]byte, error),
+	newAEAD func(key []byte) (cipher.AEAD, error),
+) func(SecretKey, []byte, []byte) (AEADResults, error) {
+	return func(secret SecretKey, plaintext, additionalData []byte) (AEADResults, error) {
+		key, err := kdf(secret)
+		if err != nil {
+			return AEADResults{}, err
+		}
+		aead, err := newAEAD(key[:])
+		if err != nil {
+			return AEADResults{}, err
+		}
+
+		// Generate random nonce
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return AEADResults{}, err
+		}
+
+		// Encrypt
+		ciphertext := aead.Seal(nil, nonce, plaintext, additionalData)
+		return AEADResults{CipherText: ciphertext, Nonce: nonce}, nil
+	}
+}
+
+// aeadDecrypt returns the decryption function matching aeadEncrypt.
+func aeadDecrypt(
+	kdf func(SecretKey) ([32]byte, error),
+	newAEAD func(key []byte) (cipher.AEAD, error),
+) func(SecretKey, AEADResults, []byte) ([]byte, error) {
+	return func(secret SecretKey, nonceCiphertext AEADResults, additionalData []byte) ([]byte, error) {
+		key, err := kdf(secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := newAEAD(key[:])
+		if err != nil {
+			return nil, err
+		}
+		if len(nonceCiphertext.Nonce) != aead.NonceSize() {
+			return nil, errors.New("cryptohelpers: invalid nonce length")
+		}
+
+		// Decrypt
+		return aead.Open(nil, nonceCiphertext.Nonce, nonceCiphertext.CipherText, additionalData)
+	}
+}
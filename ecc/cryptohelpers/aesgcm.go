@@ -3,75 +3,50 @@ package cryptohelpers
 import (
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
-	"io"
 )
 
-type AES256GCMResults struct {
-	CipherText []byte
-	Nonce      []byte
+// AES256GCMResults holds an AES-256-GCM ciphertext and its nonce.
+type AES256GCMResults = AEADResults
+
+func newAES256GCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
 
 func AES256GCMEncrypt(
 	kdf func(SecretKey) ([32]byte, error),
 ) func(SecretKey, []byte) (AES256GCMResults, error) {
+	encrypt := AES256GCMEncryptWithAAD(kdf)
 	return func(secret SecretKey, plaintext []byte) (AES256GCMResults, error) {
-		// Use HKDF-SHA256 to derive a 32-byte key from the secret
-		key, err := kdf(secret)
-		if err != nil {
-			return AES256GCMResults{}, err
-		}
-
-		block, err := aes.NewCipher(key[:])
-		if err != nil {
-			return AES256GCMResults{}, err
-		}
-
-		// GCM mode
-		aesGCM, err := cipher.NewGCM(block)
-		if err != nil {
-			return AES256GCMResults{}, err
-		}
-
-		// Generate random nonce
-		nonce := make([]byte, aesGCM.NonceSize())
-		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-			return AES256GCMResults{}, err
-		}
-
-		// Encrypt
-		ciphertext := aesGCM.Seal(nil, nonce, plaintext, nil)
-		return AES256GCMResults{CipherText: ciphertext, Nonce: nonce}, nil
+		return encrypt(secret, plaintext, nil)
 	}
 }
 
 func AES256GCMDecrypt(
 	kdf func(SecretKey) ([32]byte, error),
 ) func(SecretKey, AES256GCMResults) ([]byte, error) {
+	decrypt := AES256GCMDecryptWithAAD(kdf)
 	return func(secret SecretKey, nonceCiphertext AES256GCMResults) ([]byte, error) {
-		// Use HKDF-SHA256 to derive a 32-byte key from the secret
-		key, err := kdf(secret)
-		if err != nil {
-			return nil, err
-		}
-
-		block, err := aes.NewCipher(key[:])
-		if err != nil {
-			return nil, err
-		}
-
-		// GCM mode
-		aesGCM, err := cipher.NewGCM(block)
-		if err != nil {
-			return nil, err
-		}
+		return decrypt(secret, nonceCiphertext, nil)
+	}
+}
 
-		// Decrypt
-		plaintext, err := aesGCM.Open(nil, nonceCiphertext.Nonce, nonceCiphertext.CipherText, nil)
-		if err != nil {
-			return nil, err
-		}
+// AES256GCMEncryptWithAAD is like AES256GCMEncrypt, but the returned function
+// also authenticates additional data, which is not encrypted and must be
+// given again to decrypt.
+func AES256GCMEncryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte, []byte) (AES256GCMResults, error) {
+	return aeadEncrypt(kdf, newAES256GCM)
+}
 
-		return plaintext, nil
-	}
+// AES256GCMDecryptWithAAD is like AES256GCMDecrypt, but the returned function
+// also checks the additional data the ciphertext was encrypted with.
+func AES256GCMDecryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AES256GCMResults, []byte) ([]byte, error) {
+	return aeadDecrypt(kdf, newAES256GCM)
 }
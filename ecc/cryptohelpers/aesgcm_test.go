@@ -0,0 +1,35 @@
+package cryptohelpers_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+)
+
+func TestAES256GCMWithAAD(t *testing.T) {
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	secret := cryptohelpers.SecretKey("shared secret")
+	plaintext := []byte("plaintext")
+	aad := []byte("header")
+
+	result, err := cryptohelpers.AES256GCMEncryptWithAAD(kdf)(secret, plaintext, aad)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	decrypted, err := cryptohelpers.AES256GCMDecryptWithAAD(kdf)(secret, result, aad)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Error("Decrypted plaintext does not match")
+	}
+
+	if _, err := cryptohelpers.AES256GCMDecryptWithAAD(kdf)(secret, result, []byte("other")); err == nil {
+		t.Error("Expected error for wrong additional data")
+	}
+	if _, err := cryptohelpers.AES256GCMDecrypt(kdf)(secret, result); err == nil {
+		t.Error("Expected error for missing additional data")
+	}
+}
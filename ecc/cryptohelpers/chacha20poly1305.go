@@ -0,0 +1,81 @@
+package cryptohelpers
+
+import "golang.org/x/crypto/chacha20poly1305"
+
+// ChaCha20Poly1305Encrypt is like AES256GCMEncrypt, but encrypts with
+// ChaCha20-Poly1305 (RFC 8439) under a random 12-byte nonce. It is faster
+// than AES-GCM on processors without AES instructions.
+func ChaCha20Poly1305Encrypt(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte) (AEADResults, error) {
+	encrypt := ChaCha20Poly1305EncryptWithAAD(kdf)
+	return func(secret SecretKey, plaintext []byte) (AEADResults, error) {
+		return encrypt(secret, plaintext, nil)
+	}
+}
+
+// ChaCha20Poly1305Decrypt decrypts ciphertexts produced by
+// ChaCha20Poly1305Encrypt.
+func ChaCha20Poly1305Decrypt(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AEADResults) ([]byte, error) {
+	decrypt := ChaCha20Poly1305DecryptWithAAD(kdf)
+	return func(secret SecretKey, nonceCiphertext AEADResults) ([]byte, error) {
+		return decrypt(secret, nonceCiphertext, nil)
+	}
+}
+
+// ChaCha20Poly1305EncryptWithAAD is like ChaCha20Poly1305Encrypt, but also
+// authenticates additional data.
+func ChaCha20Poly1305EncryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte, []byte) (AEADResults, error) {
+	return aeadEncrypt(kdf, chacha20poly1305.New)
+}
+
+// ChaCha20Poly1305DecryptWithAAD is like ChaCha20Poly1305Decrypt, but also
+// checks the additional data.
+func ChaCha20Poly1305DecryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AEADResults, []byte) ([]byte, error) {
+	return aeadDecrypt(kdf, chacha20poly1305.New)
+}
+
+// XChaCha20Poly1305Encrypt is like ChaCha20Poly1305Encrypt, but uses
+// XChaCha20-Poly1305, whose 24-byte nonce is long enough that random nonces
+// are safe for any number of messages under the same key.
+func XChaCha20Poly1305Encrypt(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte) (AEADResults, error) {
+	encrypt := XChaCha20Poly1305EncryptWithAAD(kdf)
+	return func(secret SecretKey, plaintext []byte) (AEADResults, error) {
+		return encrypt(secret, plaintext, nil)
+	}
+}
+
+// XChaCha20Poly1305Decrypt decrypts ciphertexts produced by
+// XChaCha20Poly1305Encrypt.
+func XChaCha20Poly1305Decrypt(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AEADResults) ([]byte, error) {
+	decrypt := XChaCha20Poly1305DecryptWithAAD(kdf)
+	return func(secret SecretKey, nonceCiphertext AEADResults) ([]byte, error) {
+		return decrypt(secret, nonceCiphertext, nil)
+	}
+}
+
+// XChaCha20Poly1305EncryptWithAAD is like XChaCha20Poly1305Encrypt, but
+// also authenticates additional data.
+func XChaCha20Poly1305EncryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte, []byte) (AEADResults, error) {
+	return aeadEncrypt(kdf, chacha20poly1305.NewX)
+}
+
+// XChaCha20Poly1305DecryptWithAAD is like XChaCha20Poly1305Decrypt, but
+// also checks the additional data.
+func XChaCha20Poly1305DecryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AEADResults, []byte) ([]byte, error) {
+	return aeadDecrypt(kdf, chacha20poly1305.NewX)
+}
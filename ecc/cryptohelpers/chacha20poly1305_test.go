@@ -0,0 +1,93 @@
+package cryptohelpers_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+)
+
+func TestChaCha20Poly1305(t *testing.T) {
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	secret := cryptohelpers.SecretKey("shared secret")
+	plaintext := []byte("plaintext")
+	aad := []byte("header")
+
+	for _, test := range []struct {
+		name      string
+		nonceSize int
+		encrypt   func(func(cryptohelpers.SecretKey) ([32]byte, error)) func(cryptohelpers.SecretKey, []byte, []byte) (cryptohelpers.AEADResults, error)
+		decrypt   func(func(cryptohelpers.SecretKey) ([32]byte, error)) func(cryptohelpers.SecretKey, cryptohelpers.AEADResults, []byte) ([]byte, error)
+	}{
+		{"ChaCha20-Poly1305", 12, cryptohelpers.ChaCha20Poly1305EncryptWithAAD, cryptohelpers.ChaCha20Poly1305DecryptWithAAD},
+		{"XChaCha20-Poly1305", 24, cryptohelpers.XChaCha20Poly1305EncryptWithAAD, cryptohelpers.XChaCha20Poly1305DecryptWithAAD},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.encrypt(kdf)(secret, plaintext, aad)
+			if err != nil {
+				t.Fatalf("Failed to encrypt: %v", err)
+			}
+			if len(result.Nonce) != test.nonceSize {
+				t.Errorf("Nonce is %d bytes, want %d", len(result.Nonce), test.nonceSize)
+			}
+			decrypted, err := test.decrypt(kdf)(secret, result, aad)
+			if err != nil {
+				t.Fatalf("Failed to decrypt: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Error("Decrypted plaintext does not match")
+			}
+
+			if _, err := test.decrypt(kdf)(secret, result, []byte("other")); err == nil {
+				t.Error("Expected error for wrong additional data")
+			}
+			if _, err := test.decrypt(kdf)(cryptohelpers.SecretKey("other secret"), result, aad); err == nil {
+				t.Error("Expected error for wrong secret")
+			}
+			short := cryptohelpers.AEADResults{CipherText: result.CipherText, Nonce: result.Nonce[1:]}
+			if _, err := test.decrypt(kdf)(secret, short, aad); err == nil {
+				t.Error("Expected error for short nonce")
+			}
+		})
+	}
+}
+
+func TestCipherSuite(t *testing.T) {
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	secret := cryptohelpers.SecretKey("shared secret")
+
+	for _, suite := range []cryptohelpers.CipherSuite{
+		cryptohelpers.CipherAES256GCM,
+		cryptohelpers.CipherChaCha20Poly1305,
+		cryptohelpers.CipherXChaCha20Poly1305,
+		cryptohelpers.CipherAES256GCMSIV,
+	} {
+		parsed, err := cryptohelpers.ParseCipherSuite(suite.String())
+		if err != nil || parsed != suite {
+			t.Errorf("ParseCipherSuite(%q) = %v, %v", suite.String(), parsed, err)
+		}
+
+		result, err := suite.Encrypt(kdf)(secret, []byte("plaintext"))
+		if err != nil {
+			t.Fatalf("%v: failed to encrypt: %v", suite, err)
+		}
+		decrypted, err := suite.Decrypt(kdf)(secret, result)
+		if err != nil || string(decrypted) != "plaintext" {
+			t.Errorf("%v: failed to decrypt: %v", suite, err)
+		}
+	}
+
+	// The suites are not interchangeable
+	result, _ := cryptohelpers.CipherChaCha20Poly1305.Encrypt(kdf)(secret, []byte("plaintext"))
+	if _, err := cryptohelpers.CipherAES256GCM.Decrypt(kdf)(secret, result); err == nil {
+		t.Error("Expected error decrypting with another suite")
+	}
+
+	if _, err := cryptohelpers.ParseCipherSuite("rot13"); err == nil {
+		t.Error("Expected error for unknown cipher name")
+	}
+	if _, err := cryptohelpers.CipherSuite(0).NewAEAD(make([]byte, 32)); err == nil {
+		t.Error("Expected error for the zero suite")
+	}
+}
@@ -0,0 +1,131 @@
+package cryptohelpers
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// CipherSuite identifies an AEAD in formats that let the sender choose it.
+// Every suite takes a 32-byte key. The zero value is not a valid suite.
+//
+// A suite with the CipherCommitting bit set is the key-committing variant
+// of the suite without it, as returned by NewCommittingAEAD. Formats that
+// record the suite therefore record whether decryption must check a
+// commitment.
+type CipherSuite uint8
+
+const (
+	CipherAES256GCM         CipherSuite = 1
+	CipherChaCha20Poly1305  CipherSuite = 2
+	CipherXChaCha20Poly1305 CipherSuite = 3
+	CipherAES256GCMSIV      CipherSuite = 4
+
+	// CipherCommitting marks the key-committing variant of a suite.
+	CipherCommitting CipherSuite = 0x80
+)
+
+const committingSuffix = "-commit"
+
+var cipherSuiteNames = map[CipherSuite]string{
+	CipherAES256GCM:         "aes-256-gcm",
+	CipherChaCha20Poly1305:  "chacha20-poly1305",
+	CipherXChaCha20Poly1305: "xchacha20-poly1305",
+	CipherAES256GCMSIV:      "aes-256-gcm-siv",
+}
+
+// ParseCipherSuite returns the suite with the given name, as returned by
+// String.
+func ParseCipherSuite(name string) (CipherSuite, error) {
+	base, committing := strings.CutSuffix(name, committingSuffix)
+	for suite, n := range cipherSuiteNames {
+		if n == base {
+			if committing {
+				return suite.WithCommitment(), nil
+			}
+			return suite, nil
+		}
+	}
+	return 0, fmt.Errorf("cryptohelpers: unknown cipher %q", name)
+}
+
+// String returns the name of the suite, such as "aes-256-gcm", or
+// "aes-256-gcm-commit" for its committing variant.
+func (c CipherSuite) String() string {
+	if name, ok := cipherSuiteNames[c&^CipherCommitting]; ok {
+		if c.Committing() {
+			return name + committingSuffix
+		}
+		return name
+	}
+	return fmt.Sprintf("CipherSuite(%d)", uint8(c))
+}
+
+// Committing reports whether c is a key-committing suite.
+func (c CipherSuite) Committing() bool {
+	return c&CipherCommitting != 0
+}
+
+// WithCommitment returns the key-committing variant of c.
+func (c CipherSuite) WithCommitment() CipherSuite {
+	return c | CipherCommitting
+}
+
+// NewAEAD returns the suite's AEAD keyed with a 32-byte key.
+func (c CipherSuite) NewAEAD(key []byte) (cipher.AEAD, error) {
+	if c.Committing() {
+		return NewCommittingAEAD(key, (c &^ CipherCommitting).NewAEAD)
+	}
+	switch c {
+	case CipherAES256GCM:
+		return newAES256GCM(key)
+	case CipherChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	case CipherAES256GCMSIV:
+		return NewAES256GCMSIV(key)
+	}
+	return nil, fmt.Errorf("cryptohelpers: unknown cipher suite %d", uint8(c))
+}
+
+// EncryptWithAAD returns the suite's counterpart of AES256GCMEncryptWithAAD.
+// Like the other encryption functions, it can be wrapped to plug into
+// ecies.NewEncryptor.
+func (c CipherSuite) EncryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte, []byte) (AEADResults, error) {
+	return aeadEncrypt(kdf, c.NewAEAD)
+}
+
+// DecryptWithAAD returns the suite's counterpart of
+// AES256GCMDecryptWithAAD.
+func (c CipherSuite) DecryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AEADResults, []byte) ([]byte, error) {
+	return aeadDecrypt(kdf, c.NewAEAD)
+}
+
+// Encrypt returns the suite's counterpart of AES256GCMEncrypt, for use
+// with ecies.NewEncryptor.
+func (c CipherSuite) Encrypt(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte) (AEADResults, error) {
+	encrypt := c.EncryptWithAAD(kdf)
+	return func(secret SecretKey, plaintext []byte) (AEADResults, error) {
+		return encrypt(secret, plaintext, nil)
+	}
+}
+
+// Decrypt returns the suite's counterpart of AES256GCMDecrypt, for use
+// with ecies.NewDecryptor.
+func (c CipherSuite) Decrypt(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AEADResults) ([]byte, error) {
+	decrypt := c.DecryptWithAAD(kdf)
+	return func(secret SecretKey, nonceCiphertext AEADResults) ([]byte, error) {
+		return decrypt(secret, nonceCiphertext, nil)
+	}
+}
@@ -0,0 +1,81 @@
+package cryptohelpers
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// CommitmentSize is the size of the key commitment that a committing AEAD
+// prepends to every ciphertext.
+const CommitmentSize = 32
+
+const (
+	commitmentKeyInfo = "eccfrog512ck2 committing aead key"
+	commitmentTagInfo = "eccfrog512ck2 committing aead commitment"
+)
+
+// committingAEAD makes an AEAD key-committing. The AEAD key and a
+// commitment are both derived from the caller's key with HKDF-SHA256, and
+// the commitment is prepended to every ciphertext. Open compares it before
+// decrypting, so a ciphertext opens under at most one key, as long as
+// SHA-256 is collision resistant.
+//
+// Without commitment, a ciphertext can be crafted to decrypt under several
+// keys with GCM or Poly1305, which lets an attacker who can try ciphertexts
+// against a decryptor learn which of many candidate keys it holds: a
+// partitioning oracle.
+type committingAEAD struct {
+	aead       cipher.AEAD
+	commitment [CommitmentSize]byte
+}
+
+// NewCommittingAEAD returns a key-committing AEAD that derives its AEAD key
+// from key with HKDF-SHA256 and encrypts with the AEAD that newAEAD creates.
+// Ciphertexts are CommitmentSize bytes longer than those of the underlying
+// AEAD.
+func NewCommittingAEAD(
+	key []byte,
+	newAEAD func(key []byte) (cipher.AEAD, error),
+) (cipher.AEAD, error) {
+	prk := hkdf.Extract(sha256.New, key, nil)
+	aeadKey := make([]byte, len(key))
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte(commitmentKeyInfo)), aeadKey); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(aeadKey)
+	if err != nil {
+		return nil, err
+	}
+	c := &committingAEAD{aead: aead}
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte(commitmentTagInfo)), c.commitment[:]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *committingAEAD) NonceSize() int { return c.aead.NonceSize() }
+func (c *committingAEAD) Overhead() int  { return c.aead.Overhead() + CommitmentSize }
+
+// Seal and Open go through a separate buffer, since the commitment shifts
+// the output against the input, which may share its storage with dst.
+func (c *committingAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	ciphertext := c.aead.Seal(nil, nonce, plaintext, additionalData)
+	return append(append(dst, c.commitment[:]...), ciphertext...)
+}
+
+func (c *committingAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < CommitmentSize ||
+		subtle.ConstantTimeCompare(ciphertext[:CommitmentSize], c.commitment[:]) != 1 {
+		return nil, errors.New("cryptohelpers: key commitment mismatch")
+	}
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext[CommitmentSize:], additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, plaintext...), nil
+}
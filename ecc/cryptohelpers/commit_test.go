@@ -0,0 +1,87 @@
+package cryptohelpers_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+)
+
+func TestCommittingAEAD(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	otherKey := bytes.Repeat([]byte{2}, 32)
+	nonce := make([]byte, 12)
+	plaintext := []byte("plaintext")
+	aad := []byte("header")
+
+	for _, suite := range []cryptohelpers.CipherSuite{
+		cryptohelpers.CipherAES256GCM,
+		cryptohelpers.CipherChaCha20Poly1305,
+		cryptohelpers.CipherAES256GCMSIV,
+	} {
+		committing := suite.WithCommitment()
+		if !committing.Committing() || suite.Committing() {
+			t.Fatalf("%v: Committing is wrong", suite)
+		}
+		aead, err := committing.NewAEAD(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, _ := suite.NewAEAD(key)
+		if aead.Overhead() != plain.Overhead()+cryptohelpers.CommitmentSize {
+			t.Errorf("%v: Overhead = %d", committing, aead.Overhead())
+		}
+
+		// Sealing and opening in place
+		buf := append([]byte{}, plaintext...)
+		ciphertext := aead.Seal(buf[:0], nonce, buf, aad)
+		if len(ciphertext) != len(plaintext)+aead.Overhead() {
+			t.Fatalf("%v: ciphertext is %d bytes", committing, len(ciphertext))
+		}
+		opened, err := aead.Open(ciphertext[:0], nonce, ciphertext, aad)
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Fatalf("%v: Open = %q, %v", committing, opened, err)
+		}
+		ciphertext = aead.Seal(nil, nonce, plaintext, aad)
+
+		// The commitment is checked before the underlying AEAD is used
+		other, _ := committing.NewAEAD(otherKey)
+		if _, err := other.Open(nil, nonce, ciphertext, aad); err == nil || err.Error() != "cryptohelpers: key commitment mismatch" {
+			t.Errorf("%v: expected a commitment mismatch, got %v", committing, err)
+		}
+		if _, err := aead.Open(nil, nonce, ciphertext[:cryptohelpers.CommitmentSize-1], aad); err == nil {
+			t.Errorf("%v: expected error for a short ciphertext", committing)
+		}
+		if _, err := plain.Open(nil, nonce, ciphertext, aad); err == nil {
+			t.Errorf("%v: expected the plain suite to reject a committing ciphertext", committing)
+		}
+
+		parsed, err := cryptohelpers.ParseCipherSuite(committing.String())
+		if err != nil || parsed != committing {
+			t.Errorf("ParseCipherSuite(%q) = %v, %v", committing.String(), parsed, err)
+		}
+	}
+
+	if got := cryptohelpers.CipherAES256GCM.WithCommitment().String(); got != "aes-256-gcm-commit" {
+		t.Errorf("String = %q", got)
+	}
+	if _, err := cryptohelpers.CipherCommitting.NewAEAD(key); err == nil {
+		t.Error("Expected error for the committing bit alone")
+	}
+
+	// The suites plug into the KDF-based functions like any other
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	suite := cryptohelpers.CipherXChaCha20Poly1305.WithCommitment()
+	result, err := suite.Encrypt(kdf)(cryptohelpers.SecretKey("secret"), plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := suite.Decrypt(kdf)(cryptohelpers.SecretKey("other"), result); err == nil {
+		t.Error("Expected error for the wrong secret")
+	}
+	decrypted, err := suite.Decrypt(kdf)(cryptohelpers.SecretKey("secret"), result)
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Failed to decrypt: %v", err)
+	}
+}
@@ -1,8 +1,9 @@
 // Package cryptohelpers provides cryptographic utility functions for secure key
 // derivation and authenticated encryption.
 //
-// The package implements AES-256-GCM authenticated encryption and HKDF key
-// derivation functions. The main components are:
+// The package implements AES-256-GCM, AES-256-GCM-SIV, ChaCha20-Poly1305 and
+// XChaCha20-Poly1305 authenticated encryption and HKDF key derivation
+// functions. The main components are:
 //
 // SecretKey - A type representing cryptographic secret key material
 //
@@ -12,8 +13,32 @@
 //
 // AES256GCMDecrypt - Creates a decryption function using AES-256-GCM with a KDF
 //
+// AES256GCMEncryptWithAAD, AES256GCMDecryptWithAAD - Like AES256GCMEncrypt and
+// AES256GCMDecrypt, but also authenticate additional data
+//
+// ChaCha20Poly1305Encrypt, XChaCha20Poly1305Encrypt and their Decrypt and
+// WithAAD variants - Counterparts of the AES-256-GCM functions for processors
+// without AES instructions
+//
+// NewAES256GCMSIV, AES256GCMSIVEncrypt and its Decrypt and WithAAD variants -
+// AES-256-GCM-SIV (RFC 8452), which stays secure when a nonce repeats
+//
+// AES256GCMSIVEncryptDeterministic - AES-256-GCM-SIV under a fixed nonce, for
+// deterministic encryption
+//
+// NewCommittingAEAD - Makes any AEAD key-committing, so that a ciphertext
+// decrypts under only one key
+//
+// CipherSuite - An identifier for one of the AEADs, for formats and tools that
+// let the sender choose it
+//
 // HKDF256 - Creates a key derivation function using HKDF with SHA-256
 //
+// X963KDF, X963KDF256 - The ANSI X9.63 key derivation function from SEC 1
+//
+// AESKeyWrap, AESKeyUnwrap - Wrap and unwrap keys with the RFC 3394 AES key
+// wrap algorithm
+//
 // The encryption functions use AES-256 in Galois/Counter Mode (GCM) which provides
 // both confidentiality and authenticity. The key derivation uses HKDF (HMAC-based
 // Key Derivation Function) to derive encryption keys from secret key material.
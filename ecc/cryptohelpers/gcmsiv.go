@@ -0,0 +1,215 @@
+package cryptohelpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+	// gcmSIVMaxLength is the limit RFC 8452 places on plaintexts and
+	// additional data: 2^36 bytes.
+	gcmSIVMaxLength = 1 << 36
+)
+
+// aes256GCMSIV is AEAD_AES_256_GCM_SIV from RFC 8452.
+type aes256GCMSIV struct {
+	block cipher.Block
+}
+
+// NewAES256GCMSIV returns AES-256-GCM-SIV (RFC 8452) keyed with a 32-byte
+// key. Unlike GCM, reusing a nonce does not reveal the authentication key or
+// the XOR of plaintexts: it only reveals whether two messages with the same
+// nonce and additional data are identical.
+func NewAES256GCMSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("cryptohelpers: AES-256-GCM-SIV key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aes256GCMSIV{block: block}, nil
+}
+
+func (*aes256GCMSIV) NonceSize() int { return gcmSIVNonceSize }
+func (*aes256GCMSIV) Overhead() int  { return gcmSIVTagSize }
+
+// deriveKeys derives the per-nonce authentication and encryption keys, as in
+// RFC 8452, section 4: the first 8 bytes of each encryption of a
+// little-endian counter and the nonce.
+func (a *aes256GCMSIV) deriveKeys(nonce []byte) (authKey [16]byte, encKey [32]byte) {
+	var in, out [16]byte
+	copy(in[4:], nonce)
+	for i := uint32(0); i < 6; i++ {
+		binary.LittleEndian.PutUint32(in[:4], i)
+		a.block.Encrypt(out[:], in[:])
+		if i < 2 {
+			copy(authKey[8*i:], out[:8])
+		} else {
+			copy(encKey[8*(i-2):], out[:8])
+		}
+	}
+	return authKey, encKey
+}
+
+// gcmSIVTag computes the tag of plaintext and additionalData under the derived
+// keys.
+func gcmSIVTag(block cipher.Block, authKey []byte, nonce, plaintext, additionalData []byte) [16]byte {
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+
+	p := newPolyval(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+	p.update(lengths[:])
+	s := p.sum()
+	for i := range nonce {
+		s[i] ^= nonce[i]
+	}
+	s[15] &= 0x7f
+	block.Encrypt(s[:], s[:])
+	return s
+}
+
+// gcmSIVCTR XORs src into dst with the keystream that starts at the tag, with the
+// top bit set, and increments the first 32 bits as a little-endian counter.
+func gcmSIVCTR(block cipher.Block, tag [16]byte, dst, src []byte) {
+	counter := tag
+	counter[15] |= 0x80
+	var keystream [16]byte
+	for len(src) > 0 {
+		block.Encrypt(keystream[:], counter[:])
+		n := subtle.XORBytes(dst, src, keystream[:])
+		dst, src = dst[n:], src[n:]
+		binary.LittleEndian.PutUint32(counter[:4], binary.LittleEndian.Uint32(counter[:4])+1)
+	}
+}
+
+func (a *aes256GCMSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("cryptohelpers: incorrect nonce length given to AES-256-GCM-SIV")
+	}
+	if uint64(len(plaintext)) > gcmSIVMaxLength || uint64(len(additionalData)) > gcmSIVMaxLength {
+		panic("cryptohelpers: message too large for AES-256-GCM-SIV")
+	}
+	authKey, encKey := a.deriveKeys(nonce)
+	block, err := aes.NewCipher(encKey[:])
+	if err != nil {
+		panic(err)
+	}
+
+	t := gcmSIVTag(block, authKey[:], nonce, plaintext, additionalData)
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmSIVTagSize)
+	gcmSIVCTR(block, t, out[:len(plaintext)], plaintext)
+	copy(out[len(plaintext):], t[:])
+	return ret
+}
+
+func (a *aes256GCMSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("cryptohelpers: incorrect nonce length given to AES-256-GCM-SIV")
+	}
+	if len(ciphertext) < gcmSIVTagSize ||
+		uint64(len(ciphertext)) > gcmSIVMaxLength+gcmSIVTagSize ||
+		uint64(len(additionalData)) > gcmSIVMaxLength {
+		return nil, errors.New("cryptohelpers: message authentication failed")
+	}
+	authKey, encKey := a.deriveKeys(nonce)
+	block, err := aes.NewCipher(encKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var t [16]byte
+	copy(t[:], ciphertext[len(ciphertext)-gcmSIVTagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-gcmSIVTagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	gcmSIVCTR(block, t, out, ciphertext)
+	expected := gcmSIVTag(block, authKey[:], nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(expected[:], t[:]) != 1 {
+		clear(out)
+		return nil, errors.New("cryptohelpers: message authentication failed")
+	}
+	return ret, nil
+}
+
+// sliceForAppend extends in by n bytes, and returns the extended slice and
+// the n new bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return head, tail
+}
+
+// AES256GCMSIVEncrypt is like AES256GCMEncrypt, but encrypts with
+// AES-256-GCM-SIV under a random 12-byte nonce. A repeated nonce does not
+// break confidentiality or authenticity as it would with GCM.
+func AES256GCMSIVEncrypt(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte) (AEADResults, error) {
+	encrypt := AES256GCMSIVEncryptWithAAD(kdf)
+	return func(secret SecretKey, plaintext []byte) (AEADResults, error) {
+		return encrypt(secret, plaintext, nil)
+	}
+}
+
+// AES256GCMSIVDecrypt decrypts ciphertexts produced by AES256GCMSIVEncrypt
+// and AES256GCMSIVEncryptDeterministic.
+func AES256GCMSIVDecrypt(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AEADResults) ([]byte, error) {
+	decrypt := AES256GCMSIVDecryptWithAAD(kdf)
+	return func(secret SecretKey, nonceCiphertext AEADResults) ([]byte, error) {
+		return decrypt(secret, nonceCiphertext, nil)
+	}
+}
+
+// AES256GCMSIVEncryptWithAAD is like AES256GCMSIVEncrypt, but also
+// authenticates additional data.
+func AES256GCMSIVEncryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte, []byte) (AEADResults, error) {
+	return aeadEncrypt(kdf, NewAES256GCMSIV)
+}
+
+// AES256GCMSIVDecryptWithAAD is like AES256GCMSIVDecrypt, but also checks
+// the additional data.
+func AES256GCMSIVDecryptWithAAD(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, AEADResults, []byte) ([]byte, error) {
+	return aeadDecrypt(kdf, NewAES256GCMSIV)
+}
+
+// AES256GCMSIVEncryptDeterministic is like AES256GCMSIVEncrypt, but always
+// uses the all-zero nonce, so that the same secret and plaintext always give
+// the same ciphertext. This is what deduplicating storage needs, and it is
+// safe with GCM-SIV: an observer learns only which ciphertexts hold
+// identical plaintexts. The output decrypts with AES256GCMSIVDecrypt.
+func AES256GCMSIVEncryptDeterministic(
+	kdf func(SecretKey) ([32]byte, error),
+) func(SecretKey, []byte) (AEADResults, error) {
+	return func(secret SecretKey, plaintext []byte) (AEADResults, error) {
+		key, err := kdf(secret)
+		if err != nil {
+			return AEADResults{}, err
+		}
+		aead, err := NewAES256GCMSIV(key[:])
+		if err != nil {
+			return AEADResults{}, err
+		}
+		nonce := make([]byte, gcmSIVNonceSize)
+		return AEADResults{CipherText: aead.Seal(nil, nonce, plaintext, nil), Nonce: nonce}, nil
+	}
+}
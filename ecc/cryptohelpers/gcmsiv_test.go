@@ -0,0 +1,129 @@
+package cryptohelpers_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+)
+
+func TestAES256GCMSIVVectors(t *testing.T) {
+	for _, test := range []struct {
+		name, key, nonce, plaintext, aad, result string
+	}{
+		// RFC 8452, appendix C.2
+		{
+			"RFC 8452 empty",
+			"0100000000000000000000000000000000000000000000000000000000000000",
+			"030000000000000000000000", "", "",
+			"07f5f4169bbf55a8400cd47ea6fd400f",
+		},
+		{
+			"RFC 8452 8 bytes",
+			"0100000000000000000000000000000000000000000000000000000000000000",
+			"030000000000000000000000", "0100000000000000", "",
+			"c2ef328e5c71c83b843122130f7364b761e0b97427e3df28",
+		},
+		{
+			"RFC 8452 12 bytes aad",
+			"0100000000000000000000000000000000000000000000000000000000000000",
+			"030000000000000000000000", "02000000", "010000000000000000000000",
+			"22b3f4cd1835e517741dfddccfa07fa4661b74cf",
+		},
+		{
+			"RFC 8452 18 bytes aad",
+			"0100000000000000000000000000000000000000000000000000000000000000",
+			"030000000000000000000000",
+			"0300000000000000000000000000000004000000",
+			"010000000000000000000000000000000200",
+			"43dd0163cdb48f9fe3212bf61b201976067f342bb879ad976d8242acc188ab59cabfe307",
+		},
+		{
+			"RFC 8452 20 bytes aad",
+			"0100000000000000000000000000000000000000000000000000000000000000",
+			"030000000000000000000000",
+			"030000000000000000000000000000000400",
+			"0100000000000000000000000000000002000000",
+			"462401724b5ce6588d5a54aae5375513a075cfcdf5042112aa29685c912fc2056543",
+		},
+		// Several partial blocks of plaintext and additional data, checked
+		// against an independent implementation
+		{
+			"partial blocks",
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"000102030405060708090a0b",
+			hex.EncodeToString([]byte("The quick brown fox jumps over the lazy dog")),
+			hex.EncodeToString([]byte("additional data")),
+			"ffebbe0c5c0a7e31d60034c5832020358a6d6ee9a6410b7472b434d1a90d5f2585bca13c06503a6f086d0749007b569712111c40be0dafb3389a75",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			aead, err := cryptohelpers.NewAES256GCMSIV(mustDecodeHex(t, test.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonce := mustDecodeHex(t, test.nonce)
+			plaintext := mustDecodeHex(t, test.plaintext)
+			aad := mustDecodeHex(t, test.aad)
+			want := mustDecodeHex(t, test.result)
+
+			got := aead.Seal(nil, nonce, plaintext, aad)
+			if !bytes.Equal(got, want) {
+				t.Errorf("Seal = %x, want %x", got, want)
+			}
+			opened, err := aead.Open(nil, nonce, want, aad)
+			if err != nil || !bytes.Equal(opened, plaintext) {
+				t.Errorf("Open = %x, %v", opened, err)
+			}
+
+			for i := range want {
+				tampered := bytes.Clone(want)
+				tampered[i] ^= 1
+				if _, err := aead.Open(nil, nonce, tampered, aad); err == nil {
+					t.Fatalf("Expected error with byte %d flipped", i)
+				}
+			}
+		})
+	}
+}
+
+func TestAES256GCMSIV(t *testing.T) {
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	secret := cryptohelpers.SecretKey("shared secret")
+	plaintext := []byte("plaintext")
+	aad := []byte("header")
+
+	result, err := cryptohelpers.AES256GCMSIVEncryptWithAAD(kdf)(secret, plaintext, aad)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	decrypted, err := cryptohelpers.AES256GCMSIVDecryptWithAAD(kdf)(secret, result, aad)
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if _, err := cryptohelpers.AES256GCMSIVDecryptWithAAD(kdf)(secret, result, []byte("other")); err == nil {
+		t.Error("Expected error for wrong additional data")
+	}
+
+	// Deterministic encryption repeats only for identical plaintexts
+	encrypt := cryptohelpers.AES256GCMSIVEncryptDeterministic(kdf)
+	a, _ := encrypt(secret, plaintext)
+	b, _ := encrypt(secret, plaintext)
+	c, _ := encrypt(secret, []byte("plaintexT"))
+	if !bytes.Equal(a.CipherText, b.CipherText) {
+		t.Error("Expected identical ciphertexts for identical plaintexts")
+	}
+	if bytes.Equal(a.CipherText, c.CipherText) {
+		t.Error("Expected different ciphertexts for different plaintexts")
+	}
+	decrypted, err = cryptohelpers.AES256GCMSIVDecrypt(kdf)(secret, a)
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Failed to decrypt deterministic ciphertext: %v", err)
+	}
+
+	if _, err := cryptohelpers.NewAES256GCMSIV(make([]byte, 16)); err == nil {
+		t.Error("Expected error for a 16-byte key")
+	}
+}
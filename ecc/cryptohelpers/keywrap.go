@@ -0,0 +1,83 @@
+package cryptohelpers
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// defaultIV is the initial value from RFC 3394, section 2.2.3.1.
+var defaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// AESKeyWrap wraps a key using the AES key wrap algorithm from RFC 3394. The
+// key encryption key must be 16, 24 or 32 bytes long, and the key being
+// wrapped must be a multiple of 8 bytes and at least 16 bytes long.
+//
+// The result is 8 bytes longer than the wrapped key.
+func AESKeyWrap(kek, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, errors.New("cryptohelpers: wrapped key must be a multiple of 8 bytes and at least 16 bytes")
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(key) / 8
+	out := make([]byte, 8+len(key))
+	copy(out[:8], defaultIV)
+	copy(out[8:], key)
+
+	var buf [16]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf[:8], out[:8])
+			copy(buf[8:], out[i*8:(i+1)*8])
+			block.Encrypt(buf[:], buf[:])
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[i*8:(i+1)*8], buf[8:])
+		}
+	}
+
+	return out, nil
+}
+
+// AESKeyUnwrap unwraps a key wrapped with AESKeyWrap, and returns an error if
+// the integrity check fails.
+func AESKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, errors.New("cryptohelpers: wrapped key must be a multiple of 8 bytes and at least 24 bytes")
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+
+	var buf [16]byte
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(out[:8])^t)
+			copy(buf[8:], out[i*8:(i+1)*8])
+			block.Decrypt(buf[:], buf[:])
+
+			copy(out[:8], buf[:8])
+			copy(out[i*8:(i+1)*8], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(out[:8], defaultIV) != 1 {
+		return nil, errors.New("cryptohelpers: key unwrap integrity check failed")
+	}
+
+	return out[8:], nil
+}
@@ -0,0 +1,70 @@
+package cryptohelpers_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestAESKeyWrap(t *testing.T) {
+	// Test vectors from RFC 3394, sections 4.1 and 4.6.
+	tests := []struct {
+		name    string
+		kek     string
+		key     string
+		wrapped string
+	}{
+		{
+			name:    "128-bit key with 128-bit KEK",
+			kek:     "000102030405060708090A0B0C0D0E0F",
+			key:     "00112233445566778899AABBCCDDEEFF",
+			wrapped: "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5",
+		},
+		{
+			name:    "256-bit key with 256-bit KEK",
+			kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			key:     "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+			wrapped: "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kek := mustDecodeHex(t, tt.kek)
+			key := mustDecodeHex(t, tt.key)
+			want := mustDecodeHex(t, tt.wrapped)
+
+			got, err := cryptohelpers.AESKeyWrap(kek, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("AESKeyWrap() = %X, want %X", got, want)
+			}
+
+			unwrapped, err := cryptohelpers.AESKeyUnwrap(kek, want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(unwrapped, key) {
+				t.Errorf("AESKeyUnwrap() = %X, want %X", unwrapped, key)
+			}
+
+			want[len(want)-1] ^= 1
+			if _, err := cryptohelpers.AESKeyUnwrap(kek, want); err == nil {
+				t.Error("expected the integrity check to fail for a tampered key")
+			}
+		})
+	}
+}
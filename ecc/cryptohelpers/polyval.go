@@ -0,0 +1,105 @@
+package cryptohelpers
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// polyval computes POLYVAL from RFC 8452, section 3, over GF(2^128) with the
+// polynomial x^128 + x^127 + x^126 + x^121 + 1. Field elements are 16
+// little-endian bytes, held as two 64-bit halves.
+//
+// Multiplication uses no table lookups or secret-dependent branches: each
+// 64-bit carry-less product is computed with integer multiplications whose
+// carries are kept out of the way by masking, as in BearSSL's ghash_ctmul64.
+type polyval struct {
+	h fieldElement
+	s fieldElement
+}
+
+type fieldElement struct {
+	lo, hi uint64
+}
+
+func newPolyval(key []byte) *polyval {
+	return &polyval{h: loadFieldElement(key)}
+}
+
+func loadFieldElement(b []byte) fieldElement {
+	return fieldElement{
+		lo: binary.LittleEndian.Uint64(b[0:8]),
+		hi: binary.LittleEndian.Uint64(b[8:16]),
+	}
+}
+
+// update absorbs data, which is zero-padded to a multiple of 16 bytes.
+func (p *polyval) update(data []byte) {
+	for len(data) > 0 {
+		var block [16]byte
+		n := copy(block[:], data)
+		data = data[n:]
+		x := loadFieldElement(block[:])
+		p.s.lo ^= x.lo
+		p.s.hi ^= x.hi
+		p.s = dot(p.s, p.h)
+	}
+}
+
+func (p *polyval) sum() [16]byte {
+	var out [16]byte
+	binary.LittleEndian.PutUint64(out[0:8], p.s.lo)
+	binary.LittleEndian.PutUint64(out[8:16], p.s.hi)
+	return out
+}
+
+// bmul64 returns the low 64 bits of the carry-less product of x and y. Only
+// every fourth bit of each operand takes part in each multiplication, so
+// that carries land in bits that are masked off.
+func bmul64(x, y uint64) uint64 {
+	const (
+		m0 = 0x1111111111111111
+		m1 = 0x2222222222222222
+		m2 = 0x4444444444444444
+		m3 = 0x8888888888888888
+	)
+	x0, x1, x2, x3 := x&m0, x&m1, x&m2, x&m3
+	y0, y1, y2, y3 := y&m0, y&m1, y&m2, y&m3
+	z0 := (x0 * y0) ^ (x1 * y3) ^ (x2 * y2) ^ (x3 * y1)
+	z1 := (x0 * y1) ^ (x1 * y0) ^ (x2 * y3) ^ (x3 * y2)
+	z2 := (x0 * y2) ^ (x1 * y1) ^ (x2 * y0) ^ (x3 * y3)
+	z3 := (x0 * y3) ^ (x1 * y2) ^ (x2 * y1) ^ (x3 * y0)
+	return (z0 & m0) | (z1 & m1) | (z2 & m2) | (z3 & m3)
+}
+
+// clmul returns the 128-bit carry-less product of x and y. The high half is
+// the low half of the product of the bit-reversed operands, reversed.
+func clmul(x, y uint64) (lo, hi uint64) {
+	lo = bmul64(x, y)
+	hi = bits.Reverse64(bmul64(bits.Reverse64(x), bits.Reverse64(y))) >> 1
+	return lo, hi
+}
+
+// dot returns a·b·x^-128, the POLYVAL product.
+func dot(a, b fieldElement) fieldElement {
+	// Karatsuba: the middle term is (a0+a1)(b0+b1) - a0b0 - a1b1.
+	z0lo, z0hi := clmul(a.lo, b.lo)
+	z2lo, z2hi := clmul(a.hi, b.hi)
+	z1lo, z1hi := clmul(a.lo^a.hi, b.lo^b.hi)
+	z1lo ^= z0lo ^ z2lo
+	z1hi ^= z0hi ^ z2hi
+
+	d0 := z0lo
+	d1 := z0hi ^ z1lo
+	d2 := z2lo ^ z1hi
+	d3 := z2hi
+
+	// Montgomery reduction, 64 bits at a time: adding d·P, where
+	// P = x^128 + x^127 + x^126 + x^121 + 1, clears the lowest word d and
+	// adds d·(x^127 + x^126 + x^121) to the words above it.
+	d1 ^= (d0 << 63) ^ (d0 << 62) ^ (d0 << 57)
+	d2 ^= d0 ^ (d0 >> 1) ^ (d0 >> 2) ^ (d0 >> 7)
+	d2 ^= (d1 << 63) ^ (d1 << 62) ^ (d1 << 57)
+	d3 ^= d1 ^ (d1 >> 1) ^ (d1 >> 2) ^ (d1 >> 7)
+
+	return fieldElement{lo: d2, hi: d3}
+}
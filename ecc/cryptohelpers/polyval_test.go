@@ -0,0 +1,40 @@
+package cryptohelpers
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPolyval(t *testing.T) {
+	// RFC 8452, appendix A
+	h, _ := hex.DecodeString("25629347589242761d31f826ba4b757b")
+	x, _ := hex.DecodeString("4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362")
+	p := newPolyval(h)
+	p.update(x)
+	sum := p.sum()
+	if got := hex.EncodeToString(sum[:]); got != "f7a3b47b846119fae5b7866cf5e5b77e" {
+		t.Errorf("POLYVAL = %s", got)
+	}
+}
+
+func TestClmul(t *testing.T) {
+	// Compare against schoolbook carry-less multiplication
+	for _, v := range [][2]uint64{
+		{0, 0}, {1, 1}, {^uint64(0), ^uint64(0)}, {0x8000000000000000, 0x8000000000000000},
+		{0x0123456789abcdef, 0xfedcba9876543210}, {0xdeadbeefcafef00d, 0x1111111111111111},
+	} {
+		var wantLo, wantHi uint64
+		for i := 0; i < 64; i++ {
+			if v[1]>>i&1 == 1 {
+				wantLo ^= v[0] << i
+				if i > 0 {
+					wantHi ^= v[0] >> (64 - i)
+				}
+			}
+		}
+		lo, hi := clmul(v[0], v[1])
+		if lo != wantLo || hi != wantHi {
+			t.Errorf("clmul(%#x, %#x) = %#x:%#x, want %#x:%#x", v[0], v[1], hi, lo, wantHi, wantLo)
+		}
+	}
+}
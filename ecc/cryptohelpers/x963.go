@@ -0,0 +1,46 @@
+package cryptohelpers
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+// X963KDF derives length bytes from secret with the ANSI X9.63 key
+// derivation function, as specified in SEC 1 v2, section 3.6.1. The output
+// is the concatenation of hash(secret || counter || sharedInfo) for a 32-bit
+// big-endian counter starting at 1. It is the same function as KDF2 from
+// ISO 18033-2.
+func X963KDF(hash func() hash.Hash, secret, sharedInfo []byte, length int) ([]byte, error) {
+	h := hash()
+	if length < 0 || uint64(length) > uint64(h.Size())*(1<<32-1) {
+		return nil, errors.New("cryptohelpers: invalid X9.63 KDF output length")
+	}
+
+	out := make([]byte, 0, length+h.Size())
+	var counter [4]byte
+	for i := uint32(1); len(out) < length; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h.Reset()
+		h.Write(secret)
+		h.Write(counter[:])
+		h.Write(sharedInfo)
+		out = h.Sum(out)
+	}
+	return out[:length], nil
+}
+
+// X963KDF256 returns a key derivation function that uses the ANSI X9.63 KDF
+// to derive a 32-byte key, for use in place of HKDF256 with the encryption
+// functions in this package.
+func X963KDF256(hash func() hash.Hash, sharedInfo []byte) func(secret SecretKey) ([32]byte, error) {
+	return func(secret SecretKey) ([32]byte, error) {
+		var key [32]byte
+		out, err := X963KDF(hash, secret, sharedInfo, len(key))
+		if err != nil {
+			return key, err
+		}
+		copy(key[:], out)
+		return key, nil
+	}
+}
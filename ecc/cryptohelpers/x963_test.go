@@ -0,0 +1,66 @@
+package cryptohelpers_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+)
+
+func TestX963KDF(t *testing.T) {
+	secret := []byte("shared secret")
+	sharedInfo := []byte("shared info")
+
+	out, err := cryptohelpers.X963KDF(sha256.New, secret, sharedInfo, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each block is hash(secret || counter || sharedInfo), counting from 1
+	var want []byte
+	for _, counter := range []byte{1, 2} {
+		block := sha256.Sum256(append(append(append([]byte{}, secret...), 0, 0, 0, counter), sharedInfo...))
+		want = append(want, block[:]...)
+	}
+	if !bytes.Equal(out, want[:40]) {
+		t.Errorf("X963KDF = %x, want %x", out, want[:40])
+	}
+
+	// A shorter output is a prefix of a longer one
+	short, err := cryptohelpers.X963KDF(sha256.New, secret, sharedInfo, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(short, out[:16]) {
+		t.Error("Expected a shorter output to be a prefix")
+	}
+
+	if _, err := cryptohelpers.X963KDF(sha256.New, secret, sharedInfo, -1); err == nil {
+		t.Error("Expected error for a negative length")
+	}
+}
+
+func TestX963KDFKnownAnswer(t *testing.T) {
+	out, err := cryptohelpers.X963KDF(sha512.New, []byte("EccFrog512ck2"), []byte("X9.63"), 96)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := hex.DecodeString(x963KnownAnswer)
+	if !bytes.Equal(out, want) {
+		t.Errorf("X963KDF = %x, want %x", out, want)
+	}
+
+	key, err := cryptohelpers.X963KDF256(sha512.New, []byte("X9.63"))(cryptohelpers.SecretKey("EccFrog512ck2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key[:], want[:32]) {
+		t.Error("X963KDF256 does not match X963KDF")
+	}
+}
+
+// x963KnownAnswer was computed independently of this package.
+const x963KnownAnswer = "faa5a7d78fd385410bf8a039adea61c344dd1cdcb52f306bd8d02cbc26e6169e92b02577058ca5f9252c9d82167e09ad17157b066f0a723dba36de7cf9902e42dd5fd061211fe662c05bbb9ae00e2b3239d88517f5bcd78ad695811a01e00cfa"
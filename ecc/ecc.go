@@ -3,6 +3,7 @@ package ecc
 import (
 	"crypto/rand"
 	"errors"
+	"io"
 	"math/big"
 
 	"github.com/shovon/go-eccfrog512ck2"
@@ -41,8 +42,18 @@ func add1(value *big.Int) *big.Int {
 //
 // Effectively a shorthand for `rand.Int(rand.Reader, GeneratorOrder())`
 func GeneratePrivateKey() (PrivateKey, error) {
-	value, err := rand.Int(rand.Reader, sub1(eccfrog512ck2.GeneratorOrder()))
-	return PrivateKey{value: add1(value)}, err
+	return GeneratePrivateKeyFrom(rand.Reader)
+}
+
+// GeneratePrivateKeyFrom is like GeneratePrivateKey, but draws the key from
+// random instead of crypto/rand. The same bytes from random give the same
+// key, which lets tests reproduce known answers.
+func GeneratePrivateKeyFrom(random io.Reader) (PrivateKey, error) {
+	value, err := rand.Int(random, sub1(eccfrog512ck2.GeneratorOrder()))
+	if err != nil {
+		return PrivateKey{}, err
+	}
+	return PrivateKey{value: add1(value)}, nil
 }
 
 // ParsePrivateKeySEC1 parses a private key in SEC1 format.
@@ -123,6 +134,9 @@ func ParsePublicKeySEC1(data []byte) (eccfrog512ck2.CurvePoint, error) {
 		}
 		x := new(big.Int).SetBytes(data[1:65])
 		y := new(big.Int).SetBytes(data[65:129])
+		if x.Cmp(eccfrog512ck2.P()) >= 0 || y.Cmp(eccfrog512ck2.P()) >= 0 {
+			return eccfrog512ck2.CurvePoint{}, errors.New("public key coordinate is not less than p")
+		}
 		point, err := eccfrog512ck2.NewCurvePoint(x, y)
 		if err != nil {
 			return eccfrog512ck2.CurvePoint{}, err
@@ -137,6 +151,9 @@ func ParsePublicKeySEC1(data []byte) (eccfrog512ck2.CurvePoint, error) {
 			return eccfrog512ck2.CurvePoint{}, errors.New("invalid compressed public key length")
 		}
 		x := new(big.Int).SetBytes(data[1:65])
+		if x.Cmp(eccfrog512ck2.P()) >= 0 {
+			return eccfrog512ck2.CurvePoint{}, errors.New("public key coordinate is not less than p")
+		}
 
 		// Calculate y from x using the curve equation: y^2 = x^3 + ax + b (mod p)
 		// y^2 = x^3 + ax + b
@@ -174,3 +191,22 @@ func ParsePublicKeySEC1(data []byte) (eccfrog512ck2.CurvePoint, error) {
 		return eccfrog512ck2.CurvePoint{}, errors.New("invalid public key format")
 	}
 }
+
+// ValidatePublicKey checks that publicKey can be used as a public key: it is
+// not the point at infinity, its coordinates are less than p, and it lies on
+// the curve. The curve has prime order, so every such point generates the
+// whole group, and no subgroup check is needed.
+func ValidatePublicKey(publicKey eccfrog512ck2.CurvePoint) error {
+	x, y, ok := publicKey.CoordinateIfNotInfinity()
+	if !ok {
+		return errors.New("public key is the point at infinity")
+	}
+	if x.Sign() < 0 || x.Cmp(eccfrog512ck2.P()) >= 0 ||
+		y.Sign() < 0 || y.Cmp(eccfrog512ck2.P()) >= 0 {
+		return errors.New("public key coordinate is not less than p")
+	}
+	if !eccfrog512ck2.IsCoordinateInCurve([2]*big.Int{x, y}) {
+		return errors.New("point is not on the curve")
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package ecc_test
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
 
@@ -135,6 +136,14 @@ func TestParsePublicKeySEC1(t *testing.T) {
 		t.Fatal("Public key is point at infinity")
 	}
 
+	// An on-curve point whose x-coordinate is small enough for x+p to fit
+	// in 64 bytes, so that it has a non-canonical encoding.
+	smallX, smallY := smallPoint(t)
+	unreduced := make([]byte, 129)
+	unreduced[0] = 0x04
+	new(big.Int).Add(smallX, eccfrog512ck2.P()).FillBytes(unreduced[1:65])
+	smallY.FillBytes(unreduced[65:])
+
 	tests := []struct {
 		name    string
 		data    []byte
@@ -180,6 +189,16 @@ func TestParsePublicKeySEC1(t *testing.T) {
 			data:    append([]byte{0x04}, append(big.NewInt(0).Bytes(), big.NewInt(0).Bytes()...)...),
 			wantErr: true,
 		},
+		{
+			name:    "uncompressed coordinate not less than p",
+			data:    unreduced,
+			wantErr: true,
+		},
+		{
+			name:    "compressed coordinate not less than p",
+			data:    append([]byte{0x02}, unreduced[1:65]...),
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -199,6 +218,53 @@ func TestParsePublicKeySEC1(t *testing.T) {
 	}
 }
 
+// smallPoint returns the point of the curve with the smallest x-coordinate.
+func smallPoint(t *testing.T) (*big.Int, *big.Int) {
+	p := eccfrog512ck2.P()
+	for x := big.NewInt(0); ; x.Add(x, big.NewInt(1)) {
+		y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+		y2.Add(y2, new(big.Int).Mul(eccfrog512ck2.A(), x))
+		y2.Add(y2, eccfrog512ck2.B())
+		y2.Mod(y2, p)
+		if y := new(big.Int).ModSqrt(y2, p); y != nil {
+			if !eccfrog512ck2.IsCoordinateInCurve([2]*big.Int{x, y}) {
+				t.Fatal("smallPoint found a point off the curve")
+			}
+			return x, y
+		}
+	}
+}
+
+func TestValidatePublicKey(t *testing.T) {
+	privKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey, err := privKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecc.ValidatePublicKey(pubKey); err != nil {
+		t.Errorf("Expected a derived public key to be valid, got %v", err)
+	}
+
+	if err := ecc.ValidatePublicKey(eccfrog512ck2.PointAtInfinity()); err == nil {
+		t.Error("Expected an error for the point at infinity")
+	}
+	if err := ecc.ValidatePublicKey(eccfrog512ck2.CurvePoint{}); err == nil {
+		t.Error("Expected an error for the zero CurvePoint")
+	}
+
+	x, y := smallPoint(t)
+	unreduced, err := eccfrog512ck2.NewCurvePoint(new(big.Int).Add(x, eccfrog512ck2.P()), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecc.ValidatePublicKey(unreduced); err == nil {
+		t.Error("Expected an error for a coordinate not less than p")
+	}
+}
+
 // mustParseKey is a helper function that creates a PrivateKey from bytes,
 // failing the test if parsing fails.
 func mustParseKey(t *testing.T, data []byte) ecc.PrivateKey {
@@ -208,3 +274,24 @@ func mustParseKey(t *testing.T, data []byte) ecc.PrivateKey {
 	}
 	return key
 }
+
+func TestGeneratePrivateKeyFrom(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x5a}, 128)
+	a, err := ecc.GeneratePrivateKeyFrom(bytes.NewReader(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ecc.GeneratePrivateKeyFrom(bytes.NewReader(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.GetKey().Cmp(b.GetKey()) != 0 {
+		t.Error("Expected the same key from the same randomness")
+	}
+	if _, err := a.DerivePublicKey(); err != nil {
+		t.Errorf("Generated key is invalid: %v", err)
+	}
+	if _, err := ecc.GeneratePrivateKeyFrom(bytes.NewReader(nil)); err == nil {
+		t.Error("Expected error for empty randomness")
+	}
+}
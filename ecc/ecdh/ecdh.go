@@ -16,3 +16,22 @@ func (e ECDHPrivateKey) DeriveSharedSecret(publicKey eccfrog512ck2.CurvePoint) (
 	}
 	return nil, errors.New("either the other party's public key was the point at infinity, or the private key was either 0 or the multiple of the order of the curve")
 }
+
+// SharedSecretSize is the size, in bytes, of a shared secret returned by
+// DeriveFixedSizeSharedSecret, which is the size of a field element of the
+// curve.
+const SharedSecretSize = 64
+
+// DeriveFixedSizeSharedSecret derives the same shared secret as
+// DeriveSharedSecret, but left-pads it with zeros to SharedSecretSize bytes.
+//
+// DeriveSharedSecret drops leading zero bytes of the x-coordinate, whereas
+// most standardized key derivation schemes expect the field element in its
+// fixed-length encoding.
+func (e ECDHPrivateKey) DeriveFixedSizeSharedSecret(publicKey eccfrog512ck2.CurvePoint) ([]byte, error) {
+	secret, err := e.DeriveSharedSecret(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(make([]byte, SharedSecretSize-len(secret)), secret...), nil
+}
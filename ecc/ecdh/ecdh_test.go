@@ -1,6 +1,7 @@
 package ecdh_test
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/shovon/go-eccfrog512ck2/ecc"
@@ -47,3 +48,34 @@ func TestDeriveSharedSecret(t *testing.T) {
 		t.Error("DeriveSharedSecret returned empty shared secret with different public key")
 	}
 }
+
+func TestDeriveFixedSizeSharedSecret(t *testing.T) {
+	alicePrivateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPrivateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPublicKey, err := bobPrivateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := ecdh.ECDHPrivateKey(alicePrivateKey).DeriveSharedSecret(bobPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed, err := ecdh.ECDHPrivateKey(alicePrivateKey).DeriveFixedSizeSharedSecret(bobPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fixed) != ecdh.SharedSecretSize {
+		t.Errorf("expected %d bytes but got %d", ecdh.SharedSecretSize, len(fixed))
+	}
+	if new(big.Int).SetBytes(fixed).Cmp(new(big.Int).SetBytes(secret)) != 0 {
+		t.Error("fixed-size shared secret does not encode the same value")
+	}
+}
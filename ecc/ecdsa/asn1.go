@@ -0,0 +1,47 @@
+package ecdsa
+
+import (
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"github.com/shovon/go-eccfrog512ck2"
+)
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// SignASN1 signs the message and returns the signature encoded as a DER
+// ECDSA-Sig-Value, which is the encoding used by X.509, PKCS#10 and CMS.
+func (signParams Signer) SignASN1(message []byte) ([]byte, error) {
+	r, s, err := signParams.Sign(message)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ecdsaSignature{R: r, S: s})
+}
+
+// VerifyASN1 verifies a DER-encoded ECDSA-Sig-Value against the message.
+//
+// Unlike Verify, it rejects signatures whose r or s component lies outside
+// the range [1, n-1] instead of operating on them, which makes it safe to use
+// on signatures taken from untrusted input.
+func (params Verification) VerifyASN1(signature []byte, message []byte) (bool, error) {
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(signature, &sig)
+	if err != nil {
+		return false, err
+	}
+	if len(rest) != 0 {
+		return false, errors.New("trailing data after ECDSA signature")
+	}
+	if !inScalarRange(sig.R) || !inScalarRange(sig.S) {
+		return false, nil
+	}
+	return params.Verify([2]*big.Int{sig.R, sig.S}, message)
+}
+
+func inScalarRange(value *big.Int) bool {
+	return value.Sign() > 0 && value.Cmp(eccfrog512ck2.GeneratorOrder()) < 0
+}
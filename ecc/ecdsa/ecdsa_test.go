@@ -2,6 +2,7 @@ package ecdsa_test
 
 import (
 	"crypto/sha256"
+	"encoding/asn1"
 	"math/big"
 	"testing"
 
@@ -126,3 +127,55 @@ func TestSignWithDifferentKeys(t *testing.T) {
 		t.Error("Signature verification should fail for signature from different key")
 	}
 }
+
+func TestSignAndVerifyASN1(t *testing.T) {
+	privKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey, err := privKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("test message")
+
+	signature, err := ecdsa.NewSign(sha256.New, privKey).SignASN1(message)
+	if err != nil {
+		t.Fatalf("Failed to sign message: %v", err)
+	}
+
+	verification := ecdsa.NewVerification(sha256.New, pubKey)
+	valid, err := verification.VerifyASN1(signature, message)
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("Signature verification failed for valid signature")
+	}
+
+	valid, err = verification.VerifyASN1(signature, []byte("tampered message"))
+	if err != nil {
+		t.Error(err)
+	}
+	if valid {
+		t.Error("Signature verification should fail for tampered message")
+	}
+
+	// A zero s component must be rejected rather than panic.
+	zero, err := asn1.Marshal(struct{ R, S *big.Int }{big.NewInt(1), big.NewInt(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err = verification.VerifyASN1(zero, message)
+	if err != nil {
+		t.Error(err)
+	}
+	if valid {
+		t.Error("Signature verification should fail for out-of-range signature")
+	}
+
+	if _, err := verification.VerifyASN1(append(signature, 0x00), message); err == nil {
+		t.Error("Expected an error for trailing data")
+	}
+}
@@ -0,0 +1,171 @@
+package ecies
+
+import (
+	"encoding/binary"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+)
+
+// Authenticated ("authcrypt") encryption uses ECDH-1PU key agreement, and
+// binds the parties into the key derivation the way ECDH-1PU feeds party
+// info into its KDF. The binding is
+//
+//	label length (4 bytes, big-endian) || label
+//	|| rG (129 bytes) || sender public key (129 bytes)
+//	|| recipient public key (129 bytes)
+//	|| info length (4 bytes, big-endian) || info
+//	|| additional data length (4 bytes, big-endian) || additional data
+//
+// where the points are uncompressed SEC1. The secret handed to the
+// encryption function is Ze || Zs followed by the binding, and the binding
+// is its additional data. Ze and Zs are only x-coordinates, which the
+// negations of rG and of the sender's key share, so without the binding a
+// ciphertext could be attributed to either.
+
+const authcryptLabel = "EccFrog512ck2-ECIES-1PU-v1"
+
+// authcryptBinding returns the data that ties an authenticated ciphertext
+// to its ephemeral key, its sender, its recipient and the caller's info and
+// additional data.
+func authcryptBinding(rG, sender, recipient eccfrog512ck2.CurvePoint, info, additionalData []byte) []byte {
+	binding := binary.BigEndian.AppendUint32(nil, uint32(len(authcryptLabel)))
+	binding = append(binding, authcryptLabel...)
+	binding = append(binding, rG.MarshalSEC1(false)...)
+	binding = append(binding, sender.MarshalSEC1(false)...)
+	binding = append(binding, recipient.MarshalSEC1(false)...)
+	binding = binary.BigEndian.AppendUint32(binding, uint32(len(info)))
+	binding = append(binding, info...)
+	binding = binary.BigEndian.AppendUint32(binding, uint32(len(additionalData)))
+	binding = append(binding, additionalData...)
+	return binding
+}
+
+// authcryptSecret computes the ECDH-1PU (one-pass unified model) shared
+// secret Ze || Zs, where Ze is the ephemeral-static secret and Zs the
+// static-static secret between the sender and the recipient. Both are
+// fixed-size x-coordinates. Each exchange is given as our private key and
+// the peer's public key, which differ between the sender and the recipient.
+func authcryptSecret(
+	ephemeralStaticKey ecc.PrivateKey, ephemeralStaticPeer eccfrog512ck2.CurvePoint,
+	staticStaticKey ecc.PrivateKey, staticStaticPeer eccfrog512ck2.CurvePoint,
+) ([]byte, error) {
+	ze, err := ecdh.ECDHPrivateKey(ephemeralStaticKey).DeriveFixedSizeSharedSecret(ephemeralStaticPeer)
+	if err != nil {
+		return nil, err
+	}
+	zs, err := ecdh.ECDHPrivateKey(staticStaticKey).DeriveFixedSizeSharedSecret(staticStaticPeer)
+	if err != nil {
+		return nil, err
+	}
+	return append(ze, zs...), nil
+}
+
+// AuthEncrypt performs authenticated ("authcrypt") ECIES encryption from the
+// sender's private key to the recipient's public key, using ECDH-1PU key
+// agreement: the secret combines an ephemeral-static exchange with the
+// recipient and a static-static exchange between the sender and the
+// recipient. Only the holder of the sender's private key or of the
+// recipient's private key could have produced the ciphertext.
+//
+// As with Encrypt, the ephemeral key, both parties' public keys, info and
+// additionalData are bound into both the derived key and the
+// authentication tag.
+//
+// Returns:
+// - The ephemeral public key rG
+// - The encrypted ciphertext of type C
+// - Any error that occurred during encryption
+func (e ContextEncryptor[C]) AuthEncrypt(
+	senderPrivateKey ecc.PrivateKey,
+	recipientPublicKey eccfrog512ck2.CurvePoint,
+	message, info, additionalData []byte,
+) (eccfrog512ck2.CurvePoint, C, error) {
+	ephemeralKey, err := GenerateEphemeralKey(nil)
+	if err != nil {
+		var defaultC C
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	return e.AuthEncryptWithEphemeralKey(ephemeralKey, senderPrivateKey, recipientPublicKey, message, info, additionalData)
+}
+
+// AuthEncryptWithEphemeralKey is like AuthEncrypt, but uses ephemeralKey
+// instead of generating one.
+//
+// Returns:
+// - The ephemeral public key rG
+// - The encrypted ciphertext of type C
+// - Any error that occurred during encryption
+func (e ContextEncryptor[C]) AuthEncryptWithEphemeralKey(
+	ephemeralKey EphemeralKey,
+	senderPrivateKey ecc.PrivateKey,
+	recipientPublicKey eccfrog512ck2.CurvePoint,
+	message, info, additionalData []byte,
+) (eccfrog512ck2.CurvePoint, C, error) {
+	var defaultC C
+	if err := ephemeralKey.check(); err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	if err := checkPublicKey(recipientPublicKey); err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	senderPublicKey, err := senderPrivateKey.DerivePublicKey()
+	if err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	rG := ephemeralKey.publicKey
+	secret, err := authcryptSecret(ephemeralKey.privateKey, recipientPublicKey, senderPrivateKey, recipientPublicKey)
+	if err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+
+	binding := authcryptBinding(rG, senderPublicKey, recipientPublicKey, info, additionalData)
+	ciphertext, err := e(append(secret, binding...), message, binding)
+	if err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+
+	return rG, ciphertext, nil
+}
+
+// AuthDecrypt decrypts a ciphertext produced by AuthEncrypt. It fails if the
+// ciphertext was not encrypted by the holder of the private key for
+// senderPublicKey, or if rG, info or additionalData differ from those it was
+// encrypted with.
+//
+// Parameters:
+//   - recipientPrivateKey: The recipient's private key used for decryption
+//   - senderPublicKey: The public key of the expected sender
+//   - rG: The ephemeral public key generated during encryption
+//   - ciphertext: The encrypted message of type C to decrypt
+//   - info, additionalData: The values given to AuthEncrypt
+//
+// Returns:
+//   - The decrypted plaintext message bytes
+//   - Any error that occurred during decryption
+func (e ContextDecryptor[C]) AuthDecrypt(
+	recipientPrivateKey ecc.PrivateKey,
+	senderPublicKey eccfrog512ck2.CurvePoint,
+	rG eccfrog512ck2.CurvePoint,
+	ciphertext C,
+	info, additionalData []byte,
+) ([]byte, error) {
+	if err := checkPublicKey(senderPublicKey); err != nil {
+		return nil, err
+	}
+	if err := checkEphemeralKey(rG); err != nil {
+		return nil, err
+	}
+	recipientPublicKey, err := recipientPrivateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := authcryptSecret(recipientPrivateKey, rG, recipientPrivateKey, senderPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	binding := authcryptBinding(rG, senderPublicKey, recipientPublicKey, info, additionalData)
+	return e(append(secret, binding...), ciphertext, binding)
+}
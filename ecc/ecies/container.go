@@ -0,0 +1,353 @@
+package ecies
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/hybrid"
+)
+
+// A Container is a message encrypted in one piece to a single recipient,
+// together with everything needed to decrypt it but the private key. It is
+// laid out as:
+//
+//	magic (8 bytes) || version (1 byte)
+//	KEM ID (2 bytes) || KDF ID (2 bytes) || AEAD ID (2 bytes)
+//	encapsulation length (2 bytes) || encapsulation
+//	nonce length (1 byte) || nonce
+//	ciphertext length (4 bytes) || ciphertext
+//
+// where integers are big-endian. Every length must match what the
+// identifiers call for, and the ciphertext must end the data. The first 15
+// bytes, up to and including the AEAD ID, are the additional data of the
+// AEAD, so the identifiers cannot be swapped without failing
+// authentication. An AEAD ID with cryptohelpers.CipherCommitting set names
+// the committing variant of the suite, and Open then checks the key
+// commitment before decrypting.
+//
+// With KEMEccFrog512ck2, the encapsulation is the ephemeral key rG as a
+// compressed SEC1 point, and the message is encrypted as by
+// ContextEncryptor. With KEMEccFrog512ck2MLKEM1024, it is the ciphertext of
+// the hybrid KEM, whose shared key is the secret handed to the KDF.
+
+// ContainerMagic starts every marshaled Container.
+const ContainerMagic = "EFROGMSG"
+
+// ContainerVersion is the version of the Container format written by
+// MarshalBinary.
+const ContainerVersion = 1
+
+const (
+	containerHeaderSize = len(ContainerMagic) + 1 + 3*2
+	containerPointSize  = 1 + 64
+)
+
+// KEMID identifies the key encapsulation mechanism of a Container.
+type KEMID uint16
+
+const (
+	// KEMEccFrog512ck2 is ECDH with an ephemeral EccFrog512ck2 key.
+	KEMEccFrog512ck2 KEMID = 1
+	// KEMEccFrog512ck2MLKEM1024 is the hybrid KEM of package hybrid.
+	KEMEccFrog512ck2MLKEM1024 KEMID = 2
+)
+
+func (k KEMID) encapsulationSize() (int, error) {
+	switch k {
+	case KEMEccFrog512ck2:
+		return containerPointSize, nil
+	case KEMEccFrog512ck2MLKEM1024:
+		return hybrid.CiphertextSize, nil
+	}
+	return 0, fmt.Errorf("ecies: unknown KEM %d", uint16(k))
+}
+
+// KDFID identifies how a Container derives the AEAD key from the KEM's
+// shared secret.
+type KDFID uint16
+
+const (
+	// KDFHKDFSHA256 is HKDF256 with SHA-256.
+	KDFHKDFSHA256 KDFID = 1
+	// KDFHKDFSHA512 is HKDF256 with SHA-512.
+	KDFHKDFSHA512 KDFID = 2
+	// KDFX963SHA512 is the ANSI X9.63 KDF with SHA-512.
+	KDFX963SHA512 KDFID = 3
+)
+
+func (k KDFID) kdf() (func(cryptohelpers.SecretKey) ([32]byte, error), error) {
+	switch k {
+	case KDFHKDFSHA256:
+		return cryptohelpers.HKDF256(sha256.New), nil
+	case KDFHKDFSHA512:
+		return cryptohelpers.HKDF256(sha512.New), nil
+	case KDFX963SHA512:
+		return cryptohelpers.X963KDF256(sha512.New, nil), nil
+	}
+	return nil, fmt.Errorf("ecies: unknown KDF %d", uint16(k))
+}
+
+// Container is a self-describing ciphertext. See SealContainer.
+type Container struct {
+	KEM  KEMID
+	KDF  KDFID
+	AEAD cryptohelpers.CipherSuite
+
+	Encapsulation []byte
+	Nonce         []byte
+	Ciphertext    []byte
+}
+
+// Envelope is another name for Container, the self-describing ciphertext
+// the CLI writes by default. It is unrelated to the multi-recipient
+// envelopes of NewEnvelopeWriter, which start with EnvelopeMagic rather than
+// ContainerMagic.
+type Envelope = Container
+
+// header returns the part of the marshaled container that is authenticated
+// as additional data.
+func (c *Container) header() []byte {
+	header := make([]byte, 0, containerHeaderSize)
+	header = append(header, ContainerMagic...)
+	header = append(header, ContainerVersion)
+	header = binary.BigEndian.AppendUint16(header, uint16(c.KEM))
+	header = binary.BigEndian.AppendUint16(header, uint16(c.KDF))
+	return binary.BigEndian.AppendUint16(header, uint16(c.AEAD))
+}
+
+// check reports whether the algorithms are known and the fields have the
+// lengths they call for.
+func (c *Container) check() error {
+	encapsulationSize, err := c.KEM.encapsulationSize()
+	if err != nil {
+		return err
+	}
+	if _, err := c.KDF.kdf(); err != nil {
+		return err
+	}
+	aead, err := c.AEAD.NewAEAD(make([]byte, 32))
+	if err != nil {
+		return err
+	}
+	if len(c.Encapsulation) != encapsulationSize {
+		return errors.New("ecies: invalid encapsulation length")
+	}
+	if len(c.Nonce) != aead.NonceSize() {
+		return errors.New("ecies: invalid nonce length")
+	}
+	if len(c.Ciphertext) < aead.Overhead() || uint64(len(c.Ciphertext)) > 1<<32-1 {
+		return errors.New("ecies: invalid ciphertext length")
+	}
+	return nil
+}
+
+// MarshalBinary encodes the container.
+func (c *Container) MarshalBinary() ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	out := c.header()
+	out = binary.BigEndian.AppendUint16(out, uint16(len(c.Encapsulation)))
+	out = append(out, c.Encapsulation...)
+	out = append(out, byte(len(c.Nonce)))
+	out = append(out, c.Nonce...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(c.Ciphertext)))
+	return append(out, c.Ciphertext...), nil
+}
+
+// UnmarshalBinary decodes a container encoded by MarshalBinary. It rejects
+// other versions, unknown algorithms, lengths that do not match the
+// algorithms and trailing data.
+func (c *Container) UnmarshalBinary(data []byte) error {
+	if len(data) < containerHeaderSize || string(data[:len(ContainerMagic)]) != ContainerMagic {
+		return errors.New("ecies: not a container")
+	}
+	if version := data[len(ContainerMagic)]; version != ContainerVersion {
+		return fmt.Errorf("ecies: unsupported container version %d", version)
+	}
+	ids := data[len(ContainerMagic)+1 : containerHeaderSize]
+	data = data[containerHeaderSize:]
+
+	next := func(n int) ([]byte, error) {
+		if len(data) < n {
+			return nil, errors.New("ecies: truncated container")
+		}
+		b := data[:n]
+		data = data[n:]
+		return b, nil
+	}
+	b, err := next(2)
+	if err != nil {
+		return err
+	}
+	encapsulation, err := next(int(binary.BigEndian.Uint16(b)))
+	if err != nil {
+		return err
+	}
+	if b, err = next(1); err != nil {
+		return err
+	}
+	nonce, err := next(int(b[0]))
+	if err != nil {
+		return err
+	}
+	if b, err = next(4); err != nil {
+		return err
+	}
+	if uint64(len(data)) != uint64(binary.BigEndian.Uint32(b)) {
+		return errors.New("ecies: invalid container ciphertext length")
+	}
+
+	container := Container{
+		KEM:           KEMID(binary.BigEndian.Uint16(ids[0:2])),
+		KDF:           KDFID(binary.BigEndian.Uint16(ids[2:4])),
+		Encapsulation: append([]byte{}, encapsulation...),
+		Nonce:         append([]byte{}, nonce...),
+		Ciphertext:    append([]byte{}, data...),
+	}
+	aead := binary.BigEndian.Uint16(ids[4:6])
+	if aead > 0xFF {
+		return fmt.Errorf("ecies: unknown AEAD %d", aead)
+	}
+	container.AEAD = cryptohelpers.CipherSuite(aead)
+	if err := container.check(); err != nil {
+		return err
+	}
+	*c = container
+	return nil
+}
+
+// newContainer returns a container for the given algorithms, where zero
+// values select HKDF-SHA256 and AES-256-GCM, along with the KDF.
+func newContainer(
+	kem KEMID, kdf KDFID, aead cryptohelpers.CipherSuite,
+) (*Container, func(cryptohelpers.SecretKey) ([32]byte, error), error) {
+	if kdf == 0 {
+		kdf = KDFHKDFSHA256
+	}
+	if aead == 0 {
+		aead = cryptohelpers.CipherAES256GCM
+	}
+	f, err := kdf.kdf()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := aead.NewAEAD(make([]byte, 32)); err != nil {
+		return nil, nil, err
+	}
+	return &Container{KEM: kem, KDF: kdf, AEAD: aead}, f, nil
+}
+
+// SealContainer encrypts plaintext to publicKey with an ephemeral
+// EccFrog512ck2 key. kdf and aead pick the algorithms; zero values select
+// HKDF-SHA256 and AES-256-GCM.
+func SealContainer(
+	publicKey eccfrog512ck2.CurvePoint,
+	plaintext []byte,
+	kdf KDFID,
+	aead cryptohelpers.CipherSuite,
+) (*Container, error) {
+	ephemeralKey, err := GenerateEphemeralKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	return SealContainerWithEphemeralKey(ephemeralKey, publicKey, plaintext, kdf, aead)
+}
+
+// SealContainerWithEphemeralKey is like SealContainer, but uses
+// ephemeralKey instead of generating one. The nonce is still random.
+func SealContainerWithEphemeralKey(
+	ephemeralKey EphemeralKey,
+	publicKey eccfrog512ck2.CurvePoint,
+	plaintext []byte,
+	kdf KDFID,
+	aead cryptohelpers.CipherSuite,
+) (*Container, error) {
+	c, f, err := newContainer(KEMEccFrog512ck2, kdf, aead)
+	if err != nil {
+		return nil, err
+	}
+	rG, result, err := NewContextEncryptor(c.AEAD.EncryptWithAAD(f)).
+		EncryptWithEphemeralKey(ephemeralKey, publicKey, plaintext, nil, c.header())
+	if err != nil {
+		return nil, err
+	}
+	c.Encapsulation = rG.MarshalSEC1(true)
+	c.Nonce = result.Nonce
+	c.Ciphertext = result.CipherText
+	return c, nil
+}
+
+// SealHybridContainer is like SealContainer, but encapsulates the key to a
+// hybrid EccFrog512ck2 and ML-KEM-1024 public key. It has no
+// WithEphemeralKey variant, because hybrid.PublicKey.Encapsulate draws its
+// own randomness for both halves.
+func SealHybridContainer(
+	publicKey *hybrid.PublicKey,
+	plaintext []byte,
+	kdf KDFID,
+	aead cryptohelpers.CipherSuite,
+) (*Container, error) {
+	c, f, err := newContainer(KEMEccFrog512ck2MLKEM1024, kdf, aead)
+	if err != nil {
+		return nil, err
+	}
+	sharedKey, kemCiphertext, err := publicKey.Encapsulate()
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.AEAD.EncryptWithAAD(f)(sharedKey, plaintext, c.header())
+	if err != nil {
+		return nil, err
+	}
+	c.Encapsulation = kemCiphertext
+	c.Nonce = result.Nonce
+	c.Ciphertext = result.CipherText
+	return c, nil
+}
+
+// Open decrypts a container sealed by SealContainer.
+func (c *Container) Open(privateKey ecc.PrivateKey) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	if c.KEM != KEMEccFrog512ck2 {
+		return nil, errors.New("ecies: container was not sealed to an EccFrog512ck2 key")
+	}
+	rG, err := parseEphemeralKey(c.Encapsulation)
+	if err != nil {
+		return nil, err
+	}
+	f, _ := c.KDF.kdf()
+	return NewContextDecryptor(c.AEAD.DecryptWithAAD(f)).Decrypt(
+		privateKey, rG,
+		cryptohelpers.AEADResults{CipherText: c.Ciphertext, Nonce: c.Nonce},
+		nil, c.header(),
+	)
+}
+
+// OpenHybrid decrypts a container sealed by SealHybridContainer.
+func (c *Container) OpenHybrid(privateKey *hybrid.PrivateKey) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	if c.KEM != KEMEccFrog512ck2MLKEM1024 {
+		return nil, errors.New("ecies: container was not sealed to a hybrid key")
+	}
+	sharedKey, err := privateKey.Decapsulate(c.Encapsulation)
+	if err != nil {
+		return nil, err
+	}
+	f, _ := c.KDF.kdf()
+	return c.AEAD.DecryptWithAAD(f)(
+		sharedKey,
+		cryptohelpers.AEADResults{CipherText: c.Ciphertext, Nonce: c.Nonce},
+		c.header(),
+	)
+}
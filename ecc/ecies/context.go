@@ -0,0 +1,178 @@
+package ecies
+
+import (
+	"encoding/binary"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+)
+
+// Encryptor.Encrypt derives the key from the shared x-coordinate alone and
+// authenticates no additional data, so nothing ties a ciphertext to the
+// ephemeral key it was sent with, to its recipient or to the purpose it was
+// encrypted for. ContextEncryptor binds all of these into both the key
+// derivation and the AEAD. Both see the same binding:
+//
+//	label || rG (129 bytes) || recipient public key (129 bytes)
+//	|| info length (4 bytes, big-endian) || info
+//	|| additional data length (4 bytes, big-endian) || additional data
+//
+// where the points are uncompressed SEC1. The secret handed to the
+// encryption function is the fixed-size shared x-coordinate followed by the
+// binding, and the binding is its additional data.
+
+const contextLabel = "EccFrog512ck2-ECIES-v2"
+
+// contextBinding returns the data that ties a ciphertext to its ephemeral
+// key, its recipient and the caller's info and additional data.
+func contextBinding(rG, recipient eccfrog512ck2.CurvePoint, info, additionalData []byte) []byte {
+	binding := []byte(contextLabel)
+	binding = append(binding, rG.MarshalSEC1(false)...)
+	binding = append(binding, recipient.MarshalSEC1(false)...)
+	binding = binary.BigEndian.AppendUint32(binding, uint32(len(info)))
+	binding = append(binding, info...)
+	binding = binary.BigEndian.AppendUint32(binding, uint32(len(additionalData)))
+	binding = append(binding, additionalData...)
+	return binding
+}
+
+// ContextEncryptor is like Encryptor, but its function also takes the
+// additional data to authenticate, as returned by AES256GCMEncryptWithAAD
+// or CipherSuite.EncryptWithAAD.
+type ContextEncryptor[C any] func(cryptohelpers.SecretKey, []byte, []byte) (C, error)
+
+// NewContextEncryptor creates a ContextEncryptor that wraps the provided
+// encryption function, which takes a secret key, plaintext and additional
+// data.
+func NewContextEncryptor[C any](e func(cryptohelpers.SecretKey, []byte, []byte) (C, error)) ContextEncryptor[C] {
+	return e
+}
+
+// Encrypt performs ECIES encryption of message to publicKey. The ephemeral
+// key, the recipient's public key, info and additionalData are bound into
+// both the derived key and the authentication tag, so decryption fails
+// unless the same values are given to Decrypt.
+//
+// info describes the context the message is encrypted for, such as a
+// protocol name and version. additionalData is authenticated but not
+// encrypted, such as a header sent alongside the ciphertext. Either may be
+// nil.
+//
+// Returns:
+// - The ephemeral public key rG
+// - The encrypted ciphertext of type C
+// - Any error that occurred during encryption
+func (e ContextEncryptor[C]) Encrypt(
+	publicKey eccfrog512ck2.CurvePoint,
+	message, info, additionalData []byte,
+) (eccfrog512ck2.CurvePoint, C, error) {
+	ephemeralKey, err := GenerateEphemeralKey(nil)
+	if err != nil {
+		var defaultC C
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	return e.EncryptWithEphemeralKey(ephemeralKey, publicKey, message, info, additionalData)
+}
+
+// EncryptWithEphemeralKey is like Encrypt, but uses ephemeralKey instead of
+// generating one.
+//
+// Returns:
+// - The ephemeral public key rG
+// - The encrypted ciphertext of type C
+// - Any error that occurred during encryption
+func (e ContextEncryptor[C]) EncryptWithEphemeralKey(
+	ephemeralKey EphemeralKey,
+	publicKey eccfrog512ck2.CurvePoint,
+	message, info, additionalData []byte,
+) (eccfrog512ck2.CurvePoint, C, error) {
+	var defaultC C
+	if err := ephemeralKey.check(); err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	if err := checkPublicKey(publicKey); err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	rG := ephemeralKey.publicKey
+	secret, err := ecdh.ECDHPrivateKey(ephemeralKey.privateKey).DeriveFixedSizeSharedSecret(publicKey)
+	if err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+
+	binding := contextBinding(rG, publicKey, info, additionalData)
+	ciphertext, err := e(append(secret, binding...), message, binding)
+	if err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+
+	return rG, ciphertext, nil
+}
+
+// ContextDecryptor is like Decryptor, but its function also takes the
+// additional data to check, as returned by AES256GCMDecryptWithAAD or
+// CipherSuite.DecryptWithAAD.
+type ContextDecryptor[C any] func(cryptohelpers.SecretKey, C, []byte) ([]byte, error)
+
+// NewContextDecryptor creates a ContextDecryptor that wraps the provided
+// decryption function, which takes a secret key, ciphertext and additional
+// data.
+func NewContextDecryptor[C any](e func(cryptohelpers.SecretKey, C, []byte) ([]byte, error)) ContextDecryptor[C] {
+	return e
+}
+
+// Decrypt decrypts a ciphertext produced by ContextEncryptor.Encrypt. info
+// and additionalData must match those given to Encrypt.
+//
+// Parameters:
+//   - privateKey: The recipient's private key used for decryption
+//   - rG: The ephemeral public key generated during encryption
+//   - ciphertext: The encrypted message of type C to decrypt
+//   - info, additionalData: The context the message was encrypted with
+//
+// Returns:
+//   - The decrypted plaintext message bytes
+//   - Any error that occurred during decryption
+func (e ContextDecryptor[C]) Decrypt(
+	privateKey ecc.PrivateKey,
+	rG eccfrog512ck2.CurvePoint,
+	ciphertext C,
+	info, additionalData []byte,
+) ([]byte, error) {
+	if err := checkEphemeralKey(rG); err != nil {
+		return nil, err
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ecdh.ECDHPrivateKey(privateKey).DeriveFixedSizeSharedSecret(rG)
+	if err != nil {
+		return nil, err
+	}
+
+	binding := contextBinding(rG, publicKey, info, additionalData)
+	return e(append(secret, binding...), ciphertext, binding)
+}
+
+// DecryptLegacy decrypts a ciphertext produced by Encryptor.Encrypt, which
+// binds no context. It exists for compatibility with ciphertexts written
+// before ContextEncryptor, and should not be used for new ones, since it
+// accepts a ciphertext under any ephemeral key that yields the same shared
+// secret and for any purpose.
+func (e ContextDecryptor[C]) DecryptLegacy(
+	privateKey ecc.PrivateKey,
+	rG eccfrog512ck2.CurvePoint,
+	ciphertext C,
+) ([]byte, error) {
+	if err := checkEphemeralKey(rG); err != nil {
+		return nil, err
+	}
+	secret, err := ecdh.ECDHPrivateKey(privateKey).DeriveSharedSecret(rG)
+	if err != nil {
+		return nil, err
+	}
+
+	return e(secret, ciphertext, nil)
+}
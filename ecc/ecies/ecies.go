@@ -1,7 +1,7 @@
 package ecies
 
 import (
-	"crypto/rand"
+	"errors"
 	"math/big"
 
 	"github.com/shovon/go-eccfrog512ck2"
@@ -29,27 +29,68 @@ func NewEncryptor[C any](e func(cryptohelpers.SecretKey, []byte) (C, error)) Enc
 	return e
 }
 
-// Encrypt performs ECIES encryption using the provided private key, public key
-// and message. It returns an ephemeral public key and encrypted ciphertext.
+// Encrypt performs ECIES encryption of message to publicKey, and returns
+// an ephemeral public key and the encrypted ciphertext.
+//
+// Deprecated: privateKey is not used; the ciphertext is anonymous. Use
+// EncryptAnonymous, or ContextEncryptor.AuthEncrypt to authenticate the
+// sender.
+func (e Encryptor[C]) Encrypt(
+	privateKey ecc.PrivateKey,
+	publicKey eccfrog512ck2.CurvePoint,
+	message []byte,
+) (eccfrog512ck2.CurvePoint, C, error) {
+	return e.EncryptAnonymous(publicKey, message)
+}
+
+// EncryptAnonymous performs ECIES encryption of message to publicKey with a
+// fresh ephemeral key. Nothing identifies the sender; use
+// ContextEncryptor.AuthEncrypt to authenticate them.
+//
+// The key is derived from the shared x-coordinate alone. New code should use
+// ContextEncryptor, which also binds the ephemeral key, the recipient's
+// public key and caller-supplied context.
 //
 // Returns:
 // - The ephemeral public key rG
 // - The encrypted ciphertext of type C
 // - Any error that occurred during encryption
-func (e Encryptor[C]) Encrypt(
-	privateKey ecc.PrivateKey,
+func (e Encryptor[C]) EncryptAnonymous(
 	publicKey eccfrog512ck2.CurvePoint,
 	message []byte,
 ) (eccfrog512ck2.CurvePoint, C, error) {
-	var defaultC C
-	r, err := rand.Int(rand.Reader, eccfrog512ck2.GeneratorOrder())
+	ephemeralKey, err := GenerateEphemeralKey(nil)
 	if err != nil {
+		var defaultC C
 		return eccfrog512ck2.PointAtInfinity(), defaultC, err
 	}
-	rG := eccfrog512ck2.Generator().Multiply(r)
+	return e.EncryptWithEphemeralKey(ephemeralKey, publicKey, message)
+}
 
-	s := publicKey.Multiply(r)
-	secret, _, _ := s.CoordinateIfNotInfinity()
+// EncryptWithEphemeralKey is like EncryptAnonymous, but uses ephemeralKey
+// instead of generating one.
+//
+// Returns:
+// - The ephemeral public key rG
+// - The encrypted ciphertext of type C
+// - Any error that occurred during encryption
+func (e Encryptor[C]) EncryptWithEphemeralKey(
+	ephemeralKey EphemeralKey,
+	publicKey eccfrog512ck2.CurvePoint,
+	message []byte,
+) (eccfrog512ck2.CurvePoint, C, error) {
+	var defaultC C
+	if err := ephemeralKey.check(); err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	if err := checkPublicKey(publicKey); err != nil {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, err
+	}
+	s := publicKey.Multiply(ephemeralKey.privateKey.GetKey())
+	secret, _, ok := s.CoordinateIfNotInfinity()
+	if !ok {
+		return eccfrog512ck2.PointAtInfinity(), defaultC, errors.New("ecies: shared secret is the point at infinity")
+	}
 	secretCopy := (&big.Int{}).Set(secret).Bytes()
 
 	ciphertext, err := e(secretCopy, message)
@@ -57,7 +98,7 @@ func (e Encryptor[C]) Encrypt(
 		return eccfrog512ck2.PointAtInfinity(), defaultC, err
 	}
 
-	return rG, ciphertext, nil
+	return ephemeralKey.publicKey, ciphertext, nil
 }
 
 // Decryptor is a generic function type that takes a secret key and ciphertext
@@ -90,14 +131,21 @@ func NewDecryptor[C any](e func(cryptohelpers.SecretKey, C) ([]byte, error)) Dec
 //
 // Returns:
 //   - The decrypted plaintext message bytes
-//   - Any error that occurred during decryption
+//   - ErrInvalidEphemeralKey if rG is not a valid public key, or any other
+//     error that occurred during decryption
 func (e Decryptor[C]) Decrypt(
 	privateKey ecc.PrivateKey,
 	rG eccfrog512ck2.CurvePoint,
 	ciphertext C,
 ) ([]byte, error) {
+	if err := checkEphemeralKey(rG); err != nil {
+		return nil, err
+	}
 	s := rG.Multiply(privateKey.GetKey())
-	secret, _, _ := s.CoordinateIfNotInfinity()
+	secret, _, ok := s.CoordinateIfNotInfinity()
+	if !ok {
+		return nil, errors.New("ecies: shared secret is the point at infinity")
+	}
 	secretCopy := (&big.Int{}).Set(secret).Bytes()
 
 	plaintext, err := e(secretCopy, ciphertext)
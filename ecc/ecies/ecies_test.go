@@ -1,12 +1,21 @@
 package ecies_test
 
 import (
+	"bytes"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
 	"testing"
+	"testing/iotest"
 
+	"github.com/shovon/go-eccfrog512ck2"
 	"github.com/shovon/go-eccfrog512ck2/ecc"
 	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
 	"github.com/shovon/go-eccfrog512ck2/ecc/ecies"
+	"github.com/shovon/go-eccfrog512ck2/ecc/hybrid"
 )
 
 func TestEncryptDecrypt(t *testing.T) {
@@ -52,3 +61,1047 @@ func TestEncryptDecrypt(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestEncryptDecryptXChaCha20Poly1305(t *testing.T) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	ephemeralKey, _ := ecc.GeneratePrivateKey()
+	kdf := cryptohelpers.HKDF256(sha256.New)
+
+	rG, result, err := ecies.
+		NewEncryptor(cryptohelpers.XChaCha20Poly1305Encrypt(kdf)).
+		Encrypt(ephemeralKey, publicKey, []byte("Hello, World!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := ecies.
+		NewDecryptor(cryptohelpers.XChaCha20Poly1305Decrypt(kdf)).
+		Decrypt(privateKey, rG, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "Hello, World!" {
+		t.Errorf("expected %q but got %q", "Hello, World!", plaintext)
+	}
+}
+
+func TestAuthEncryptDecrypt(t *testing.T) {
+	message := []byte("Hello, Bob! -- Alice")
+
+	alicePrivateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alicePublicKey, err := alicePrivateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPrivateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPublicKey, err := bobPrivateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	malloryPrivateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	malloryPublicKey, err := malloryPrivateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	encryptor := ecies.NewContextEncryptor(cryptohelpers.AES256GCMEncryptWithAAD(kdf))
+	decryptor := ecies.NewContextDecryptor(cryptohelpers.AES256GCMDecryptWithAAD(kdf))
+	info := []byte("test v1")
+
+	rG, result, err := encryptor.AuthEncrypt(alicePrivateKey, bobPublicKey, message, info, nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	plaintext, err := decryptor.AuthDecrypt(bobPrivateKey, alicePublicKey, rG, result, info, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if string(plaintext) != string(message) {
+		t.Errorf("expected %q but got %q", message, plaintext)
+	}
+
+	if _, err := decryptor.AuthDecrypt(bobPrivateKey, malloryPublicKey, rG, result, info, nil); err == nil {
+		t.Error("Expected error for wrong sender")
+	}
+	if _, err := decryptor.AuthDecrypt(bobPrivateKey, alicePublicKey, rG, result, []byte("test v2"), nil); err == nil {
+		t.Error("Expected error for different info")
+	}
+	if _, err := decryptor.AuthDecrypt(bobPrivateKey, alicePublicKey, rG, result, info, []byte("header")); err == nil {
+		t.Error("Expected error for different additional data")
+	}
+	if _, err := decryptor.Decrypt(bobPrivateKey, rG, result, info, nil); err == nil {
+		t.Error("Expected error for anonymous decryption of an authenticated ciphertext")
+	}
+
+	// The negations of rG and of Alice's key give the same Ze and Zs, but
+	// not the same binding
+	minusOne := new(big.Int).Sub(eccfrog512ck2.GeneratorOrder(), big.NewInt(1))
+	negatedRG := rG.Multiply(minusOne)
+	if _, err := decryptor.AuthDecrypt(bobPrivateKey, alicePublicKey, negatedRG, result, info, nil); err == nil {
+		t.Error("Expected error for a swapped ephemeral key")
+	}
+	negatedAlice := alicePublicKey.Multiply(minusOne)
+	if _, err := decryptor.AuthDecrypt(bobPrivateKey, negatedAlice, rG, result, info, nil); err == nil {
+		t.Error("Expected error for a sender with the same static-static secret")
+	}
+
+	// Mallory cannot produce a ciphertext that appears to come from Alice
+	rG, result, err = encryptor.AuthEncrypt(malloryPrivateKey, bobPublicKey, message, info, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptor.AuthDecrypt(bobPrivateKey, alicePublicKey, rG, result, info, nil); err == nil {
+		t.Error("Expected error for forged sender")
+	}
+}
+
+func encryptStream(t *testing.T, publicKey eccfrog512ck2.CurvePoint, message []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := ecies.NewStreamWriter(&buf, publicKey, cryptohelpers.HKDF256(sha256.New))
+	if err != nil {
+		t.Fatalf("Failed to create stream writer: %v", err)
+	}
+	// Write in odd-sized pieces so that writes straddle chunk boundaries
+	for len(message) > 0 {
+		n := min(1000, len(message))
+		if _, err := w.Write(message[:n]); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		message = message[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decryptStream(privateKey ecc.PrivateKey, ciphertext []byte) ([]byte, error) {
+	r, err := ecies.NewStreamReader(bytes.NewReader(ciphertext), privateKey, cryptohelpers.HKDF256(sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, _ := privateKey.DerivePublicKey()
+
+	for _, size := range []int{0, 1, ecies.StreamChunkSize - 1, ecies.StreamChunkSize, ecies.StreamChunkSize + 1, 3*ecies.StreamChunkSize + 17} {
+		message := make([]byte, size)
+		rand.Read(message)
+
+		ciphertext := encryptStream(t, publicKey, message)
+		plaintext, err := decryptStream(privateKey, ciphertext)
+		if err != nil {
+			t.Fatalf("Size %d: failed to decrypt: %v", size, err)
+		}
+		if !bytes.Equal(plaintext, message) {
+			t.Errorf("Size %d: plaintext does not match", size)
+		}
+	}
+}
+
+func TestStreamTampering(t *testing.T) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	message := make([]byte, 3*ecies.StreamChunkSize+100)
+	rand.Read(message)
+	ciphertext := encryptStream(t, publicKey, message)
+
+	const header = 129
+	const chunk = ecies.StreamChunkSize + 16
+	cases := map[string][]byte{
+		// Dropping whole trailing chunks must not look like a shorter message
+		"truncated at chunk boundary": ciphertext[:header+2*chunk],
+		"truncated mid chunk":         ciphertext[:header+chunk+100],
+		"trailing data":               append(append([]byte{}, ciphertext...), 0),
+		"reordered chunks": func() []byte {
+			c := append([]byte{}, ciphertext...)
+			copy(c[header:], ciphertext[header+chunk:header+2*chunk])
+			copy(c[header+chunk:], ciphertext[header:header+chunk])
+			return c
+		}(),
+		"flipped bit": func() []byte {
+			c := append([]byte{}, ciphertext...)
+			c[header+chunk+5] ^= 1
+			return c
+		}(),
+	}
+	for name, tampered := range cases {
+		if _, err := decryptStream(privateKey, tampered); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	otherKey, _ := ecc.GeneratePrivateKey()
+	if _, err := decryptStream(otherKey, ciphertext); err == nil {
+		t.Error("Expected error for wrong private key")
+	}
+	if _, err := decryptStream(privateKey, ciphertext[:50]); err == nil {
+		t.Error("Expected error for truncated header")
+	}
+}
+
+func sealEnvelope(t *testing.T, recipients []eccfrog512ck2.CurvePoint, opts *ecies.EnvelopeOptions, message []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := ecies.NewEnvelopeWriter(&buf, recipients, opts)
+	if err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func openEnvelope(privateKey ecc.PrivateKey, envelope []byte) ([]byte, error) {
+	r, err := ecies.NewEnvelopeReader(bytes.NewReader(envelope), privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEnvelope(t *testing.T) {
+	var privateKeys []ecc.PrivateKey
+	var publicKeys []eccfrog512ck2.CurvePoint
+	for i := 0; i < 5; i++ {
+		privateKey, _ := ecc.GeneratePrivateKey()
+		publicKey, _ := privateKey.DerivePublicKey()
+		privateKeys = append(privateKeys, privateKey)
+		publicKeys = append(publicKeys, publicKey)
+	}
+	outsider, _ := ecc.GeneratePrivateKey()
+	message := make([]byte, ecies.StreamChunkSize+500)
+	rand.Read(message)
+
+	for _, opts := range []*ecies.EnvelopeOptions{
+		nil,
+		{SharedEphemeralKey: true},
+		{Hints: true},
+		{SharedEphemeralKey: true, Hints: true},
+		{Cipher: cryptohelpers.CipherChaCha20Poly1305},
+		{Cipher: cryptohelpers.CipherXChaCha20Poly1305, Hints: true},
+		{Cipher: cryptohelpers.CipherAES256GCMSIV},
+		{Cipher: cryptohelpers.CipherAES256GCM.WithCommitment()},
+	} {
+		envelope := sealEnvelope(t, publicKeys, opts, message)
+		if !bytes.HasPrefix(envelope, []byte(ecies.EnvelopeMagic)) {
+			t.Fatalf("%+v: envelope does not start with the magic", opts)
+		}
+		for i, privateKey := range privateKeys {
+			plaintext, err := openEnvelope(privateKey, envelope)
+			if err != nil {
+				t.Fatalf("%+v: recipient %d failed to open: %v", opts, i, err)
+			}
+			if !bytes.Equal(plaintext, message) {
+				t.Errorf("%+v: recipient %d got the wrong plaintext", opts, i)
+			}
+		}
+		if _, err := openEnvelope(outsider, envelope); err == nil {
+			t.Errorf("%+v: expected error for a non-recipient", opts)
+		}
+	}
+}
+
+func TestEnvelopeTampering(t *testing.T) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	otherKey, _ := ecc.GeneratePrivateKey()
+	otherPublicKey, _ := otherKey.DerivePublicKey()
+	recipients := []eccfrog512ck2.CurvePoint{publicKey, otherPublicKey}
+	envelope := sealEnvelope(t, recipients, &ecies.EnvelopeOptions{SharedEphemeralKey: true}, []byte("shared secret"))
+
+	// The header is magic, version, IDs, flags, rG, count and two 40-byte
+	// entries
+	const prefixSize = 8 + 1 + 3*2 + 1
+	const headerSize = prefixSize + 129 + 2 + 2*40
+	if envelope[8] != ecies.EnvelopeVersion {
+		t.Fatal("Expected a versioned header")
+	}
+	if kem := binary.BigEndian.Uint16(envelope[9:]); kem != uint16(ecies.KEMEccFrog512ck2) {
+		t.Errorf("Expected KEM %d but got %d", ecies.KEMEccFrog512ck2, kem)
+	}
+	if kdf := binary.BigEndian.Uint16(envelope[11:]); kdf != uint16(ecies.KDFHKDFSHA256) {
+		t.Errorf("Expected KDF %d but got %d", ecies.KDFHKDFSHA256, kdf)
+	}
+	for i, b := range map[int]byte{8: 2, 10: 2, 12: 2, 15: envelope[15] | 1<<7} {
+		tampered := append([]byte{}, envelope...)
+		tampered[i] = b
+		if _, err := openEnvelope(privateKey, tampered); err == nil {
+			t.Errorf("Expected error for byte %d set to %#x", i, b)
+		}
+	}
+
+	// Changing the other recipient's entry changes the payload key
+	tampered := append([]byte{}, envelope...)
+	tampered[headerSize-1] ^= 1
+	if _, err := openEnvelope(privateKey, tampered); err == nil {
+		t.Error("Expected error for tampered header")
+	}
+
+	// Dropping the other recipient's entry is detected
+	dropped := append([]byte{}, envelope[:headerSize-40]...)
+	dropped[prefixSize+129+1] = 1
+	dropped = append(dropped, envelope[headerSize:]...)
+	if _, err := openEnvelope(privateKey, dropped); err == nil {
+		t.Error("Expected error for dropped recipient")
+	}
+
+	tampered = append([]byte{}, envelope...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := openEnvelope(privateKey, tampered); err == nil {
+		t.Error("Expected error for tampered payload")
+	}
+	if _, err := openEnvelope(privateKey, envelope[:headerSize-1]); err == nil {
+		t.Error("Expected error for truncated header")
+	}
+
+	// Switching the cipher suite changes the payload key
+	chacha := sealEnvelope(t, recipients, &ecies.EnvelopeOptions{Cipher: cryptohelpers.CipherChaCha20Poly1305}, []byte("shared secret"))
+	if binary.BigEndian.Uint16(chacha[13:]) != uint16(cryptohelpers.CipherChaCha20Poly1305) {
+		t.Fatal("Expected the cipher suite in the header")
+	}
+	chacha[14] = byte(cryptohelpers.CipherXChaCha20Poly1305)
+	if _, err := openEnvelope(privateKey, chacha); err == nil {
+		t.Error("Expected error for a switched cipher suite")
+	}
+	chacha[14] = 0xFF
+	if _, err := openEnvelope(privateKey, chacha); err == nil {
+		t.Error("Expected error for an unknown cipher suite")
+	}
+
+	// A committing suite checks the commitment that starts the payload
+	committing := sealEnvelope(t, recipients, &ecies.EnvelopeOptions{Cipher: cryptohelpers.CipherAES256GCM.WithCommitment()}, []byte("shared secret"))
+	if committing[14] != byte(cryptohelpers.CipherAES256GCM|cryptohelpers.CipherCommitting) {
+		t.Fatal("Expected the committing suite in the header")
+	}
+	// The prefix, count and two entries with their own rG
+	committing[prefixSize+2+2*(129+40)] ^= 1
+	if _, err := openEnvelope(privateKey, committing); err == nil {
+		t.Error("Expected error for a tampered commitment")
+	}
+
+	if _, err := ecies.NewEnvelopeWriter(io.Discard, nil, nil); err == nil {
+		t.Error("Expected error for no recipients")
+	}
+	if _, err := ecies.NewEnvelopeWriter(io.Discard, recipients, &ecies.EnvelopeOptions{Cipher: 0xFF}); err == nil {
+		t.Error("Expected error for an unknown cipher suite")
+	}
+}
+
+func TestContextEncryptDecrypt(t *testing.T) {
+	message := []byte("Hello, World!")
+	info := []byte("test v1")
+	aad := []byte("header")
+
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	encryptor := ecies.NewContextEncryptor(cryptohelpers.AES256GCMEncryptWithAAD(kdf))
+	decryptor := ecies.NewContextDecryptor(cryptohelpers.AES256GCMDecryptWithAAD(kdf))
+
+	rG, result, err := encryptor.Encrypt(publicKey, message, info, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := decryptor.Decrypt(privateKey, rG, result, info, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, message) {
+		t.Errorf("expected %q but got %q", message, plaintext)
+	}
+
+	if _, err := decryptor.Decrypt(privateKey, rG, result, []byte("test v2"), aad); err == nil {
+		t.Error("Expected error for different info")
+	}
+	if _, err := decryptor.Decrypt(privateKey, rG, result, info, []byte("other")); err == nil {
+		t.Error("Expected error for different additional data")
+	}
+	if _, err := decryptor.Decrypt(privateKey, rG, result, aad, info); err == nil {
+		t.Error("Expected error for swapped info and additional data")
+	}
+	if _, err := decryptor.Decrypt(otherKey, rG, result, info, aad); err == nil {
+		t.Error("Expected error for the wrong private key")
+	}
+	// The inverse of rG gives the same x-coordinate, but not the same binding
+	negatedRG := rG.Multiply(new(big.Int).Sub(eccfrog512ck2.GeneratorOrder(), big.NewInt(1)))
+	if _, err := decryptor.Decrypt(privateKey, negatedRG, result, info, aad); err == nil {
+		t.Error("Expected error for a different ephemeral key")
+	}
+	if _, err := decryptor.DecryptLegacy(privateKey, rG, result); err == nil {
+		t.Error("Expected error decrypting a bound ciphertext as legacy")
+	}
+
+	// Legacy ciphertexts need the compatibility path
+	rG, result, err = ecies.
+		NewEncryptor(cryptohelpers.AES256GCMEncrypt(kdf)).
+		Encrypt(privateKey, publicKey, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptor.Decrypt(privateKey, rG, result, nil, nil); err == nil {
+		t.Error("Expected error decrypting a legacy ciphertext without the compatibility path")
+	}
+	plaintext, err = decryptor.DecryptLegacy(privateKey, rG, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, message) {
+		t.Errorf("expected %q but got %q", message, plaintext)
+	}
+}
+
+func TestContainer(t *testing.T) {
+	message := []byte("Hello, World!")
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hybridKey, err := hybrid.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hybridPublicKey, err := hybridKey.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kdf := range []ecies.KDFID{ecies.KDFHKDFSHA256, ecies.KDFHKDFSHA512, ecies.KDFX963SHA512} {
+		for _, suite := range []cryptohelpers.CipherSuite{
+			cryptohelpers.CipherAES256GCM,
+			cryptohelpers.CipherChaCha20Poly1305,
+			cryptohelpers.CipherXChaCha20Poly1305,
+			cryptohelpers.CipherAES256GCMSIV,
+			cryptohelpers.CipherChaCha20Poly1305.WithCommitment(),
+		} {
+			container, err := ecies.SealContainer(publicKey, message, kdf, suite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(container.Encapsulation) != 65 {
+				t.Errorf("Expected a compressed ephemeral key, got %d bytes", len(container.Encapsulation))
+			}
+			data, err := container.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var parsed ecies.Container
+			if err := parsed.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if parsed.KEM != ecies.KEMEccFrog512ck2 || parsed.KDF != kdf || parsed.AEAD != suite {
+				t.Errorf("Parsed algorithms %d, %d, %v do not match", parsed.KEM, parsed.KDF, parsed.AEAD)
+			}
+			plaintext, err := parsed.Open(privateKey)
+			if err != nil {
+				t.Fatalf("%d, %v: %v", kdf, suite, err)
+			}
+			if !bytes.Equal(plaintext, message) {
+				t.Errorf("expected %q but got %q", message, plaintext)
+			}
+			if _, err := parsed.OpenHybrid(hybridKey); err == nil {
+				t.Error("Expected error opening an EccFrog512ck2 container with a hybrid key")
+			}
+		}
+	}
+
+	container, err := ecies.SealHybridContainer(hybridPublicKey, message, 0, cryptohelpers.CipherXChaCha20Poly1305)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.KDF != ecies.KDFHKDFSHA256 {
+		t.Errorf("Expected the default KDF, got %d", container.KDF)
+	}
+	data, err := container.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Envelope is the same type
+	var parsed ecies.Envelope
+	if err := parsed.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := parsed.OpenHybrid(hybridKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, message) {
+		t.Errorf("expected %q but got %q", message, plaintext)
+	}
+	if _, err := parsed.Open(privateKey); err == nil {
+		t.Error("Expected error opening a hybrid container with an EccFrog512ck2 key")
+	}
+}
+
+func TestContainerTampering(t *testing.T) {
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err := ecies.SealContainer(publicKey, []byte("Hello, World!"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := container.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(data []byte) error {
+		var e ecies.Container
+		if err := e.UnmarshalBinary(data); err != nil {
+			return err
+		}
+		_, err := e.Open(privateKey)
+		return err
+	}
+	if err := open(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Algorithms with the same field sizes are authenticated
+	for _, swap := range []struct {
+		offset int
+		value  byte
+	}{
+		{12, byte(ecies.KDFHKDFSHA512)},
+		{14, byte(cryptohelpers.CipherChaCha20Poly1305)},
+	} {
+		tampered := bytes.Clone(data)
+		tampered[swap.offset] = swap.value
+		var e ecies.Container
+		if err := e.UnmarshalBinary(tampered); err != nil {
+			t.Fatalf("Expected a well-formed container: %v", err)
+		}
+		if _, err := e.Open(privateKey); err == nil {
+			t.Errorf("Expected error for a switched algorithm at offset %d", swap.offset)
+		}
+	}
+
+	for name, tampered := range map[string][]byte{
+		"bad magic":       append([]byte("EFROGXXX"), data[8:]...),
+		"future version":  append(append(bytes.Clone(data[:8]), 2), data[9:]...),
+		"unknown KEM":     append(append(bytes.Clone(data[:10]), 9), data[11:]...),
+		"unknown AEAD":    append(append(bytes.Clone(data[:13]), 1, 0), data[15:]...),
+		"trailing data":   append(bytes.Clone(data), 0),
+		"truncated":       data[:len(data)-1],
+		"short header":    data[:10],
+		"flipped payload": append(bytes.Clone(data[:len(data)-1]), data[len(data)-1]^1),
+	} {
+		if err := open(tampered); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+
+	// Field lengths must match the algorithms
+	short := *container
+	short.Nonce = short.Nonce[1:]
+	if _, err := short.MarshalBinary(); err == nil {
+		t.Error("Expected error for a short nonce")
+	}
+	long := *container
+	long.Encapsulation = append(bytes.Clone(long.Encapsulation), 0)
+	if _, err := long.MarshalBinary(); err == nil {
+		t.Error("Expected error for a long encapsulation")
+	}
+	if _, err := ecies.SealContainer(publicKey, nil, 99, 0); err == nil {
+		t.Error("Expected error for an unknown KDF")
+	}
+}
+
+func TestPadding(t *testing.T) {
+	block256, err := ecies.BlockPadding(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block16, err := ecies.BlockPadding(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		padding ecies.Padding
+		n, want int64
+	}{
+		{ecies.PadmePadding, 0, 1},
+		{ecies.PadmePadding, 7, 8},
+		{ecies.PadmePadding, 8, 10},
+		{ecies.PadmePadding, 1000, 1024},
+		{ecies.PadmePadding, 1024, 1088},
+		{ecies.PowerOfTwoPadding, 0, 1},
+		{ecies.PowerOfTwoPadding, 3, 4},
+		{ecies.PowerOfTwoPadding, 4, 8},
+		{ecies.PowerOfTwoPadding, 1000, 1024},
+		{block256, 0, 256},
+		{block256, 255, 256},
+		{block256, 256, 512},
+	} {
+		if got := test.padding(test.n); got != test.want {
+			t.Errorf("padding(%d) = %d, want %d", test.n, got, test.want)
+		}
+	}
+	for n := int64(0); n < 5000; n++ {
+		if l := ecies.PadmePadding(n); l <= n || float64(l) > float64(n+1)*1.12 {
+			t.Fatalf("PadmePadding(%d) = %d", n, l)
+		}
+	}
+
+	for _, message := range [][]byte{{}, []byte("message"), {0x80}, {1, 0x80, 0, 0}} {
+		padded, err := ecies.Pad(message, block16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(padded) != 16 {
+			t.Errorf("Padded to %d bytes", len(padded))
+		}
+		unpadded, err := ecies.Unpad(padded)
+		if err != nil || !bytes.Equal(unpadded, message) {
+			t.Errorf("Unpad = %x, %v, want %x", unpadded, err, message)
+		}
+	}
+	for _, padded := range [][]byte{{}, {0, 0}, {0x80, 1}, {0x81, 0}} {
+		if _, err := ecies.Unpad(padded); err == nil {
+			t.Errorf("Expected error for %x", padded)
+		}
+	}
+	if _, err := ecies.Pad(nil, func(int64) int64 { return 0 }); err == nil {
+		t.Error("Expected error for a padded length that is too short")
+	}
+	for _, size := range []int64{0, -1} {
+		if _, err := ecies.BlockPadding(size); err == nil {
+			t.Errorf("Expected error for block size %d", size)
+		}
+	}
+
+	for name, want := range map[string]int64{"padme": 1024, "power-of-two": 1024, "block:4096": 4096} {
+		padding, err := ecies.ParsePadding(name)
+		if err != nil || padding(1000) != want {
+			t.Errorf("ParsePadding(%q) gave %d, %v", name, padding(1000), err)
+		}
+	}
+	for _, name := range []string{"", "pkcs7", "block:", "block:0", "block:-1"} {
+		if _, err := ecies.ParsePadding(name); err == nil {
+			t.Errorf("Expected error for %q", name)
+		}
+	}
+}
+
+func TestEnvelopePadding(t *testing.T) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	recipients := []eccfrog512ck2.CurvePoint{publicKey}
+	padding, err := ecies.BlockPadding(ecies.StreamChunkSize / 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &ecies.EnvelopeOptions{Padding: padding}
+
+	// Messages whose trailing bytes look like padding, across chunk
+	// boundaries, must come back intact
+	data := make([]byte, 100)
+	rand.Read(data)
+	zeros := make([]byte, 3*ecies.StreamChunkSize)
+	for _, message := range [][]byte{
+		{},
+		data,
+		append(append([]byte{}, data...), zeros...),
+		append(append(append([]byte{}, data...), 0x80), zeros...),
+		append(append(append([]byte{}, zeros...), 0x80), zeros[:ecies.StreamChunkSize-1]...),
+		append(append(append([]byte{0x80}, zeros[:ecies.StreamChunkSize-1]...), zeros...), 1),
+		zeros,
+	} {
+		envelope := sealEnvelope(t, recipients, opts, message)
+		plaintext, err := openEnvelope(privateKey, envelope)
+		if err != nil {
+			t.Fatalf("%d-byte message: failed to open: %v", len(message), err)
+		}
+		if !bytes.Equal(plaintext, message) {
+			t.Errorf("%d-byte message: got %d bytes back", len(message), len(plaintext))
+		}
+
+		r, err := ecies.NewEnvelopeReader(bytes.NewReader(envelope), privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plaintext, err = io.ReadAll(iotest.OneByteReader(r))
+		if err != nil || !bytes.Equal(plaintext, message) {
+			t.Errorf("%d-byte message: reading a byte at a time failed: %v", len(message), err)
+		}
+	}
+
+	// Messages padded to the same length have envelopes of the same length
+	a := sealEnvelope(t, recipients, opts, make([]byte, 10))
+	b := sealEnvelope(t, recipients, opts, make([]byte, 20000))
+	if len(a) != len(b) {
+		t.Errorf("Envelope lengths differ: %d and %d", len(a), len(b))
+	}
+	if unpadded := sealEnvelope(t, recipients, nil, make([]byte, 10)); len(unpadded) >= len(a) {
+		t.Error("Expected the padded envelope to be longer")
+	}
+
+	// Clearing the padded flag changes the payload key
+	a[15] &^= 1 << 2
+	if _, err := openEnvelope(privateKey, a); err == nil {
+		t.Error("Expected error for a cleared padding flag")
+	}
+}
+
+func TestEphemeralKey(t *testing.T) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	senderKey, _ := ecc.GeneratePrivateKey()
+	senderPublicKey, _ := senderKey.DerivePublicKey()
+	message := []byte("Hello, World!")
+	kdf := cryptohelpers.HKDF256(sha256.New)
+
+	// The same randomness gives the same ephemeral key
+	seed := bytes.Repeat([]byte{0x42}, 128)
+	a, err := ecies.GenerateEphemeralKey(bytes.NewReader(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ecies.GenerateEphemeralKey(bytes.NewReader(seed))
+	if !a.PublicKey().Equal(b.PublicKey()) {
+		t.Fatal("Expected the same ephemeral key from the same randomness")
+	}
+	if _, err := ecies.GenerateEphemeralKey(bytes.NewReader(seed[:10])); err == nil {
+		t.Error("Expected error for exhausted randomness")
+	}
+
+	// With a deterministic DEM, the whole ciphertext is reproducible
+	encryptor := ecies.NewEncryptor(cryptohelpers.AES256GCMSIVEncryptDeterministic(kdf))
+	rG1, c1, err := encryptor.EncryptWithEphemeralKey(a, publicKey, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rG2, c2, _ := encryptor.EncryptWithEphemeralKey(b, publicKey, message)
+	if !rG1.Equal(a.PublicKey()) || !rG1.Equal(rG2) || !bytes.Equal(c1.CipherText, c2.CipherText) {
+		t.Error("Expected identical ciphertexts from the same ephemeral key")
+	}
+	plaintext, err := ecies.NewDecryptor(cryptohelpers.AES256GCMSIVDecrypt(kdf)).Decrypt(privateKey, rG1, c1)
+	if err != nil || !bytes.Equal(plaintext, message) {
+		t.Errorf("Failed to decrypt: %v", err)
+	}
+
+	rG, c, err := ecies.NewContextEncryptor(cryptohelpers.AES256GCMEncryptWithAAD(kdf)).
+		EncryptWithEphemeralKey(a, publicKey, message, []byte("info"), nil)
+	if err != nil || !rG.Equal(a.PublicKey()) {
+		t.Fatalf("Context encryption failed: %v", err)
+	}
+	plaintext, err = ecies.NewContextDecryptor(cryptohelpers.AES256GCMDecryptWithAAD(kdf)).
+		Decrypt(privateKey, rG, c, []byte("info"), nil)
+	if err != nil || !bytes.Equal(plaintext, message) {
+		t.Errorf("Failed to decrypt: %v", err)
+	}
+
+	rG, c, err = ecies.NewContextEncryptor(cryptohelpers.AES256GCMEncryptWithAAD(kdf)).
+		AuthEncryptWithEphemeralKey(a, senderKey, publicKey, message, nil, nil)
+	if err != nil || !rG.Equal(a.PublicKey()) {
+		t.Fatalf("Authenticated encryption failed: %v", err)
+	}
+	plaintext, err = ecies.NewContextDecryptor(cryptohelpers.AES256GCMDecryptWithAAD(kdf)).
+		AuthDecrypt(privateKey, senderPublicKey, rG, c, nil, nil)
+	if err != nil || !bytes.Equal(plaintext, message) {
+		t.Errorf("Failed to decrypt: %v", err)
+	}
+
+	// Streams count their nonces, so they are reproducible too
+	var s1, s2 bytes.Buffer
+	for _, dst := range []*bytes.Buffer{&s1, &s2} {
+		w, err := ecies.NewStreamWriterWithEphemeralKey(dst, a, publicKey, kdf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(message)
+		w.Close()
+	}
+	if !bytes.Equal(s1.Bytes(), s2.Bytes()) || !bytes.Equal(s1.Bytes()[:129], a.PublicKey().MarshalSEC1(false)) {
+		t.Error("Expected identical streams from the same ephemeral key")
+	}
+	r, err := ecies.NewStreamReader(&s1, privateKey, kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err = io.ReadAll(r); err != nil || !bytes.Equal(plaintext, message) {
+		t.Errorf("Failed to decrypt stream: %v", err)
+	}
+
+	// So are envelopes, whose keys all come from Rand
+	for _, shared := range []bool{false, true} {
+		var e1, e2 bytes.Buffer
+		for _, dst := range []*bytes.Buffer{&e1, &e2} {
+			w, err := ecies.NewEnvelopeWriter(dst, []eccfrog512ck2.CurvePoint{publicKey, senderPublicKey},
+				&ecies.EnvelopeOptions{SharedEphemeralKey: shared, Rand: bytes.NewReader(bytes.Repeat(seed, 8))})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(message)
+			w.Close()
+		}
+		if !bytes.Equal(e1.Bytes(), e2.Bytes()) {
+			t.Errorf("shared %v: Expected identical envelopes from the same randomness", shared)
+		}
+		if plaintext, err = openEnvelope(privateKey, e1.Bytes()); err != nil || !bytes.Equal(plaintext, message) {
+			t.Errorf("shared %v: Failed to open envelope: %v", shared, err)
+		}
+	}
+	if _, err := ecies.NewEnvelopeWriter(io.Discard, []eccfrog512ck2.CurvePoint{publicKey},
+		&ecies.EnvelopeOptions{Rand: bytes.NewReader(seed[:10])}); err == nil {
+		t.Error("Expected error for exhausted randomness")
+	}
+
+	container, err := ecies.SealContainerWithEphemeralKey(a, publicKey, message, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(container.Encapsulation, a.PublicKey().MarshalSEC1(true)) {
+		t.Error("Expected the container to carry the given ephemeral key")
+	}
+	if plaintext, err = container.Open(privateKey); err != nil || !bytes.Equal(plaintext, message) {
+		t.Errorf("Failed to open container: %v", err)
+	}
+
+	// EncryptAnonymous uses a fresh key every time
+	rG1, _, _ = encryptor.EncryptAnonymous(publicKey, message)
+	rG2, _, _ = encryptor.EncryptAnonymous(publicKey, message)
+	if rG1.Equal(rG2) {
+		t.Error("Expected a fresh ephemeral key for each message")
+	}
+
+	if _, _, err := encryptor.EncryptWithEphemeralKey(ecies.EphemeralKey{}, publicKey, message); err == nil {
+		t.Error("Expected error for the zero ephemeral key")
+	}
+	if _, err := ecies.SchemeSEC1.EncryptWithEphemeralKey(ecies.EphemeralKey{}, publicKey, message, nil, nil); err == nil {
+		t.Error("Expected error for the zero ephemeral key")
+	}
+	if _, err := ecies.NewStreamWriterWithEphemeralKey(io.Discard, ecies.EphemeralKey{}, publicKey, kdf); err == nil {
+		t.Error("Expected error for the zero ephemeral key")
+	}
+	if _, err := ecies.SealContainerWithEphemeralKey(ecies.EphemeralKey{}, publicKey, message, 0, 0); err == nil {
+		t.Error("Expected error for the zero ephemeral key")
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	message := []byte("Hello, World!")
+	kdf := cryptohelpers.HKDF256(sha256.New)
+	encryptor := ecies.NewEncryptor(cryptohelpers.AES256GCMEncrypt(kdf))
+	decryptor := ecies.NewDecryptor(cryptohelpers.AES256GCMDecrypt(kdf))
+	contextEncryptor := ecies.NewContextEncryptor(cryptohelpers.AES256GCMEncryptWithAAD(kdf))
+	contextDecryptor := ecies.NewContextDecryptor(cryptohelpers.AES256GCMDecryptWithAAD(kdf))
+	_, c, err := encryptor.EncryptAnonymous(publicKey, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The zero CurvePoint is the point at infinity
+	for _, point := range []eccfrog512ck2.CurvePoint{eccfrog512ck2.PointAtInfinity(), {}} {
+		if _, _, err := encryptor.EncryptAnonymous(point, message); !errors.Is(err, ecies.ErrInvalidPublicKey) {
+			t.Errorf("EncryptAnonymous: expected ErrInvalidPublicKey, got %v", err)
+		}
+		if _, _, err := contextEncryptor.AuthEncrypt(privateKey, point, message, nil, nil); !errors.Is(err, ecies.ErrInvalidPublicKey) {
+			t.Errorf("AuthEncrypt: expected ErrInvalidPublicKey, got %v", err)
+		}
+		if _, _, err := contextEncryptor.Encrypt(point, message, nil, nil); !errors.Is(err, ecies.ErrInvalidPublicKey) {
+			t.Errorf("ContextEncryptor.Encrypt: expected ErrInvalidPublicKey, got %v", err)
+		}
+		if _, err := ecies.NewStreamWriter(io.Discard, point, kdf); !errors.Is(err, ecies.ErrInvalidPublicKey) {
+			t.Errorf("NewStreamWriter: expected ErrInvalidPublicKey, got %v", err)
+		}
+		if _, err := ecies.NewEnvelopeWriter(io.Discard, []eccfrog512ck2.CurvePoint{publicKey, point}, nil); !errors.Is(err, ecies.ErrInvalidPublicKey) {
+			t.Errorf("NewEnvelopeWriter: expected ErrInvalidPublicKey, got %v", err)
+		}
+		if _, err := ecies.SealContainer(point, message, 0, 0); !errors.Is(err, ecies.ErrInvalidPublicKey) {
+			t.Errorf("SealContainer: expected ErrInvalidPublicKey, got %v", err)
+		}
+		if _, err := ecies.SchemeSEC1.Encrypt(point, message, nil, nil); !errors.Is(err, ecies.ErrInvalidPublicKey) {
+			t.Errorf("Scheme.Encrypt: expected ErrInvalidPublicKey, got %v", err)
+		}
+
+		if _, err := decryptor.Decrypt(privateKey, point, c); !errors.Is(err, ecies.ErrInvalidEphemeralKey) {
+			t.Errorf("Decrypt: expected ErrInvalidEphemeralKey, got %v", err)
+		}
+		if _, err := contextDecryptor.AuthDecrypt(privateKey, publicKey, point, c, nil, nil); !errors.Is(err, ecies.ErrInvalidEphemeralKey) {
+			t.Errorf("AuthDecrypt: expected ErrInvalidEphemeralKey, got %v", err)
+		}
+		if _, err := contextDecryptor.AuthDecrypt(privateKey, point, publicKey, c, nil, nil); !errors.Is(err, ecies.ErrInvalidPublicKey) {
+			t.Errorf("AuthDecrypt: expected ErrInvalidPublicKey, got %v", err)
+		}
+		if _, err := contextDecryptor.Decrypt(privateKey, point, c, nil, nil); !errors.Is(err, ecies.ErrInvalidEphemeralKey) {
+			t.Errorf("ContextDecryptor.Decrypt: expected ErrInvalidEphemeralKey, got %v", err)
+		}
+		if _, err := contextDecryptor.DecryptLegacy(privateKey, point, c); !errors.Is(err, ecies.ErrInvalidEphemeralKey) {
+			t.Errorf("DecryptLegacy: expected ErrInvalidEphemeralKey, got %v", err)
+		}
+	}
+
+	// An encoded rG that is not on the curve
+	offCurve := append([]byte{0x04}, make([]byte, 128)...)
+	if _, err := ecies.SchemeSEC1.Decrypt(privateKey, append(offCurve, make([]byte, 64)...), nil, nil); !errors.Is(err, ecies.ErrInvalidEphemeralKey) {
+		t.Errorf("Scheme.Decrypt: expected ErrInvalidEphemeralKey, got %v", err)
+	}
+	if _, err := decryptStream(privateKey, offCurve); !errors.Is(err, ecies.ErrInvalidEphemeralKey) {
+		t.Errorf("NewStreamReader: expected ErrInvalidEphemeralKey, got %v", err)
+	}
+	container, err := ecies.SealContainer(publicKey, message, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	container.Encapsulation = append([]byte{0x02}, make([]byte, 64)...)
+	container.Encapsulation[64] = 1
+	if _, err := container.Open(privateKey); !errors.Is(err, ecies.ErrInvalidEphemeralKey) {
+		t.Errorf("Container.Open: expected ErrInvalidEphemeralKey, got %v", err)
+	}
+}
+
+// The fuzz tests feed malformed ciphertexts to every parser and decryptor,
+// seeded with valid ones. Each input must either be rejected with an error
+// or, if it survives authentication, decrypt to the sealed message.
+
+var fuzzMessage = []byte("Hello, World!")
+
+func FuzzContainer(f *testing.F) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	hybridKey, err := hybrid.GenerateKey()
+	if err != nil {
+		f.Fatal(err)
+	}
+	hybridPublicKey, _ := hybridKey.PublicKey()
+	for _, suite := range []cryptohelpers.CipherSuite{
+		cryptohelpers.CipherAES256GCM,
+		cryptohelpers.CipherXChaCha20Poly1305.WithCommitment(),
+	} {
+		container, err := ecies.SealContainer(publicKey, fuzzMessage, 0, suite)
+		if err != nil {
+			f.Fatal(err)
+		}
+		data, _ := container.MarshalBinary()
+		f.Add(data)
+	}
+	container, err := ecies.SealHybridContainer(hybridPublicKey, fuzzMessage, 0, 0)
+	if err != nil {
+		f.Fatal(err)
+	}
+	data, _ := container.MarshalBinary()
+	f.Add(data)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var e ecies.Container
+		if err := e.UnmarshalBinary(data); err != nil {
+			return
+		}
+		if plaintext, err := e.Open(privateKey); err == nil && !bytes.Equal(plaintext, fuzzMessage) {
+			t.Errorf("Open returned %q", plaintext)
+		}
+		if plaintext, err := e.OpenHybrid(hybridKey); err == nil && !bytes.Equal(plaintext, fuzzMessage) {
+			t.Errorf("OpenHybrid returned %q", plaintext)
+		}
+	})
+}
+
+func FuzzEnvelope(f *testing.F) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	recipients := []eccfrog512ck2.CurvePoint{publicKey}
+	for _, opts := range []*ecies.EnvelopeOptions{
+		nil,
+		{SharedEphemeralKey: true, Hints: true},
+		{Cipher: cryptohelpers.CipherChaCha20Poly1305.WithCommitment(), Padding: ecies.PadmePadding},
+	} {
+		var buf bytes.Buffer
+		w, err := ecies.NewEnvelopeWriter(&buf, recipients, opts)
+		if err != nil {
+			f.Fatal(err)
+		}
+		w.Write(fuzzMessage)
+		if err := w.Close(); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if plaintext, err := openEnvelope(privateKey, data); err == nil && !bytes.Equal(plaintext, fuzzMessage) {
+			t.Errorf("Envelope opened to %q", plaintext)
+		}
+	})
+}
+
+func FuzzStream(f *testing.F) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	var buf bytes.Buffer
+	w, err := ecies.NewStreamWriter(&buf, publicKey, cryptohelpers.HKDF256(sha256.New))
+	if err != nil {
+		f.Fatal(err)
+	}
+	w.Write(fuzzMessage)
+	if err := w.Close(); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if plaintext, err := decryptStream(privateKey, data); err == nil && !bytes.Equal(plaintext, fuzzMessage) {
+			t.Errorf("Stream decrypted to %q", plaintext)
+		}
+	})
+}
+
+func FuzzScheme(f *testing.F) {
+	privateKey, _ := ecc.GeneratePrivateKey()
+	publicKey, _ := privateKey.DerivePublicKey()
+	ciphertext, err := ecies.SchemeSEC1.Encrypt(publicKey, fuzzMessage, nil, nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(ciphertext)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if plaintext, err := ecies.SchemeSEC1.Decrypt(privateKey, data, nil, nil); err == nil && !bytes.Equal(plaintext, fuzzMessage) {
+			t.Errorf("Scheme decrypted to %q", plaintext)
+		}
+	})
+}
@@ -0,0 +1,393 @@
+package ecies
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+	"golang.org/x/crypto/hkdf"
+)
+
+// An envelope encrypts a payload once, under a random data key, for any
+// number of recipients. It is laid out as:
+//
+//	magic (8 bytes) || version (1 byte)
+//	KEM ID (2 bytes) || KDF ID (2 bytes) || AEAD ID (2 bytes) || flags (1 byte)
+//	[rG (129 bytes), if the ephemeral key is shared]
+//	recipient count (2 bytes, big-endian)
+//	for each recipient:
+//	    [rG (129 bytes), if the ephemeral key is not shared]
+//	    [hint (8 bytes), if hints are included]
+//	    wrapped data key (40 bytes)
+//	payload
+//
+// Each recipient's key-encryption key is derived with HKDF-SHA256 from the
+// ECDH shared secret, with the ephemeral and recipient public keys as info,
+// and wraps the data key with AES key wrap. Without hints, a recipient finds
+// its entry by trying to unwrap each one. The payload is a stream, as
+// written by StreamWriter, whose key is derived from the data key and a hash
+// of the header, so the header cannot be altered either. The payload is
+// encrypted with the cipher suite of the AEAD ID.
+// A committing suite adds a key commitment to every chunk, which is checked
+// before the chunk is decrypted, so that no payload can be crafted to
+// decrypt differently for different recipients. If the padded flag is set,
+// the payload's plaintext is padded as by Pad.
+//
+// The version and the identifiers are laid out as in a Container: the KEM
+// is KEMEccFrog512ck2, which here wraps the data key for each recipient,
+// and the KDF is KDFHKDFSHA256.
+
+// EnvelopeMagic starts every envelope.
+const EnvelopeMagic = "EFROGENV"
+
+// EnvelopeVersion is the version of the envelope format written by
+// NewEnvelopeWriter.
+const EnvelopeVersion = 1
+
+const (
+	envelopeFlagSharedEphemeral = 1 << 0
+	envelopeFlagHints           = 1 << 1
+	envelopeFlagPadded          = 1 << 2
+	envelopeFlags               = envelopeFlagSharedEphemeral | envelopeFlagHints | envelopeFlagPadded
+
+	envelopeHintSize       = 8
+	envelopeDataKeySize    = 32
+	envelopeWrappedKeySize = envelopeDataKeySize + 8
+	envelopeMaxRecipients  = 0xFFFF
+)
+
+// EnvelopeOptions controls how an envelope is written. The zero value gives
+// every recipient its own ephemeral key, includes no hints and encrypts the
+// payload with AES-256-GCM.
+type EnvelopeOptions struct {
+	// Cipher is the AEAD that encrypts the payload. Zero means
+	// AES-256-GCM. The suite is recorded in the header, so readers need
+	// not be told. Pick a committing suite, such as
+	// CipherAES256GCM.WithCommitment(), when recipients must all see the
+	// same plaintext.
+	Cipher cryptohelpers.CipherSuite
+
+	// SharedEphemeralKey uses a single ephemeral key for every recipient,
+	// which saves 129 bytes per recipient. The recipient's public key is
+	// still bound into the derivation of each key-encryption key.
+	SharedEphemeralKey bool
+
+	// Hints includes a short hash of each recipient's public key, so that
+	// recipients find their entry without trial decryption. Hints reveal
+	// who the recipients are to anyone who knows their public keys.
+	Hints bool
+
+	// Padding, if set, pads the payload to hide its exact length. Readers
+	// remove the padding without being told.
+	Padding Padding
+
+	// Rand is the source of the data key and the ephemeral keys, which is
+	// crypto/rand's Reader if nil. The payload's nonces are counters, so
+	// a fixed Rand reproduces an envelope byte for byte, as known-answer
+	// tests need; it must never be reused to encrypt another payload.
+	Rand io.Reader
+}
+
+// recipientHint returns the hint that identifies publicKey in an envelope.
+func recipientHint(publicKey eccfrog512ck2.CurvePoint) []byte {
+	sum := sha256.Sum256(publicKey.MarshalSEC1(true))
+	return sum[:envelopeHintSize]
+}
+
+// envelopeKEK derives the key-encryption key of a recipient.
+func envelopeKEK(z []byte, rG, publicKey eccfrog512ck2.CurvePoint) ([]byte, error) {
+	info := append([]byte("eccfrog512ck2 ecies envelope kek"), rG.MarshalSEC1(false)...)
+	info = append(info, publicKey.MarshalSEC1(false)...)
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, z, nil, info), kek); err != nil {
+		return nil, err
+	}
+	return kek, nil
+}
+
+// envelopePayloadKey derives the payload key from the data key, binding the
+// header.
+func envelopePayloadKey(dataKey, header []byte) ([]byte, error) {
+	salt := sha256.Sum256(header)
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, dataKey, salt[:], []byte("eccfrog512ck2 ecies envelope payload")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// agree returns the fixed-size ECDH shared secret of privateKey and
+// publicKey.
+func agree(privateKey ecc.PrivateKey, publicKey eccfrog512ck2.CurvePoint) ([]byte, error) {
+	return ecdh.ECDHPrivateKey(privateKey).DeriveFixedSizeSharedSecret(publicKey)
+}
+
+// NewEnvelopeWriter writes the header of an envelope for recipients to dst,
+// and returns a StreamWriter that encrypts the payload written to it. The
+// StreamWriter must be closed to finish the envelope. opts may be nil.
+func NewEnvelopeWriter(
+	dst io.Writer,
+	recipients []eccfrog512ck2.CurvePoint,
+	opts *EnvelopeOptions,
+) (*StreamWriter, error) {
+	if opts == nil {
+		opts = &EnvelopeOptions{}
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("ecies: no recipients")
+	}
+	if len(recipients) > envelopeMaxRecipients {
+		return nil, errors.New("ecies: too many recipients")
+	}
+	for _, publicKey := range recipients {
+		if err := checkPublicKey(publicKey); err != nil {
+			return nil, err
+		}
+	}
+	suite := opts.Cipher
+	if suite == 0 {
+		suite = cryptohelpers.CipherAES256GCM
+	}
+	if _, err := suite.NewAEAD(make([]byte, 32)); err != nil {
+		return nil, err
+	}
+
+	random := opts.Rand
+	if random == nil {
+		random = rand.Reader
+	}
+	dataKey := make([]byte, envelopeDataKeySize)
+	if _, err := io.ReadFull(random, dataKey); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(EnvelopeMagic), EnvelopeVersion)
+	header = binary.BigEndian.AppendUint16(header, uint16(KEMEccFrog512ck2))
+	header = binary.BigEndian.AppendUint16(header, uint16(KDFHKDFSHA256))
+	header = binary.BigEndian.AppendUint16(header, uint16(suite))
+	var flags byte
+	if opts.SharedEphemeralKey {
+		flags |= envelopeFlagSharedEphemeral
+	}
+	if opts.Hints {
+		flags |= envelopeFlagHints
+	}
+	if opts.Padding != nil {
+		flags |= envelopeFlagPadded
+	}
+	header = append(header, flags)
+
+	var sharedKey EphemeralKey
+	if opts.SharedEphemeralKey {
+		var err error
+		if sharedKey, err = GenerateEphemeralKey(random); err != nil {
+			return nil, err
+		}
+		header = append(header, sharedKey.publicKey.MarshalSEC1(false)...)
+	}
+	header = binary.BigEndian.AppendUint16(header, uint16(len(recipients)))
+
+	for _, publicKey := range recipients {
+		ephemeralKey := sharedKey
+		if !opts.SharedEphemeralKey {
+			var err error
+			if ephemeralKey, err = GenerateEphemeralKey(random); err != nil {
+				return nil, err
+			}
+		}
+		rG := ephemeralKey.publicKey
+		z, err := agree(ephemeralKey.privateKey, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		kek, err := envelopeKEK(z, rG, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		wrapped, err := cryptohelpers.AESKeyWrap(kek, dataKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if !opts.SharedEphemeralKey {
+			header = append(header, rG.MarshalSEC1(false)...)
+		}
+		if opts.Hints {
+			header = append(header, recipientHint(publicKey)...)
+		}
+		header = append(header, wrapped...)
+	}
+
+	payloadKey, err := envelopePayloadKey(dataKey, header)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := suite.NewAEAD(payloadKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return nil, err
+	}
+	w := newStreamWriter(aead, dst)
+	w.padding = opts.Padding
+	return w, nil
+}
+
+// envelopeHeaderReader reads the header from src, keeping a copy of
+// everything read.
+type envelopeHeaderReader struct {
+	src    io.Reader
+	header []byte
+}
+
+func (r *envelopeHeaderReader) next(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.src, b); err != nil {
+		return nil, errors.New("ecies: truncated envelope header")
+	}
+	r.header = append(r.header, b...)
+	return b, nil
+}
+
+func (r *envelopeHeaderReader) point() (eccfrog512ck2.CurvePoint, error) {
+	b, err := r.next(streamHeaderSize)
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, err
+	}
+	if b[0] != 0x04 {
+		return eccfrog512ck2.CurvePoint{}, errors.New("ecies: invalid ephemeral key in envelope")
+	}
+	return parseEphemeralKey(b)
+}
+
+// NewEnvelopeReader reads the header of an envelope from src, unwraps the
+// data key with privateKey, and returns a StreamReader that decrypts the
+// payload.
+func NewEnvelopeReader(src io.Reader, privateKey ecc.PrivateKey) (*StreamReader, error) {
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	hint := recipientHint(publicKey)
+
+	r := &envelopeHeaderReader{src: src}
+	magic, err := r.next(len(EnvelopeMagic))
+	if err != nil {
+		return nil, err
+	}
+	if string(magic) != EnvelopeMagic {
+		return nil, errors.New("ecies: not an envelope")
+	}
+	b, err := r.next(1 + 3*2)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] != EnvelopeVersion {
+		return nil, fmt.Errorf("ecies: unsupported envelope version %d", b[0])
+	}
+	if kem := KEMID(binary.BigEndian.Uint16(b[1:])); kem != KEMEccFrog512ck2 {
+		return nil, fmt.Errorf("ecies: unsupported envelope KEM %d", uint16(kem))
+	}
+	if kdf := KDFID(binary.BigEndian.Uint16(b[3:])); kdf != KDFHKDFSHA256 {
+		return nil, fmt.Errorf("ecies: unsupported envelope KDF %d", uint16(kdf))
+	}
+	aeadID := binary.BigEndian.Uint16(b[5:])
+	if aeadID > 0xFF {
+		return nil, fmt.Errorf("ecies: unknown AEAD %d", aeadID)
+	}
+	suite := cryptohelpers.CipherSuite(aeadID)
+	flags, err := r.next(1)
+	if err != nil {
+		return nil, err
+	}
+	if flags[0]&^envelopeFlags != 0 {
+		return nil, errors.New("ecies: unknown envelope flags")
+	}
+	shared := flags[0]&envelopeFlagSharedEphemeral != 0
+	hints := flags[0]&envelopeFlagHints != 0
+
+	var rG eccfrog512ck2.CurvePoint
+	var kek []byte
+	if shared {
+		if rG, err = r.point(); err != nil {
+			return nil, err
+		}
+		z, err := agree(privateKey, rG)
+		if err != nil {
+			return nil, err
+		}
+		if kek, err = envelopeKEK(z, rG, publicKey); err != nil {
+			return nil, err
+		}
+	}
+	count, err := r.next(2)
+	if err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint16(count))
+	if n == 0 {
+		return nil, errors.New("ecies: envelope has no recipients")
+	}
+
+	// Every entry is read, even after the data key is found, since the
+	// payload key depends on the whole header.
+	var dataKey []byte
+	for i := 0; i < n; i++ {
+		if !shared {
+			if rG, err = r.point(); err != nil {
+				return nil, err
+			}
+		}
+		var entryHint []byte
+		if hints {
+			if entryHint, err = r.next(envelopeHintSize); err != nil {
+				return nil, err
+			}
+		}
+		wrapped, err := r.next(envelopeWrappedKeySize)
+		if err != nil {
+			return nil, err
+		}
+		if dataKey != nil || (hints && subtle.ConstantTimeCompare(entryHint, hint) != 1) {
+			continue
+		}
+
+		entryKEK := kek
+		if !shared {
+			z, err := agree(privateKey, rG)
+			if err != nil {
+				continue
+			}
+			if entryKEK, err = envelopeKEK(z, rG, publicKey); err != nil {
+				return nil, err
+			}
+		}
+		if key, err := cryptohelpers.AESKeyUnwrap(entryKEK, wrapped); err == nil {
+			dataKey = key
+		}
+	}
+	if dataKey == nil {
+		return nil, errors.New("ecies: no envelope entry for the private key")
+	}
+
+	payloadKey, err := envelopePayloadKey(dataKey, r.header)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := suite.NewAEAD(payloadKey)
+	if err != nil {
+		return nil, err
+	}
+	sr := newStreamReader(aead, src)
+	sr.padded = flags[0]&envelopeFlagPadded != 0
+	return sr, nil
+}
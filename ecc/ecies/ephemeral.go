@@ -0,0 +1,75 @@
+package ecies
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+)
+
+// EphemeralKey is the key pair an encryption generates for a single
+// message, whose public half rG is sent with the ciphertext. The
+// encryption functions generate one themselves; their WithEphemeralKey
+// variants take one from the caller instead, so that pairs can be
+// generated ahead of time, or from a fixed source of randomness to
+// reproduce known-answer tests. Envelopes, which may need a key for every
+// recipient, take the source of randomness in EnvelopeOptions.Rand instead.
+//
+// An ephemeral key must not encrypt two messages to the same recipient: they
+// would share the shared secret, and so the key of the AEAD.
+type EphemeralKey struct {
+	privateKey ecc.PrivateKey
+	publicKey  eccfrog512ck2.CurvePoint
+}
+
+// GenerateEphemeralKey generates an ephemeral key pair from random, which is
+// crypto/rand's Reader if nil.
+//
+// Parameters:
+//   - random: The source of randomness for the private key
+//
+// Returns:
+//   - The ephemeral key pair
+//   - Any error from reading random
+func GenerateEphemeralKey(random io.Reader) (EphemeralKey, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+	privateKey, err := ecc.GeneratePrivateKeyFrom(random)
+	if err != nil {
+		return EphemeralKey{}, err
+	}
+	return NewEphemeralKey(privateKey)
+}
+
+// NewEphemeralKey returns the ephemeral key pair of privateKey.
+//
+// Parameters:
+//   - privateKey: The ephemeral private key r
+//
+// Returns:
+//   - The ephemeral key pair, with rG computed
+//   - An error if privateKey is zero or a multiple of the group order
+func NewEphemeralKey(privateKey ecc.PrivateKey) (EphemeralKey, error) {
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		return EphemeralKey{}, err
+	}
+	return EphemeralKey{privateKey: privateKey, publicKey: publicKey}, nil
+}
+
+// PublicKey returns rG, the public half of the pair.
+func (k EphemeralKey) PublicKey() eccfrog512ck2.CurvePoint {
+	return k.publicKey
+}
+
+// check returns an error for the zero EphemeralKey, which has no private
+// key.
+func (k EphemeralKey) check() error {
+	if k.privateKey.MarshalSEC1(false) == nil {
+		return errors.New("ecies: ephemeral key is not set")
+	}
+	return nil
+}
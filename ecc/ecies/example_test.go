@@ -12,12 +12,6 @@ import (
 func Example_encryptDecrypt() {
 	message := []byte("Hello, World!")
 
-	alicePrivateKey, err := ecc.GeneratePrivateKey()
-	if err != nil {
-		fmt.Println("Error generating Alice's private key:", err)
-		return
-	}
-
 	bobPrivateKey, err := ecc.GeneratePrivateKey()
 	if err != nil {
 		fmt.Println("Error generating Bob's private key:", err)
@@ -31,7 +25,7 @@ func Example_encryptDecrypt() {
 
 	kdf := cryptohelpers.HKDF256(sha256.New)
 	rG, ciphertext, err := NewEncryptor(cryptohelpers.AES256GCMEncrypt(kdf)).
-		Encrypt(alicePrivateKey, bobPublicKey, message)
+		EncryptAnonymous(bobPublicKey, message)
 	if err != nil {
 		fmt.Println("Encryption error:", err)
 		return
@@ -0,0 +1,309 @@
+package ecies
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/big"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+)
+
+// A Scheme is ECIES as specified by one of the published standards, rather
+// than the HKDF and AES-GCM construction of Encryptor. It is built from a
+// KEM and a DEM:
+//
+//   - The KEM picks an ephemeral key r, sends C0 = rG as an uncompressed
+//     SEC1 point and computes the shared x-coordinate Z, whose computation
+//     depends on the DH mode. The key is derived with the ANSI X9.63 KDF
+//     (KDF2 in ISO 18033-2) from Z, or from C0 || Z unless SingleHash is
+//     set, and the KDF shared info.
+//   - The DEM is DEM1 from ISO 18033-2 with AES-256-CTR and HMAC-SHA-512:
+//     the first 32 bytes of the key encrypt the message under a zero IV,
+//     and the next 64 bytes authenticate c || label, followed by the bit
+//     length of the label as a 64-bit big-endian integer if LabelLength is
+//     set.
+//
+// The ciphertext is C0 || c || tag, where the tag is 64 bytes.
+
+// DHMode selects how the KEM computes the shared point from a scalar and the
+// other party's point.
+//
+// EccFrog512ck2 has a cofactor of 1, so every mode computes the same secret
+// for valid points, and the modes differ only in how they would treat
+// points outside the prime-order subgroup on curves with a cofactor. They
+// are provided so that a Scheme can name the mode its standard requires.
+type DHMode int
+
+const (
+	// DHStandard computes k·P, as in ECSVDP-DH from IEEE 1363 and standard
+	// Diffie-Hellman from SEC 1.
+	DHStandard DHMode = iota
+
+	// DHCofactor computes (h·k)·P, as in cofactor Diffie-Hellman from
+	// SEC 1 and ECSVDP-DHC from IEEE 1363.
+	DHCofactor
+
+	// DHCompatibility computes (k·h⁻¹ mod n)·(h·P), which equals k·P for
+	// valid points while clearing any small-subgroup component. It is the
+	// compatibility option of ECSVDP-DHC from IEEE 1363, and CofactorMode
+	// in ISO 18033-2.
+	DHCompatibility
+
+	// DHOldCofactor is OldCofactorMode from ISO 18033-2: the sender
+	// computes (h·r mod n)·Q and the recipient computes x·(h·C0).
+	DHOldCofactor
+)
+
+// cofactor is the cofactor h of EccFrog512ck2.
+var cofactor = big.NewInt(1)
+
+// sharedSecret returns the fixed-size x-coordinate of the point computed
+// from our scalar k and the other party's point, where sender tells whether
+// k is the ephemeral key of the sender or the private key of the recipient.
+func (m DHMode) sharedSecret(k *big.Int, peer eccfrog512ck2.CurvePoint, sender bool) ([]byte, error) {
+	n := eccfrog512ck2.GeneratorOrder()
+	scalar := new(big.Int).Set(k)
+	point := peer
+	switch m {
+	case DHStandard:
+	case DHCofactor:
+		scalar.Mul(scalar, cofactor)
+	case DHCompatibility:
+		scalar.Mul(scalar, new(big.Int).ModInverse(cofactor, n))
+		scalar.Mod(scalar, n)
+		point = point.Multiply(cofactor)
+	case DHOldCofactor:
+		if sender {
+			scalar.Mul(scalar, cofactor)
+			scalar.Mod(scalar, n)
+		} else {
+			point = point.Multiply(cofactor)
+		}
+	default:
+		return nil, errors.New("ecies: unknown DH mode")
+	}
+
+	x, _, ok := point.Multiply(scalar).CoordinateIfNotInfinity()
+	if !ok {
+		return nil, errors.New("ecies: shared point is the point at infinity")
+	}
+	return x.FillBytes(make([]byte, ecdh.SharedSecretSize)), nil
+}
+
+// Scheme is a standards-based ECIES configuration. The zero value is not
+// usable; start from SchemeSEC1, SchemeIEEE1363a or SchemeISO18033.
+type Scheme struct {
+	// Hash is the hash function of the X9.63 KDF.
+	Hash func() hash.Hash
+
+	// DH is the Diffie-Hellman mode of the KEM.
+	DH DHMode
+
+	// SingleHash derives the key from Z alone, as SEC 1 does, rather than
+	// from C0 || Z. It is SingleHashMode in ISO 18033-2.
+	SingleHash bool
+
+	// LabelLength appends the bit length of the label to the MAC input,
+	// as DEM1 and DHAES do. SEC 1 does not.
+	LabelLength bool
+}
+
+var (
+	// SchemeSEC1 is ECIES from SEC 1 v2, section 5.1, with the X9.63 KDF
+	// over SHA-512, AES-256-CTR and HMAC-SHA-512. The KDF shared info is
+	// SharedInfo1 and the label is SharedInfo2.
+	SchemeSEC1 = Scheme{Hash: sha512.New, DH: DHStandard, SingleHash: true}
+
+	// SchemeIEEE1363a is DHAES from IEEE 1363a, which binds the ephemeral
+	// key into the KDF and the label length into the MAC, using the
+	// compatibility option of ECSVDP-DHC.
+	SchemeIEEE1363a = Scheme{Hash: sha512.New, DH: DHCompatibility, LabelLength: true}
+
+	// SchemeISO18033 is ECIES-HC from ISO 18033-2: ECIES-KEM with KDF2
+	// over SHA-512, and DEM1 with AES-256-CTR and HMAC-SHA-512.
+	SchemeISO18033 = Scheme{Hash: sha512.New, DH: DHStandard, LabelLength: true}
+)
+
+const (
+	schemeEncKeySize = 32
+	schemeMACKeySize = 64
+	schemeKeySize    = schemeEncKeySize + schemeMACKeySize
+	schemeTagSize    = sha512.Size
+	schemePointSize  = 1 + 2*ecdh.SharedSecretSize
+)
+
+func (s Scheme) deriveKey(c0, z, sharedInfo []byte, keyLen int) ([]byte, error) {
+	if s.Hash == nil {
+		return nil, errors.New("ecies: scheme has no hash function")
+	}
+	input := z
+	if !s.SingleHash {
+		input = append(append([]byte{}, c0...), z...)
+	}
+	return cryptohelpers.X963KDF(s.Hash, input, sharedInfo, keyLen)
+}
+
+// Encapsulate runs the scheme's KEM to publicKey, returning a keyLen-byte
+// key and the encapsulation C0 to send with whatever the key protects.
+// sharedInfo is passed to the KDF, and is empty in ISO 18033-2.
+func (s Scheme) Encapsulate(
+	publicKey eccfrog512ck2.CurvePoint,
+	keyLen int,
+	sharedInfo []byte,
+) (key, c0 []byte, err error) {
+	ephemeralKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.encapsulate(ephemeralKey, publicKey, keyLen, sharedInfo)
+}
+
+func (s Scheme) encapsulate(
+	ephemeralKey ecc.PrivateKey,
+	publicKey eccfrog512ck2.CurvePoint,
+	keyLen int,
+	sharedInfo []byte,
+) (key, c0 []byte, err error) {
+	if err := checkPublicKey(publicKey); err != nil {
+		return nil, nil, err
+	}
+	rG, err := ephemeralKey.DerivePublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	z, err := s.DH.sharedSecret(ephemeralKey.GetKey(), publicKey, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	c0 = rG.MarshalSEC1(false)
+	key, err = s.deriveKey(c0, z, sharedInfo, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, c0, nil
+}
+
+// Decapsulate recovers the key encapsulated in c0 by Encapsulate.
+func (s Scheme) Decapsulate(
+	privateKey ecc.PrivateKey,
+	c0 []byte,
+	keyLen int,
+	sharedInfo []byte,
+) ([]byte, error) {
+	if len(c0) != schemePointSize {
+		return nil, errors.New("ecies: invalid encapsulation length")
+	}
+	rG, err := parseEphemeralKey(c0)
+	if err != nil {
+		return nil, err
+	}
+	z, err := s.DH.sharedSecret(privateKey.GetKey(), rG, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.deriveKey(c0, z, sharedInfo, keyLen)
+}
+
+// Encrypt encrypts message to publicKey, returning C0 || c || tag.
+// sharedInfo is passed to the KDF and label is authenticated by the MAC;
+// either may be nil, and both must be given again to Decrypt.
+func (s Scheme) Encrypt(
+	publicKey eccfrog512ck2.CurvePoint,
+	message, sharedInfo, label []byte,
+) ([]byte, error) {
+	ephemeralKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return s.encrypt(ephemeralKey, publicKey, message, sharedInfo, label)
+}
+
+// EncryptWithEphemeralKey is like Encrypt, but uses ephemeralKey instead of
+// generating one, so that known-answer tests can be reproduced.
+func (s Scheme) EncryptWithEphemeralKey(
+	ephemeralKey EphemeralKey,
+	publicKey eccfrog512ck2.CurvePoint,
+	message, sharedInfo, label []byte,
+) ([]byte, error) {
+	if err := ephemeralKey.check(); err != nil {
+		return nil, err
+	}
+	return s.encrypt(ephemeralKey.privateKey, publicKey, message, sharedInfo, label)
+}
+
+func (s Scheme) encrypt(
+	ephemeralKey ecc.PrivateKey,
+	publicKey eccfrog512ck2.CurvePoint,
+	message, sharedInfo, label []byte,
+) ([]byte, error) {
+	key, c0, err := s.encapsulate(ephemeralKey, publicKey, schemeKeySize, sharedInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(c0)+len(message), len(c0)+len(message)+schemeTagSize)
+	copy(out, c0)
+	if err := schemeXORKeyStream(key[:schemeEncKeySize], out[len(c0):], message); err != nil {
+		return nil, err
+	}
+	return append(out, s.tag(key[schemeEncKeySize:], out[len(c0):], label)...), nil
+}
+
+// Decrypt decrypts a ciphertext produced by Encrypt with the same
+// sharedInfo and label.
+func (s Scheme) Decrypt(
+	privateKey ecc.PrivateKey,
+	ciphertext, sharedInfo, label []byte,
+) ([]byte, error) {
+	if len(ciphertext) < schemePointSize+schemeTagSize {
+		return nil, errors.New("ecies: ciphertext too short")
+	}
+	c0 := ciphertext[:schemePointSize]
+	c := ciphertext[schemePointSize : len(ciphertext)-schemeTagSize]
+	tag := ciphertext[len(ciphertext)-schemeTagSize:]
+
+	key, err := s.Decapsulate(privateKey, c0, schemeKeySize, sharedInfo)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(tag, s.tag(key[schemeEncKeySize:], c, label)) != 1 {
+		return nil, errors.New("ecies: message authentication failed")
+	}
+
+	plaintext := make([]byte, len(c))
+	if err := schemeXORKeyStream(key[:schemeEncKeySize], plaintext, c); err != nil {
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// tag computes the DEM1 tag over c and the label.
+func (s Scheme) tag(macKey, c, label []byte) []byte {
+	mac := hmac.New(sha512.New, macKey)
+	mac.Write(c)
+	mac.Write(label)
+	if s.LabelLength {
+		mac.Write(binary.BigEndian.AppendUint64(nil, 8*uint64(len(label))))
+	}
+	return mac.Sum(nil)
+}
+
+// schemeXORKeyStream encrypts or decrypts src into dst with AES-256-CTR
+// under a zero IV, which is safe because every key is used once.
+func schemeXORKeyStream(key, dst, src []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(dst, src)
+	return nil
+}
@@ -0,0 +1,193 @@
+package ecies
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+)
+
+// The scalars are SHA-512("EccFrog512ck2 ECIES test recipient") and
+// SHA-512("EccFrog512ck2 ECIES test ephemeral") reduced modulo the order.
+const (
+	schemeTestRecipientKey = "9342854b33f730cd0a9fed3afcdb60f61900719c7a345150bf10d4ac5d904f5cb62c3cc777140af9398901d84f1a440eae08c6bc9b0c54405f6e9611db246fa7"
+	schemeTestEphemeralKey = "3bc45d7fa88fd93ffb5d3bef923012fe408377d20560343fc800ab87ce10722a73945c2f1d87e63570d09dd56a47f5ebff8a1946cfdd377450d5bc94273b110e"
+
+	// The KEM vectors were computed independently of this package.
+	schemeTestC0        = "040a5853e3d90eadbbaafc1b123894ccae698e60b5ba7fc74385cc5f4f36874b5a3d76d25a87b871529dd8684aa394972f904a82ce20a37250bdb7668d03a9e44924122f179ce57a2c919f85b2be274c80df30d703302ee2e44f26e242158ea74dd12a1ddbfbb0dc181802f51b23f3421d138b465c0c874c5846187dd7ea7761c2"
+	schemeTestSingleKey = "3101e5ef43adee1779ac6d04f68207603475caec8e96cb40a68bf04d4cc74799c8ae960ef15d672bc7fb96f2648efb64303d246e24f7fb61a28bda21d856ef377b440943bb4f3c4b91a7d6fe3b34ddc52d10ed34eaeb17d0562f7b172f5c87e3"
+	schemeTestFullKey   = "d289a7bd703ecb8edfcb1a50070d16132859c80924591d17c0b1b5da669fdd1244d175948ee2f009c5fbba18dccc9912826d69fb560eebbd985e84fae21905b30d59d418da0c712ce37c0dd1d83727c55ad3f072c65584d54c04932be165eadf"
+)
+
+func schemeTestKeys(t *testing.T) (recipient, ephemeral ecc.PrivateKey) {
+	t.Helper()
+	for _, k := range []struct {
+		hex string
+		key *ecc.PrivateKey
+	}{{schemeTestRecipientKey, &recipient}, {schemeTestEphemeralKey, &ephemeral}} {
+		b, _ := hex.DecodeString(k.hex)
+		key, err := ecc.ParsePrivateKeySEC1(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*k.key = key
+	}
+	return recipient, ephemeral
+}
+
+func TestSchemeKEMKnownAnswer(t *testing.T) {
+	recipient, ephemeral := schemeTestKeys(t)
+	publicKey, err := recipient.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []DHMode{DHStandard, DHCofactor, DHCompatibility, DHOldCofactor} {
+		for _, test := range []struct {
+			singleHash bool
+			sharedInfo []byte
+			want       string
+		}{
+			{true, []byte("shared info"), schemeTestSingleKey},
+			{false, nil, schemeTestFullKey},
+		} {
+			scheme := Scheme{Hash: SchemeISO18033.Hash, DH: mode, SingleHash: test.singleHash}
+			key, c0, err := scheme.encapsulate(ephemeral, publicKey, schemeKeySize, test.sharedInfo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(c0) != schemeTestC0 {
+				t.Errorf("mode %d: C0 = %x, want %s", mode, c0, schemeTestC0)
+			}
+			if hex.EncodeToString(key) != test.want {
+				t.Errorf("mode %d, single hash %v: key = %x, want %s", mode, test.singleHash, key, test.want)
+			}
+
+			decapsulated, err := scheme.Decapsulate(recipient, c0, schemeKeySize, test.sharedInfo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decapsulated, key) {
+				t.Errorf("mode %d: decapsulated key does not match", mode)
+			}
+		}
+	}
+}
+
+func TestSchemeKnownAnswer(t *testing.T) {
+	recipient, ephemeral := schemeTestKeys(t)
+	publicKey, err := recipient.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ephemeralKey, err := NewEphemeralKey(ephemeral)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("Hello, World!")
+	sharedInfo := []byte("shared info")
+	label := []byte("label")
+
+	// The tags were checked independently of this package. IEEE 1363a and
+	// ISO 18033-2 agree, since the DH modes do on this curve.
+	for _, test := range []struct {
+		name   string
+		scheme Scheme
+		want   string
+	}{
+		{"SEC1", SchemeSEC1, "8dab88090a45e419136f8801cb00e1f7e0f0f2b661c5d2987fc38d32bb2bedcb6455f414759dcd6094793ba58970e316d18c3dbb804f3cb04d6b1f1e4a5f4ac30834e82fd8c90c5e014a1b5555"},
+		{"IEEE1363a", SchemeIEEE1363a, "bf077beba2c806923aac3ba939a3d3fe583216d8e512776259c8697bb1535b0c41d42d66f406faeb64b57e2c4910ed33ef59c77edcac4103e34f168bfa28f21288227f45ff51d8474dd1b73b2b"},
+		{"ISO18033", SchemeISO18033, "bf077beba2c806923aac3ba939a3d3fe583216d8e512776259c8697bb1535b0c41d42d66f406faeb64b57e2c4910ed33ef59c77edcac4103e34f168bfa28f21288227f45ff51d8474dd1b73b2b"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ciphertext, err := test.scheme.EncryptWithEphemeralKey(ephemeralKey, publicKey, message, sharedInfo, label)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(ciphertext[schemePointSize:]); got != test.want {
+				t.Errorf("c || tag = %s, want %s", got, test.want)
+			}
+
+			want, _ := hex.DecodeString(schemeTestC0 + test.want)
+			plaintext, err := test.scheme.Decrypt(recipient, want, sharedInfo, label)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(plaintext, message) {
+				t.Errorf("expected %q but got %q", message, plaintext)
+			}
+		})
+	}
+}
+
+func TestSchemeEncryptDecrypt(t *testing.T) {
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("Hello, World!")
+	sharedInfo := []byte("shared info")
+	label := []byte("label")
+
+	for _, scheme := range []Scheme{SchemeSEC1, SchemeIEEE1363a, SchemeISO18033} {
+		ciphertext, err := scheme.Encrypt(publicKey, message, sharedInfo, label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ciphertext) != schemePointSize+len(message)+schemeTagSize {
+			t.Errorf("ciphertext is %d bytes", len(ciphertext))
+		}
+		plaintext, err := scheme.Decrypt(privateKey, ciphertext, sharedInfo, label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plaintext, message) {
+			t.Errorf("expected %q but got %q", message, plaintext)
+		}
+
+		if _, err := scheme.Decrypt(privateKey, ciphertext, sharedInfo, []byte("other")); err == nil {
+			t.Error("Expected error for a different label")
+		}
+		if _, err := scheme.Decrypt(privateKey, ciphertext, []byte("other"), label); err == nil {
+			t.Error("Expected error for different shared info")
+		}
+		if _, err := scheme.Decrypt(otherKey, ciphertext, sharedInfo, label); err == nil {
+			t.Error("Expected error for the wrong private key")
+		}
+		for _, i := range []int{0, schemePointSize, len(ciphertext) - 1} {
+			tampered := bytes.Clone(ciphertext)
+			tampered[i] ^= 1
+			if _, err := scheme.Decrypt(privateKey, tampered, sharedInfo, label); err == nil {
+				t.Errorf("Expected error for a flipped bit at %d", i)
+			}
+		}
+		if _, err := scheme.Decrypt(privateKey, ciphertext[:schemePointSize+schemeTagSize-1], sharedInfo, label); err == nil {
+			t.Error("Expected error for a short ciphertext")
+		}
+	}
+
+	// The schemes are not interchangeable
+	ciphertext, err := SchemeSEC1.Encrypt(publicKey, message, sharedInfo, label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SchemeISO18033.Decrypt(privateKey, ciphertext, sharedInfo, label); err == nil {
+		t.Error("Expected error decrypting a SEC 1 ciphertext as ISO 18033-2")
+	}
+
+	if _, err := (Scheme{}).Encrypt(publicKey, message, nil, nil); err == nil {
+		t.Error("Expected error for the zero scheme")
+	}
+	if _, err := (Scheme{Hash: SchemeSEC1.Hash, DH: DHMode(99)}).Encrypt(publicKey, message, nil, nil); err == nil {
+		t.Error("Expected error for an unknown DH mode")
+	}
+}
@@ -0,0 +1,409 @@
+package ecies
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+)
+
+// A stream starts with the ephemeral public key rG as an uncompressed SEC1
+// point, followed by the message encrypted with the STREAM construction:
+// AES-256-GCM over StreamChunkSize chunks, each with a nonce made of an
+// 11-byte big-endian chunk counter and a final byte that is 1 for the last
+// chunk and 0 otherwise. A single key, derived from the ECDH shared secret,
+// encrypts every chunk, so chunks cannot be reordered, dropped or
+// truncated without failing authentication.
+//
+// Envelopes use the same construction with other AEADs, whose nonce is laid
+// out the same way: a counter in every byte but the last, then the flag.
+// They may also pad the plaintext, as Pad does, before it is split into
+// chunks.
+
+// StreamChunkSize is the size of the plaintext of every chunk of a stream
+// but the last.
+const StreamChunkSize = 64 * 1024
+
+const streamHeaderSize = 129
+
+type streamNonce []byte
+
+func (n streamNonce) increment() {
+	for i := len(n) - 2; i >= 0; i-- {
+		n[i]++
+		if n[i] != 0 {
+			return
+		}
+	}
+	// The counter wrapped around, which takes at least 2^88 chunks.
+	panic("ecies: stream nonce overflow")
+}
+
+func (n streamNonce) setLastChunk() {
+	n[len(n)-1] = 1
+}
+
+func newStreamAEAD(
+	kdf func(cryptohelpers.SecretKey) ([32]byte, error),
+	secret []byte,
+) (cipher.AEAD, error) {
+	key, err := kdf(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cryptohelpers.CipherAES256GCM.NewAEAD(key[:])
+}
+
+// StreamWriter encrypts a stream of unbounded length. It must be closed to
+// write the last chunk.
+type StreamWriter struct {
+	aead  cipher.AEAD
+	dst   io.Writer
+	nonce streamNonce
+	buf   []byte
+	err   error
+
+	// padding, if set, pads the plaintext on Close, and length counts the
+	// bytes written.
+	padding Padding
+	length  int64
+}
+
+// NewStreamWriter writes the header of a stream encrypted to publicKey to
+// dst, and returns a StreamWriter that encrypts the data written to it.
+// kdf derives the AES-256-GCM key from the ECDH shared secret, as for
+// AES256GCMEncrypt.
+func NewStreamWriter(
+	dst io.Writer,
+	publicKey eccfrog512ck2.CurvePoint,
+	kdf func(cryptohelpers.SecretKey) ([32]byte, error),
+) (*StreamWriter, error) {
+	ephemeralKey, err := GenerateEphemeralKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamWriterWithEphemeralKey(dst, ephemeralKey, publicKey, kdf)
+}
+
+// NewStreamWriterWithEphemeralKey is like NewStreamWriter, but uses
+// ephemeralKey instead of generating one. The nonces are counters, so the
+// stream is then determined by ephemeralKey and the data written.
+func NewStreamWriterWithEphemeralKey(
+	dst io.Writer,
+	ephemeralKey EphemeralKey,
+	publicKey eccfrog512ck2.CurvePoint,
+	kdf func(cryptohelpers.SecretKey) ([32]byte, error),
+) (*StreamWriter, error) {
+	if err := ephemeralKey.check(); err != nil {
+		return nil, err
+	}
+	if err := checkPublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	rG := ephemeralKey.publicKey
+	secret, err := ecdh.ECDHPrivateKey(ephemeralKey.privateKey).DeriveFixedSizeSharedSecret(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newStreamAEAD(kdf, secret)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(rG.MarshalSEC1(false)); err != nil {
+		return nil, err
+	}
+	return newStreamWriter(aead, dst), nil
+}
+
+func newStreamWriter(aead cipher.AEAD, dst io.Writer) *StreamWriter {
+	return &StreamWriter{
+		aead:  aead,
+		dst:   dst,
+		nonce: make(streamNonce, aead.NonceSize()),
+		buf:   make([]byte, 0, StreamChunkSize+aead.Overhead()),
+	}
+}
+
+// Write encrypts p. Data is written to the underlying writer a chunk at a
+// time.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only flushed once more data arrives, since the
+		// last chunk is encrypted differently and may be full.
+		if len(w.buf) == StreamChunkSize {
+			if err := w.flushChunk(false); err != nil {
+				w.err = err
+				return written, err
+			}
+		}
+		n := min(StreamChunkSize-len(w.buf), len(p))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	w.length += int64(written)
+	return written, nil
+}
+
+// pad writes the padding: 0x80 and then zeros, up to the padded length.
+func (w *StreamWriter) pad() error {
+	length := w.padding(w.length)
+	if length <= w.length {
+		return errors.New("ecies: padded length is too short")
+	}
+	if _, err := w.Write([]byte{0x80}); err != nil {
+		return err
+	}
+	zeros := make([]byte, min(length-w.length, StreamChunkSize))
+	for w.length < length {
+		if _, err := w.Write(zeros[:min(length-w.length, int64(len(zeros)))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *StreamWriter) flushChunk(last bool) error {
+	if last {
+		w.nonce.setLastChunk()
+	}
+	w.buf = w.aead.Seal(w.buf[:0], w.nonce, w.buf, nil)
+	_, err := w.dst.Write(w.buf)
+	w.buf = w.buf[:0]
+	w.nonce.increment()
+	return err
+}
+
+// Close encrypts and writes the last chunk. It does not close the
+// underlying writer.
+func (w *StreamWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.padding != nil {
+		if w.err = w.pad(); w.err != nil {
+			return w.err
+		}
+	}
+	w.err = w.flushChunk(true)
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errors.New("ecies: write to closed stream")
+	return nil
+}
+
+// StreamReader decrypts a stream written by a StreamWriter.
+//
+// Read only returns data that has been authenticated, a chunk at a time,
+// and returns io.EOF only after the last chunk has been authenticated. A
+// truncated stream is reported as an error rather than io.EOF.
+type StreamReader struct {
+	aead  cipher.AEAD
+	src   io.Reader
+	nonce streamNonce
+
+	// unread is the decrypted data not yet returned by Read, and buf holds
+	// the chunk being read.
+	unread    []byte
+	buf       []byte
+	lookahead []byte
+	done      bool
+	err       error
+
+	// If the plaintext is padded, held is the run at the end of the data
+	// read so far that may be padding, and released is a run that turned
+	// out to be data, to be returned before unread.
+	padded   bool
+	held     padRun
+	released padRun
+}
+
+// padRun is a 0x80 byte, if marker is set, followed by zeros. Runs are
+// counted rather than buffered, since they may be arbitrarily long.
+type padRun struct {
+	marker bool
+	zeros  int64
+}
+
+func (run padRun) empty() bool {
+	return !run.marker && run.zeros == 0
+}
+
+// read copies as much of the run as fits into p, and removes it from the
+// run.
+func (run *padRun) read(p []byte) int {
+	n := 0
+	if run.marker && len(p) > 0 {
+		p[0] = 0x80
+		run.marker = false
+		n = 1
+	}
+	m := int(min(int64(len(p)-n), run.zeros))
+	clear(p[n : n+m])
+	run.zeros -= int64(m)
+	return n + m
+}
+
+// NewStreamReader reads the header of a stream from src and returns a
+// StreamReader that decrypts it with privateKey. kdf must be the function
+// the stream was encrypted with.
+func NewStreamReader(
+	src io.Reader,
+	privateKey ecc.PrivateKey,
+	kdf func(cryptohelpers.SecretKey) ([32]byte, error),
+) (*StreamReader, error) {
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, errors.New("ecies: failed to read stream header")
+	}
+	if header[0] != 0x04 {
+		return nil, errors.New("ecies: invalid stream header")
+	}
+	rG, err := parseEphemeralKey(header)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ecdh.ECDHPrivateKey(privateKey).DeriveFixedSizeSharedSecret(rG)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newStreamAEAD(kdf, secret)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamReader(aead, src), nil
+}
+
+func newStreamReader(aead cipher.AEAD, src io.Reader) *StreamReader {
+	return &StreamReader{
+		aead:  aead,
+		src:   src,
+		nonce: make(streamNonce, aead.NonceSize()),
+		buf:   make([]byte, StreamChunkSize+aead.Overhead()+1),
+	}
+}
+
+// Read reads decrypted data into p.
+func (r *StreamReader) Read(p []byte) (int, error) {
+	for len(r.unread) == 0 && r.released.empty() {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.unread, r.err = r.readChunk()
+	}
+	n := r.released.read(p)
+	if r.released.empty() {
+		m := copy(p[n:], r.unread)
+		r.unread = r.unread[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// readChunk reads and decrypts the next chunk. One byte beyond the chunk is
+// read to find out whether it is the last one; it is kept for the next call.
+func (r *StreamReader) readChunk() ([]byte, error) {
+	n := copy(r.buf, r.lookahead)
+	m, err := io.ReadFull(r.src, r.buf[n:])
+	n += m
+	r.lookahead = nil
+
+	var chunk []byte
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		if n < r.aead.Overhead() {
+			return nil, errors.New("ecies: truncated stream")
+		}
+		chunk, last = r.buf[:n], true
+	case err != nil:
+		return nil, err
+	default:
+		size := StreamChunkSize + r.aead.Overhead()
+		chunk = r.buf[:size]
+		r.lookahead = append([]byte{}, r.buf[size:n]...)
+	}
+
+	if last {
+		r.nonce.setLastChunk()
+	}
+	plaintext, err := r.aead.Open(chunk[:0], r.nonce, chunk, nil)
+	if err != nil {
+		return nil, errors.New("ecies: failed to decrypt and authenticate stream chunk")
+	}
+	r.nonce.increment()
+
+	if last {
+		r.done = true
+		// An empty last chunk is only allowed if it is the only chunk.
+		if len(plaintext) == 0 && !r.isFirstChunk() {
+			return nil, errors.New("ecies: last chunk is empty")
+		}
+	}
+	if r.padded {
+		return r.unpad(plaintext, last)
+	}
+	return plaintext, nil
+}
+
+// unpad returns the data of a chunk that is certain not to be padding, and
+// holds back the rest until a later chunk shows whether it is.
+func (r *StreamReader) unpad(plaintext []byte, last bool) ([]byte, error) {
+	i := len(plaintext)
+	for i > 0 && plaintext[i-1] == 0 {
+		i--
+	}
+	zeros := int64(len(plaintext) - i)
+
+	if last {
+		if i == 0 {
+			// The padding started in an earlier chunk.
+			if !r.held.marker {
+				return nil, errors.New("ecies: invalid padding")
+			}
+			r.held = padRun{}
+			return nil, nil
+		}
+		if plaintext[i-1] != 0x80 {
+			return nil, errors.New("ecies: invalid padding")
+		}
+		r.released, r.held = r.held, padRun{}
+		return plaintext[:i-1], nil
+	}
+
+	if i == 0 {
+		r.held.zeros += zeros
+		return nil, nil
+	}
+	// The chunk holds data, so whatever was held is data too.
+	r.released = r.held
+	if plaintext[i-1] == 0x80 {
+		r.held = padRun{marker: true, zeros: zeros}
+		return plaintext[:i-1], nil
+	}
+	r.held = padRun{zeros: zeros}
+	return plaintext[:i], nil
+}
+
+// isFirstChunk reports whether the chunk that was just read was the first,
+// that is, whether the counter is now one.
+func (r *StreamReader) isFirstChunk() bool {
+	for i := 0; i < len(r.nonce)-2; i++ {
+		if r.nonce[i] != 0 {
+			return false
+		}
+	}
+	return r.nonce[len(r.nonce)-2] == 1
+}
@@ -0,0 +1,49 @@
+package ecies
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+)
+
+var (
+	// ErrInvalidPublicKey is returned when a recipient or sender public key
+	// is the point at infinity, is not on the curve, or has a coordinate
+	// that is not less than p.
+	ErrInvalidPublicKey = errors.New("ecies: invalid public key")
+
+	// ErrInvalidEphemeralKey is returned when the ephemeral public key rG of
+	// a ciphertext fails to parse or is not a valid public key, so that a
+	// crafted rG is rejected before any scalar multiplication.
+	ErrInvalidEphemeralKey = errors.New("ecies: invalid ephemeral public key")
+)
+
+// checkPublicKey wraps ErrInvalidPublicKey around the reason publicKey is
+// not a valid public key, if any.
+func checkPublicKey(publicKey eccfrog512ck2.CurvePoint) error {
+	if err := ecc.ValidatePublicKey(publicKey); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	return nil
+}
+
+// checkEphemeralKey wraps ErrInvalidEphemeralKey around the reason rG is not
+// a valid public key, if any.
+func checkEphemeralKey(rG eccfrog512ck2.CurvePoint) error {
+	if err := ecc.ValidatePublicKey(rG); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEphemeralKey, err)
+	}
+	return nil
+}
+
+// parseEphemeralKey parses the SEC1 encoding of an ephemeral public key rG
+// read from a ciphertext.
+func parseEphemeralKey(data []byte) (eccfrog512ck2.CurvePoint, error) {
+	rG, err := ecc.ParsePublicKeySEC1(data)
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, fmt.Errorf("%w: %v", ErrInvalidEphemeralKey, err)
+	}
+	return rG, nil
+}
@@ -0,0 +1,16 @@
+// Package hpke implements Hybrid Public Key Encryption (RFC 9180) with a
+// DHKEM over EccFrog512ck2.
+//
+// The KEM is DHKEM(EccFrog512ck2, HKDF-SHA512), built the same way as the
+// RFC's DHKEMs over the NIST curves: public keys are uncompressed SEC1
+// points, private keys are 64-byte big-endian scalars and the Diffie-Hellman
+// output is the 64-byte x-coordinate of the shared point. The curve has no
+// registered KEM ID, so it uses KEMEccFrog512ck2HKDFSHA512, a value that
+// IANA has not assigned, and other implementations will only interoperate
+// if they are taught the same ID.
+//
+// All four modes are supported. A Sender or Receiver selects the mode by the
+// fields it sets: a pre-shared key selects the PSK modes and a sender key the
+// authenticated modes. The AEADs are AES-256-GCM and ChaCha20-Poly1305, and
+// the export-only AEAD can be used for contexts that only export secrets.
+package hpke
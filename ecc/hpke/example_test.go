@@ -0,0 +1,35 @@
+package hpke_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/hpke"
+)
+
+func ExampleSender_Seal() {
+	recipientKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	recipientPublicKey, err := recipientKey.DerivePublicKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite := hpke.Suite{KDF: hpke.KDFHKDFSHA512, AEAD: hpke.AEADAES256GCM}
+
+	sender := hpke.Sender{Suite: suite, RecipientKey: recipientPublicKey, Info: []byte("example")}
+	enc, ciphertext, err := sender.Seal(nil, []byte("Hello, HPKE!"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	receiver := hpke.Receiver{Suite: suite, PrivateKey: recipientKey, Info: []byte("example")}
+	plaintext, err := receiver.Open(enc, nil, ciphertext)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(plaintext))
+	// Output: Hello, HPKE!
+}
@@ -0,0 +1,403 @@
+package hpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Mode is an HPKE mode.
+type Mode uint8
+
+const (
+	ModeBase    Mode = 0x00
+	ModePSK     Mode = 0x01
+	ModeAuth    Mode = 0x02
+	ModeAuthPSK Mode = 0x03
+)
+
+// KDF identifies the key derivation function of the key schedule.
+type KDF uint16
+
+const (
+	KDFHKDFSHA256 KDF = 0x0001
+	KDFHKDFSHA384 KDF = 0x0002
+	KDFHKDFSHA512 KDF = 0x0003
+)
+
+func (k KDF) hash() (func() hash.Hash, error) {
+	switch k {
+	case KDFHKDFSHA256:
+		return sha256.New, nil
+	case KDFHKDFSHA384:
+		return sha512.New384, nil
+	case KDFHKDFSHA512:
+		return sha512.New, nil
+	}
+	return nil, errors.New("hpke: unsupported KDF")
+}
+
+// AEAD identifies the authenticated encryption algorithm of a context.
+type AEAD uint16
+
+const (
+	AEADAES256GCM        AEAD = 0x0002
+	AEADChaCha20Poly1305 AEAD = 0x0003
+	// AEADExportOnly is for contexts that are only used to export secrets.
+	AEADExportOnly AEAD = 0xFFFF
+)
+
+// keySize returns Nk, the size of the AEAD's key.
+func (a AEAD) keySize() (int, error) {
+	switch a {
+	case AEADAES256GCM:
+		return 32, nil
+	case AEADChaCha20Poly1305:
+		return chacha20poly1305.KeySize, nil
+	case AEADExportOnly:
+		return 0, nil
+	}
+	return 0, errors.New("hpke: unsupported AEAD")
+}
+
+func (a AEAD) new(key []byte) (cipher.AEAD, error) {
+	switch a {
+	case AEADAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case AEADChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	}
+	return nil, nil
+}
+
+// nonceSize is Nn, the nonce size of both supported AEADs.
+const nonceSize = 12
+
+var (
+	// ErrOpen is returned when a ciphertext fails to decrypt.
+	ErrOpen = errors.New("hpke: decryption failed")
+	// ErrMessageLimit is returned when a context's sequence number is
+	// exhausted.
+	ErrMessageLimit = errors.New("hpke: message limit reached")
+	// ErrExportOnly is returned when Seal or Open is called on a context
+	// that uses AEADExportOnly.
+	ErrExportOnly = errors.New("hpke: export-only context")
+)
+
+// Suite is an HPKE cipher suite. The KEM is always
+// KEMEccFrog512ck2HKDFSHA512.
+type Suite struct {
+	KDF  KDF
+	AEAD AEAD
+}
+
+func (s Suite) id() []byte {
+	id := []byte("HPKE")
+	id = binary.BigEndian.AppendUint16(id, uint16(KEMEccFrog512ck2HKDFSHA512))
+	id = binary.BigEndian.AppendUint16(id, uint16(s.KDF))
+	return binary.BigEndian.AppendUint16(id, uint16(s.AEAD))
+}
+
+// labeledKDF implements LabeledExtract and LabeledExpand for a suite ID.
+type labeledKDF struct {
+	hash    func() hash.Hash
+	suiteID []byte
+}
+
+func (k labeledKDF) labeledExtract(salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := append([]byte("HPKE-v1"), k.suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, ikm...)
+	return hkdf.Extract(k.hash, labeledIKM, salt)
+}
+
+func (k labeledKDF) labeledExpand(prk []byte, label string, info []byte, length int) []byte {
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = append(labeledInfo, "HPKE-v1"...)
+	labeledInfo = append(labeledInfo, k.suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	out := make([]byte, length)
+	if _, err := hkdf.Expand(k.hash, prk, labeledInfo).Read(out); err != nil {
+		// Callers bound length by 255 times the hash size
+		panic(err)
+	}
+	return out
+}
+
+func (s Suite) kdf() (labeledKDF, error) {
+	h, err := s.KDF.hash()
+	if err != nil {
+		return labeledKDF{}, err
+	}
+	return labeledKDF{hash: h, suiteID: s.id()}, nil
+}
+
+// mode returns the mode selected by the presence of a PSK and a sender key.
+func mode(psk, pskID []byte, auth bool) (Mode, error) {
+	if (len(psk) == 0) != (len(pskID) == 0) {
+		return 0, errors.New("hpke: PSK and PSK ID must be given together")
+	}
+	m := ModeBase
+	if len(psk) > 0 {
+		if len(psk) < 32 {
+			return 0, errors.New("hpke: PSK is shorter than 32 bytes")
+		}
+		m = ModePSK
+	}
+	if auth {
+		m |= ModeAuth
+	}
+	return m, nil
+}
+
+// keyScheduleContext returns the key_schedule_context of the key schedule.
+func (k labeledKDF) keyScheduleContext(m Mode, info, pskID []byte) []byte {
+	pskIDHash := k.labeledExtract(nil, "psk_id_hash", pskID)
+	infoHash := k.labeledExtract(nil, "info_hash", info)
+	return append(append([]byte{byte(m)}, pskIDHash...), infoHash...)
+}
+
+// schedule holds the intermediate values of the key schedule.
+type schedule struct {
+	keyScheduleContext []byte
+	secret             []byte
+	key                []byte
+	baseNonce          []byte
+	exporterSecret     []byte
+}
+
+// schedule runs the key schedule on the KEM shared secret.
+func (s Suite) schedule(m Mode, sharedSecret, info, psk, pskID []byte) (labeledKDF, schedule, error) {
+	k, err := s.kdf()
+	if err != nil {
+		return labeledKDF{}, schedule{}, err
+	}
+	keySize, err := s.AEAD.keySize()
+	if err != nil {
+		return labeledKDF{}, schedule{}, err
+	}
+	var out schedule
+	out.keyScheduleContext = k.keyScheduleContext(m, info, pskID)
+	out.secret = k.labeledExtract(sharedSecret, "secret", psk)
+	if s.AEAD != AEADExportOnly {
+		out.key = k.labeledExpand(out.secret, "key", out.keyScheduleContext, keySize)
+		out.baseNonce = k.labeledExpand(out.secret, "base_nonce", out.keyScheduleContext, nonceSize)
+	}
+	out.exporterSecret = k.labeledExpand(out.secret, "exp", out.keyScheduleContext, k.hash().Size())
+	return k, out, nil
+}
+
+// keySchedule derives an encryption context from the KEM shared secret.
+func (s Suite) keySchedule(m Mode, sharedSecret, info, psk, pskID []byte) (*context, error) {
+	k, out, err := s.schedule(m, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	c := &context{kdf: k, baseNonce: out.baseNonce, exporterSecret: out.exporterSecret}
+	if s.AEAD != AEADExportOnly {
+		if c.aead, err = s.AEAD.new(out.key); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// context is the state shared by SealContext and OpenContext.
+type context struct {
+	kdf            labeledKDF
+	aead           cipher.AEAD
+	baseNonce      []byte
+	exporterSecret []byte
+	seq            uint64
+}
+
+// nextNonce returns the nonce for the current sequence number and advances
+// it.
+func (c *context) nextNonce() ([]byte, error) {
+	if c.aead == nil {
+		return nil, ErrExportOnly
+	}
+	if c.seq == math.MaxUint64 {
+		return nil, ErrMessageLimit
+	}
+	nonce := computeNonce(c.baseNonce, c.seq)
+	c.seq++
+	return nonce, nil
+}
+
+// computeNonce returns the nonce of the message with sequence number seq.
+func computeNonce(baseNonce []byte, seq uint64) []byte {
+	nonce := make([]byte, nonceSize)
+	binary.BigEndian.PutUint64(nonce[nonceSize-8:], seq)
+	for i := range nonce {
+		nonce[i] ^= baseNonce[i]
+	}
+	return nonce
+}
+
+// Export derives a secret of the given length from the context and
+// exporterContext.
+func (c *context) Export(exporterContext []byte, length int) ([]byte, error) {
+	if length < 0 || length > 255*c.kdf.hash().Size() {
+		return nil, errors.New("hpke: invalid export length")
+	}
+	return c.kdf.labeledExpand(c.exporterSecret, "sec", exporterContext, length), nil
+}
+
+// SealContext is a sender's encryption context. Its messages must be opened
+// in the same order by the receiver's OpenContext.
+type SealContext struct {
+	context
+}
+
+// Seal encrypts and authenticates plaintext and authenticates aad.
+func (c *SealContext) Seal(aad, plaintext []byte) ([]byte, error) {
+	nonce, err := c.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+// OpenContext is a receiver's decryption context.
+type OpenContext struct {
+	context
+}
+
+// Open decrypts and authenticates ciphertext and authenticates aad. The
+// sequence number only advances if it succeeds.
+func (c *OpenContext) Open(aad, ciphertext []byte) ([]byte, error) {
+	nonce, err := c.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		c.seq--
+		return nil, ErrOpen
+	}
+	return plaintext, nil
+}
+
+// Sender holds the parameters with which a sender sets up a context. The
+// mode is chosen by the fields that are set: PSK and PSKID select ModePSK,
+// PrivateKey selects ModeAuth, and both select ModeAuthPSK.
+type Sender struct {
+	Suite Suite
+	// RecipientKey is the recipient's public key.
+	RecipientKey eccfrog512ck2.CurvePoint
+	// Info is application-supplied information that binds the context.
+	Info []byte
+	// PSK is a pre-shared key of at least 32 bytes, and PSKID its identifier.
+	PSK, PSKID []byte
+	// PrivateKey is the sender's static key, which authenticates the sender
+	// to the recipient.
+	PrivateKey *ecc.PrivateKey
+}
+
+// Mode returns the mode that the sender's parameters select.
+func (s *Sender) Mode() (Mode, error) {
+	return mode(s.PSK, s.PSKID, s.PrivateKey != nil)
+}
+
+// Setup encapsulates a fresh shared secret to the recipient and returns the
+// encapsulated key enc, which the recipient needs to set up its context.
+func (s *Sender) Setup() ([]byte, *SealContext, error) {
+	ephemeralKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.setup(ephemeralKey)
+}
+
+func (s *Sender) setup(ephemeralKey ecc.PrivateKey) ([]byte, *SealContext, error) {
+	m, err := s.Mode()
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedSecret, enc, err := encap(s.RecipientKey, s.PrivateKey, ephemeralKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := s.Suite.keySchedule(m, sharedSecret, s.Info, s.PSK, s.PSKID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, &SealContext{*c}, nil
+}
+
+// Seal is the single-shot API: it sets up a context and seals one message
+// with it, returning enc and the ciphertext.
+func (s *Sender) Seal(aad, plaintext []byte) ([]byte, []byte, error) {
+	enc, c, err := s.Setup()
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err := c.Seal(aad, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ciphertext, nil
+}
+
+// Receiver holds the parameters with which a recipient sets up a context.
+// They must select the same mode as the sender's: PSK and PSKID select
+// ModePSK, SenderKey selects ModeAuth, and both select ModeAuthPSK.
+type Receiver struct {
+	Suite Suite
+	// PrivateKey is the recipient's private key.
+	PrivateKey ecc.PrivateKey
+	// Info is application-supplied information that binds the context.
+	Info []byte
+	// PSK is a pre-shared key of at least 32 bytes, and PSKID its identifier.
+	PSK, PSKID []byte
+	// SenderKey is the public key of the sender that the recipient expects.
+	SenderKey *eccfrog512ck2.CurvePoint
+}
+
+// Mode returns the mode that the receiver's parameters select.
+func (r *Receiver) Mode() (Mode, error) {
+	return mode(r.PSK, r.PSKID, r.SenderKey != nil)
+}
+
+// Setup decapsulates the shared secret from enc and sets up the context.
+func (r *Receiver) Setup(enc []byte) (*OpenContext, error) {
+	m, err := r.Mode()
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := decap(enc, r.PrivateKey, r.SenderKey)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.Suite.keySchedule(m, sharedSecret, r.Info, r.PSK, r.PSKID)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenContext{*c}, nil
+}
+
+// Open is the single-shot API: it sets up a context from enc and opens one
+// message with it.
+func (r *Receiver) Open(enc, aad, ciphertext []byte) ([]byte, error) {
+	c, err := r.Setup(enc)
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(aad, ciphertext)
+}
@@ -0,0 +1,249 @@
+package hpke_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/hpke"
+)
+
+func generateKeyPair(t *testing.T) ecc.PrivateKey {
+	t.Helper()
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	return privateKey
+}
+
+func TestSealOpenModes(t *testing.T) {
+	recipient := generateKeyPair(t)
+	recipientPublicKey, _ := recipient.DerivePublicKey()
+	sender := generateKeyPair(t)
+	senderPublicKey, _ := sender.DerivePublicKey()
+	psk := bytes.Repeat([]byte{0x42}, 32)
+	pskID := []byte("psk-1")
+
+	tests := []struct {
+		mode     hpke.Mode
+		psk      []byte
+		pskID    []byte
+		withAuth bool
+	}{
+		{hpke.ModeBase, nil, nil, false},
+		{hpke.ModePSK, psk, pskID, false},
+		{hpke.ModeAuth, nil, nil, true},
+		{hpke.ModeAuthPSK, psk, pskID, true},
+	}
+	for _, aead := range []hpke.AEAD{hpke.AEADAES256GCM, hpke.AEADChaCha20Poly1305} {
+		suite := hpke.Suite{KDF: hpke.KDFHKDFSHA512, AEAD: aead}
+		for _, tt := range tests {
+			s := hpke.Sender{Suite: suite, RecipientKey: recipientPublicKey, Info: []byte("info"), PSK: tt.psk, PSKID: tt.pskID}
+			r := hpke.Receiver{Suite: suite, PrivateKey: recipient, Info: []byte("info"), PSK: tt.psk, PSKID: tt.pskID}
+			if tt.withAuth {
+				s.PrivateKey = &sender
+				r.SenderKey = &senderPublicKey
+			}
+			if mode, err := s.Mode(); err != nil || mode != tt.mode {
+				t.Fatalf("Sender mode is %d, %v; want %d", mode, err, tt.mode)
+			}
+
+			enc, ciphertext, err := s.Seal([]byte("aad"), []byte("Hello, HPKE!"))
+			if err != nil {
+				t.Fatalf("Mode %d: failed to seal: %v", tt.mode, err)
+			}
+			plaintext, err := r.Open(enc, []byte("aad"), ciphertext)
+			if err != nil {
+				t.Fatalf("Mode %d: failed to open: %v", tt.mode, err)
+			}
+			if string(plaintext) != "Hello, HPKE!" {
+				t.Errorf("Mode %d: plaintext mismatch", tt.mode)
+			}
+
+			if _, err := r.Open(enc, []byte("other aad"), ciphertext); !errors.Is(err, hpke.ErrOpen) {
+				t.Errorf("Mode %d: expected ErrOpen for wrong AAD, got %v", tt.mode, err)
+			}
+			wrongInfo := r
+			wrongInfo.Info = []byte("other info")
+			if _, err := wrongInfo.Open(enc, []byte("aad"), ciphertext); err == nil {
+				t.Errorf("Mode %d: expected error for wrong info", tt.mode)
+			}
+		}
+	}
+}
+
+func TestModeMismatch(t *testing.T) {
+	suite := hpke.Suite{KDF: hpke.KDFHKDFSHA512, AEAD: hpke.AEADAES256GCM}
+	recipient := generateKeyPair(t)
+	recipientPublicKey, _ := recipient.DerivePublicKey()
+	sender := generateKeyPair(t)
+	other := generateKeyPair(t)
+	otherPublicKey, _ := other.DerivePublicKey()
+
+	s := hpke.Sender{Suite: suite, RecipientKey: recipientPublicKey, PrivateKey: &sender}
+	enc, ciphertext, err := s.Seal(nil, []byte("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The wrong sender, or no sender at all, fails to open
+	r := hpke.Receiver{Suite: suite, PrivateKey: recipient, SenderKey: &otherPublicKey}
+	if _, err := r.Open(enc, nil, ciphertext); err == nil {
+		t.Error("Expected error for wrong sender key")
+	}
+	r.SenderKey = nil
+	if _, err := r.Open(enc, nil, ciphertext); err == nil {
+		t.Error("Expected error for base mode receiver")
+	}
+
+	// The wrong PSK fails to open
+	psk := bytes.Repeat([]byte{1}, 32)
+	s = hpke.Sender{Suite: suite, RecipientKey: recipientPublicKey, PSK: psk, PSKID: []byte("id")}
+	enc, ciphertext, err = s.Seal(nil, []byte("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = hpke.Receiver{Suite: suite, PrivateKey: recipient, PSK: bytes.Repeat([]byte{2}, 32), PSKID: []byte("id")}
+	if _, err := r.Open(enc, nil, ciphertext); err == nil {
+		t.Error("Expected error for wrong PSK")
+	}
+}
+
+func TestInvalidPSK(t *testing.T) {
+	recipient := generateKeyPair(t)
+	recipientPublicKey, _ := recipient.DerivePublicKey()
+	suite := hpke.Suite{KDF: hpke.KDFHKDFSHA512, AEAD: hpke.AEADAES256GCM}
+	for _, s := range []hpke.Sender{
+		{Suite: suite, RecipientKey: recipientPublicKey, PSK: bytes.Repeat([]byte{1}, 32)},
+		{Suite: suite, RecipientKey: recipientPublicKey, PSKID: []byte("id")},
+		{Suite: suite, RecipientKey: recipientPublicKey, PSK: []byte("short"), PSKID: []byte("id")},
+	} {
+		if _, _, err := s.Setup(); err == nil {
+			t.Errorf("Expected error for PSK %q and ID %q", s.PSK, s.PSKID)
+		}
+	}
+	if _, _, err := (&hpke.Sender{Suite: hpke.Suite{KDF: 0x99, AEAD: hpke.AEADAES256GCM}, RecipientKey: recipientPublicKey}).Setup(); err == nil {
+		t.Error("Expected error for unsupported KDF")
+	}
+}
+
+func TestContext(t *testing.T) {
+	suite := hpke.Suite{KDF: hpke.KDFHKDFSHA256, AEAD: hpke.AEADChaCha20Poly1305}
+	recipient := generateKeyPair(t)
+	recipientPublicKey, _ := recipient.DerivePublicKey()
+
+	s := hpke.Sender{Suite: suite, RecipientKey: recipientPublicKey, Info: []byte("session")}
+	enc, sealer, err := s.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := hpke.Receiver{Suite: suite, PrivateKey: recipient, Info: []byte("session")}
+	opener, err := r.Setup(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ciphertexts [][]byte
+	for _, message := range []string{"first", "second", "third"} {
+		ciphertext, err := sealer.Seal(nil, []byte(message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphertexts = append(ciphertexts, ciphertext)
+	}
+
+	// Messages must be opened in order, and a failure does not advance the
+	// sequence number
+	if _, err := opener.Open(nil, ciphertexts[1]); !errors.Is(err, hpke.ErrOpen) {
+		t.Errorf("Expected ErrOpen for out of order message, got %v", err)
+	}
+	for i, message := range []string{"first", "second", "third"} {
+		plaintext, err := opener.Open(nil, ciphertexts[i])
+		if err != nil {
+			t.Fatalf("Failed to open message %d: %v", i, err)
+		}
+		if string(plaintext) != message {
+			t.Errorf("Message %d is %q, want %q", i, plaintext, message)
+		}
+	}
+
+	senderSecret, err := sealer.Export([]byte("context"), 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiverSecret, err := opener.Export([]byte("context"), 48)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(senderSecret) != 48 || !bytes.Equal(senderSecret, receiverSecret) {
+		t.Error("Exported secrets do not match")
+	}
+	otherSecret, _ := opener.Export([]byte("other context"), 48)
+	if bytes.Equal(senderSecret, otherSecret) {
+		t.Error("Exported secrets for different contexts match")
+	}
+	if _, err := sealer.Export(nil, 255*32+1); err == nil {
+		t.Error("Expected error for overlong export")
+	}
+}
+
+func TestExportOnly(t *testing.T) {
+	suite := hpke.Suite{KDF: hpke.KDFHKDFSHA512, AEAD: hpke.AEADExportOnly}
+	recipient := generateKeyPair(t)
+	recipientPublicKey, _ := recipient.DerivePublicKey()
+
+	enc, sealer, err := (&hpke.Sender{Suite: suite, RecipientKey: recipientPublicKey}).Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sealer.Seal(nil, []byte("message")); !errors.Is(err, hpke.ErrExportOnly) {
+		t.Errorf("Expected ErrExportOnly, got %v", err)
+	}
+	opener, err := (&hpke.Receiver{Suite: suite, PrivateKey: recipient}).Setup(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := sealer.Export(nil, 32)
+	b, _ := opener.Export(nil, 32)
+	if !bytes.Equal(a, b) {
+		t.Error("Exported secrets do not match")
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	privateKey, publicKey, err := hpke.DeriveKeyPair(bytes.Repeat([]byte{7}, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := hpke.DeriveKeyPair(make([]byte, 63)); err == nil {
+		t.Error("Expected error for short input keying material")
+	}
+
+	encoded := hpke.MarshalPrivateKey(privateKey)
+	if len(encoded) != hpke.PrivateKeySize {
+		t.Errorf("Private key is %d bytes, want %d", len(encoded), hpke.PrivateKeySize)
+	}
+	parsed, err := hpke.ParsePrivateKey(encoded)
+	if err != nil || parsed.GetKey().Cmp(privateKey.GetKey()) != 0 {
+		t.Errorf("Private key round trip failed: %v", err)
+	}
+
+	encodedPublic := hpke.MarshalPublicKey(publicKey)
+	if len(encodedPublic) != hpke.PublicKeySize {
+		t.Errorf("Public key is %d bytes, want %d", len(encodedPublic), hpke.PublicKeySize)
+	}
+	parsedPublic, err := hpke.ParsePublicKey(encodedPublic)
+	if err != nil || !parsedPublic.Equal(publicKey) {
+		t.Errorf("Public key round trip failed: %v", err)
+	}
+	if _, err := hpke.ParsePublicKey(publicKey.MarshalSEC1(true)); err == nil {
+		t.Error("Expected error for compressed public key")
+	}
+	corrupted := append([]byte{}, encodedPublic...)
+	corrupted[len(corrupted)-1] ^= 1
+	if _, err := hpke.ParsePublicKey(corrupted); err == nil {
+		t.Error("Expected error for point not on the curve")
+	}
+}
@@ -0,0 +1,181 @@
+package hpke
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+)
+
+// KEM identifies a key encapsulation mechanism.
+type KEM uint16
+
+// KEMEccFrog512ck2HKDFSHA512 is DHKEM(EccFrog512ck2, HKDF-SHA512). It is not
+// registered with IANA.
+const KEMEccFrog512ck2HKDFSHA512 KEM = 0xFF12
+
+// Sizes of the DHKEM's encodings, in bytes.
+const (
+	// PublicKeySize is Npk, the size of an encoded public key and of enc.
+	PublicKeySize = 129
+	// PrivateKeySize is Nsk, the size of an encoded private key.
+	PrivateKeySize = 64
+
+	// sharedSecretSize is Nsecret, the output size of HKDF-SHA512.
+	sharedSecretSize = 64
+)
+
+var kemSuiteID = binary.BigEndian.AppendUint16([]byte("KEM"), uint16(KEMEccFrog512ck2HKDFSHA512))
+
+// kemKDF is the KDF of the DHKEM, which is independent of the suite's KDF.
+var kemKDF = labeledKDF{hash: sha512.New, suiteID: kemSuiteID}
+
+// MarshalPublicKey encodes a public key as SerializePublicKey specifies, as
+// an uncompressed SEC1 point.
+func MarshalPublicKey(publicKey eccfrog512ck2.CurvePoint) []byte {
+	return publicKey.MarshalSEC1(false)
+}
+
+// ParsePublicKey decodes a public key encoded by MarshalPublicKey.
+func ParsePublicKey(data []byte) (eccfrog512ck2.CurvePoint, error) {
+	if len(data) != PublicKeySize || data[0] != 0x04 {
+		return eccfrog512ck2.CurvePoint{}, errors.New("hpke: invalid public key encoding")
+	}
+	publicKey, err := ecc.ParsePublicKeySEC1(data)
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, errors.New("hpke: invalid public key")
+	}
+	return publicKey, nil
+}
+
+// MarshalPrivateKey encodes a private key as SerializePrivateKey specifies,
+// as a PrivateKeySize-byte big-endian integer.
+func MarshalPrivateKey(privateKey ecc.PrivateKey) []byte {
+	return privateKey.GetKey().FillBytes(make([]byte, PrivateKeySize))
+}
+
+// ParsePrivateKey decodes a private key encoded by MarshalPrivateKey.
+func ParsePrivateKey(data []byte) (ecc.PrivateKey, error) {
+	if len(data) != PrivateKeySize {
+		return ecc.PrivateKey{}, errors.New("hpke: invalid private key encoding")
+	}
+	privateKey, err := ecc.ParsePrivateKeySEC1(data)
+	if err != nil {
+		return ecc.PrivateKey{}, errors.New("hpke: invalid private key")
+	}
+	return privateKey, nil
+}
+
+// DeriveKeyPair deterministically derives a key pair from the input keying
+// material ikm, which must be at least PrivateKeySize bytes of uniformly
+// random data.
+//
+// Like the RFC's DHKEMs over the NIST curves, it samples candidate scalars
+// until one is in range.
+func DeriveKeyPair(ikm []byte) (ecc.PrivateKey, eccfrog512ck2.CurvePoint, error) {
+	if len(ikm) < PrivateKeySize {
+		return ecc.PrivateKey{}, eccfrog512ck2.CurvePoint{}, errors.New("hpke: input keying material is too short")
+	}
+	prk := kemKDF.labeledExtract(nil, "dkp_prk", ikm)
+	order := eccfrog512ck2.GeneratorOrder()
+	for counter := 0; counter < 256; counter++ {
+		// The order is 512 bits long, so every bit of the candidate is kept
+		candidate := kemKDF.labeledExpand(prk, "candidate", []byte{byte(counter)}, PrivateKeySize)
+		sk := new(big.Int).SetBytes(candidate)
+		if sk.Sign() == 0 || sk.Cmp(order) >= 0 {
+			continue
+		}
+		privateKey, err := ecc.ParsePrivateKeySEC1(candidate)
+		if err != nil {
+			return ecc.PrivateKey{}, eccfrog512ck2.CurvePoint{}, err
+		}
+		publicKey, err := privateKey.DerivePublicKey()
+		if err != nil {
+			return ecc.PrivateKey{}, eccfrog512ck2.CurvePoint{}, err
+		}
+		return privateKey, publicKey, nil
+	}
+	return ecc.PrivateKey{}, eccfrog512ck2.CurvePoint{}, errors.New("hpke: failed to derive a key pair")
+}
+
+// dh computes the Diffie-Hellman shared secret of privateKey and publicKey.
+func dh(privateKey ecc.PrivateKey, publicKey eccfrog512ck2.CurvePoint) ([]byte, error) {
+	z, err := ecdh.ECDHPrivateKey(privateKey).DeriveFixedSizeSharedSecret(publicKey)
+	if err != nil {
+		return nil, errors.New("hpke: invalid Diffie-Hellman shared secret")
+	}
+	return z, nil
+}
+
+// extractAndExpand derives the KEM shared secret from the Diffie-Hellman
+// output and the KEM context.
+func extractAndExpand(dh, kemContext []byte) []byte {
+	prk := kemKDF.labeledExtract(nil, "eae_prk", dh)
+	return kemKDF.labeledExpand(prk, "shared_secret", kemContext, sharedSecretSize)
+}
+
+// encap is Encap, or AuthEncap if senderKey is not nil, with the ephemeral
+// key ephemeralKey. It returns the shared secret and enc.
+func encap(
+	recipientKey eccfrog512ck2.CurvePoint,
+	senderKey *ecc.PrivateKey,
+	ephemeralKey ecc.PrivateKey,
+) ([]byte, []byte, error) {
+	ephemeralPublicKey, err := ephemeralKey.DerivePublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	z, err := dh(ephemeralKey, recipientKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := MarshalPublicKey(ephemeralPublicKey)
+	kemContext := append(append([]byte{}, enc...), MarshalPublicKey(recipientKey)...)
+	if senderKey != nil {
+		zs, err := dh(*senderKey, recipientKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		senderPublicKey, err := senderKey.DerivePublicKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		z = append(z, zs...)
+		kemContext = append(kemContext, MarshalPublicKey(senderPublicKey)...)
+	}
+	return extractAndExpand(z, kemContext), enc, nil
+}
+
+// decap is Decap, or AuthDecap if senderKey is not nil.
+func decap(
+	enc []byte,
+	recipientKey ecc.PrivateKey,
+	senderKey *eccfrog512ck2.CurvePoint,
+) ([]byte, error) {
+	ephemeralPublicKey, err := ParsePublicKey(enc)
+	if err != nil {
+		return nil, err
+	}
+	z, err := dh(recipientKey, ephemeralPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	recipientPublicKey, err := recipientKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(append([]byte{}, enc...), MarshalPublicKey(recipientPublicKey)...)
+	if senderKey != nil {
+		zs, err := dh(recipientKey, *senderKey)
+		if err != nil {
+			return nil, err
+		}
+		z = append(z, zs...)
+		kemContext = append(kemContext, MarshalPublicKey(*senderKey)...)
+	}
+	return extractAndExpand(z, kemContext), nil
+}
@@ -0,0 +1,272 @@
+package hpke
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+)
+
+var update = flag.Bool("update", false, "regenerate testdata/test-vectors.json")
+
+const vectorsFile = "testdata/test-vectors.json"
+
+// hexBytes is a byte string that is hex encoded in JSON, as in the RFC's
+// test vectors.
+type hexBytes []byte
+
+func (h hexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(h))
+}
+
+func (h *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	b, err := hex.DecodeString(s)
+	*h = b
+	return err
+}
+
+// vector has the layout of the test vectors published with RFC 9180.
+type vector struct {
+	Mode               Mode               `json:"mode"`
+	KEMID              KEM                `json:"kem_id"`
+	KDFID              KDF                `json:"kdf_id"`
+	AEADID             AEAD               `json:"aead_id"`
+	Info               hexBytes           `json:"info"`
+	IKMR               hexBytes           `json:"ikmR"`
+	IKMS               hexBytes           `json:"ikmS,omitempty"`
+	IKME               hexBytes           `json:"ikmE"`
+	SKRm               hexBytes           `json:"skRm"`
+	SKSm               hexBytes           `json:"skSm,omitempty"`
+	SKEm               hexBytes           `json:"skEm"`
+	PSK                hexBytes           `json:"psk,omitempty"`
+	PSKID              hexBytes           `json:"psk_id,omitempty"`
+	PKRm               hexBytes           `json:"pkRm"`
+	PKSm               hexBytes           `json:"pkSm,omitempty"`
+	PKEm               hexBytes           `json:"pkEm"`
+	Enc                hexBytes           `json:"enc"`
+	SharedSecret       hexBytes           `json:"shared_secret"`
+	KeyScheduleContext hexBytes           `json:"key_schedule_context"`
+	Secret             hexBytes           `json:"secret"`
+	Key                hexBytes           `json:"key"`
+	BaseNonce          hexBytes           `json:"base_nonce"`
+	ExporterSecret     hexBytes           `json:"exporter_secret"`
+	Encryptions        []vectorEncryption `json:"encryptions"`
+	Exports            []vectorExport     `json:"exports"`
+}
+
+type vectorEncryption struct {
+	AAD        hexBytes `json:"aad"`
+	Ciphertext hexBytes `json:"ciphertext"`
+	Nonce      hexBytes `json:"nonce"`
+	Plaintext  hexBytes `json:"plaintext"`
+}
+
+type vectorExport struct {
+	ExporterContext hexBytes `json:"exporter_context"`
+	L               int      `json:"L"`
+	ExportedValue   hexBytes `json:"exported_value"`
+}
+
+func mustDeriveKeyPair(t *testing.T, ikm []byte) ecc.PrivateKey {
+	t.Helper()
+	privateKey, _, err := DeriveKeyPair(ikm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateKey
+}
+
+// fill computes every output of v from its inputs.
+func (v *vector) fill(t *testing.T) {
+	t.Helper()
+	suite := Suite{KDF: v.KDFID, AEAD: v.AEADID}
+	recipientKey := mustDeriveKeyPair(t, v.IKMR)
+	ephemeralKey := mustDeriveKeyPair(t, v.IKME)
+	recipientPublicKey, _ := recipientKey.DerivePublicKey()
+	ephemeralPublicKey, _ := ephemeralKey.DerivePublicKey()
+	v.SKRm, v.PKRm = MarshalPrivateKey(recipientKey), MarshalPublicKey(recipientPublicKey)
+	v.SKEm, v.PKEm = MarshalPrivateKey(ephemeralKey), MarshalPublicKey(ephemeralPublicKey)
+
+	sender := Sender{Suite: suite, RecipientKey: recipientPublicKey, Info: v.Info, PSK: v.PSK, PSKID: v.PSKID}
+	if v.IKMS != nil {
+		senderKey := mustDeriveKeyPair(t, v.IKMS)
+		senderPublicKey, _ := senderKey.DerivePublicKey()
+		v.SKSm, v.PKSm = MarshalPrivateKey(senderKey), MarshalPublicKey(senderPublicKey)
+		sender.PrivateKey = &senderKey
+	}
+
+	sharedSecret, enc, err := encap(recipientPublicKey, sender.PrivateKey, ephemeralKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.SharedSecret, v.Enc = sharedSecret, enc
+	_, s, err := suite.schedule(v.Mode, sharedSecret, v.Info, v.PSK, v.PSKID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.KeyScheduleContext, v.Secret, v.Key = s.keyScheduleContext, s.secret, s.key
+	v.BaseNonce, v.ExporterSecret = s.baseNonce, s.exporterSecret
+
+	_, c, err := sender.setup(ephemeralKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range v.Encryptions {
+		e := &v.Encryptions[i]
+		if c.aead != nil {
+			e.Nonce = computeNonce(v.BaseNonce, uint64(i))
+		}
+		if e.Ciphertext, err = c.Seal(e.AAD, e.Plaintext); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range v.Exports {
+		x := &v.Exports[i]
+		if x.ExportedValue, err = c.Export(x.ExporterContext, x.L); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// seed returns deterministic input keying material for generated vectors, so
+// that regenerating them does not change the file.
+func seed(label string) []byte {
+	sum := sha512.Sum512([]byte(label))
+	return sum[:]
+}
+
+func generateVectors(t *testing.T) []vector {
+	var vectors []vector
+	for _, kdf := range []KDF{KDFHKDFSHA256, KDFHKDFSHA512} {
+		for _, aead := range []AEAD{AEADAES256GCM, AEADChaCha20Poly1305, AEADExportOnly} {
+			for _, m := range []Mode{ModeBase, ModePSK, ModeAuth, ModeAuthPSK} {
+				name := fmt.Sprintf("%d-%d-%d", m, kdf, aead)
+				v := vector{
+					Mode:   m,
+					KEMID:  KEMEccFrog512ck2HKDFSHA512,
+					KDFID:  kdf,
+					AEADID: aead,
+					Info:   []byte("Ode on a Grecian Urn"),
+					IKMR:   seed(name + " ikmR"),
+					IKME:   seed(name + " ikmE"),
+				}
+				if m&ModePSK != 0 {
+					v.PSK = seed(name + " psk")[:32]
+					v.PSKID = []byte("Ennyn Durin aran Moria")
+				}
+				if m&ModeAuth != 0 {
+					v.IKMS = seed(name + " ikmS")
+				}
+				if aead != AEADExportOnly {
+					for i := 0; i < 10; i++ {
+						v.Encryptions = append(v.Encryptions, vectorEncryption{
+							AAD:       []byte(fmt.Sprintf("Count-%d", i)),
+							Plaintext: []byte("Beauty is truth, truth beauty"),
+						})
+					}
+				}
+				for _, ctx := range []string{"", "\x00", "TestContext"} {
+					v.Exports = append(v.Exports, vectorExport{ExporterContext: []byte(ctx), L: 32})
+				}
+				v.fill(t)
+				vectors = append(vectors, v)
+			}
+		}
+	}
+	return vectors
+}
+
+func TestVectors(t *testing.T) {
+	if *update {
+		data, err := json.MarshalIndent(generateVectors(t), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(vectorsFile, append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(vectorsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) == 0 {
+		t.Fatal("no test vectors")
+	}
+
+	for _, want := range vectors {
+		t.Run(fmt.Sprintf("mode=%d,kdf=%d,aead=%d", want.Mode, want.KDFID, want.AEADID), func(t *testing.T) {
+			if want.KEMID != KEMEccFrog512ck2HKDFSHA512 {
+				t.Skipf("unsupported KEM %#x", want.KEMID)
+			}
+
+			got := want
+			got.Encryptions = append([]vectorEncryption(nil), want.Encryptions...)
+			got.Exports = append([]vectorExport(nil), want.Exports...)
+			got.fill(t)
+			gotJSON, _ := json.MarshalIndent(got, "", "  ")
+			wantJSON, _ := json.MarshalIndent(want, "", "  ")
+			if !bytes.Equal(gotJSON, wantJSON) {
+				t.Fatalf("vector mismatch:\ngot:  %s\nwant: %s", gotJSON, wantJSON)
+			}
+
+			// The receiver, set up from the serialized keys, opens every
+			// encryption in order
+			recipientKey, err := ParsePrivateKey(want.SKRm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			receiver := Receiver{
+				Suite:      Suite{KDF: want.KDFID, AEAD: want.AEADID},
+				PrivateKey: recipientKey,
+				Info:       want.Info,
+				PSK:        want.PSK,
+				PSKID:      want.PSKID,
+			}
+			if want.PKSm != nil {
+				senderKey, err := ParsePublicKey(want.PKSm)
+				if err != nil {
+					t.Fatal(err)
+				}
+				receiver.SenderKey = &senderKey
+			}
+			c, err := receiver.Setup(want.Enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, e := range want.Encryptions {
+				plaintext, err := c.Open(e.AAD, e.Ciphertext)
+				if err != nil {
+					t.Fatalf("encryption %d: %v", i, err)
+				}
+				if !bytes.Equal(plaintext, e.Plaintext) {
+					t.Errorf("encryption %d: plaintext mismatch", i)
+				}
+			}
+			for i, x := range want.Exports {
+				value, err := c.Export(x.ExporterContext, x.L)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(value, x.ExportedValue) {
+					t.Errorf("export %d: value mismatch", i)
+				}
+			}
+		})
+	}
+}
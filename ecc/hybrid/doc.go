@@ -0,0 +1,16 @@
+// Package hybrid implements a post-quantum hybrid key encapsulation
+// mechanism that combines ephemeral-static ECDH over EccFrog512ck2 with
+// ML-KEM-1024 (FIPS 203).
+//
+// The shared key stays secret as long as either component is unbroken. Both
+// KEMs run side by side and their shared secrets are combined as in X-Wing
+// (draft-connolly-cfrg-xwing-kem): the combined key is SHA3-256 over the
+// ML-KEM shared secret, the ECDH shared secret, the ECDH ciphertext and the
+// recipient's ECDH public key, followed by a label that is distinct from
+// X-Wing's. The ML-KEM ciphertext and public key are not hashed, since
+// ML-KEM's own key derivation already binds them.
+//
+// Public keys and ciphertexts are the ML-KEM encoding followed by an
+// uncompressed SEC1 point. Private keys are the 64-byte ML-KEM seed followed
+// by the 64-byte ECDH scalar.
+package hybrid
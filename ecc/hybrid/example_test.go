@@ -0,0 +1,34 @@
+package hybrid_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/hybrid"
+)
+
+func ExamplePublicKey_Encapsulate() {
+	privateKey, err := hybrid.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	publicKey, err := privateKey.PublicKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The sender encapsulates a shared key to the recipient's public key
+	sharedKey, ciphertext, err := publicKey.Encapsulate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The recipient recovers it from the ciphertext
+	recovered, err := privateKey.Decapsulate(ciphertext)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(bytes.Equal(sharedKey, recovered))
+	// Output: true
+}
@@ -0,0 +1,187 @@
+package hybrid
+
+import (
+	"crypto/mlkem"
+	"crypto/sha3"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+)
+
+const (
+	// pointSize is the size of an uncompressed SEC1 point.
+	pointSize = 129
+	// scalarSize is the size of an ECDH private key.
+	scalarSize = 64
+
+	// PublicKeySize is the size of an encoded public key.
+	PublicKeySize = mlkem.EncapsulationKeySize1024 + pointSize
+	// PrivateKeySize is the size of an encoded private key.
+	PrivateKeySize = mlkem.SeedSize + scalarSize
+	// CiphertextSize is the size of a ciphertext produced by Encapsulate.
+	CiphertextSize = mlkem.CiphertextSize1024 + pointSize
+	// SharedKeySize is the size of the shared key.
+	SharedKeySize = 32
+)
+
+// label separates the combiner from X-Wing's and from other hybrids.
+var label = []byte("EccFrog512ck2+ML-KEM-1024")
+
+// PublicKey is a hybrid encapsulation key.
+type PublicKey struct {
+	mlkem *mlkem.EncapsulationKey1024
+	ecdh  eccfrog512ck2.CurvePoint
+}
+
+// PrivateKey is a hybrid decapsulation key.
+type PrivateKey struct {
+	mlkem *mlkem.DecapsulationKey1024
+	ecdh  ecc.PrivateKey
+}
+
+// GenerateKey generates a new hybrid private key.
+func GenerateKey() (*PrivateKey, error) {
+	mlkemKey, err := mlkem.GenerateKey1024()
+	if err != nil {
+		return nil, err
+	}
+	ecdhKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{mlkem: mlkemKey, ecdh: ecdhKey}, nil
+}
+
+// NewPrivateKey decodes a private key encoded by PrivateKey.Bytes.
+func NewPrivateKey(data []byte) (*PrivateKey, error) {
+	if len(data) != PrivateKeySize {
+		return nil, errors.New("hybrid: invalid private key length")
+	}
+	mlkemKey, err := mlkem.NewDecapsulationKey1024(data[:mlkem.SeedSize])
+	if err != nil {
+		return nil, err
+	}
+	ecdhKey, err := ecc.ParsePrivateKeySEC1(data[mlkem.SeedSize:])
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{mlkem: mlkemKey, ecdh: ecdhKey}, nil
+}
+
+// Bytes encodes the private key as the ML-KEM seed followed by the
+// fixed-size ECDH scalar.
+func (k *PrivateKey) Bytes() []byte {
+	out := append([]byte{}, k.mlkem.Bytes()...)
+	return append(out, k.ecdh.GetKey().FillBytes(make([]byte, scalarSize))...)
+}
+
+// PublicKey returns the public key of k.
+func (k *PrivateKey) PublicKey() (*PublicKey, error) {
+	ecdhPublicKey, err := k.ecdh.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{mlkem: k.mlkem.EncapsulationKey(), ecdh: ecdhPublicKey}, nil
+}
+
+// NewPublicKey decodes a public key encoded by PublicKey.Bytes.
+func NewPublicKey(data []byte) (*PublicKey, error) {
+	if len(data) != PublicKeySize {
+		return nil, errors.New("hybrid: invalid public key length")
+	}
+	mlkemKey, err := mlkem.NewEncapsulationKey1024(data[:mlkem.EncapsulationKeySize1024])
+	if err != nil {
+		return nil, err
+	}
+	ecdhKey, err := parsePoint(data[mlkem.EncapsulationKeySize1024:])
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{mlkem: mlkemKey, ecdh: ecdhKey}, nil
+}
+
+// Bytes encodes the public key as the ML-KEM encapsulation key followed by
+// the uncompressed ECDH public key.
+func (k *PublicKey) Bytes() []byte {
+	return append(append([]byte{}, k.mlkem.Bytes()...), k.ecdh.MarshalSEC1(false)...)
+}
+
+// Equal reports whether k and other are the same public key.
+func (k *PublicKey) Equal(other *PublicKey) bool {
+	return subtle.ConstantTimeCompare(k.Bytes(), other.Bytes()) == 1
+}
+
+func parsePoint(data []byte) (eccfrog512ck2.CurvePoint, error) {
+	if len(data) != pointSize || data[0] != 0x04 {
+		return eccfrog512ck2.CurvePoint{}, errors.New("hybrid: invalid ECDH point encoding")
+	}
+	return ecc.ParsePublicKeySEC1(data)
+}
+
+// combine derives the shared key from the component shared secrets, binding
+// the ECDH transcript.
+func combine(mlkemSecret, ecdhSecret, ecdhCiphertext []byte, ecdhPublicKey eccfrog512ck2.CurvePoint) []byte {
+	h := sha3.New256()
+	h.Write(mlkemSecret)
+	h.Write(ecdhSecret)
+	h.Write(ecdhCiphertext)
+	h.Write(ecdhPublicKey.MarshalSEC1(false))
+	h.Write(label)
+	return h.Sum(nil)
+}
+
+// Encapsulate generates a fresh shared key and the ciphertext that
+// encapsulates it to k.
+func (k *PublicKey) Encapsulate() ([]byte, []byte, error) {
+	mlkemSecret, mlkemCiphertext := k.mlkem.Encapsulate()
+
+	ephemeralKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	ephemeralPublicKey, err := ephemeralKey.DerivePublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	ecdhSecret, err := ecdh.ECDHPrivateKey(ephemeralKey).DeriveFixedSizeSharedSecret(k.ecdh)
+	if err != nil {
+		return nil, nil, err
+	}
+	ecdhCiphertext := ephemeralPublicKey.MarshalSEC1(false)
+
+	sharedKey := combine(mlkemSecret, ecdhSecret, ecdhCiphertext, k.ecdh)
+	return sharedKey, append(mlkemCiphertext, ecdhCiphertext...), nil
+}
+
+// Decapsulate recovers the shared key from a ciphertext produced by
+// Encapsulate.
+//
+// Like ML-KEM, a tampered ML-KEM ciphertext does not cause an error but
+// yields an unrelated shared key, so the key must be used with an
+// authenticated cipher. An invalid ECDH point is rejected.
+func (k *PrivateKey) Decapsulate(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) != CiphertextSize {
+		return nil, errors.New("hybrid: invalid ciphertext length")
+	}
+	mlkemSecret, err := k.mlkem.Decapsulate(ciphertext[:mlkem.CiphertextSize1024])
+	if err != nil {
+		return nil, err
+	}
+	ecdhCiphertext := ciphertext[mlkem.CiphertextSize1024:]
+	ephemeralPublicKey, err := parsePoint(ecdhCiphertext)
+	if err != nil {
+		return nil, err
+	}
+	ecdhSecret, err := ecdh.ECDHPrivateKey(k.ecdh).DeriveFixedSizeSharedSecret(ephemeralPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	ecdhPublicKey, err := k.ecdh.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return combine(mlkemSecret, ecdhSecret, ecdhCiphertext, ecdhPublicKey), nil
+}
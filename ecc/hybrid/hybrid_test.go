@@ -0,0 +1,162 @@
+package hybrid_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc/hybrid"
+)
+
+func TestEncapsulateDecapsulate(t *testing.T) {
+	privateKey, err := hybrid.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	publicKey, err := privateKey.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sharedKey, ciphertext, err := publicKey.Encapsulate()
+	if err != nil {
+		t.Fatalf("Failed to encapsulate: %v", err)
+	}
+	if len(sharedKey) != hybrid.SharedKeySize || len(ciphertext) != hybrid.CiphertextSize {
+		t.Fatalf("Unexpected sizes %d and %d", len(sharedKey), len(ciphertext))
+	}
+	decapsulated, err := privateKey.Decapsulate(ciphertext)
+	if err != nil {
+		t.Fatalf("Failed to decapsulate: %v", err)
+	}
+	if !bytes.Equal(sharedKey, decapsulated) {
+		t.Error("Shared keys do not match")
+	}
+
+	other, _, err := publicKey.Encapsulate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sharedKey, other) {
+		t.Error("Two encapsulations produced the same key")
+	}
+}
+
+func TestDecapsulateTampered(t *testing.T) {
+	privateKey, _ := hybrid.GenerateKey()
+	publicKey, _ := privateKey.PublicKey()
+	sharedKey, ciphertext, err := publicKey.Encapsulate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tampering with the ML-KEM ciphertext yields a different key
+	tampered := append([]byte{}, ciphertext...)
+	tampered[0] ^= 1
+	decapsulated, err := privateKey.Decapsulate(tampered)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if bytes.Equal(sharedKey, decapsulated) {
+		t.Error("Tampered ML-KEM ciphertext decapsulated to the same key")
+	}
+
+	// Tampering with the ECDH point is rejected
+	tampered = append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := privateKey.Decapsulate(tampered); err == nil {
+		t.Error("Expected error for invalid ECDH point")
+	}
+
+	if _, err := privateKey.Decapsulate(ciphertext[:len(ciphertext)-1]); err == nil {
+		t.Error("Expected error for short ciphertext")
+	}
+
+	// Another recipient derives a different key
+	otherKey, _ := hybrid.GenerateKey()
+	decapsulated, err = otherKey.Decapsulate(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sharedKey, decapsulated) {
+		t.Error("Wrong private key decapsulated to the same key")
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	privateKey, _ := hybrid.GenerateKey()
+	publicKey, _ := privateKey.PublicKey()
+
+	encoded := privateKey.Bytes()
+	if len(encoded) != hybrid.PrivateKeySize {
+		t.Fatalf("Private key is %d bytes, want %d", len(encoded), hybrid.PrivateKeySize)
+	}
+	parsed, err := hybrid.NewPrivateKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.Bytes(), encoded) {
+		t.Error("Private key round trip failed")
+	}
+
+	encodedPublic := publicKey.Bytes()
+	if len(encodedPublic) != hybrid.PublicKeySize {
+		t.Fatalf("Public key is %d bytes, want %d", len(encodedPublic), hybrid.PublicKeySize)
+	}
+	parsedPublic, err := hybrid.NewPublicKey(encodedPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsedPublic.Equal(publicKey) {
+		t.Error("Public key round trip failed")
+	}
+
+	// The parsed keys interoperate
+	sharedKey, ciphertext, err := parsedPublic.Encapsulate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decapsulated, err := parsed.Decapsulate(ciphertext)
+	if err != nil || !bytes.Equal(sharedKey, decapsulated) {
+		t.Errorf("Parsed keys do not interoperate: %v", err)
+	}
+
+	if _, err := hybrid.NewPublicKey(encodedPublic[1:]); err == nil {
+		t.Error("Expected error for short public key")
+	}
+}
+
+func TestPEM(t *testing.T) {
+	privateKey, _ := hybrid.GenerateKey()
+	publicKey, _ := privateKey.PublicKey()
+
+	pemBytes, err := privateKey.MarshalPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pemBytes, []byte("ECCFROG512CK2 MLKEM1024 PRIVATE KEY")) {
+		t.Errorf("Unexpected PEM type:\n%s", pemBytes)
+	}
+	parsed, err := hybrid.UnmarshalPEM(pemBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.Bytes(), privateKey.Bytes()) {
+		t.Error("Private key PEM round trip failed")
+	}
+
+	publicPEM, err := hybrid.MarshalPublicPEM(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedPublic, err := hybrid.UnmarshalPublicPEM(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsedPublic.Equal(publicKey) {
+		t.Error("Public key PEM round trip failed")
+	}
+
+	if _, err := hybrid.UnmarshalPEM(publicPEM); err == nil {
+		t.Error("Expected error for public key PEM parsed as private key")
+	}
+}
@@ -0,0 +1,45 @@
+package hybrid
+
+import (
+	"encoding/pem"
+	"fmt"
+)
+
+const (
+	privateKeyPEMType = "ECCFROG512CK2 MLKEM1024 PRIVATE KEY"
+	publicKeyPEMType  = "ECCFROG512CK2 MLKEM1024 PUBLIC KEY"
+)
+
+// MarshalPEM converts a private key to PEM format.
+func (k *PrivateKey) MarshalPEM() ([]byte, error) {
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: k.Bytes()}), nil
+}
+
+// UnmarshalPEM parses a PEM-encoded private key.
+func UnmarshalPEM(pemBytes []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	if block.Type != privateKeyPEMType {
+		return nil, fmt.Errorf("invalid PEM block type: %s", block.Type)
+	}
+	return NewPrivateKey(block.Bytes)
+}
+
+// MarshalPublicPEM converts a public key to PEM format.
+func MarshalPublicPEM(k *PublicKey) ([]byte, error) {
+	return pem.EncodeToMemory(&pem.Block{Type: publicKeyPEMType, Bytes: k.Bytes()}), nil
+}
+
+// UnmarshalPublicPEM parses a PEM-encoded public key.
+func UnmarshalPublicPEM(pemBytes []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	if block.Type != publicKeyPEMType {
+		return nil, fmt.Errorf("invalid PEM block type: %s", block.Type)
+	}
+	return NewPublicKey(block.Bytes)
+}
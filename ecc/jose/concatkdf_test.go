@@ -0,0 +1,19 @@
+package jose
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestConcatKDF checks the key derivation example of RFC 7518, Appendix C.
+func TestConcatKDF(t *testing.T) {
+	z := []byte{
+		158, 86, 217, 29, 129, 113, 53, 211, 114, 131, 66, 131, 191, 132, 38, 156,
+		251, 49, 110, 163, 218, 128, 106, 72, 246, 218, 167, 121, 140, 254, 144, 196,
+	}
+	expected := []byte{86, 170, 141, 234, 248, 35, 109, 32, 92, 34, 40, 205, 113, 167, 16, 26}
+	key := concatKDF(z, "A128GCM", []byte("Alice"), []byte("Bob"), 16)
+	if !bytes.Equal(key, expected) {
+		t.Errorf("concatKDF = %v, expected %v", key, expected)
+	}
+}
@@ -0,0 +1,16 @@
+// Package jose implements JSON Web Signatures (RFC 7515), JSON Web
+// Encryption (RFC 7516), JSON Web Keys (RFC 7517) and JSON Web Tokens
+// (RFC 7519) for EccFrog512ck2 keys.
+//
+// Signatures use the algorithm AlgEF512, ECDSA over EccFrog512ck2 with
+// SHA-512, whose signature is the 64-byte big-endian r and s concatenated,
+// like the registered ES512. Encryption uses the registered ECDH-ES and
+// ECDH-ES+A256KW key management algorithms, with an EccFrog512ck2 ephemeral
+// key in the epk header parameter, and A256GCM content encryption.
+// Authenticated encryption adds the sender's static key to the agreement
+// with ECDH-1PU (draft-madden-jose-ecdh-1pu-04).
+//
+// Keys are EC JWKs with the curve name CurveEccFrog512ck2. Neither it nor
+// AlgEF512 is registered with IANA, so other implementations will only
+// accept these keys and tokens if they are taught the same names.
+package jose
@@ -0,0 +1,97 @@
+package jose_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/jose"
+)
+
+func ExampleSignJWT() {
+	// The identity provider signs tokens with its current key
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate key:", err)
+		return
+	}
+	key, err := jose.NewPrivateJWK(privateKey, "2024-01")
+	if err != nil {
+		fmt.Println("Failed to create key:", err)
+		return
+	}
+	token, err := jose.SignJWT(jose.Claims{
+		Issuer:         "https://auth.example.com",
+		Subject:        "alice",
+		Audience:       jose.Audience{"https://api.example.com"},
+		ExpirationTime: jose.NewNumericDate(time.Now().Add(time.Hour)),
+	}, key)
+	if err != nil {
+		fmt.Println("Failed to sign token:", err)
+		return
+	}
+
+	// and publishes its public keys as a JWK Set
+	published, err := json.Marshal(jose.JWKSet{Keys: []*jose.JWK{key.Public()}})
+	if err != nil {
+		fmt.Println("Failed to marshal JWK Set:", err)
+		return
+	}
+
+	// The API gateway verifies tokens against the published keys
+	var set jose.JWKSet
+	if err := json.Unmarshal(published, &set); err != nil {
+		fmt.Println("Failed to parse JWK Set:", err)
+		return
+	}
+	claims, err := jose.VerifyJWT(token, &set, jose.Expected{
+		Issuer:   "https://auth.example.com",
+		Audience: "https://api.example.com",
+	}, nil)
+	if err != nil {
+		fmt.Println("Failed to verify token:", err)
+		return
+	}
+	fmt.Println(claims.Subject)
+	// Output: alice
+}
+
+func ExampleEncrypt() {
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate key:", err)
+		return
+	}
+	key, err := jose.NewPrivateJWK(privateKey, "enc-1")
+	if err != nil {
+		fmt.Println("Failed to create key:", err)
+		return
+	}
+
+	// Encrypt to the public key
+	jwe, err := jose.Encrypt([]byte("secret token"), jose.KeyAlgECDHESA256KW, jose.Header{}, key.Public())
+	if err != nil {
+		fmt.Println("Failed to encrypt:", err)
+		return
+	}
+	compact, err := jwe.CompactSerialize()
+	if err != nil {
+		fmt.Println("Failed to serialize:", err)
+		return
+	}
+
+	// Decrypt with the private key
+	parsed, err := jose.ParseEncrypted(compact)
+	if err != nil {
+		fmt.Println("Failed to parse:", err)
+		return
+	}
+	plaintext, err := parsed.Decrypt(key)
+	if err != nil {
+		fmt.Println("Failed to decrypt:", err)
+		return
+	}
+	fmt.Println(string(plaintext))
+	// Output: secret token
+}
@@ -0,0 +1,446 @@
+package jose_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/jose"
+)
+
+func generateJWK(t *testing.T, keyID string) *jose.JWK {
+	t.Helper()
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jose.NewPrivateJWK(privateKey, keyID)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	return key
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	key := generateJWK(t, "key-1")
+	key.Use = "sig"
+
+	for _, k := range []*jose.JWK{key, key.Public()} {
+		data, err := json.Marshal(k)
+		if err != nil {
+			t.Fatalf("Failed to marshal key: %v", err)
+		}
+		var parsed jose.JWK
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			t.Fatalf("Failed to parse key: %v", err)
+		}
+		if parsed.KeyID != k.KeyID || parsed.Use != k.Use || !parsed.PublicKey.Equal(k.PublicKey) {
+			t.Error("Parsed key does not match")
+		}
+		if (parsed.PrivateKey == nil) != (k.PrivateKey == nil) {
+			t.Error("Parsed key private part does not match")
+		}
+	}
+
+	data, _ := json.Marshal(key.Public())
+	if strings.Contains(string(data), `"d"`) {
+		t.Error("Public key contains private part")
+	}
+
+	// The thumbprint only depends on the public key
+	a, err := key.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := key.Public().Thumbprint()
+	if string(a) != string(b) || len(a) != 32 {
+		t.Error("Thumbprints do not match")
+	}
+}
+
+func TestJWKSet(t *testing.T) {
+	a := generateJWK(t, "a")
+	b := generateJWK(t, "b")
+	data, err := json.Marshal(jose.JWKSet{Keys: []*jose.JWK{a.Public(), b.Public()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A set with a key of another type, which is skipped
+	var raw map[string][]json.RawMessage
+	json.Unmarshal(data, &raw)
+	raw["keys"] = append(raw["keys"], json.RawMessage(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`))
+	data, _ = json.Marshal(raw)
+
+	var set jose.JWKSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		t.Fatalf("Failed to parse JWK Set: %v", err)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(set.Keys))
+	}
+	if keys := set.LookupKeyID("b"); len(keys) != 1 || !keys[0].PublicKey.Equal(b.PublicKey) {
+		t.Error("Failed to look up key by ID")
+	}
+}
+
+func TestJWSCompact(t *testing.T) {
+	key := generateJWK(t, "signer")
+	jws, err := jose.Sign([]byte("payload"), jose.Header{ContentType: "text/plain"}, key)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	compact, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := jose.ParseSigned(compact)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	h := parsed.Signatures[0].Protected
+	if h.Algorithm != jose.AlgEF512 || h.KeyID != "signer" || h.ContentType != "text/plain" {
+		t.Errorf("Unexpected header %+v", h)
+	}
+	if len(parsed.Signatures[0].Signature) != 128 {
+		t.Errorf("Expected a 128-byte signature, got %d bytes", len(parsed.Signatures[0].Signature))
+	}
+	payload, err := parsed.Verify(key.Public())
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if string(payload) != "payload" {
+		t.Errorf("Unexpected payload %q", payload)
+	}
+
+	if _, err := parsed.Verify(generateJWK(t, "").Public()); !errors.Is(err, jose.ErrVerification) {
+		t.Errorf("Expected ErrVerification for wrong key, got %v", err)
+	}
+
+	parts := strings.Split(compact, ".")
+	tampered := parts[0] + ".cGF5bG9hZA." + parts[2]
+	if parts[1] == "cGF5bG9hZA" {
+		tampered = parts[0] + ".cGF5bG9hZQ." + parts[2]
+	}
+	parsed, err = jose.ParseSigned(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parsed.Verify(key.Public()); !errors.Is(err, jose.ErrVerification) {
+		t.Errorf("Expected ErrVerification for tampered payload, got %v", err)
+	}
+}
+
+func TestJWSJSON(t *testing.T) {
+	alice := generateJWK(t, "alice")
+	bob := generateJWK(t, "bob")
+	jws, err := jose.Sign([]byte("contract"), jose.Header{}, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jws.CompactSerialize(); err == nil {
+		t.Error("Expected error for compact serialization of two signatures")
+	}
+	data, err := jws.FullSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jose.ParseSigned(string(data))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	set := &jose.JWKSet{Keys: []*jose.JWK{bob.Public()}}
+	_, key, err := parsed.VerifyWithKeySet(set)
+	if err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+	if key.KeyID != "bob" {
+		t.Errorf("Verified with unexpected key %q", key.KeyID)
+	}
+
+	// The flattened syntax
+	var general struct {
+		Payload    string            `json:"payload"`
+		Signatures []json.RawMessage `json:"signatures"`
+	}
+	json.Unmarshal(data, &general)
+	var flattened map[string]any
+	json.Unmarshal(general.Signatures[0], &flattened)
+	flattened["payload"] = general.Payload
+	data, _ = json.Marshal(flattened)
+	parsed, err = jose.ParseSigned(string(data))
+	if err != nil {
+		t.Fatalf("Failed to parse flattened JWS: %v", err)
+	}
+	if _, err := parsed.Verify(alice.Public()); err != nil {
+		t.Errorf("Failed to verify flattened JWS: %v", err)
+	}
+}
+
+func TestJWSCritical(t *testing.T) {
+	key := generateJWK(t, "")
+	jws, err := jose.Sign([]byte("payload"), jose.Header{Critical: []string{"exp"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, _ := jws.CompactSerialize()
+	if _, err := jose.ParseSigned(compact); err == nil {
+		t.Error("Expected error for unsupported critical header parameter")
+	}
+}
+
+type customClaims struct {
+	jose.Claims
+	Scope string `json:"scope"`
+}
+
+func TestJWT(t *testing.T) {
+	key := generateJWK(t, "2024-01")
+	other := generateJWK(t, "2023-12")
+	set := &jose.JWKSet{Keys: []*jose.JWK{other.Public(), key.Public()}}
+	now := time.Unix(1700000000, 0)
+
+	token, err := jose.SignJWT(customClaims{
+		Claims: jose.Claims{
+			Issuer:         "https://auth.example.com",
+			Subject:        "user-1",
+			Audience:       jose.Audience{"https://api.example.com"},
+			ExpirationTime: jose.NewNumericDate(now.Add(time.Hour)),
+			NotBefore:      jose.NewNumericDate(now),
+			IssuedAt:       jose.NewNumericDate(now),
+		},
+		Scope: "read",
+	}, key)
+	if err != nil {
+		t.Fatalf("Failed to sign JWT: %v", err)
+	}
+
+	expected := jose.Expected{
+		Issuer:   "https://auth.example.com",
+		Audience: "https://api.example.com",
+		Time:     now.Add(time.Minute),
+	}
+	var custom customClaims
+	claims, err := jose.VerifyJWT(token, set, expected, &custom)
+	if err != nil {
+		t.Fatalf("Failed to verify JWT: %v", err)
+	}
+	if claims.Subject != "user-1" || custom.Scope != "read" {
+		t.Errorf("Unexpected claims %+v, %+v", claims, custom)
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*jose.Expected)
+		expected error
+	}{
+		{"expired", func(e *jose.Expected) { e.Time = now.Add(2 * time.Hour) }, jose.ErrTokenExpired},
+		{"not yet valid", func(e *jose.Expected) { e.Time = now.Add(-time.Minute) }, jose.ErrTokenNotValidYet},
+		{"issuer", func(e *jose.Expected) { e.Issuer = "https://evil.example.com" }, jose.ErrInvalidIssuer},
+		{"audience", func(e *jose.Expected) { e.Audience = "https://other.example.com" }, jose.ErrInvalidAudience},
+		{"no audience", func(e *jose.Expected) { e.Audience = "" }, jose.ErrInvalidAudience},
+		{"leeway", func(e *jose.Expected) { e.Time = now.Add(-time.Minute); e.Leeway = 2 * time.Minute }, nil},
+	}
+	for _, test := range tests {
+		e := expected
+		test.modify(&e)
+		if _, err := jose.VerifyJWT(token, set, e, nil); !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+
+	// A token whose key ID is not in the set
+	if _, err := jose.VerifyJWT(token, &jose.JWKSet{Keys: []*jose.JWK{other.Public()}}, expected, nil); !errors.Is(err, jose.ErrVerification) {
+		t.Errorf("Expected ErrVerification, got %v", err)
+	}
+}
+
+func TestAudience(t *testing.T) {
+	var c jose.Claims
+	if err := json.Unmarshal([]byte(`{"aud":["a","b"],"exp":1.5}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Audience) != 2 || *c.ExpirationTime != 1 {
+		t.Errorf("Unexpected claims %+v", c)
+	}
+	data, _ := json.Marshal(jose.Claims{Audience: jose.Audience{"a"}})
+	if string(data) != `{"aud":"a"}` {
+		t.Errorf("Unexpected encoding %s", data)
+	}
+}
+
+func TestJWEDirect(t *testing.T) {
+	key := generateJWK(t, "enc-1")
+	plaintext := []byte("The true sign of intelligence is not knowledge but imagination.")
+
+	jwe, err := jose.Encrypt(plaintext, jose.KeyAlgECDHES, jose.Header{AgreementPartyUInfo: "QWxpY2U", AgreementPartyVInfo: "Qm9i"}, key.Public())
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	compact, err := jwe.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := strings.Split(compact, "."); len(parts) != 5 || parts[1] != "" {
+		t.Errorf("Expected five parts with an empty encrypted key, got %q", compact)
+	}
+
+	parsed, err := jose.ParseEncrypted(compact)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	h := parsed.Protected
+	if h.Algorithm != jose.KeyAlgECDHES || h.Encryption != jose.EncA256GCM || h.KeyID != "enc-1" || h.EphemeralPublicKey == nil {
+		t.Errorf("Unexpected header %+v", h)
+	}
+	if h.EphemeralPublicKey.PrivateKey != nil {
+		t.Error("Ephemeral key has a private part")
+	}
+	decrypted, err := parsed.Decrypt(key)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if string(decrypted) != string(plaintext) {
+		t.Error("Decrypted plaintext does not match")
+	}
+
+	if _, err := parsed.Decrypt(generateJWK(t, "")); err == nil {
+		t.Error("Expected error for wrong key")
+	}
+	if _, err := jose.Encrypt(plaintext, jose.KeyAlgECDHES, jose.Header{}, key, generateJWK(t, "")); err == nil {
+		t.Error("Expected error for ECDH-ES with two recipients")
+	}
+
+	// Changing the protected header breaks the authentication tag
+	parts := strings.Split(compact, ".")
+	var header map[string]any
+	protected, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	json.Unmarshal(protected, &header)
+	header["cty"] = "JWT"
+	protected, _ = json.Marshal(header)
+	parts[0] = base64.RawURLEncoding.EncodeToString(protected)
+	parsed, err = jose.ParseEncrypted(strings.Join(parts, "."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parsed.Decrypt(key); err == nil {
+		t.Error("Expected error for modified protected header")
+	}
+}
+
+func TestJWEKeyWrap(t *testing.T) {
+	alice := generateJWK(t, "alice")
+	bob := generateJWK(t, "bob")
+	plaintext := []byte("for both of you")
+
+	// A single recipient has a compact serialization
+	jwe, err := jose.Encrypt(plaintext, jose.KeyAlgECDHESA256KW, jose.Header{}, alice.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, err := jwe.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jose.ParseEncrypted(compact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Recipients[0].EncryptedKey) != 40 {
+		t.Errorf("Expected a 40-byte wrapped key, got %d bytes", len(parsed.Recipients[0].EncryptedKey))
+	}
+	if decrypted, err := parsed.Decrypt(alice); err != nil || string(decrypted) != string(plaintext) {
+		t.Errorf("Failed to decrypt: %v", err)
+	}
+
+	// Several recipients need the JSON serialization
+	jwe, err = jose.Encrypt(plaintext, jose.KeyAlgECDHESA256KW, jose.Header{ContentType: "text/plain"}, alice.Public(), bob.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwe.CompactSerialize(); err == nil {
+		t.Error("Expected error for compact serialization of two recipients")
+	}
+	data, err := jwe.FullSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = jose.ParseEncrypted(string(data))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	for _, key := range []*jose.JWK{alice, bob} {
+		decrypted, err := parsed.Decrypt(key)
+		if err != nil {
+			t.Fatalf("Failed to decrypt for %s: %v", key.KeyID, err)
+		}
+		if string(decrypted) != string(plaintext) {
+			t.Error("Decrypted plaintext does not match")
+		}
+	}
+	if _, err := parsed.Decrypt(generateJWK(t, "carol")); err == nil {
+		t.Error("Expected error for wrong key")
+	}
+	if _, err := parsed.Decrypt(alice.Public()); err == nil {
+		t.Error("Expected error for public key")
+	}
+}
+
+func TestJWEAuthenticated(t *testing.T) {
+	alice := generateJWK(t, "alice")
+	bob := generateJWK(t, "bob")
+	mallory := generateJWK(t, "mallory")
+	plaintext := []byte("Hello, Bob! -- Alice")
+
+	jwe, err := jose.EncryptAuthenticated(plaintext, jose.Header{}, alice, bob.Public())
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	compact, err := jwe.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jose.ParseEncrypted(compact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := parsed.Protected
+	if h.Algorithm != jose.KeyAlgECDH1PU || h.SenderKeyID != "alice" || h.KeyID != "bob" {
+		t.Errorf("Unexpected header %+v", h)
+	}
+
+	decrypted, err := parsed.DecryptAuthenticated(bob, alice.Public())
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if string(decrypted) != string(plaintext) {
+		t.Error("Decrypted plaintext does not match")
+	}
+
+	// Mallory's key, whether under her key ID or Alice's, does not match
+	if _, err := parsed.DecryptAuthenticated(bob, mallory.Public()); err == nil {
+		t.Error("Expected error for wrong sender")
+	}
+	impostor := mallory.Public()
+	impostor.KeyID = "alice"
+	if _, err := parsed.DecryptAuthenticated(bob, impostor); err == nil {
+		t.Error("Expected error for wrong sender key")
+	}
+	if _, err := parsed.Decrypt(bob); err == nil {
+		t.Error("Expected error for anonymous decryption")
+	}
+
+	// An anonymous JWE is not accepted as authenticated
+	anonymous, err := jose.Encrypt(plaintext, jose.KeyAlgECDHES, jose.Header{}, bob.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.DecryptAuthenticated(bob, alice.Public()); err == nil {
+		t.Error("Expected error for authenticated decryption of an anonymous JWE")
+	}
+}
@@ -0,0 +1,511 @@
+package jose
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+)
+
+const (
+	// KeyAlgECDHES is ephemeral-static ECDH with the content key derived
+	// directly by the Concat KDF. It supports a single recipient.
+	KeyAlgECDHES = "ECDH-ES"
+	// KeyAlgECDHESA256KW is ephemeral-static ECDH with a key derived by the
+	// Concat KDF wrapping the content key with AES key wrap.
+	KeyAlgECDHESA256KW = "ECDH-ES+A256KW"
+
+	// KeyAlgECDH1PU is ECDH-1PU (one-pass unified model) key agreement, with
+	// the content key derived directly by the Concat KDF from both an
+	// ephemeral-static and a static-static exchange, which authenticates the
+	// sender. It supports a single recipient.
+	KeyAlgECDH1PU = "ECDH-1PU"
+
+	// EncA256GCM is AES-256-GCM content encryption.
+	EncA256GCM = "A256GCM"
+
+	contentKeySize = 32
+	gcmNonceSize   = 12
+	gcmTagSize     = 16
+)
+
+// concatKDF derives a key of keySize bytes from the shared secret z with the
+// Concat KDF of NIST SP 800-56A, using SHA-256, as RFC 7518 section 4.6.2
+// specifies.
+func concatKDF(z []byte, algorithmID string, apu, apv []byte, keySize int) []byte {
+	var otherInfo []byte
+	for _, field := range [][]byte{[]byte(algorithmID), apu, apv} {
+		otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(len(field)))
+		otherInfo = append(otherInfo, field...)
+	}
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(keySize*8))
+
+	var key []byte
+	for counter := uint32(1); len(key) < keySize; counter++ {
+		h := sha256.New()
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(z)
+		h.Write(otherInfo)
+		key = h.Sum(key)
+	}
+	return key[:keySize]
+}
+
+// ephemeralAgreement generates an ephemeral key and agrees on a shared
+// secret with recipient. It returns the public ephemeral key and the secret.
+func ephemeralAgreement(recipient *JWK) (*JWK, []byte, error) {
+	ephemeralPrivateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	ephemeralPublicKey, err := ephemeralPrivateKey.DerivePublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	z, err := ecdh.ECDHPrivateKey(ephemeralPrivateKey).DeriveFixedSizeSharedSecret(recipient.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewPublicJWK(ephemeralPublicKey, ""), z, nil
+}
+
+// rawKey is a key derivation function for the AES-GCM helpers that uses the
+// secret, which is already a content key, as is.
+func rawKey(secret cryptohelpers.SecretKey) ([32]byte, error) {
+	var key [32]byte
+	if len(secret) != len(key) {
+		return key, errors.New("jose: invalid content key length")
+	}
+	copy(key[:], secret)
+	return key, nil
+}
+
+// Recipient is one of the recipients of a JWE: its per-recipient header
+// and the content key encrypted for it.
+type Recipient struct {
+	Header       Header
+	EncryptedKey []byte
+}
+
+// JSONWebEncryption is an encrypted payload with one or more recipients.
+type JSONWebEncryption struct {
+	// Protected is the integrity-protected header, and Unprotected the
+	// shared unprotected header, which only the JSON serialization can
+	// carry.
+	Protected   Header
+	Unprotected Header
+	Recipients  []*Recipient
+	// AAD is the additional authenticated data of the JSON serialization,
+	// if any.
+	AAD        []byte
+	IV         []byte
+	Ciphertext []byte
+	Tag        []byte
+
+	rawProtected string
+}
+
+func (jwe *JSONWebEncryption) additionalData() []byte {
+	if jwe.AAD != nil {
+		return []byte(jwe.rawProtected + "." + b64.EncodeToString(jwe.AAD))
+	}
+	return []byte(jwe.rawProtected)
+}
+
+func partyInfo(h Header) ([]byte, []byte, error) {
+	apu, err := b64.DecodeString(h.AgreementPartyUInfo)
+	if err != nil {
+		return nil, nil, errors.New("jose: malformed apu header parameter")
+	}
+	apv, err := b64.DecodeString(h.AgreementPartyVInfo)
+	if err != nil {
+		return nil, nil, errors.New("jose: malformed apv header parameter")
+	}
+	return apu, apv, nil
+}
+
+// Encrypt encrypts plaintext with AES-256-GCM for the recipients, with the
+// key management algorithm alg. header holds any other header parameters,
+// such as cty or apu, which are integrity protected.
+//
+// With a single recipient all header parameters are protected, so that the
+// result has a compact serialization. With several, the per-recipient
+// parameters go in the recipients' unprotected headers.
+func Encrypt(plaintext []byte, alg string, header Header, recipients ...*JWK) (*JSONWebEncryption, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("jose: no recipients")
+	}
+	if header.Encryption == "" {
+		header.Encryption = EncA256GCM
+	}
+	if header.Encryption != EncA256GCM {
+		return nil, fmt.Errorf("jose: unsupported content encryption algorithm %q", header.Encryption)
+	}
+	apu, apv, err := partyInfo(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var cek []byte
+	switch alg {
+	case KeyAlgECDHES:
+		if len(recipients) != 1 {
+			return nil, errors.New("jose: ECDH-ES supports a single recipient")
+		}
+	case KeyAlgECDHESA256KW:
+		cek = make([]byte, contentKeySize)
+		if _, err := rand.Read(cek); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("jose: unsupported key management algorithm %q", alg)
+	}
+
+	jwe := &JSONWebEncryption{Protected: header}
+	for i, recipient := range recipients {
+		epk, z, err := ephemeralAgreement(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("jose: key agreement with recipient #%d failed: %v", i, err)
+		}
+		r := &Recipient{Header: Header{Algorithm: alg, KeyID: recipient.KeyID, EphemeralPublicKey: epk}}
+		if alg == KeyAlgECDHES {
+			cek = concatKDF(z, header.Encryption, apu, apv, contentKeySize)
+		} else {
+			kek := concatKDF(z, alg, apu, apv, contentKeySize)
+			if r.EncryptedKey, err = cryptohelpers.AESKeyWrap(kek, cek); err != nil {
+				return nil, err
+			}
+		}
+		jwe.Recipients = append(jwe.Recipients, r)
+	}
+	if len(jwe.Recipients) == 1 {
+		jwe.Protected = jwe.Protected.merge(jwe.Recipients[0].Header)
+		jwe.Recipients[0].Header = Header{}
+	}
+
+	if err := jwe.encryptContent(cek, plaintext); err != nil {
+		return nil, err
+	}
+	return jwe, nil
+}
+
+// EncryptAuthenticated encrypts plaintext with AES-256-GCM from sender to
+// recipient with KeyAlgECDH1PU, so that the recipient can check who sent
+// it. sender must have a private part; its key ID, if any, is put in the
+// skid header parameter.
+func EncryptAuthenticated(plaintext []byte, header Header, sender, recipient *JWK) (*JSONWebEncryption, error) {
+	senderPrivateKey, err := sender.privateKey()
+	if err != nil {
+		return nil, err
+	}
+	if header.Encryption == "" {
+		header.Encryption = EncA256GCM
+	}
+	if header.Encryption != EncA256GCM {
+		return nil, fmt.Errorf("jose: unsupported content encryption algorithm %q", header.Encryption)
+	}
+	apu, apv, err := partyInfo(header)
+	if err != nil {
+		return nil, err
+	}
+
+	epk, ze, err := ephemeralAgreement(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("jose: key agreement with recipient failed: %v", err)
+	}
+	zs, err := ecdh.ECDHPrivateKey(senderPrivateKey).DeriveFixedSizeSharedSecret(recipient.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("jose: key agreement with recipient failed: %v", err)
+	}
+	cek := concatKDF(append(ze, zs...), header.Encryption, apu, apv, contentKeySize)
+
+	header.Algorithm = KeyAlgECDH1PU
+	header.EphemeralPublicKey = epk
+	if header.KeyID == "" {
+		header.KeyID = recipient.KeyID
+	}
+	if header.SenderKeyID == "" {
+		header.SenderKeyID = sender.KeyID
+	}
+	jwe := &JSONWebEncryption{Protected: header, Recipients: []*Recipient{{}}}
+	if err := jwe.encryptContent(cek, plaintext); err != nil {
+		return nil, err
+	}
+	return jwe, nil
+}
+
+// encryptContent encodes the protected header and encrypts plaintext with
+// the content key.
+func (jwe *JSONWebEncryption) encryptContent(cek, plaintext []byte) error {
+	protected, err := json.Marshal(jwe.Protected)
+	if err != nil {
+		return err
+	}
+	jwe.rawProtected = b64.EncodeToString(protected)
+
+	result, err := cryptohelpers.AES256GCMEncryptWithAAD(rawKey)(cek, plaintext, jwe.additionalData())
+	if err != nil {
+		return err
+	}
+	jwe.IV = result.Nonce
+	split := len(result.CipherText) - gcmTagSize
+	jwe.Ciphertext, jwe.Tag = result.CipherText[:split], result.CipherText[split:]
+	return nil
+}
+
+// decryptContent decrypts the content with a candidate content key.
+func (jwe *JSONWebEncryption) decryptContent(cek []byte) ([]byte, error) {
+	return cryptohelpers.AES256GCMDecryptWithAAD(rawKey)(cek, cryptohelpers.AES256GCMResults{
+		CipherText: append(append([]byte{}, jwe.Ciphertext...), jwe.Tag...),
+		Nonce:      jwe.IV,
+	}, jwe.additionalData())
+}
+
+// Decrypt decrypts the JWE with key, which must have a private part. If both
+// the key and a recipient have a key ID, the recipient is only tried if they
+// match. Recipients using KeyAlgECDH1PU are skipped; see
+// DecryptAuthenticated.
+func (jwe *JSONWebEncryption) Decrypt(key *JWK) ([]byte, error) {
+	return jwe.decrypt(key, nil)
+}
+
+// DecryptAuthenticated decrypts a JWE encrypted with EncryptAuthenticated by
+// sender. It fails if the JWE was not encrypted by the holder of the
+// sender's private key.
+func (jwe *JSONWebEncryption) DecryptAuthenticated(key, sender *JWK) ([]byte, error) {
+	return jwe.decrypt(key, sender)
+}
+
+func (jwe *JSONWebEncryption) decrypt(key, sender *JWK) ([]byte, error) {
+	privateKey, err := key.privateKey()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range jwe.Recipients {
+		h := jwe.Protected.merge(jwe.Unprotected).merge(r.Header)
+		if h.Encryption != EncA256GCM {
+			return nil, fmt.Errorf("jose: unsupported content encryption algorithm %q", h.Encryption)
+		}
+		if key.KeyID != "" && h.KeyID != "" && key.KeyID != h.KeyID {
+			continue
+		}
+		cek, err := jwe.recipientKey(h, r, privateKey, sender)
+		if err != nil {
+			return nil, err
+		}
+		if cek == nil {
+			continue
+		}
+		if plaintext, err := jwe.decryptContent(cek); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, errors.New("jose: JWE could not be decrypted with the key")
+}
+
+// recipientKey returns the content key for a recipient with the merged
+// header h, or nil if the recipient is not for the key. sender is nil
+// unless the sender is authenticated with ECDH-1PU.
+func (jwe *JSONWebEncryption) recipientKey(h Header, r *Recipient, privateKey ecc.PrivateKey, sender *JWK) ([]byte, error) {
+	if sender == nil && h.Algorithm != KeyAlgECDHES && h.Algorithm != KeyAlgECDHESA256KW {
+		return nil, nil
+	}
+	if sender != nil && (h.Algorithm != KeyAlgECDH1PU ||
+		sender.KeyID != "" && h.SenderKeyID != "" && sender.KeyID != h.SenderKeyID) {
+		return nil, nil
+	}
+	if h.EphemeralPublicKey == nil {
+		return nil, errors.New("jose: missing epk header parameter")
+	}
+	apu, apv, err := partyInfo(h)
+	if err != nil {
+		return nil, err
+	}
+	z, err := ecdh.ECDHPrivateKey(privateKey).DeriveFixedSizeSharedSecret(h.EphemeralPublicKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("jose: key agreement failed: %v", err)
+	}
+
+	if h.Algorithm == KeyAlgECDH1PU {
+		zs, err := ecdh.ECDHPrivateKey(privateKey).DeriveFixedSizeSharedSecret(sender.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("jose: key agreement failed: %v", err)
+		}
+		z = append(z, zs...)
+	}
+
+	if h.Algorithm != KeyAlgECDHESA256KW {
+		if len(r.EncryptedKey) != 0 {
+			return nil, fmt.Errorf("jose: unexpected encrypted key for %s", h.Algorithm)
+		}
+		return concatKDF(z, h.Encryption, apu, apv, contentKeySize), nil
+	}
+	kek := concatKDF(z, h.Algorithm, apu, apv, contentKeySize)
+	cek, err := cryptohelpers.AESKeyUnwrap(kek, r.EncryptedKey)
+	if err != nil || len(cek) != contentKeySize {
+		// The recipient is for a different key.
+		return nil, nil
+	}
+	return cek, nil
+}
+
+// CompactSerialize returns the compact serialization of a JWE with a single
+// recipient and only a protected header.
+func (jwe *JSONWebEncryption) CompactSerialize() (string, error) {
+	if len(jwe.Recipients) != 1 || !jwe.Recipients[0].Header.isEmpty() || !jwe.Unprotected.isEmpty() || jwe.AAD != nil {
+		return "", errors.New("jose: compact serialization needs a single recipient and no unprotected header or AAD")
+	}
+	return strings.Join([]string{
+		jwe.rawProtected,
+		b64.EncodeToString(jwe.Recipients[0].EncryptedKey),
+		b64.EncodeToString(jwe.IV),
+		b64.EncodeToString(jwe.Ciphertext),
+		b64.EncodeToString(jwe.Tag),
+	}, "."), nil
+}
+
+type rawRecipient struct {
+	Header       *Header `json:"header,omitempty"`
+	EncryptedKey string  `json:"encrypted_key,omitempty"`
+}
+
+type rawJWE struct {
+	Protected   string         `json:"protected,omitempty"`
+	Unprotected *Header        `json:"unprotected,omitempty"`
+	Recipients  []rawRecipient `json:"recipients,omitempty"`
+	AAD         string         `json:"aad,omitempty"`
+	IV          string         `json:"iv"`
+	Ciphertext  string         `json:"ciphertext"`
+	Tag         string         `json:"tag"`
+
+	// The flattened syntax has a single recipient at the top level.
+	rawRecipient
+}
+
+// FullSerialize returns the general JSON serialization of the JWE.
+func (jwe *JSONWebEncryption) FullSerialize() ([]byte, error) {
+	raw := rawJWE{
+		Protected:  jwe.rawProtected,
+		IV:         b64.EncodeToString(jwe.IV),
+		Ciphertext: b64.EncodeToString(jwe.Ciphertext),
+		Tag:        b64.EncodeToString(jwe.Tag),
+	}
+	if !jwe.Unprotected.isEmpty() {
+		unprotected := jwe.Unprotected
+		raw.Unprotected = &unprotected
+	}
+	if jwe.AAD != nil {
+		raw.AAD = b64.EncodeToString(jwe.AAD)
+	}
+	for _, r := range jwe.Recipients {
+		rr := rawRecipient{EncryptedKey: b64.EncodeToString(r.EncryptedKey)}
+		if !r.Header.isEmpty() {
+			header := r.Header
+			rr.Header = &header
+		}
+		raw.Recipients = append(raw.Recipients, rr)
+	}
+	return json.Marshal(raw)
+}
+
+// ParseEncrypted parses a JWE in the compact serialization or in the general
+// or flattened JSON serialization.
+func ParseEncrypted(input string) (*JSONWebEncryption, error) {
+	input = strings.TrimSpace(input)
+	var raw rawJWE
+	if strings.HasPrefix(input, "{") {
+		if err := json.Unmarshal([]byte(input), &raw); err != nil {
+			return nil, fmt.Errorf("jose: malformed JWE: %v", err)
+		}
+		if raw.Header != nil || raw.EncryptedKey != "" {
+			if raw.Recipients != nil {
+				return nil, errors.New("jose: JWE mixes the general and flattened syntaxes")
+			}
+			raw.Recipients = []rawRecipient{raw.rawRecipient}
+		}
+	} else {
+		parts := strings.Split(input, ".")
+		if len(parts) != 5 {
+			return nil, errors.New("jose: compact JWE must have five parts")
+		}
+		raw = rawJWE{
+			Protected:  parts[0],
+			Recipients: []rawRecipient{{EncryptedKey: parts[1]}},
+			IV:         parts[2],
+			Ciphertext: parts[3],
+			Tag:        parts[4],
+		}
+	}
+	if len(raw.Recipients) == 0 {
+		// A flattened JWE for ECDH-ES has neither a header nor an
+		// encrypted key.
+		raw.Recipients = []rawRecipient{{}}
+	}
+
+	jwe := &JSONWebEncryption{rawProtected: raw.Protected}
+	if raw.Protected != "" {
+		protected, err := b64.DecodeString(raw.Protected)
+		if err != nil {
+			return nil, fmt.Errorf("jose: malformed protected header: %v", err)
+		}
+		if err := json.Unmarshal(protected, &jwe.Protected); err != nil {
+			return nil, fmt.Errorf("jose: malformed protected header: %v", err)
+		}
+	}
+	if len(jwe.Protected.Critical) > 0 {
+		return nil, fmt.Errorf("jose: unsupported critical header parameters %v", jwe.Protected.Critical)
+	}
+	if raw.Unprotected != nil {
+		jwe.Unprotected = *raw.Unprotected
+	}
+
+	var err error
+	if jwe.IV, err = decodeField("iv", raw.IV); err != nil {
+		return nil, err
+	}
+	if jwe.Ciphertext, err = decodeField("ciphertext", raw.Ciphertext); err != nil {
+		return nil, err
+	}
+	if jwe.Tag, err = decodeField("tag", raw.Tag); err != nil {
+		return nil, err
+	}
+	if raw.AAD != "" {
+		if jwe.AAD, err = decodeField("aad", raw.AAD); err != nil {
+			return nil, err
+		}
+	}
+	if len(jwe.IV) != gcmNonceSize {
+		return nil, errors.New("jose: invalid IV length")
+	}
+	if len(jwe.Tag) != gcmTagSize {
+		return nil, errors.New("jose: invalid authentication tag length")
+	}
+
+	for _, rr := range raw.Recipients {
+		encryptedKey, err := decodeField("encrypted key", rr.EncryptedKey)
+		if err != nil {
+			return nil, err
+		}
+		r := &Recipient{EncryptedKey: encryptedKey}
+		if rr.Header != nil {
+			r.Header = *rr.Header
+		}
+		jwe.Recipients = append(jwe.Recipients, r)
+	}
+	return jwe, nil
+}
+
+func decodeField(name, value string) ([]byte, error) {
+	b, err := b64.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("jose: malformed %s: %v", name, err)
+	}
+	return b, nil
+}
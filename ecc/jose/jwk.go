@@ -0,0 +1,210 @@
+package jose
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+)
+
+const (
+	// KeyTypeEC is the JWK key type of elliptic curve keys.
+	KeyTypeEC = "EC"
+	// CurveEccFrog512ck2 is the JWK curve name of EccFrog512ck2 keys.
+	CurveEccFrog512ck2 = "EccFrog512ck2"
+
+	coordinateSize = 64
+)
+
+var b64 = base64.RawURLEncoding
+
+// JWK is an EccFrog512ck2 JSON Web Key. PrivateKey is nil for public keys.
+type JWK struct {
+	KeyID      string
+	Algorithm  string
+	Use        string
+	PublicKey  eccfrog512ck2.CurvePoint
+	PrivateKey *ecc.PrivateKey
+}
+
+type rawJWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	D         string `json:"d,omitempty"`
+}
+
+// NewPrivateJWK returns a JWK for privateKey with the given key ID, which may
+// be empty.
+func NewPrivateJWK(privateKey ecc.PrivateKey, keyID string) (*JWK, error) {
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &JWK{KeyID: keyID, PublicKey: publicKey, PrivateKey: &privateKey}, nil
+}
+
+// NewPublicJWK returns a JWK for publicKey with the given key ID, which may
+// be empty.
+func NewPublicJWK(publicKey eccfrog512ck2.CurvePoint, keyID string) *JWK {
+	return &JWK{KeyID: keyID, PublicKey: publicKey}
+}
+
+// Public returns the public part of the key.
+func (k *JWK) Public() *JWK {
+	return &JWK{KeyID: k.KeyID, Algorithm: k.Algorithm, Use: k.Use, PublicKey: k.PublicKey}
+}
+
+func (k *JWK) coordinates() (string, string, error) {
+	x, y, ok := k.PublicKey.CoordinateIfNotInfinity()
+	if !ok {
+		return "", "", errors.New("jose: public key is the point at infinity")
+	}
+	return b64.EncodeToString(x.FillBytes(make([]byte, coordinateSize))),
+		b64.EncodeToString(y.FillBytes(make([]byte, coordinateSize))), nil
+}
+
+// MarshalJSON encodes the key as a JWK, including the private key if there
+// is one.
+func (k *JWK) MarshalJSON() ([]byte, error) {
+	x, y, err := k.coordinates()
+	if err != nil {
+		return nil, err
+	}
+	raw := rawJWK{
+		KeyType:   KeyTypeEC,
+		KeyID:     k.KeyID,
+		Algorithm: k.Algorithm,
+		Use:       k.Use,
+		Curve:     CurveEccFrog512ck2,
+		X:         x,
+		Y:         y,
+	}
+	if k.PrivateKey != nil {
+		raw.D = b64.EncodeToString(k.PrivateKey.GetKey().FillBytes(make([]byte, coordinateSize)))
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON decodes an EccFrog512ck2 JWK.
+func (k *JWK) UnmarshalJSON(data []byte) error {
+	var raw rawJWK
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.KeyType != KeyTypeEC {
+		return fmt.Errorf("jose: unsupported key type %q", raw.KeyType)
+	}
+	if raw.Curve != CurveEccFrog512ck2 {
+		return fmt.Errorf("jose: unsupported curve %q", raw.Curve)
+	}
+
+	x, err := b64.DecodeString(raw.X)
+	if err != nil || len(x) != coordinateSize {
+		return errors.New("jose: invalid x coordinate")
+	}
+	y, err := b64.DecodeString(raw.Y)
+	if err != nil || len(y) != coordinateSize {
+		return errors.New("jose: invalid y coordinate")
+	}
+	publicKey, err := ecc.ParsePublicKeySEC1(append(append([]byte{0x04}, x...), y...))
+	if err != nil {
+		return fmt.Errorf("jose: invalid public key: %v", err)
+	}
+
+	key := JWK{KeyID: raw.KeyID, Algorithm: raw.Algorithm, Use: raw.Use, PublicKey: publicKey}
+	if raw.D != "" {
+		d, err := b64.DecodeString(raw.D)
+		if err != nil || len(d) != coordinateSize {
+			return errors.New("jose: invalid private key")
+		}
+		privateKey, err := ecc.ParsePrivateKeySEC1(d)
+		if err != nil {
+			return fmt.Errorf("jose: invalid private key: %v", err)
+		}
+		derived, err := privateKey.DerivePublicKey()
+		if err != nil || !derived.Equal(publicKey) {
+			return errors.New("jose: private key does not match public key")
+		}
+		key.PrivateKey = &privateKey
+	}
+	*k = key
+	return nil
+}
+
+// Thumbprint returns the SHA-256 JWK thumbprint of the key (RFC 7638), which
+// is a common choice of key ID.
+func (k *JWK) Thumbprint() ([]byte, error) {
+	x, y, err := k.coordinates()
+	if err != nil {
+		return nil, err
+	}
+	// The required members in lexicographic order, without whitespace.
+	canonical := fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, CurveEccFrog512ck2, KeyTypeEC, x, y)
+	sum := sha256.Sum256([]byte(canonical))
+	return sum[:], nil
+}
+
+func (k *JWK) privateKey() (ecc.PrivateKey, error) {
+	if k.PrivateKey == nil {
+		return ecc.PrivateKey{}, errors.New("jose: key has no private part")
+	}
+	return *k.PrivateKey, nil
+}
+
+// JWKSet is a JWK Set.
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// UnmarshalJSON decodes a JWK Set. Keys of other types and curves are
+// skipped, as RFC 7517 requires.
+func (s *JWKSet) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Keys == nil {
+		return errors.New("jose: JWK Set has no keys member")
+	}
+	s.Keys = nil
+	for _, data := range raw.Keys {
+		var header struct {
+			KeyType string `json:"kty"`
+			Curve   string `json:"crv"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil {
+			return err
+		}
+		if header.KeyType != KeyTypeEC || header.Curve != CurveEccFrog512ck2 {
+			continue
+		}
+		key := &JWK{}
+		if err := json.Unmarshal(data, key); err != nil {
+			return err
+		}
+		s.Keys = append(s.Keys, key)
+	}
+	return nil
+}
+
+// LookupKeyID returns the keys with the given key ID.
+func (s *JWKSet) LookupKeyID(keyID string) []*JWK {
+	var keys []*JWK
+	for _, k := range s.Keys {
+		if k.KeyID == keyID {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,332 @@
+package jose
+
+import (
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdsa"
+)
+
+// AlgEF512 is ECDSA over EccFrog512ck2 with SHA-512.
+const AlgEF512 = "EF512"
+
+// ErrVerification is returned when no signature verifies with the given
+// keys.
+var ErrVerification = errors.New("jose: verification failed")
+
+// Header holds the JOSE header parameters of a JWS or JWE.
+type Header struct {
+	Algorithm   string   `json:"alg,omitempty"`
+	KeyID       string   `json:"kid,omitempty"`
+	Type        string   `json:"typ,omitempty"`
+	ContentType string   `json:"cty,omitempty"`
+	Critical    []string `json:"crit,omitempty"`
+
+	// The JWE parameters. The agreement party infos are base64url encoded.
+	Encryption          string `json:"enc,omitempty"`
+	EphemeralPublicKey  *JWK   `json:"epk,omitempty"`
+	AgreementPartyUInfo string `json:"apu,omitempty"`
+	AgreementPartyVInfo string `json:"apv,omitempty"`
+	SenderKeyID         string `json:"skid,omitempty"`
+}
+
+func (h *Header) isEmpty() bool {
+	return h.Algorithm == "" && h.KeyID == "" && h.Type == "" && h.ContentType == "" && h.Critical == nil &&
+		h.Encryption == "" && h.EphemeralPublicKey == nil && h.AgreementPartyUInfo == "" && h.AgreementPartyVInfo == "" &&
+		h.SenderKeyID == ""
+}
+
+// merge returns the header with the parameters it lacks taken from other.
+func (h Header) merge(other Header) Header {
+	if h.Algorithm == "" {
+		h.Algorithm = other.Algorithm
+	}
+	if h.KeyID == "" {
+		h.KeyID = other.KeyID
+	}
+	if h.Type == "" {
+		h.Type = other.Type
+	}
+	if h.ContentType == "" {
+		h.ContentType = other.ContentType
+	}
+	if h.Critical == nil {
+		h.Critical = other.Critical
+	}
+	if h.Encryption == "" {
+		h.Encryption = other.Encryption
+	}
+	if h.EphemeralPublicKey == nil {
+		h.EphemeralPublicKey = other.EphemeralPublicKey
+	}
+	if h.AgreementPartyUInfo == "" {
+		h.AgreementPartyUInfo = other.AgreementPartyUInfo
+	}
+	if h.AgreementPartyVInfo == "" {
+		h.AgreementPartyVInfo = other.AgreementPartyVInfo
+	}
+	if h.SenderKeyID == "" {
+		h.SenderKeyID = other.SenderKeyID
+	}
+	return h
+}
+
+// Signature is one of the signatures of a JWS.
+type Signature struct {
+	// Protected is the integrity-protected header, and Header the
+	// unprotected header, which only the JSON serialization can carry.
+	Protected Header
+	Header    Header
+	Signature []byte
+
+	// rawProtected is the encoded protected header as it was signed or
+	// parsed.
+	rawProtected string
+}
+
+// algorithm returns the alg parameter from either header.
+func (s *Signature) algorithm() string {
+	if s.Protected.Algorithm != "" {
+		return s.Protected.Algorithm
+	}
+	return s.Header.Algorithm
+}
+
+// KeyID returns the kid parameter from either header.
+func (s *Signature) KeyID() string {
+	if s.Protected.KeyID != "" {
+		return s.Protected.KeyID
+	}
+	return s.Header.KeyID
+}
+
+// JSONWebSignature is a payload with one or more signatures.
+type JSONWebSignature struct {
+	Payload    []byte
+	Signatures []*Signature
+}
+
+func signingInput(rawProtected string, payload []byte) []byte {
+	return []byte(rawProtected + "." + b64.EncodeToString(payload))
+}
+
+// Sign signs payload with each of the keys. Each signature has header as
+// its protected header, with the alg parameter set to AlgEF512 and the kid
+// parameter set to the key ID if the header has none.
+func Sign(payload []byte, header Header, keys ...*JWK) (*JSONWebSignature, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("jose: no signing keys")
+	}
+	if header.Algorithm != "" && header.Algorithm != AlgEF512 {
+		return nil, fmt.Errorf("jose: unsupported algorithm %q", header.Algorithm)
+	}
+	jws := &JSONWebSignature{Payload: payload}
+	for _, key := range keys {
+		privateKey, err := key.privateKey()
+		if err != nil {
+			return nil, err
+		}
+		protected := header
+		protected.Algorithm = AlgEF512
+		if protected.KeyID == "" {
+			protected.KeyID = key.KeyID
+		}
+		encoded, err := json.Marshal(protected)
+		if err != nil {
+			return nil, err
+		}
+		s := &Signature{Protected: protected, rawProtected: b64.EncodeToString(encoded)}
+
+		r, ss, err := ecdsa.NewSign(sha512.New, privateKey).Sign(signingInput(s.rawProtected, payload))
+		if err != nil {
+			return nil, err
+		}
+		s.Signature = make([]byte, 2*coordinateSize)
+		r.FillBytes(s.Signature[:coordinateSize])
+		ss.FillBytes(s.Signature[coordinateSize:])
+		jws.Signatures = append(jws.Signatures, s)
+	}
+	return jws, nil
+}
+
+func (jws *JSONWebSignature) verifySignature(s *Signature, key *JWK) bool {
+	if s.algorithm() != AlgEF512 || len(s.Signature) != 2*coordinateSize {
+		return false
+	}
+	r := new(big.Int).SetBytes(s.Signature[:coordinateSize])
+	ss := new(big.Int).SetBytes(s.Signature[coordinateSize:])
+	n := eccfrog512ck2.GeneratorOrder()
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || ss.Sign() <= 0 || ss.Cmp(n) >= 0 {
+		return false
+	}
+	ok, err := ecdsa.NewVerification(sha512.New, key.PublicKey).Verify([2]*big.Int{r, ss}, signingInput(s.rawProtected, jws.Payload))
+	return err == nil && ok
+}
+
+// Verify checks that one of the signatures was made by key and returns the
+// payload. If both the key and a signature have a key ID, the signature is
+// only tried if they match.
+func (jws *JSONWebSignature) Verify(key *JWK) ([]byte, error) {
+	for _, s := range jws.Signatures {
+		if key.KeyID != "" && s.KeyID() != "" && key.KeyID != s.KeyID() {
+			continue
+		}
+		if jws.verifySignature(s, key) {
+			return jws.Payload, nil
+		}
+	}
+	return nil, ErrVerification
+}
+
+// VerifyWithKeySet checks that one of the signatures was made by a key in
+// set and returns the payload and the key. Signatures with a key ID are only
+// checked against the keys with that ID.
+func (jws *JSONWebSignature) VerifyWithKeySet(set *JWKSet) ([]byte, *JWK, error) {
+	for _, s := range jws.Signatures {
+		candidates := set.Keys
+		if s.KeyID() != "" {
+			candidates = set.LookupKeyID(s.KeyID())
+		}
+		for _, key := range candidates {
+			if jws.verifySignature(s, key) {
+				return jws.Payload, key, nil
+			}
+		}
+	}
+	return nil, nil, ErrVerification
+}
+
+// CompactSerialize returns the compact serialization of a JWS with a single
+// signature and no unprotected header.
+func (jws *JSONWebSignature) CompactSerialize() (string, error) {
+	if len(jws.Signatures) != 1 {
+		return "", errors.New("jose: compact serialization needs exactly one signature")
+	}
+	s := jws.Signatures[0]
+	if !s.Header.isEmpty() {
+		return "", errors.New("jose: compact serialization cannot carry an unprotected header")
+	}
+	return s.rawProtected + "." + b64.EncodeToString(jws.Payload) + "." + b64.EncodeToString(s.Signature), nil
+}
+
+type rawSignature struct {
+	Protected string  `json:"protected,omitempty"`
+	Header    *Header `json:"header,omitempty"`
+	Signature string  `json:"signature"`
+}
+
+type rawJWS struct {
+	Payload    *string        `json:"payload"`
+	Signatures []rawSignature `json:"signatures,omitempty"`
+
+	// The flattened syntax has a single signature at the top level.
+	rawSignature
+}
+
+// FullSerialize returns the general JSON serialization of the JWS.
+func (jws *JSONWebSignature) FullSerialize() ([]byte, error) {
+	payload := b64.EncodeToString(jws.Payload)
+	raw := struct {
+		Payload    string         `json:"payload"`
+		Signatures []rawSignature `json:"signatures"`
+	}{Payload: payload}
+	for _, s := range jws.Signatures {
+		rs := rawSignature{Protected: s.rawProtected, Signature: b64.EncodeToString(s.Signature)}
+		if !s.Header.isEmpty() {
+			header := s.Header
+			rs.Header = &header
+		}
+		raw.Signatures = append(raw.Signatures, rs)
+	}
+	return json.Marshal(raw)
+}
+
+// ParseSigned parses a JWS in the compact serialization or in the general
+// or flattened JSON serialization.
+func ParseSigned(input string) (*JSONWebSignature, error) {
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "{") {
+		return parseSignedJSON([]byte(input))
+	}
+	parts := strings.Split(input, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jose: compact JWS must have three parts")
+	}
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("jose: malformed payload: %v", err)
+	}
+	s, err := parseSignature(rawSignature{Protected: parts[0], Signature: parts[2]})
+	if err != nil {
+		return nil, err
+	}
+	return &JSONWebSignature{Payload: payload, Signatures: []*Signature{s}}, nil
+}
+
+func parseSignedJSON(data []byte) (*JSONWebSignature, error) {
+	var raw rawJWS
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("jose: malformed JWS: %v", err)
+	}
+	if raw.Payload == nil {
+		return nil, errors.New("jose: JWS has no payload")
+	}
+	payload, err := b64.DecodeString(*raw.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("jose: malformed payload: %v", err)
+	}
+
+	signatures := raw.Signatures
+	if raw.Signature != "" {
+		if signatures != nil {
+			return nil, errors.New("jose: JWS mixes the general and flattened syntaxes")
+		}
+		signatures = []rawSignature{raw.rawSignature}
+	}
+	if len(signatures) == 0 {
+		return nil, errors.New("jose: JWS has no signatures")
+	}
+
+	jws := &JSONWebSignature{Payload: payload}
+	for _, rs := range signatures {
+		s, err := parseSignature(rs)
+		if err != nil {
+			return nil, err
+		}
+		jws.Signatures = append(jws.Signatures, s)
+	}
+	return jws, nil
+}
+
+func parseSignature(rs rawSignature) (*Signature, error) {
+	s := &Signature{rawProtected: rs.Protected}
+	if rs.Protected != "" {
+		protected, err := b64.DecodeString(rs.Protected)
+		if err != nil {
+			return nil, fmt.Errorf("jose: malformed protected header: %v", err)
+		}
+		if err := json.Unmarshal(protected, &s.Protected); err != nil {
+			return nil, fmt.Errorf("jose: malformed protected header: %v", err)
+		}
+	}
+	if rs.Header != nil {
+		s.Header = *rs.Header
+		if s.Header.Critical != nil {
+			return nil, errors.New("jose: crit header parameter must be protected")
+		}
+	}
+	if len(s.Protected.Critical) > 0 {
+		return nil, fmt.Errorf("jose: unsupported critical header parameters %v", s.Protected.Critical)
+	}
+	signature, err := b64.DecodeString(rs.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("jose: malformed signature: %v", err)
+	}
+	s.Signature = signature
+	return s, nil
+}
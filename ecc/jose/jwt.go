@@ -0,0 +1,174 @@
+package jose
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrTokenExpired is returned when a JWT's expiration time has passed.
+	ErrTokenExpired = errors.New("jose: token has expired")
+	// ErrTokenNotValidYet is returned when a JWT's not-before time has not
+	// been reached.
+	ErrTokenNotValidYet = errors.New("jose: token is not valid yet")
+	// ErrInvalidIssuer is returned when a JWT's issuer is not the expected
+	// one.
+	ErrInvalidIssuer = errors.New("jose: invalid issuer")
+	// ErrInvalidAudience is returned when a JWT is not intended for the
+	// expected audience.
+	ErrInvalidAudience = errors.New("jose: invalid audience")
+)
+
+// NumericDate is a JWT time: seconds since the Unix epoch.
+type NumericDate int64
+
+// NewNumericDate returns the NumericDate of t, truncated to the second.
+func NewNumericDate(t time.Time) *NumericDate {
+	d := NumericDate(t.Unix())
+	return &d
+}
+
+// Time returns the time of the date.
+func (d NumericDate) Time() time.Time {
+	return time.Unix(int64(d), 0)
+}
+
+// UnmarshalJSON accepts integer and fractional dates; fractions of a
+// second are dropped.
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("jose: invalid date: %v", err)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) || f > math.MaxInt64 || f < math.MinInt64 {
+		return errors.New("jose: invalid date")
+	}
+	*d = NumericDate(f)
+	return nil
+}
+
+// Audience is the aud claim, which is a single string or an array of them.
+type Audience []string
+
+// MarshalJSON encodes a single audience as a string.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON accepts a string or an array of strings.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("jose: aud claim must be a string or an array of strings")
+	}
+	*a = list
+	return nil
+}
+
+// Claims are the registered claims of a JWT. Embed it in a struct to add
+// private claims.
+type Claims struct {
+	Issuer         string       `json:"iss,omitempty"`
+	Subject        string       `json:"sub,omitempty"`
+	Audience       Audience     `json:"aud,omitempty"`
+	ExpirationTime *NumericDate `json:"exp,omitempty"`
+	NotBefore      *NumericDate `json:"nbf,omitempty"`
+	IssuedAt       *NumericDate `json:"iat,omitempty"`
+	ID             string       `json:"jti,omitempty"`
+}
+
+// Expected are the values a JWT's claims are validated against.
+type Expected struct {
+	// Issuer, if not empty, must be the iss claim.
+	Issuer string
+	// Audience, if not empty, must be in the aud claim. Tokens with an aud
+	// claim are rejected if Audience is empty, as RFC 7519 requires.
+	Audience string
+	// Time is the time to check exp and nbf against, or the current time if
+	// it is zero.
+	Time time.Time
+	// Leeway is the clock skew allowed when checking exp and nbf.
+	Leeway time.Duration
+}
+
+// Validate checks the claims against expected.
+func (c *Claims) Validate(expected Expected) error {
+	now := expected.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if c.ExpirationTime != nil && !now.Add(-expected.Leeway).Before(c.ExpirationTime.Time()) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != nil && now.Add(expected.Leeway).Before(c.NotBefore.Time()) {
+		return ErrTokenNotValidYet
+	}
+	if expected.Issuer != "" && c.Issuer != expected.Issuer {
+		return ErrInvalidIssuer
+	}
+	if c.Audience != nil && !slices.Contains(c.Audience, expected.Audience) {
+		return ErrInvalidAudience
+	}
+	if c.Audience == nil && expected.Audience != "" {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// SignJWT returns a compact JWT carrying claims, which is encoded as JSON and
+// usually is or embeds a Claims, signed by key.
+func SignJWT(claims any, key *JWK) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	jws, err := Sign(payload, Header{Type: "JWT"}, key)
+	if err != nil {
+		return "", err
+	}
+	return jws.CompactSerialize()
+}
+
+// VerifyJWT verifies a compact JWT signed by a key in set, selected by the
+// token's key ID, and validates its registered claims against expected. If
+// claims is not nil, the payload is also decoded into it, to read private
+// claims.
+func VerifyJWT(token string, set *JWKSet, expected Expected, claims any) (*Claims, error) {
+	jws, err := ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(jws.Signatures) != 1 || jws.Signatures[0].rawProtected == "" {
+		return nil, errors.New("jose: a JWT must have a single signature")
+	}
+	payload, _, err := jws.VerifyWithKeySet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	var registered Claims
+	if err := json.Unmarshal(payload, &registered); err != nil {
+		return nil, fmt.Errorf("jose: malformed claims: %v", err)
+	}
+	if err := registered.Validate(expected); err != nil {
+		return nil, err
+	}
+	if claims != nil {
+		if err := json.Unmarshal(payload, claims); err != nil {
+			return nil, fmt.Errorf("jose: malformed claims: %v", err)
+		}
+	}
+	return &registered, nil
+}
@@ -0,0 +1,11 @@
+// Package ocsp implements the Online Certificate Status Protocol, as defined
+// in RFC 6960, for certificates issued by EccFrog512ck2 CAs.
+//
+// It can build and parse OCSP requests and responses, serve responses over
+// HTTP via Responder, and query responders via Checker, whose Check method
+// plugs into x509.VerifyOptions.CheckRevocation so that chain verification
+// consults OCSP.
+//
+// Responses are always signed directly by the issuing CA; delegated OCSP
+// responders are not supported.
+package ocsp
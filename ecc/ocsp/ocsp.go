@@ -0,0 +1,400 @@
+package ocsp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdsa"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+var (
+	oidSHA256       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	idPKIXOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+)
+
+// ResponseStatus contains the result of an OCSP request, as opposed to the
+// status of the certificate being asked about.
+type ResponseStatus int
+
+const (
+	Success           ResponseStatus = 0
+	Malformed         ResponseStatus = 1
+	InternalError     ResponseStatus = 2
+	TryLater          ResponseStatus = 3
+	SignatureRequired ResponseStatus = 5
+	Unauthorized      ResponseStatus = 6
+)
+
+func (r ResponseStatus) String() string {
+	switch r {
+	case Success:
+		return "success"
+	case Malformed:
+		return "malformed"
+	case InternalError:
+		return "internal error"
+	case TryLater:
+		return "try later"
+	case SignatureRequired:
+		return "signature required"
+	case Unauthorized:
+		return "unauthorized"
+	default:
+		return fmt.Sprintf("unknown OCSP status: %d", int(r))
+	}
+}
+
+// ResponseError is returned by ParseResponse when the responder did not
+// return a successful response.
+type ResponseError struct {
+	Status ResponseStatus
+}
+
+func (r ResponseError) Error() string {
+	return "ocsp: error from server: " + r.Status.String()
+}
+
+// The status of a certificate, as given by Response.Status.
+const (
+	// Good means that the certificate is valid.
+	Good = 0
+	// Revoked means that the certificate has been deliberately revoked.
+	Revoked = 1
+	// Unknown means that the responder doesn't know about the certificate.
+	Unknown = 2
+)
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type request struct {
+	Cert certID
+}
+
+type tbsRequest struct {
+	Version     int `asn1:"explicit,tag:0,default:0,optional"`
+	RequestList []request
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+type singleResponse struct {
+	CertID     certID
+	Good       asn1.Flag   `asn1:"tag:0,optional"`
+	Revoked    revokedInfo `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag   `asn1:"tag:2,optional"`
+	ThisUpdate time.Time   `asn1:"generalized"`
+	NextUpdate time.Time   `asn1:"generalized,explicit,tag:0,optional"`
+}
+
+type responseData struct {
+	Raw            asn1.RawContent
+	Version        int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID asn1.RawValue
+	ProducedAt     time.Time `asn1:"generalized"`
+	Responses      []singleResponse
+}
+
+type basicResponse struct {
+	TBSResponseData    responseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type responseASN1 struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+// Request represents an OCSP request for the status of a single certificate.
+// The certificate is identified by SHA-256 hashes of its issuer's name and
+// public key, together with its serial number.
+type Request struct {
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+func issuerHashes(issuer *x509.Certificate) (nameHash, keyHash []byte) {
+	name := sha256.Sum256(issuer.RawSubject)
+	key := sha256.Sum256(issuer.PublicKey.MarshalSEC1(false))
+	return name[:], key[:]
+}
+
+// matchesIssuer reports whether the request refers to a certificate issued by
+// issuer.
+func (req *Request) matchesIssuer(issuer *x509.Certificate) bool {
+	nameHash, keyHash := issuerHashes(issuer)
+	return bytes.Equal(req.IssuerNameHash, nameHash) && bytes.Equal(req.IssuerKeyHash, keyHash)
+}
+
+func (req *Request) certID() certID {
+	return certID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+		NameHash:      req.IssuerNameHash,
+		IssuerKeyHash: req.IssuerKeyHash,
+		SerialNumber:  req.SerialNumber,
+	}
+}
+
+// Marshal encodes the request in ASN.1 DER form.
+func (req *Request) Marshal() ([]byte, error) {
+	return asn1.Marshal(ocspRequest{
+		TBSRequest: tbsRequest{RequestList: []request{{Cert: req.certID()}}},
+	})
+}
+
+// CreateRequest returns a DER-encoded OCSP request for the status of cert,
+// which must have been issued by issuer.
+func CreateRequest(cert, issuer *x509.Certificate) ([]byte, error) {
+	nameHash, keyHash := issuerHashes(issuer)
+	req := &Request{
+		IssuerNameHash: nameHash,
+		IssuerKeyHash:  keyHash,
+		SerialNumber:   cert.SerialNumber,
+	}
+	return req.Marshal()
+}
+
+// ParseRequest parses a DER-encoded OCSP request. Only requests for a single
+// certificate, identified using SHA-256 hashes, are supported.
+func ParseRequest(der []byte) (*Request, error) {
+	var req ocspRequest
+	rest, err := asn1.Unmarshal(der, &req)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("ocsp: trailing data in OCSP request")
+	}
+	if len(req.TBSRequest.RequestList) != 1 {
+		return nil, errors.New("ocsp: OCSP request must contain exactly one certificate")
+	}
+
+	id := req.TBSRequest.RequestList[0].Cert
+	if !id.HashAlgorithm.Algorithm.Equal(oidSHA256) {
+		return nil, errors.New("ocsp: unsupported issuer hash algorithm")
+	}
+	if id.SerialNumber == nil {
+		return nil, errors.New("ocsp: OCSP request is missing a serial number")
+	}
+
+	return &Request{
+		IssuerNameHash: id.NameHash,
+		IssuerKeyHash:  id.IssuerKeyHash,
+		SerialNumber:   id.SerialNumber,
+	}, nil
+}
+
+// Response represents an OCSP response for a single certificate.
+type Response struct {
+	Raw []byte
+
+	// Status is one of Good, Revoked or Unknown.
+	Status       int
+	SerialNumber *big.Int
+
+	ProducedAt, ThisUpdate, NextUpdate, RevokedAt time.Time
+
+	// RevocationReason is the CRLReason from RFC 5280, section 5.3.1, and is
+	// only meaningful if Status is Revoked.
+	RevocationReason int
+
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+
+	RawResponderName   []byte
+	TBSResponseData    []byte
+	Signature          []byte
+	SignatureAlgorithm asn1.ObjectIdentifier
+}
+
+// CreateResponse returns a DER-encoded OCSP response for the certificate
+// described by template, signed by priv on behalf of issuer.
+//
+// The following members of template are used: Status, SerialNumber,
+// ThisUpdate, NextUpdate, RevokedAt and RevocationReason. ProducedAt is set to
+// the current time.
+func CreateResponse(issuer *x509.Certificate, template Response, priv ecc.PrivateKey) ([]byte, error) {
+	nameHash, keyHash := issuerHashes(issuer)
+	if template.SerialNumber == nil {
+		return nil, errors.New("ocsp: template is missing a serial number")
+	}
+
+	single := singleResponse{
+		CertID: (&Request{
+			IssuerNameHash: nameHash,
+			IssuerKeyHash:  keyHash,
+			SerialNumber:   template.SerialNumber,
+		}).certID(),
+		ThisUpdate: template.ThisUpdate.UTC(),
+		NextUpdate: template.NextUpdate.UTC(),
+	}
+
+	switch template.Status {
+	case Good:
+		single.Good = true
+	case Revoked:
+		if template.RevokedAt.IsZero() {
+			return nil, errors.New("ocsp: revoked template is missing a revocation time")
+		}
+		single.Revoked = revokedInfo{
+			RevocationTime: template.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(template.RevocationReason),
+		}
+	case Unknown:
+		single.Unknown = true
+	default:
+		return nil, fmt.Errorf("ocsp: invalid certificate status %d", template.Status)
+	}
+
+	tbs := responseData{
+		RawResponderID: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        1,
+			IsCompound: true,
+			Bytes:      issuer.RawSubject,
+		},
+		ProducedAt: time.Now().UTC().Truncate(time.Second),
+		Responses:  []singleResponse{single},
+	}
+	tbsBytes, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	tbs.Raw = tbsBytes
+
+	signature, err := ecdsa.NewSign(sha512.New, priv).SignASN1(tbsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	basic, err := asn1.Marshal(basicResponse{
+		TBSResponseData:    tbs,
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: x509.OIDSignatureECDSAWithSHA512},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(responseASN1{
+		Status: asn1.Enumerated(Success),
+		Response: responseBytes{
+			ResponseType: idPKIXOCSPBasic,
+			Response:     basic,
+		},
+	})
+}
+
+// errorResponse returns the DER encoding of an unsuccessful OCSP response
+// with the given status.
+func errorResponse(status ResponseStatus) []byte {
+	der, _ := asn1.Marshal(responseASN1{Status: asn1.Enumerated(status)})
+	return der
+}
+
+// ParseResponse parses a DER-encoded OCSP response containing the status of
+// a single certificate.
+//
+// If issuer is not nil, the response must have been signed by issuer and must
+// refer to a certificate issued by it. If the responder returned an error
+// status, a ResponseError is returned.
+func ParseResponse(der []byte, issuer *x509.Certificate) (*Response, error) {
+	var resp responseASN1
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("ocsp: trailing data in OCSP response")
+	}
+
+	if status := ResponseStatus(resp.Status); status != Success {
+		return nil, ResponseError{Status: status}
+	}
+	if !resp.Response.ResponseType.Equal(idPKIXOCSPBasic) {
+		return nil, errors.New("ocsp: bad OCSP response type")
+	}
+
+	var basic basicResponse
+	rest, err = asn1.Unmarshal(resp.Response.Response, &basic)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("ocsp: trailing data in basic OCSP response")
+	}
+	if len(basic.TBSResponseData.Responses) != 1 {
+		return nil, errors.New("ocsp: OCSP response must contain exactly one certificate status")
+	}
+
+	single := basic.TBSResponseData.Responses[0]
+	response := &Response{
+		Raw:                der,
+		SerialNumber:       single.CertID.SerialNumber,
+		ProducedAt:         basic.TBSResponseData.ProducedAt,
+		ThisUpdate:         single.ThisUpdate,
+		NextUpdate:         single.NextUpdate,
+		IssuerNameHash:     single.CertID.NameHash,
+		IssuerKeyHash:      single.CertID.IssuerKeyHash,
+		TBSResponseData:    basic.TBSResponseData.Raw,
+		Signature:          basic.Signature.RightAlign(),
+		SignatureAlgorithm: basic.SignatureAlgorithm.Algorithm,
+	}
+
+	if responderID := basic.TBSResponseData.RawResponderID; responderID.Class == asn1.ClassContextSpecific && responderID.Tag == 1 {
+		response.RawResponderName = responderID.Bytes
+	}
+
+	switch {
+	case bool(single.Good):
+		response.Status = Good
+	case bool(single.Unknown):
+		response.Status = Unknown
+	default:
+		response.Status = Revoked
+		response.RevokedAt = single.Revoked.RevocationTime
+		response.RevocationReason = int(single.Revoked.Reason)
+	}
+
+	if issuer != nil {
+		if !bytes.Equal(response.RawResponderName, issuer.RawSubject) {
+			return nil, errors.New("ocsp: response was not produced by the issuer")
+		}
+		if err := issuer.CheckSignature(response.SignatureAlgorithm, response.TBSResponseData, response.Signature); err != nil {
+			return nil, fmt.Errorf("ocsp: bad signature on OCSP response: %w", err)
+		}
+		req := Request{IssuerNameHash: response.IssuerNameHash, IssuerKeyHash: response.IssuerKeyHash}
+		if !req.matchesIssuer(issuer) {
+			return nil, errors.New("ocsp: response is for a certificate of another issuer")
+		}
+	}
+
+	return response, nil
+}
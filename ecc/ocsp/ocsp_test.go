@@ -0,0 +1,226 @@
+package ocsp_test
+
+import (
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ocsp"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+type pki struct {
+	ca      *x509.Certificate
+	caKey   ecc.PrivateKey
+	good    *x509.Certificate
+	revoked *x509.Certificate
+	list    *x509.RevocationList
+}
+
+// newPKI creates a CA that has issued two certificates naming responderURL
+// as their OCSP responder, and a revocation list revoking the second one.
+func newPKI(t *testing.T, responderURL string) pki {
+	t.Helper()
+	caKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	caPublicKey, err := caKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(template, template, caPublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(serial int64) *x509.Certificate {
+		der, err := x509.CreateCertificate(&x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "leaf"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			OCSPServer:   []string{responderURL},
+		}, ca, caPublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	listDER, err := x509.CreateRevocationList(&x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: big.NewInt(3), RevocationTime: time.Now().Add(-time.Minute), ReasonCode: 4},
+		},
+	}, ca, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := x509.ParseRevocationList(listDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pki{ca: ca, caKey: caKey, good: issue(2), revoked: issue(3), list: list}
+}
+
+func TestRequestRoundTrip(t *testing.T) {
+	p := newPKI(t, "http://ocsp.example.com")
+
+	der, err := ocsp.CreateRequest(p.good, p.ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := ocsp.ParseRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.SerialNumber.Cmp(p.good.SerialNumber) != 0 {
+		t.Errorf("expected serial number %v but got %v", p.good.SerialNumber, req.SerialNumber)
+	}
+	if p.good.OCSPServer[0] != "http://ocsp.example.com" {
+		t.Errorf("unexpected OCSP server %q", p.good.OCSPServer[0])
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	p := newPKI(t, "http://ocsp.example.com")
+
+	revokedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	der, err := ocsp.CreateResponse(p.ca, ocsp.Response{
+		Status:           ocsp.Revoked,
+		SerialNumber:     big.NewInt(3),
+		ThisUpdate:       time.Now(),
+		RevokedAt:        revokedAt,
+		RevocationReason: 1,
+	}, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := ocsp.ParseResponse(der, p.ca)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Status != ocsp.Revoked || response.RevocationReason != 1 || !response.RevokedAt.Equal(revokedAt) {
+		t.Errorf("unexpected response %+v", response)
+	}
+
+	other := newPKI(t, "http://ocsp.example.com")
+	if _, err := ocsp.ParseResponse(der, other.ca); err == nil {
+		t.Error("expected a response to be rejected by another issuer")
+	}
+}
+
+func TestResponder(t *testing.T) {
+	server := httptest.NewServer(nil)
+	defer server.Close()
+	p := newPKI(t, server.URL)
+	server.Config.Handler = ocsp.NewResponder(p.ca, p.caKey, ocsp.RevocationListSource(p.list))
+
+	checker := ocsp.Checker{Client: server.Client()}
+
+	if err := checker.Check(p.good, p.ca, time.Now()); err != nil {
+		t.Errorf("expected a good status but got %v", err)
+	}
+	if err := checker.Check(p.revoked, p.ca, time.Now()); !errors.Is(err, x509.ErrRevoked) {
+		t.Errorf("expected ErrRevoked but got %v", err)
+	}
+	// Responses are produced now, and are current until the list's next
+	// update
+	if err := checker.Check(p.good, p.ca, time.Now().Add(-time.Minute)); err == nil {
+		t.Error("expected a response from the future to be rejected")
+	}
+	if err := checker.Check(p.good, p.ca, time.Now().Add(2*time.Hour)); err == nil {
+		t.Error("expected an out of date response to be rejected")
+	}
+
+	t.Run("chain verification", func(t *testing.T) {
+		opts := x509.VerifyOptions{
+			Roots:           []*x509.Certificate{p.ca},
+			CheckRevocation: checker.Check,
+		}
+		if _, err := p.good.Verify(opts); err != nil {
+			t.Errorf("Failed to verify good certificate: %v", err)
+		}
+		if _, err := p.revoked.Verify(opts); !errors.Is(err, x509.ErrRevoked) {
+			t.Errorf("expected ErrRevoked but got %v", err)
+		}
+
+		// The response is checked at the verification time
+		opts.CurrentTime = time.Now().Add(-30 * time.Minute)
+		if _, err := p.good.Verify(opts); err == nil {
+			t.Error("expected a response from after the verification time to be rejected")
+		}
+	})
+
+	t.Run("malformed request", func(t *testing.T) {
+		resp, err := server.Client().Post(server.URL, "application/ocsp-request", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var responseErr ocsp.ResponseError
+		if _, err := ocsp.ParseResponse(body, nil); !errors.As(err, &responseErr) || responseErr.Status != ocsp.Malformed {
+			t.Errorf("expected a malformed response error but got %v", err)
+		}
+	})
+
+	t.Run("get request", func(t *testing.T) {
+		der, err := ocsp.CreateRequest(p.revoked, p.ca)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := server.Client().Get(server.URL + "/" + base64URLPath(der))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := ocsp.ParseResponse(body, p.ca)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Status != ocsp.Revoked || response.RevocationReason != 4 {
+			t.Errorf("unexpected response %+v", response)
+		}
+	})
+}
+
+func base64URLPath(der []byte) string {
+	return url.PathEscape(base64.StdEncoding.EncodeToString(der))
+}
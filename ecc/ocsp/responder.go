@@ -0,0 +1,187 @@
+package ocsp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+// maxRequestSize bounds the size of OCSP requests accepted by a Responder.
+const maxRequestSize = 10 * 1024
+
+// Source provides the status of certificates to a Responder.
+type Source interface {
+	// Status returns a template for the response about the certificate with
+	// the given serial number. Only the Status, NextUpdate, RevokedAt and
+	// RevocationReason members are used.
+	Status(serialNumber *big.Int) (Response, error)
+}
+
+// SourceFunc is an adapter to allow the use of ordinary functions as a
+// Source.
+type SourceFunc func(serialNumber *big.Int) (Response, error)
+
+// Status calls f(serialNumber).
+func (f SourceFunc) Status(serialNumber *big.Int) (Response, error) {
+	return f(serialNumber)
+}
+
+// RevocationListSource returns a Source that answers from a certificate
+// revocation list: certificates on the list are Revoked, and all others are
+// Good until the list's next update.
+func RevocationListSource(list *x509.RevocationList) Source {
+	return SourceFunc(func(serialNumber *big.Int) (Response, error) {
+		entry, ok := list.Lookup(serialNumber)
+		if !ok {
+			return Response{Status: Good, NextUpdate: list.NextUpdate}, nil
+		}
+		return Response{
+			Status:           Revoked,
+			NextUpdate:       list.NextUpdate,
+			RevokedAt:        entry.RevocationTime,
+			RevocationReason: entry.ReasonCode,
+		}, nil
+	})
+}
+
+// Responder is an http.Handler that answers OCSP requests, sent either with
+// POST or with GET as described in RFC 6960, appendix A, about certificates
+// issued by a single CA.
+type Responder struct {
+	issuer *x509.Certificate
+	priv   ecc.PrivateKey
+	source Source
+}
+
+// NewResponder creates a Responder that signs responses with the private key
+// of issuer, looking up certificate statuses in source.
+func NewResponder(issuer *x509.Certificate, priv ecc.PrivateKey, source Source) *Responder {
+	return &Responder{issuer: issuer, priv: priv, source: source}
+}
+
+func (r *Responder) ServeHTTP(w http.ResponseWriter, httpRequest *http.Request) {
+	der, err := readRequest(httpRequest)
+	if err != nil {
+		writeResponse(w, errorResponse(Malformed))
+		return
+	}
+
+	req, err := ParseRequest(der)
+	if err != nil {
+		writeResponse(w, errorResponse(Malformed))
+		return
+	}
+	if !req.matchesIssuer(r.issuer) {
+		writeResponse(w, errorResponse(Unauthorized))
+		return
+	}
+
+	template, err := r.source.Status(req.SerialNumber)
+	if err != nil {
+		writeResponse(w, errorResponse(InternalError))
+		return
+	}
+	template.SerialNumber = req.SerialNumber
+	template.ThisUpdate = time.Now().UTC().Truncate(time.Second)
+
+	response, err := CreateResponse(r.issuer, template, r.priv)
+	if err != nil {
+		writeResponse(w, errorResponse(InternalError))
+		return
+	}
+	writeResponse(w, response)
+}
+
+func readRequest(httpRequest *http.Request) ([]byte, error) {
+	switch httpRequest.Method {
+	case http.MethodGet:
+		encoded, err := url.PathUnescape(path.Base(httpRequest.URL.EscapedPath()))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+	case http.MethodPost:
+		return io.ReadAll(io.LimitReader(httpRequest.Body, maxRequestSize))
+	default:
+		return nil, fmt.Errorf("unsupported method %s", httpRequest.Method)
+	}
+}
+
+func writeResponse(w http.ResponseWriter, response []byte) {
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	w.Write(response)
+}
+
+// Checker queries the OCSP responders named in certificates. Its Check
+// method can be used as x509.VerifyOptions.CheckRevocation.
+type Checker struct {
+	// Client is used to send requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Check asks the first OCSP responder listed in cert for its status, and
+// returns an error wrapping x509.ErrRevoked if it has been revoked. The
+// response must be current at now, the time the chain is verified at.
+//
+// Certificates that do not name an OCSP responder are not checked.
+func (c Checker) Check(cert, issuer *x509.Certificate, now time.Time) error {
+	if len(cert.OCSPServer) == 0 {
+		return nil
+	}
+
+	der, err := CreateRequest(cert, issuer)
+	if err != nil {
+		return err
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpResponse, err := client.Post(cert.OCSPServer[0], "application/ocsp-request", bytes.NewReader(der))
+	if err != nil {
+		return fmt.Errorf("ocsp: failed to query responder: %w", err)
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("ocsp: responder returned HTTP status %d", httpResponse.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(httpResponse.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+
+	response, err := ParseResponse(body, issuer)
+	if err != nil {
+		return err
+	}
+	if response.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		return errors.New("ocsp: response is for another certificate")
+	}
+	if now.Before(response.ThisUpdate) {
+		return errors.New("ocsp: response is not yet valid")
+	}
+	if !response.NextUpdate.IsZero() && now.After(response.NextUpdate) {
+		return errors.New("ocsp: response is out of date")
+	}
+
+	switch response.Status {
+	case Good:
+		return nil
+	case Revoked:
+		return fmt.Errorf("%w: serial number %v was revoked at %s", x509.ErrRevoked,
+			cert.SerialNumber, response.RevokedAt.UTC().Format(time.RFC3339))
+	default:
+		return errors.New("ocsp: responder does not know the certificate")
+	}
+}
@@ -0,0 +1,87 @@
+package openpgp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Armor block types from RFC 9580, section 6.2.
+const (
+	ArmorPublicKey  = "PGP PUBLIC KEY BLOCK"
+	ArmorPrivateKey = "PGP PRIVATE KEY BLOCK"
+	ArmorMessage    = "PGP MESSAGE"
+	ArmorSignature  = "PGP SIGNATURE"
+)
+
+const armorLineLength = 64
+
+// Armor encodes data with ASCII armor of the given block type.
+//
+// No CRC-24 checksum is emitted, as RFC 9580 deprecates it.
+func Armor(blockType string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("-----BEGIN " + blockType + "-----\n\n")
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > armorLineLength {
+		b.WriteString(encoded[:armorLineLength] + "\n")
+		encoded = encoded[armorLineLength:]
+	}
+	if len(encoded) > 0 {
+		b.WriteString(encoded + "\n")
+	}
+	b.WriteString("-----END " + blockType + "-----\n")
+	return b.Bytes()
+}
+
+// Dearmor decodes the first ASCII-armored block in data, returning its block
+// type and contents. Armor headers are skipped, and a CRC-24 checksum, if
+// present, is ignored as RFC 9580 recommends.
+func Dearmor(data []byte) (string, []byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	var blockType string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "-----BEGIN ") && strings.HasSuffix(line, "-----") {
+			blockType = strings.TrimSuffix(strings.TrimPrefix(line, "-----BEGIN "), "-----")
+			break
+		}
+	}
+	if blockType == "" {
+		return "", nil, errors.New("openpgp: no armored data found")
+	}
+
+	// Headers, if any, end at the first blank line.
+	inHeaders := true
+	var encoded strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "-----END "+blockType+"-----" {
+			contents, err := base64.StdEncoding.DecodeString(encoded.String())
+			if err != nil {
+				return "", nil, errors.New("openpgp: invalid armored data")
+			}
+			return blockType, contents, nil
+		}
+		if inHeaders {
+			if line == "" {
+				inHeaders = false
+				continue
+			}
+			if strings.Contains(line, ": ") {
+				continue
+			}
+			// Tolerate armor without the blank line after the headers.
+			inHeaders = false
+		}
+		if strings.HasPrefix(line, "=") && len(line) == 5 {
+			continue
+		}
+		encoded.WriteString(line)
+	}
+	return "", nil, errors.New("openpgp: armored data has no END line")
+}
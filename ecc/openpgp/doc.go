@@ -0,0 +1,20 @@
+// Package openpgp implements the subset of OpenPGP version 6 (RFC 9580)
+// needed to keep EccFrog512ck2 keys in an OpenPGP keyring, and to sign and
+// encrypt messages with them.
+//
+// EccFrog512ck2 is not a registered OpenPGP algorithm, so keys use two IDs
+// from the private/experimental range: PubKeyAlgoEccFrog512ck2ECDSA for
+// signing keys, backed by the ecdsa package, and PubKeyAlgoEccFrog512ck2ECDH
+// for encryption subkeys, backed by the ecdh package. Public keys are stored
+// as uncompressed SEC1 points and secret keys as 64-byte scalars, and
+// signatures are a pair of MPIs, as for ECDSA. Session keys are wrapped the
+// same way RFC 9580 wraps them for X25519, with HKDF-SHA512 and AES-256 key
+// wrap. Other implementations will only interoperate if they are taught the
+// same private algorithm IDs.
+//
+// The supported packets are public keys and subkeys, secret keys and subkeys
+// (unprotected or protected with an Argon2 or iterated and salted S2K and
+// AES-256-GCM), user IDs, version 6 signatures, version 6 public-key
+// encrypted session keys, version 2 symmetrically encrypted and integrity
+// protected data, and literal data.
+package openpgp
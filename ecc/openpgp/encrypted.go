@@ -0,0 +1,333 @@
+package openpgp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/cryptohelpers"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdh"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	encryptedKeyVersion           = 6
+	symmetricallyEncryptedVersion = 2
+
+	sessionKeySize = 32
+	gcmNonceSize   = 12
+	gcmTagSize     = 16
+
+	// defaultChunkSizeByte selects 64 KiB chunks.
+	defaultChunkSizeByte = 10
+)
+
+// ErrKeyIncorrect is returned when a message cannot be decrypted with the
+// given key.
+var ErrKeyIncorrect = errors.New("openpgp: incorrect key")
+
+// EncryptedKey is a version 6 public-key encrypted session key packet.
+type EncryptedKey struct {
+	// KeyFingerprint is the fingerprint of the recipient key, or nil for an
+	// anonymous recipient.
+	KeyFingerprint []byte
+	Algo           PublicKeyAlgorithm
+
+	ephemeralKey eccfrog512ck2.CurvePoint
+	wrappedKey   []byte
+}
+
+// deriveWrappingKey derives the key that wraps a session key, in the same
+// way RFC 9580, section 5.1.6, does for X25519.
+func deriveWrappingKey(ephemeralKey, recipientKey eccfrog512ck2.CurvePoint, sharedSecret []byte) ([]byte, error) {
+	ikm := append(append(ephemeralKey.MarshalSEC1(false), recipientKey.MarshalSEC1(false)...), sharedSecret...)
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha512.New, ikm, nil, []byte("OpenPGP EccFrog512ck2")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// newEncryptedKey encrypts sessionKey for pub.
+func newEncryptedKey(pub *PublicKey, sessionKey []byte) (*EncryptedKey, error) {
+	if !pub.CanEncrypt() {
+		return nil, errors.New("openpgp: key cannot encrypt")
+	}
+	ephemeralPrivateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	ephemeralKey, err := ephemeralPrivateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := ecdh.ECDHPrivateKey(ephemeralPrivateKey).DeriveFixedSizeSharedSecret(pub.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := deriveWrappingKey(ephemeralKey, pub.PublicKey, sharedSecret)
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := cryptohelpers.AESKeyWrap(kek, sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedKey{
+		KeyFingerprint: pub.Fingerprint(),
+		Algo:           pub.PubKeyAlgo,
+		ephemeralKey:   ephemeralKey,
+		wrappedKey:     wrappedKey,
+	}, nil
+}
+
+// Decrypt returns the session key, using the unencrypted secret key priv.
+func (e *EncryptedKey) Decrypt(priv *PrivateKey) ([]byte, error) {
+	if priv.Encrypted {
+		return nil, errors.New("openpgp: decryption key is encrypted")
+	}
+	if e.Algo != priv.PubKeyAlgo || !priv.CanEncrypt() {
+		return nil, errors.New("openpgp: key algorithm does not match the encrypted key")
+	}
+	sharedSecret, err := ecdh.ECDHPrivateKey(priv.PrivateKey).DeriveFixedSizeSharedSecret(e.ephemeralKey)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := deriveWrappingKey(e.ephemeralKey, priv.PublicKey.PublicKey, sharedSecret)
+	if err != nil {
+		return nil, err
+	}
+	sessionKey, err := cryptohelpers.AESKeyUnwrap(kek, e.wrappedKey)
+	if err != nil {
+		return nil, ErrKeyIncorrect
+	}
+	if len(sessionKey) != sessionKeySize {
+		return nil, errors.New("openpgp: invalid session key size")
+	}
+	return sessionKey, nil
+}
+
+func (e *EncryptedKey) serialize(w io.Writer) error {
+	b := []byte{encryptedKeyVersion}
+	if e.KeyFingerprint == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, byte(1+len(e.KeyFingerprint)), keyVersion)
+		b = append(b, e.KeyFingerprint...)
+	}
+	b = append(b, byte(e.Algo))
+	b = append(b, e.ephemeralKey.MarshalSEC1(false)...)
+	b = append(b, byte(len(e.wrappedKey)))
+	b = append(b, e.wrappedKey...)
+	return writePacket(w, packetTypeEncryptedKey, b)
+}
+
+func parseEncryptedKey(b []byte) (*EncryptedKey, error) {
+	if len(b) < 2 || b[0] != encryptedKeyVersion {
+		return nil, errors.New("openpgp: unsupported encrypted key packet")
+	}
+	e := &EncryptedKey{}
+	length := int(b[1])
+	b = b[2:]
+	if len(b) < length+1 {
+		return nil, errors.New("openpgp: truncated encrypted key packet")
+	}
+	if length != 0 {
+		if length != 33 || b[0] != keyVersion {
+			return nil, errors.New("openpgp: unsupported recipient key version")
+		}
+		e.KeyFingerprint = b[1:length]
+	}
+	b = b[length:]
+	e.Algo = PublicKeyAlgorithm(b[0])
+	if e.Algo != PubKeyAlgoEccFrog512ck2ECDH {
+		return nil, fmt.Errorf("openpgp: unsupported encrypted key algorithm %v", e.Algo)
+	}
+	b = b[1:]
+	if len(b) < publicKeySize+1 {
+		return nil, errors.New("openpgp: truncated encrypted key packet")
+	}
+	ephemeralKey, err := ecc.ParsePublicKeySEC1(b[:publicKeySize])
+	if err != nil {
+		return nil, fmt.Errorf("openpgp: invalid ephemeral key: %v", err)
+	}
+	e.ephemeralKey = ephemeralKey
+	b = b[publicKeySize:]
+	if len(b) != 1+int(b[0]) {
+		return nil, errors.New("openpgp: invalid wrapped session key")
+	}
+	e.wrappedKey = b[1:]
+	return e, nil
+}
+
+// SymmetricallyEncrypted is a version 2 symmetrically encrypted and
+// integrity protected data packet, using AES-256-GCM.
+type SymmetricallyEncrypted struct {
+	chunkSizeByte byte
+	salt          []byte
+	contents      []byte
+}
+
+// aead returns the cipher, nonce prefix and associated data for the packet,
+// derived from the session key as described in RFC 9580, section 5.13.2.
+func (se *SymmetricallyEncrypted) aead(sessionKey []byte) (cipher.AEAD, []byte, []byte, error) {
+	info := []byte{packetTypeSymmetricallyEncrypted.headerByte(), symmetricallyEncryptedVersion, cipherAES256, aeadGCM, se.chunkSizeByte}
+	keyAndIV := make([]byte, sessionKeySize+gcmNonceSize-8)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sessionKey, se.salt, info), keyAndIV); err != nil {
+		return nil, nil, nil, err
+	}
+	block, err := aes.NewCipher(keyAndIV[:sessionKeySize])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return aead, keyAndIV[sessionKeySize:], info, nil
+}
+
+func (se *SymmetricallyEncrypted) chunkSize() int {
+	return 1 << (se.chunkSizeByte + 6)
+}
+
+func chunkNonce(iv []byte, index uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, iv...), index)
+}
+
+// newSymmetricallyEncrypted encrypts plaintext with sessionKey.
+func newSymmetricallyEncrypted(sessionKey, plaintext []byte) (*SymmetricallyEncrypted, error) {
+	se := &SymmetricallyEncrypted{
+		chunkSizeByte: defaultChunkSizeByte,
+		salt:          make([]byte, 32),
+	}
+	if _, err := io.ReadFull(rand.Reader, se.salt); err != nil {
+		return nil, err
+	}
+	aead, iv, additionalData, err := se.aead(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var index uint64
+	for offset := 0; offset < len(plaintext); offset += se.chunkSize() {
+		chunk := plaintext[offset:min(offset+se.chunkSize(), len(plaintext))]
+		se.contents = aead.Seal(se.contents, chunkNonce(iv, index), chunk, additionalData)
+		index++
+	}
+	finalAdditionalData := binary.BigEndian.AppendUint64(append([]byte{}, additionalData...), uint64(len(plaintext)))
+	se.contents = aead.Seal(se.contents, chunkNonce(iv, index), nil, finalAdditionalData)
+	return se, nil
+}
+
+// Decrypt decrypts the packet contents with sessionKey, checking every chunk
+// and the final authentication tag.
+func (se *SymmetricallyEncrypted) Decrypt(sessionKey []byte) ([]byte, error) {
+	aead, iv, additionalData, err := se.aead(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(se.contents) < gcmTagSize {
+		return nil, errors.New("openpgp: truncated encrypted data")
+	}
+	contents, finalTag := se.contents[:len(se.contents)-gcmTagSize], se.contents[len(se.contents)-gcmTagSize:]
+
+	var plaintext []byte
+	var index uint64
+	for len(contents) > 0 {
+		n := min(se.chunkSize()+gcmTagSize, len(contents))
+		if n <= gcmTagSize {
+			return nil, errors.New("openpgp: truncated encrypted chunk")
+		}
+		plaintext, err = aead.Open(plaintext, chunkNonce(iv, index), contents[:n], additionalData)
+		if err != nil {
+			return nil, ErrKeyIncorrect
+		}
+		contents = contents[n:]
+		index++
+	}
+	finalAdditionalData := binary.BigEndian.AppendUint64(append([]byte{}, additionalData...), uint64(len(plaintext)))
+	if _, err := aead.Open(nil, chunkNonce(iv, index), finalTag, finalAdditionalData); err != nil {
+		return nil, errors.New("openpgp: encrypted data has been truncated or modified")
+	}
+	return plaintext, nil
+}
+
+func (se *SymmetricallyEncrypted) serialize(w io.Writer) error {
+	b := []byte{symmetricallyEncryptedVersion, cipherAES256, aeadGCM, se.chunkSizeByte}
+	b = append(b, se.salt...)
+	b = append(b, se.contents...)
+	return writePacket(w, packetTypeSymmetricallyEncrypted, b)
+}
+
+func parseSymmetricallyEncrypted(b []byte) (*SymmetricallyEncrypted, error) {
+	if len(b) < 36 || b[0] != symmetricallyEncryptedVersion {
+		return nil, errors.New("openpgp: unsupported symmetrically encrypted data packet")
+	}
+	if b[1] != cipherAES256 || b[2] != aeadGCM {
+		return nil, fmt.Errorf("openpgp: unsupported cipher %d with AEAD mode %d", b[1], b[2])
+	}
+	if b[3] > 16 {
+		return nil, errors.New("openpgp: invalid chunk size")
+	}
+	return &SymmetricallyEncrypted{
+		chunkSizeByte: b[3],
+		salt:          b[4:36],
+		contents:      b[36:],
+	}, nil
+}
+
+// LiteralData is a literal data packet, which holds the plaintext of a
+// message.
+type LiteralData struct {
+	IsBinary bool
+	FileName string
+	Time     time.Time
+	Body     []byte
+}
+
+func (l *LiteralData) serialize(w io.Writer) error {
+	if len(l.FileName) > 255 {
+		return errors.New("openpgp: file name too long")
+	}
+	format := byte('u')
+	if l.IsBinary {
+		format = 'b'
+	}
+	var b bytes.Buffer
+	b.WriteByte(format)
+	b.WriteByte(byte(len(l.FileName)))
+	b.WriteString(l.FileName)
+	var timestamp uint32
+	if !l.Time.IsZero() {
+		timestamp = uint32(l.Time.Unix())
+	}
+	binary.Write(&b, binary.BigEndian, timestamp)
+	b.Write(l.Body)
+	return writePacket(w, packetTypeLiteralData, b.Bytes())
+}
+
+func parseLiteralData(b []byte) (*LiteralData, error) {
+	if len(b) < 2 || len(b) < 2+int(b[1])+4 {
+		return nil, errors.New("openpgp: truncated literal data packet")
+	}
+	l := &LiteralData{IsBinary: b[0] == 'b'}
+	nameLength := int(b[1])
+	l.FileName = string(b[2 : 2+nameLength])
+	b = b[2+nameLength:]
+	if timestamp := binary.BigEndian.Uint32(b); timestamp != 0 {
+		l.Time = time.Unix(int64(timestamp), 0)
+	}
+	l.Body = b[4:]
+	return l, nil
+}
@@ -0,0 +1,464 @@
+package openpgp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+)
+
+// Identity is a user ID of an entity, together with the self-signature that
+// binds it to the primary key.
+type Identity struct {
+	UserId        *UserID
+	SelfSignature *Signature
+}
+
+// Subkey is a subkey of an entity, together with the signature that binds it
+// to the primary key.
+type Subkey struct {
+	PublicKey  *PublicKey
+	PrivateKey *PrivateKey
+	Sig        *Signature
+}
+
+// Entity is an OpenPGP transferable public or secret key: a primary signing
+// key, its user IDs and its subkeys.
+type Entity struct {
+	PrimaryKey *PublicKey
+	PrivateKey *PrivateKey
+
+	// DirectSignature is the direct key self-signature, which holds the key
+	// flags of the primary key.
+	DirectSignature *Signature
+	Identities      []*Identity
+	Subkeys         []Subkey
+}
+
+// NewEntity returns an entity with signingKey as its primary key, userId as
+// its only user ID and encryptionKey as an encryption subkey. All
+// self-signatures are made at creationTime, which is also used as the
+// creation time of both keys and is therefore part of their fingerprints.
+func NewEntity(userId string, signingKey, encryptionKey ecc.PrivateKey, creationTime time.Time) (*Entity, error) {
+	primary, err := NewPrivateKey(creationTime, PubKeyAlgoEccFrog512ck2ECDSA, signingKey)
+	if err != nil {
+		return nil, err
+	}
+	subkey, err := NewPrivateKey(creationTime, PubKeyAlgoEccFrog512ck2ECDH, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	subkey.IsSubkey = true
+
+	e := &Entity{
+		PrimaryKey: &primary.PublicKey,
+		PrivateKey: primary,
+	}
+
+	e.DirectSignature = &Signature{CreationTime: creationTime, KeyFlags: KeyFlagCertify | KeyFlagSign}
+	if err := e.DirectSignature.SignDirectKey(primary); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		UserId:        &UserID{Id: userId},
+		SelfSignature: &Signature{CreationTime: creationTime},
+	}
+	if err := identity.SelfSignature.SignUserId(userId, e.PrimaryKey, primary); err != nil {
+		return nil, err
+	}
+	e.Identities = []*Identity{identity}
+
+	binding := &Signature{CreationTime: creationTime, KeyFlags: KeyFlagEncryptCommunications | KeyFlagEncryptStorage}
+	if err := binding.SignKey(&subkey.PublicKey, primary); err != nil {
+		return nil, err
+	}
+	e.Subkeys = []Subkey{{PublicKey: &subkey.PublicKey, PrivateKey: subkey, Sig: binding}}
+
+	return e, nil
+}
+
+// encryptionKey returns the first subkey that may be used for encryption.
+func (e *Entity) encryptionKey() (Subkey, bool) {
+	for _, subkey := range e.Subkeys {
+		if subkey.PublicKey.CanEncrypt() &&
+			subkey.Sig.KeyFlags&(KeyFlagEncryptCommunications|KeyFlagEncryptStorage) != 0 {
+			return subkey, true
+		}
+	}
+	return Subkey{}, false
+}
+
+// EncryptPrivateKeys protects the primary key and all subkeys with
+// passphrase.
+func (e *Entity) EncryptPrivateKeys(passphrase []byte) error {
+	if e.PrivateKey == nil {
+		return errors.New("openpgp: entity has no private key")
+	}
+	if err := e.PrivateKey.Encrypt(passphrase); err != nil {
+		return err
+	}
+	for _, subkey := range e.Subkeys {
+		if subkey.PrivateKey != nil {
+			if err := subkey.PrivateKey.Encrypt(passphrase); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DecryptPrivateKeys removes the passphrase protection from the primary key
+// and all subkeys.
+func (e *Entity) DecryptPrivateKeys(passphrase []byte) error {
+	if e.PrivateKey == nil {
+		return errors.New("openpgp: entity has no private key")
+	}
+	if err := e.PrivateKey.Decrypt(passphrase); err != nil {
+		return err
+	}
+	for _, subkey := range e.Subkeys {
+		if subkey.PrivateKey != nil {
+			if err := subkey.PrivateKey.Decrypt(passphrase); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Serialize writes the public part of the entity: a transferable public key.
+func (e *Entity) Serialize(w io.Writer) error {
+	return e.serialize(w, false)
+}
+
+// SerializePrivate writes the entity including its secret keys, as they are
+// currently protected: a transferable secret key.
+func (e *Entity) SerializePrivate(w io.Writer) error {
+	if e.PrivateKey == nil {
+		return errors.New("openpgp: entity has no private key")
+	}
+	return e.serialize(w, true)
+}
+
+func (e *Entity) serialize(w io.Writer, withPrivate bool) error {
+	var packets []Packet
+	if withPrivate {
+		packets = append(packets, e.PrivateKey)
+	} else {
+		packets = append(packets, e.PrimaryKey)
+	}
+	packets = append(packets, e.DirectSignature)
+	for _, identity := range e.Identities {
+		packets = append(packets, identity.UserId, identity.SelfSignature)
+	}
+	for _, subkey := range e.Subkeys {
+		if withPrivate && subkey.PrivateKey != nil {
+			packets = append(packets, subkey.PrivateKey)
+		} else {
+			packets = append(packets, subkey.PublicKey)
+		}
+		packets = append(packets, subkey.Sig)
+	}
+
+	for _, p := range packets {
+		if err := p.serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadKeyRing reads a sequence of transferable public or secret keys from r.
+// All self-signatures are verified.
+func ReadKeyRing(r io.Reader) ([]*Entity, error) {
+	var packets []Packet
+	for {
+		p, err := Read(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.(*Padding); ok {
+			continue
+		}
+		packets = append(packets, p)
+	}
+
+	var entities []*Entity
+	for len(packets) > 0 {
+		e, rest, err := readEntity(packets)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, e)
+		packets = rest
+	}
+	if len(entities) == 0 {
+		return nil, errors.New("openpgp: no keys found")
+	}
+	return entities, nil
+}
+
+// readEntity reads one entity from the start of packets and returns the
+// packets that follow it.
+func readEntity(packets []Packet) (*Entity, []Packet, error) {
+	e := &Entity{}
+	switch p := packets[0].(type) {
+	case *PrivateKey:
+		if p.IsSubkey {
+			return nil, nil, errors.New("openpgp: key ring starts with a subkey")
+		}
+		e.PrivateKey = p
+		e.PrimaryKey = &p.PublicKey
+	case *PublicKey:
+		if p.IsSubkey {
+			return nil, nil, errors.New("openpgp: key ring starts with a subkey")
+		}
+		e.PrimaryKey = p
+	default:
+		return nil, nil, fmt.Errorf("openpgp: expected a primary key, got %T", p)
+	}
+	packets = packets[1:]
+
+	var identity *Identity
+	var subkey *Subkey
+	for len(packets) > 0 {
+		switch p := packets[0].(type) {
+		case *Signature:
+			switch {
+			case subkey != nil:
+				if err := e.PrimaryKey.VerifyKeySignature(subkey.PublicKey, p); err != nil {
+					return nil, nil, fmt.Errorf("openpgp: invalid subkey binding signature: %v", err)
+				}
+				subkey.Sig = p
+			case identity != nil:
+				if err := e.PrimaryKey.VerifyUserIdSignature(identity.UserId.Id, e.PrimaryKey, p); err != nil {
+					return nil, nil, fmt.Errorf("openpgp: invalid user ID self-signature: %v", err)
+				}
+				identity.SelfSignature = p
+			default:
+				if err := e.PrimaryKey.VerifyDirectKeySignature(p); err != nil {
+					return nil, nil, fmt.Errorf("openpgp: invalid direct key signature: %v", err)
+				}
+				e.DirectSignature = p
+			}
+		case *UserID:
+			if subkey != nil {
+				return nil, nil, errors.New("openpgp: user ID after subkey")
+			}
+			identity = &Identity{UserId: p}
+			e.Identities = append(e.Identities, identity)
+		case *PublicKey:
+			if !p.IsSubkey {
+				return finishEntity(e, packets)
+			}
+			e.Subkeys = append(e.Subkeys, Subkey{PublicKey: p})
+			subkey = &e.Subkeys[len(e.Subkeys)-1]
+		case *PrivateKey:
+			if !p.IsSubkey {
+				return finishEntity(e, packets)
+			}
+			e.Subkeys = append(e.Subkeys, Subkey{PublicKey: &p.PublicKey, PrivateKey: p})
+			subkey = &e.Subkeys[len(e.Subkeys)-1]
+		default:
+			return nil, nil, fmt.Errorf("openpgp: unexpected %T in key", p)
+		}
+		packets = packets[1:]
+	}
+	return finishEntity(e, packets)
+}
+
+func finishEntity(e *Entity, rest []Packet) (*Entity, []Packet, error) {
+	if e.DirectSignature == nil {
+		return nil, nil, errors.New("openpgp: key has no direct key signature")
+	}
+	for _, identity := range e.Identities {
+		if identity.SelfSignature == nil {
+			return nil, nil, fmt.Errorf("openpgp: user ID %q has no self-signature", identity.UserId.Id)
+		}
+	}
+	for _, subkey := range e.Subkeys {
+		if subkey.Sig == nil {
+			return nil, nil, errors.New("openpgp: subkey has no binding signature")
+		}
+	}
+	return e, rest, nil
+}
+
+// Encrypt encrypts a message for each of the recipients' encryption subkeys,
+// and returns the encrypted message: one public-key encrypted session key
+// packet per recipient followed by the encrypted literal data.
+func Encrypt(plaintext []byte, fileName string, recipients []*Entity) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("openpgp: no recipients")
+	}
+
+	sessionKey := make([]byte, sessionKeySize)
+	if _, err := io.ReadFull(rand.Reader, sessionKey); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	for _, recipient := range recipients {
+		subkey, ok := recipient.encryptionKey()
+		if !ok {
+			return nil, fmt.Errorf("openpgp: key %X has no encryption subkey", recipient.PrimaryKey.Fingerprint())
+		}
+		encryptedKey, err := newEncryptedKey(subkey.PublicKey, sessionKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := encryptedKey.serialize(&b); err != nil {
+			return nil, err
+		}
+	}
+
+	var literal bytes.Buffer
+	if err := (&LiteralData{IsBinary: true, FileName: fileName, Time: time.Now(), Body: plaintext}).serialize(&literal); err != nil {
+		return nil, err
+	}
+	encrypted, err := newSymmetricallyEncrypted(sessionKey, literal.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if err := encrypted.serialize(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decrypt decrypts a message produced by Encrypt, using the first entity in
+// keyring whose encryption subkey is a recipient. The secret subkeys must
+// already be decrypted.
+func Decrypt(message []byte, keyring []*Entity) (*LiteralData, error) {
+	r := bytes.NewReader(message)
+	var encryptedKeys []*EncryptedKey
+	var encrypted *SymmetricallyEncrypted
+	for encrypted == nil {
+		p, err := Read(r)
+		if err == io.EOF {
+			return nil, errors.New("openpgp: message has no encrypted data")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch p := p.(type) {
+		case *EncryptedKey:
+			encryptedKeys = append(encryptedKeys, p)
+		case *SymmetricallyEncrypted:
+			encrypted = p
+		case *Padding:
+		default:
+			return nil, fmt.Errorf("openpgp: unexpected %T in encrypted message", p)
+		}
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("openpgp: trailing data after encrypted message")
+	}
+
+	sessionKey, err := findSessionKey(encryptedKeys, keyring)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := encrypted.Decrypt(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	r = bytes.NewReader(plaintext)
+	var literal *LiteralData
+	for {
+		p, err := Read(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch p := p.(type) {
+		case *LiteralData:
+			if literal != nil {
+				return nil, errors.New("openpgp: message has more than one literal data packet")
+			}
+			literal = p
+		case *Padding:
+		default:
+			return nil, fmt.Errorf("openpgp: unsupported %T in encrypted message", p)
+		}
+	}
+	if literal == nil {
+		return nil, errors.New("openpgp: message has no literal data")
+	}
+	return literal, nil
+}
+
+func findSessionKey(encryptedKeys []*EncryptedKey, keyring []*Entity) ([]byte, error) {
+	for _, encryptedKey := range encryptedKeys {
+		for _, e := range keyring {
+			for _, subkey := range e.Subkeys {
+				if subkey.PrivateKey == nil || !subkey.PublicKey.CanEncrypt() {
+					continue
+				}
+				if encryptedKey.KeyFingerprint != nil &&
+					!bytes.Equal(encryptedKey.KeyFingerprint, subkey.PublicKey.Fingerprint()) {
+					continue
+				}
+				sessionKey, err := encryptedKey.Decrypt(subkey.PrivateKey)
+				if err == nil {
+					return sessionKey, nil
+				}
+				if encryptedKey.KeyFingerprint != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, errors.New("openpgp: no key in the key ring can decrypt the message")
+}
+
+// DetachSign signs message with the primary key of signer, and returns the
+// signature packet.
+func DetachSign(message []byte, signer *Entity) ([]byte, error) {
+	if signer.PrivateKey == nil {
+		return nil, errors.New("openpgp: signer has no private key")
+	}
+	sig := &Signature{}
+	if err := sig.Sign(message, signer.PrivateKey); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := sig.serialize(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// CheckDetachedSignature checks a signature produced by DetachSign against
+// message, and returns the entity from keyring that made it.
+func CheckDetachedSignature(keyring []*Entity, message, signature []byte) (*Entity, error) {
+	r := bytes.NewReader(signature)
+	p, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := p.(*Signature)
+	if !ok || r.Len() != 0 {
+		return nil, errors.New("openpgp: expected a single signature packet")
+	}
+	for _, e := range keyring {
+		if bytes.Equal(sig.IssuerFingerprint, e.PrimaryKey.Fingerprint()) {
+			if err := e.PrimaryKey.VerifySignature(message, sig); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	}
+	return nil, errors.New("openpgp: signature was made by an unknown key")
+}
@@ -0,0 +1,63 @@
+package openpgp_test
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/openpgp"
+)
+
+func ExampleEncrypt() {
+	// Alice turns two EccFrog512ck2 keys into an OpenPGP key
+	signingKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate signing key:", err)
+		return
+	}
+	encryptionKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate encryption key:", err)
+		return
+	}
+	alice, err := openpgp.NewEntity("Alice <alice@example.com>", signingKey, encryptionKey, time.Now())
+	if err != nil {
+		fmt.Println("Failed to create key:", err)
+		return
+	}
+
+	// She publishes the armored public key
+	var public bytes.Buffer
+	if err := alice.Serialize(&public); err != nil {
+		fmt.Println("Failed to serialize key:", err)
+		return
+	}
+	armored := openpgp.Armor(openpgp.ArmorPublicKey, public.Bytes())
+
+	// Bob imports it and encrypts a message to Alice
+	_, data, err := openpgp.Dearmor(armored)
+	if err != nil {
+		fmt.Println("Failed to dearmor key:", err)
+		return
+	}
+	keyring, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+	if err != nil {
+		fmt.Println("Failed to read key:", err)
+		return
+	}
+	message, err := openpgp.Encrypt([]byte("Hello, Alice!"), "", keyring)
+	if err != nil {
+		fmt.Println("Failed to encrypt:", err)
+		return
+	}
+
+	// Alice decrypts it with her secret keys
+	literal, err := openpgp.Decrypt(message, []*openpgp.Entity{alice})
+	if err != nil {
+		fmt.Println("Failed to decrypt:", err)
+		return
+	}
+	fmt.Println(string(literal.Body))
+	// Output: Hello, Alice!
+}
@@ -0,0 +1,317 @@
+package openpgp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	keyVersion = 6
+
+	publicKeySize  = 129
+	privateKeySize = 64
+
+	// S2K usage octets from RFC 9580, section 3.7.2.1.
+	s2kUsageNone = 0
+	s2kUsageAEAD = 253
+)
+
+// ErrIncorrectPassphrase is returned when a secret key cannot be decrypted
+// with the given passphrase.
+var ErrIncorrectPassphrase = errors.New("openpgp: incorrect passphrase")
+
+// PublicKey is a version 6 public key or public subkey packet.
+type PublicKey struct {
+	CreationTime time.Time
+	PubKeyAlgo   PublicKeyAlgorithm
+	PublicKey    eccfrog512ck2.CurvePoint
+	IsSubkey     bool
+}
+
+// NewPublicKey returns a public key packet for key, which will be used with
+// the given algorithm.
+func NewPublicKey(creationTime time.Time, algorithm PublicKeyAlgorithm, key eccfrog512ck2.CurvePoint) *PublicKey {
+	return &PublicKey{
+		CreationTime: time.Unix(creationTime.Unix(), 0),
+		PubKeyAlgo:   algorithm,
+		PublicKey:    key,
+	}
+}
+
+func (pk *PublicKey) packetType() packetType {
+	if pk.IsSubkey {
+		return packetTypePublicSubkey
+	}
+	return packetTypePublicKey
+}
+
+// body returns the contents of the public key packet, which is also the part
+// of a secret key packet that is covered by fingerprints and signatures.
+func (pk *PublicKey) body() []byte {
+	material := pk.PublicKey.MarshalSEC1(false)
+	b := []byte{keyVersion}
+	b = binary.BigEndian.AppendUint32(b, uint32(pk.CreationTime.Unix()))
+	b = append(b, byte(pk.PubKeyAlgo))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(material)))
+	return append(b, material...)
+}
+
+// parsePublicKey parses the public part of a key packet and returns the
+// remaining bytes, which hold the secret key material for secret keys.
+func parsePublicKey(b []byte, isSubkey bool) (*PublicKey, []byte, error) {
+	if len(b) < 10 {
+		return nil, nil, errors.New("openpgp: truncated public key packet")
+	}
+	if b[0] != keyVersion {
+		return nil, nil, fmt.Errorf("openpgp: unsupported key version %d", b[0])
+	}
+	pk := &PublicKey{
+		CreationTime: time.Unix(int64(binary.BigEndian.Uint32(b[1:5])), 0),
+		PubKeyAlgo:   PublicKeyAlgorithm(b[5]),
+		IsSubkey:     isSubkey,
+	}
+	if pk.PubKeyAlgo != PubKeyAlgoEccFrog512ck2ECDSA && pk.PubKeyAlgo != PubKeyAlgoEccFrog512ck2ECDH {
+		return nil, nil, fmt.Errorf("openpgp: unsupported public key algorithm %v", pk.PubKeyAlgo)
+	}
+	length := int(binary.BigEndian.Uint32(b[6:10]))
+	if length != publicKeySize || len(b) < 10+length {
+		return nil, nil, errors.New("openpgp: invalid public key material")
+	}
+	key, err := ecc.ParsePublicKeySEC1(b[10 : 10+length])
+	if err != nil {
+		return nil, nil, fmt.Errorf("openpgp: invalid public key: %v", err)
+	}
+	pk.PublicKey = key
+	return pk, b[10+length:], nil
+}
+
+func (pk *PublicKey) serialize(w io.Writer) error {
+	return writePacket(w, pk.packetType(), pk.body())
+}
+
+// Fingerprint returns the version 6 fingerprint of the key, the SHA-256 hash
+// of its packet.
+func (pk *PublicKey) Fingerprint() []byte {
+	h := sha256.New()
+	pk.hashFor(h)
+	return h.Sum(nil)
+}
+
+// KeyId returns the key ID, which for version 6 keys is the first eight
+// octets of the fingerprint.
+func (pk *PublicKey) KeyId() uint64 {
+	return binary.BigEndian.Uint64(pk.Fingerprint())
+}
+
+// hashFor writes the key in the form used by fingerprints and key signatures.
+func (pk *PublicKey) hashFor(w io.Writer) {
+	body := pk.body()
+	header := binary.BigEndian.AppendUint32([]byte{0x9b}, uint32(len(body)))
+	w.Write(header)
+	w.Write(body)
+}
+
+// CanSign reports whether the key's algorithm can make signatures.
+func (pk *PublicKey) CanSign() bool {
+	return pk.PubKeyAlgo == PubKeyAlgoEccFrog512ck2ECDSA
+}
+
+// CanEncrypt reports whether the key's algorithm can receive encrypted
+// session keys.
+func (pk *PublicKey) CanEncrypt() bool {
+	return pk.PubKeyAlgo == PubKeyAlgoEccFrog512ck2ECDH
+}
+
+// PrivateKey is a version 6 secret key or secret subkey packet.
+//
+// When Encrypted is true, PrivateKey is unset and Decrypt must be called
+// with the passphrase before the key can be used.
+type PrivateKey struct {
+	PublicKey
+	Encrypted  bool
+	PrivateKey ecc.PrivateKey
+
+	s2k           s2k
+	iv            []byte
+	encryptedData []byte
+}
+
+// NewPrivateKey returns an unprotected secret key packet for key, which will
+// be used with the given algorithm.
+func NewPrivateKey(creationTime time.Time, algorithm PublicKeyAlgorithm, key ecc.PrivateKey) (*PrivateKey, error) {
+	publicKey, err := key.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{
+		PublicKey:  *NewPublicKey(creationTime, algorithm, publicKey),
+		PrivateKey: key,
+	}, nil
+}
+
+func (pk *PrivateKey) packetType() packetType {
+	if pk.IsSubkey {
+		return packetTypeSecretSubkey
+	}
+	return packetTypeSecretKey
+}
+
+func (pk *PrivateKey) marshalKeyMaterial() []byte {
+	scalar := pk.PrivateKey.MarshalSEC1(false)
+	return append(make([]byte, privateKeySize-len(scalar)), scalar...)
+}
+
+func parsePrivateKey(b []byte, isSubkey bool) (*PrivateKey, error) {
+	publicKey, rest, err := parsePublicKey(b, isSubkey)
+	if err != nil {
+		return nil, err
+	}
+	pk := &PrivateKey{PublicKey: *publicKey}
+	if len(rest) == 0 {
+		return nil, errors.New("openpgp: truncated secret key packet")
+	}
+
+	switch rest[0] {
+	case s2kUsageNone:
+		if err := pk.parseKeyMaterial(rest[1:]); err != nil {
+			return nil, err
+		}
+		return pk, nil
+	case s2kUsageAEAD:
+		rest = rest[1:]
+		if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+			return nil, errors.New("openpgp: truncated secret key packet")
+		}
+		params, encryptedData := rest[1:1+int(rest[0])], rest[1+int(rest[0]):]
+		if len(params) < 3 || params[0] != cipherAES256 || params[1] != aeadGCM {
+			return nil, errors.New("openpgp: unsupported secret key protection")
+		}
+		s2kLength := int(params[2])
+		if len(params) != 3+s2kLength+gcmNonceSize {
+			return nil, errors.New("openpgp: invalid secret key protection parameters")
+		}
+		if pk.s2k, err = parseS2K(params[3 : 3+s2kLength]); err != nil {
+			return nil, err
+		}
+		pk.iv = params[3+s2kLength:]
+		pk.encryptedData = encryptedData
+		pk.Encrypted = true
+		return pk, nil
+	}
+	return nil, fmt.Errorf("openpgp: unsupported S2K usage %d", rest[0])
+}
+
+func (pk *PrivateKey) parseKeyMaterial(b []byte) error {
+	if len(b) != privateKeySize {
+		return errors.New("openpgp: invalid secret key material")
+	}
+	key, err := ecc.ParsePrivateKeySEC1(b)
+	if err != nil {
+		return fmt.Errorf("openpgp: invalid secret key: %v", err)
+	}
+	publicKey, err := key.DerivePublicKey()
+	if err != nil {
+		return err
+	}
+	if !publicKey.Equal(pk.PublicKey.PublicKey) {
+		return errors.New("openpgp: secret key does not match public key")
+	}
+	pk.PrivateKey = key
+	return nil
+}
+
+func (pk *PrivateKey) serialize(w io.Writer) error {
+	b := pk.body()
+	if !pk.Encrypted {
+		b = append(b, s2kUsageNone)
+		b = append(b, pk.marshalKeyMaterial()...)
+	} else {
+		s2k := pk.s2k.marshal()
+		params := append([]byte{cipherAES256, aeadGCM, byte(len(s2k))}, s2k...)
+		params = append(params, pk.iv...)
+		b = append(b, s2kUsageAEAD, byte(len(params)))
+		b = append(b, params...)
+		b = append(b, pk.encryptedData...)
+	}
+	return writePacket(w, pk.packetType(), b)
+}
+
+// aead returns the cipher that protects the key material, keyed with the
+// S2K output as described in RFC 9580, section 5.5.3, and the associated
+// data that binds it to the public key.
+func (pk *PrivateKey) aead(passphrase []byte) (cipher.AEAD, []byte, error) {
+	t := pk.packetType()
+	info := []byte{t.headerByte(), keyVersion, cipherAES256, aeadGCM}
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, pk.s2k.deriveKey(passphrase, 32), nil, info), key); err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, append([]byte{t.headerByte()}, pk.body()...), nil
+}
+
+// Encrypt protects the secret key with passphrase, using an Argon2 S2K and
+// AES-256-GCM.
+func (pk *PrivateKey) Encrypt(passphrase []byte) error {
+	if pk.Encrypted {
+		return errors.New("openpgp: secret key is already encrypted")
+	}
+	s2k, err := newArgon2S2K()
+	if err != nil {
+		return err
+	}
+	iv := make([]byte, gcmNonceSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return err
+	}
+	pk.s2k, pk.iv = s2k, iv
+
+	aead, additionalData, err := pk.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	pk.encryptedData = aead.Seal(nil, iv, pk.marshalKeyMaterial(), additionalData)
+	pk.PrivateKey = ecc.PrivateKey{}
+	pk.Encrypted = true
+	return nil
+}
+
+// Decrypt removes the passphrase protection from the secret key. It returns
+// ErrIncorrectPassphrase if the passphrase is wrong.
+func (pk *PrivateKey) Decrypt(passphrase []byte) error {
+	if !pk.Encrypted {
+		return nil
+	}
+	aead, additionalData, err := pk.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	material, err := aead.Open(nil, pk.iv, pk.encryptedData, additionalData)
+	if err != nil {
+		return ErrIncorrectPassphrase
+	}
+	if err := pk.parseKeyMaterial(material); err != nil {
+		return err
+	}
+	pk.Encrypted = false
+	pk.s2k, pk.iv, pk.encryptedData = s2k{}, nil, nil
+	return nil
+}
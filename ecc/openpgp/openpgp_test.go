@@ -0,0 +1,226 @@
+package openpgp_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/openpgp"
+)
+
+// newEntity creates an entity with fresh keys, failing the test on error.
+func newEntity(t *testing.T, userId string) *openpgp.Entity {
+	t.Helper()
+	signingKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptionKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := openpgp.NewEntity(userId, signingKey, encryptionKey, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to create entity: %v", err)
+	}
+	return e
+}
+
+func readKeyRing(t *testing.T, data []byte) []*openpgp.Entity {
+	t.Helper()
+	keyring, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to read key ring: %v", err)
+	}
+	return keyring
+}
+
+func TestEntityRoundTrip(t *testing.T) {
+	alice := newEntity(t, "Alice <alice@example.com>")
+
+	var public bytes.Buffer
+	if err := alice.Serialize(&public); err != nil {
+		t.Fatal(err)
+	}
+	keyring := readKeyRing(t, public.Bytes())
+	if len(keyring) != 1 {
+		t.Fatalf("Read %d entities, want 1", len(keyring))
+	}
+	parsed := keyring[0]
+	if parsed.PrivateKey != nil {
+		t.Error("Public key ring contains a private key")
+	}
+	if !bytes.Equal(parsed.PrimaryKey.Fingerprint(), alice.PrimaryKey.Fingerprint()) {
+		t.Error("Fingerprint changed after round trip")
+	}
+	if len(parsed.Identities) != 1 || parsed.Identities[0].UserId.Id != "Alice <alice@example.com>" {
+		t.Error("User ID was not preserved")
+	}
+	if len(parsed.Subkeys) != 1 || !parsed.Subkeys[0].PublicKey.CanEncrypt() {
+		t.Error("Encryption subkey was not preserved")
+	}
+	if parsed.DirectSignature.KeyFlags != openpgp.KeyFlagCertify|openpgp.KeyFlagSign {
+		t.Errorf("Primary key flags = %#x", parsed.DirectSignature.KeyFlags)
+	}
+
+	t.Run("tampered user ID", func(t *testing.T) {
+		tampered := bytes.Replace(public.Bytes(), []byte("alice@example.com"), []byte("mallory@example.c"), 1)
+		if _, err := openpgp.ReadKeyRing(bytes.NewReader(tampered)); err == nil {
+			t.Error("ReadKeyRing accepted a user ID with an invalid self-signature")
+		}
+	})
+
+	t.Run("multiple entities", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := alice.Serialize(&b); err != nil {
+			t.Fatal(err)
+		}
+		if err := newEntity(t, "Bob").Serialize(&b); err != nil {
+			t.Fatal(err)
+		}
+		if keyring := readKeyRing(t, b.Bytes()); len(keyring) != 2 {
+			t.Errorf("Read %d entities, want 2", len(keyring))
+		}
+	})
+}
+
+func TestPrivateKeyProtection(t *testing.T) {
+	alice := newEntity(t, "Alice")
+	passphrase := []byte("correct horse battery staple")
+	if err := alice.EncryptPrivateKeys(passphrase); err != nil {
+		t.Fatal(err)
+	}
+
+	var secret bytes.Buffer
+	if err := alice.SerializePrivate(&secret); err != nil {
+		t.Fatal(err)
+	}
+	parsed := readKeyRing(t, secret.Bytes())[0]
+	if parsed.PrivateKey == nil || !parsed.PrivateKey.Encrypted {
+		t.Fatal("Secret key was not read as encrypted")
+	}
+
+	if err := parsed.DecryptPrivateKeys([]byte("wrong")); !errors.Is(err, openpgp.ErrIncorrectPassphrase) {
+		t.Errorf("DecryptPrivateKeys with the wrong passphrase returned %v", err)
+	}
+	if err := parsed.DecryptPrivateKeys(passphrase); err != nil {
+		t.Fatalf("DecryptPrivateKeys failed: %v", err)
+	}
+	if _, err := openpgp.DetachSign([]byte("test"), parsed); err != nil {
+		t.Errorf("Decrypted key cannot sign: %v", err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	alice := newEntity(t, "Alice")
+	bob := newEntity(t, "Bob")
+	carol := newEntity(t, "Carol")
+
+	for _, plaintext := range [][]byte{
+		[]byte("Hello, OpenPGP!"),
+		bytes.Repeat([]byte{0x42}, 3*64*1024+17),
+	} {
+		message, err := openpgp.Encrypt(plaintext, "hello.txt", []*openpgp.Entity{alice, bob})
+		if err != nil {
+			t.Fatalf("Encrypt failed: %v", err)
+		}
+
+		for _, recipient := range []*openpgp.Entity{alice, bob} {
+			literal, err := openpgp.Decrypt(message, []*openpgp.Entity{carol, recipient})
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(literal.Body, plaintext) || literal.FileName != "hello.txt" {
+				t.Error("Decrypted message does not match")
+			}
+		}
+
+		if _, err := openpgp.Decrypt(message, []*openpgp.Entity{carol}); err == nil {
+			t.Error("Decrypt succeeded for a non-recipient")
+		}
+
+		tampered := append([]byte{}, message...)
+		tampered[len(tampered)-20] ^= 1
+		if _, err := openpgp.Decrypt(tampered, []*openpgp.Entity{alice}); err == nil {
+			t.Error("Decrypt succeeded on a tampered message")
+		}
+	}
+}
+
+func TestDetachSign(t *testing.T) {
+	alice := newEntity(t, "Alice")
+	bob := newEntity(t, "Bob")
+	message := []byte("signed message")
+
+	signature, err := openpgp.DetachSign(message, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := openpgp.CheckDetachedSignature([]*openpgp.Entity{bob, alice}, message, signature)
+	if err != nil {
+		t.Fatalf("CheckDetachedSignature failed: %v", err)
+	}
+	if signer != alice {
+		t.Error("CheckDetachedSignature returned the wrong signer")
+	}
+
+	if _, err := openpgp.CheckDetachedSignature([]*openpgp.Entity{alice}, []byte("other message"), signature); err == nil {
+		t.Error("CheckDetachedSignature accepted the wrong message")
+	}
+	if _, err := openpgp.CheckDetachedSignature([]*openpgp.Entity{bob}, message, signature); err == nil {
+		t.Error("CheckDetachedSignature accepted an unknown signer")
+	}
+}
+
+func TestArmor(t *testing.T) {
+	alice := newEntity(t, "Alice")
+	var public bytes.Buffer
+	if err := alice.Serialize(&public); err != nil {
+		t.Fatal(err)
+	}
+
+	armored := openpgp.Armor(openpgp.ArmorPublicKey, public.Bytes())
+	if !bytes.HasPrefix(armored, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----\n")) {
+		t.Errorf("Unexpected armor header: %q", armored[:40])
+	}
+
+	// Add a header, a checksum and CRLF line endings, all of which must be
+	// tolerated.
+	withExtras := bytes.Replace(armored, []byte("-----\n\n"), []byte("-----\nComment: test\n\n"), 1)
+	withExtras = bytes.Replace(withExtras, []byte("-----END"), []byte("=AAAA\n-----END"), 1)
+	withExtras = bytes.ReplaceAll(withExtras, []byte("\n"), []byte("\r\n"))
+
+	for _, input := range [][]byte{armored, withExtras} {
+		blockType, data, err := openpgp.Dearmor(input)
+		if err != nil {
+			t.Fatalf("Dearmor failed: %v", err)
+		}
+		if blockType != openpgp.ArmorPublicKey || !bytes.Equal(data, public.Bytes()) {
+			t.Error("Dearmor did not return the original data")
+		}
+	}
+
+	if _, _, err := openpgp.Dearmor([]byte("not armored")); err == nil {
+		t.Error("Dearmor accepted data without armor")
+	}
+}
+
+func TestReadPartialLengths(t *testing.T) {
+	// A user ID packet split into a 512-octet partial body and a 3-octet
+	// final body.
+	id := bytes.Repeat([]byte("a"), 515)
+	packet := append([]byte{0xc0 | 13, 0xe0 | 9}, id[:512]...)
+	packet = append(append(packet, 3), id[512:]...)
+
+	p, err := openpgp.Read(bytes.NewReader(packet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, ok := p.(*openpgp.UserID)
+	if !ok || uid.Id != string(id) {
+		t.Error("Partial body lengths were not reassembled")
+	}
+}
@@ -0,0 +1,276 @@
+package openpgp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// PublicKeyAlgorithm is an OpenPGP public-key algorithm ID.
+type PublicKeyAlgorithm uint8
+
+const (
+	// PubKeyAlgoEccFrog512ck2ECDSA identifies EccFrog512ck2 signing keys. It
+	// is an ID from the private/experimental range.
+	PubKeyAlgoEccFrog512ck2ECDSA PublicKeyAlgorithm = 100
+	// PubKeyAlgoEccFrog512ck2ECDH identifies EccFrog512ck2 encryption keys.
+	// It is an ID from the private/experimental range.
+	PubKeyAlgoEccFrog512ck2ECDH PublicKeyAlgorithm = 101
+)
+
+// String returns a human-readable name for the algorithm.
+func (algo PublicKeyAlgorithm) String() string {
+	switch algo {
+	case PubKeyAlgoEccFrog512ck2ECDSA:
+		return "EccFrog512ck2-ECDSA"
+	case PubKeyAlgoEccFrog512ck2ECDH:
+		return "EccFrog512ck2-ECDH"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(algo))
+}
+
+// Algorithm IDs from RFC 9580, section 9. Only the algorithms used by this
+// package are listed.
+const (
+	hashSHA256 = 8
+	hashSHA512 = 10
+
+	cipherAES256 = 9
+
+	aeadGCM = 3
+)
+
+type packetType uint8
+
+const (
+	packetTypeEncryptedKey           packetType = 1
+	packetTypeSignature              packetType = 2
+	packetTypeSecretKey              packetType = 5
+	packetTypePublicKey              packetType = 6
+	packetTypeSecretSubkey           packetType = 7
+	packetTypeLiteralData            packetType = 11
+	packetTypeUserID                 packetType = 13
+	packetTypePublicSubkey           packetType = 14
+	packetTypeSymmetricallyEncrypted packetType = 18
+	packetTypePadding                packetType = 21
+)
+
+// headerByte returns the first octet of a packet of the given type in
+// OpenPGP (new) format, which is also what several hash and key derivation
+// inputs start with.
+func (t packetType) headerByte() byte {
+	return 0xc0 | byte(t)
+}
+
+// Packet is an OpenPGP packet. Use a type switch to find out which kind of
+// packet was read.
+type Packet interface {
+	serialize(w io.Writer) error
+}
+
+// Serialize writes p in OpenPGP format.
+func Serialize(w io.Writer, p Packet) error {
+	return p.serialize(w)
+}
+
+// writePacket writes body as a packet of the given type, with an OpenPGP
+// format header and a definite length.
+func writePacket(w io.Writer, t packetType, body []byte) error {
+	header := []byte{t.headerByte()}
+	header = appendLength(header, len(body))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// appendLength appends a length in the one, two or five octet encoding used
+// by packet headers and signature subpackets.
+func appendLength(b []byte, length int) []byte {
+	switch {
+	case length < 192:
+		return append(b, byte(length))
+	case length < 8384:
+		length -= 192
+		return append(b, byte(length>>8)+192, byte(length))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xff), uint32(length))
+	}
+}
+
+// readPacket reads the next packet from r, returning its type and body.
+// Both packet header formats are accepted, as are partial body lengths.
+func readPacket(r io.Reader) (packetType, []byte, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return 0, nil, err
+	}
+	if buf[0]&0x80 == 0 {
+		return 0, nil, errors.New("openpgp: invalid packet header")
+	}
+
+	if buf[0]&0x40 == 0 {
+		// Legacy format.
+		t := packetType((buf[0] >> 2) & 0xf)
+		var length int
+		switch buf[0] & 3 {
+		case 0:
+			if _, err := io.ReadFull(r, buf[:1]); err != nil {
+				return 0, nil, unexpectedEOF(err)
+			}
+			length = int(buf[0])
+		case 1:
+			if _, err := io.ReadFull(r, buf[:2]); err != nil {
+				return 0, nil, unexpectedEOF(err)
+			}
+			length = int(binary.BigEndian.Uint16(buf[:2]))
+		case 2:
+			if _, err := io.ReadFull(r, buf[:4]); err != nil {
+				return 0, nil, unexpectedEOF(err)
+			}
+			length = int(binary.BigEndian.Uint32(buf[:4]))
+		default:
+			body, err := io.ReadAll(r)
+			return t, body, err
+		}
+		body, err := readBody(r, length)
+		return t, body, err
+	}
+
+	t := packetType(buf[0] & 0x3f)
+	var body []byte
+	for {
+		length, partial, err := readLength(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		chunk, err := readBody(r, length)
+		if err != nil {
+			return 0, nil, err
+		}
+		body = append(body, chunk...)
+		if !partial {
+			return t, body, nil
+		}
+	}
+}
+
+func readLength(r io.Reader) (length int, partial bool, err error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return 0, false, unexpectedEOF(err)
+	}
+	switch {
+	case buf[0] < 192:
+		return int(buf[0]), false, nil
+	case buf[0] < 224:
+		first := int(buf[0])
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			return 0, false, unexpectedEOF(err)
+		}
+		return (first-192)<<8 + int(buf[0]) + 192, false, nil
+	case buf[0] < 255:
+		return 1 << (buf[0] & 0x1f), true, nil
+	default:
+		if _, err := io.ReadFull(r, buf[:4]); err != nil {
+			return 0, false, unexpectedEOF(err)
+		}
+		return int(binary.BigEndian.Uint32(buf[:4])), false, nil
+	}
+}
+
+func readBody(r io.Reader, length int) ([]byte, error) {
+	var body bytes.Buffer
+	if _, err := io.CopyN(&body, r, int64(length)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return body.Bytes(), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Read reads a single packet from r. It returns io.EOF when there are no
+// more packets.
+func Read(r io.Reader) (Packet, error) {
+	t, body, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	return parsePacket(t, body)
+}
+
+func parsePacket(t packetType, body []byte) (Packet, error) {
+	switch t {
+	case packetTypeEncryptedKey:
+		return parseEncryptedKey(body)
+	case packetTypeSignature:
+		return parseSignature(body)
+	case packetTypeSecretKey, packetTypeSecretSubkey:
+		return parsePrivateKey(body, t == packetTypeSecretSubkey)
+	case packetTypePublicKey, packetTypePublicSubkey:
+		pk, _, err := parsePublicKey(body, t == packetTypePublicSubkey)
+		return pk, err
+	case packetTypeLiteralData:
+		return parseLiteralData(body)
+	case packetTypeUserID:
+		return &UserID{Id: string(body)}, nil
+	case packetTypeSymmetricallyEncrypted:
+		return parseSymmetricallyEncrypted(body)
+	case packetTypePadding:
+		return &Padding{Length: len(body)}, nil
+	}
+	return nil, fmt.Errorf("openpgp: unsupported packet type %d", t)
+}
+
+// UserID is a user ID packet, conventionally of the form
+// "Name (Comment) <email@example.com>".
+type UserID struct {
+	Id string
+}
+
+func (uid *UserID) serialize(w io.Writer) error {
+	return writePacket(w, packetTypeUserID, []byte(uid.Id))
+}
+
+// Padding is a padding packet, whose contents are ignored.
+type Padding struct {
+	Length int
+}
+
+func (p *Padding) serialize(w io.Writer) error {
+	return writePacket(w, packetTypePadding, make([]byte, p.Length))
+}
+
+// appendMPI appends n as a multiprecision integer: a two-octet bit count
+// followed by the big-endian magnitude.
+func appendMPI(b []byte, n *big.Int) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(n.BitLen()))
+	return append(b, n.Bytes()...)
+}
+
+// readMPI reads a multiprecision integer from the start of b, returning it
+// and the remaining bytes.
+func readMPI(b []byte) (*big.Int, []byte, error) {
+	if len(b) < 2 {
+		return nil, nil, errors.New("openpgp: truncated MPI")
+	}
+	bits := int(binary.BigEndian.Uint16(b))
+	length := (bits + 7) / 8
+	if len(b) < 2+length {
+		return nil, nil, errors.New("openpgp: truncated MPI")
+	}
+	n := new(big.Int).SetBytes(b[2 : 2+length])
+	if n.BitLen() != bits {
+		return nil, nil, errors.New("openpgp: MPI has an incorrect bit count")
+	}
+	return n, b[2+length:], nil
+}
@@ -0,0 +1,122 @@
+package openpgp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// S2K specifier types from RFC 9580, section 3.7.1.
+const (
+	s2kIteratedSalted = 3
+	s2kArgon2         = 4
+)
+
+// Argon2 parameters used when protecting keys: three passes, four lanes and
+// 64 MiB of memory, the second recommended option of RFC 9106.
+const (
+	argon2Passes        = 3
+	argon2Parallelism   = 4
+	argon2EncodedMemory = 16
+)
+
+// s2k is a string-to-key specifier, which turns a passphrase into a key.
+type s2k struct {
+	mode byte
+	salt []byte
+
+	// Iterated and salted.
+	hash  byte
+	count byte
+
+	// Argon2.
+	passes, parallelism, encodedMemory byte
+}
+
+// newArgon2S2K returns an Argon2 specifier with a random salt.
+func newArgon2S2K() (s2k, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return s2k{}, err
+	}
+	return s2k{
+		mode:          s2kArgon2,
+		salt:          salt,
+		passes:        argon2Passes,
+		parallelism:   argon2Parallelism,
+		encodedMemory: argon2EncodedMemory,
+	}, nil
+}
+
+func (s s2k) marshal() []byte {
+	switch s.mode {
+	case s2kIteratedSalted:
+		return append(append([]byte{s.mode, s.hash}, s.salt...), s.count)
+	default:
+		return append(append([]byte{s.mode}, s.salt...), s.passes, s.parallelism, s.encodedMemory)
+	}
+}
+
+func parseS2K(b []byte) (s2k, error) {
+	if len(b) == 0 {
+		return s2k{}, errors.New("openpgp: empty S2K specifier")
+	}
+	switch b[0] {
+	case s2kIteratedSalted:
+		if len(b) != 11 {
+			return s2k{}, errors.New("openpgp: invalid iterated and salted S2K specifier")
+		}
+		if b[1] != hashSHA256 && b[1] != hashSHA512 {
+			return s2k{}, fmt.Errorf("openpgp: unsupported S2K hash algorithm %d", b[1])
+		}
+		return s2k{mode: b[0], hash: b[1], salt: b[2:10], count: b[10]}, nil
+	case s2kArgon2:
+		if len(b) != 20 {
+			return s2k{}, errors.New("openpgp: invalid Argon2 S2K specifier")
+		}
+		s := s2k{mode: b[0], salt: b[1:17], passes: b[17], parallelism: b[18], encodedMemory: b[19]}
+		// RFC 9580 requires at least 8 KiB of memory per lane, and caps the
+		// exponent at 31.
+		if s.passes == 0 || s.parallelism == 0 || s.encodedMemory > 31 ||
+			1<<s.encodedMemory < 8*uint64(s.parallelism) {
+			return s2k{}, errors.New("openpgp: invalid Argon2 S2K parameters")
+		}
+		return s, nil
+	}
+	return s2k{}, fmt.Errorf("openpgp: unsupported S2K specifier type %d", b[0])
+}
+
+// deriveKey derives a key of the given size from passphrase.
+func (s s2k) deriveKey(passphrase []byte, size int) []byte {
+	switch s.mode {
+	case s2kIteratedSalted:
+		var h hash.Hash
+		if s.hash == hashSHA512 {
+			h = sha512.New()
+		} else {
+			h = sha256.New()
+		}
+		if size > h.Size() {
+			// Only AES-256 keys are derived, which fit in a single hash.
+			panic("openpgp: S2K key size exceeds hash size")
+		}
+		count := (16 + int(s.count&15)) << (s.count>>4 + 6)
+		input := append(append([]byte{}, s.salt...), passphrase...)
+		if count < len(input) {
+			count = len(input)
+		}
+		for ; count > len(input); count -= len(input) {
+			h.Write(input)
+		}
+		h.Write(input[:count])
+		return h.Sum(nil)[:size]
+	default:
+		return argon2.IDKey(passphrase, s.salt, uint32(s.passes), 1<<s.encodedMemory, s.parallelism, uint32(size))
+	}
+}
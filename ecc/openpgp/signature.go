@@ -0,0 +1,378 @@
+package openpgp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdsa"
+)
+
+const (
+	signatureVersion = 6
+
+	// saltSize is the salt size required for SHA-512 signatures.
+	saltSize = 32
+)
+
+// ErrSignatureInvalid is returned when a signature does not verify.
+var ErrSignatureInvalid = errors.New("openpgp: invalid signature")
+
+// SignatureType is the type of a signature, which determines what it
+// covers.
+type SignatureType uint8
+
+const (
+	SigTypeBinary                SignatureType = 0x00
+	SigTypePositiveCertification SignatureType = 0x13
+	SigTypeSubkeyBinding         SignatureType = 0x18
+	SigTypeDirectKey             SignatureType = 0x1f
+)
+
+// KeyFlags are the flags of the key flags subpacket, which say what a key
+// may be used for.
+type KeyFlags uint8
+
+const (
+	KeyFlagCertify               KeyFlags = 0x01
+	KeyFlagSign                  KeyFlags = 0x02
+	KeyFlagEncryptCommunications KeyFlags = 0x04
+	KeyFlagEncryptStorage        KeyFlags = 0x08
+)
+
+// Signature subpacket types from RFC 9580, section 5.2.3.7.
+const (
+	subpacketCreationTime      = 2
+	subpacketKeyFlags          = 27
+	subpacketIssuerFingerprint = 33
+)
+
+// Signature is a version 6 signature packet.
+type Signature struct {
+	SigType    SignatureType
+	PubKeyAlgo PublicKeyAlgorithm
+
+	CreationTime      time.Time
+	IssuerFingerprint []byte
+
+	// KeyFlags is zero if the signature has no key flags subpacket.
+	KeyFlags KeyFlags
+
+	salt     []byte
+	hashTag  [2]byte
+	r, s     *big.Int
+	hashed   []byte
+	unhashed []byte
+}
+
+func (sig *Signature) appendSubpackets(b []byte) []byte {
+	subpacket := func(b []byte, t byte, data []byte) []byte {
+		b = appendLength(b, 1+len(data))
+		return append(append(b, t), data...)
+	}
+	b = subpacket(b, 0x80|subpacketCreationTime, binary.BigEndian.AppendUint32(nil, uint32(sig.CreationTime.Unix())))
+	b = subpacket(b, subpacketIssuerFingerprint, append([]byte{keyVersion}, sig.IssuerFingerprint...))
+	if sig.KeyFlags != 0 {
+		b = subpacket(b, 0x80|subpacketKeyFlags, []byte{byte(sig.KeyFlags)})
+	}
+	return b
+}
+
+func (sig *Signature) parseSubpackets(b []byte, hashed bool) error {
+	for len(b) > 0 {
+		length, n, err := parseSubpacketLength(b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+		if length == 0 || length > len(b) {
+			return errors.New("openpgp: invalid signature subpacket length")
+		}
+		t, critical, data := b[0]&0x7f, b[0]&0x80 != 0, b[1:length]
+		b = b[length:]
+
+		if !hashed {
+			// Unhashed subpackets are not protected by the signature, so
+			// none of them are trusted.
+			continue
+		}
+		switch t {
+		case subpacketCreationTime:
+			if len(data) != 4 {
+				return errors.New("openpgp: invalid signature creation time")
+			}
+			sig.CreationTime = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+		case subpacketIssuerFingerprint:
+			if len(data) < 1 || data[0] != keyVersion || len(data) != 33 {
+				return errors.New("openpgp: invalid issuer fingerprint")
+			}
+			sig.IssuerFingerprint = data[1:]
+		case subpacketKeyFlags:
+			if len(data) > 0 {
+				sig.KeyFlags = KeyFlags(data[0])
+			}
+		default:
+			if critical {
+				return fmt.Errorf("openpgp: unsupported critical signature subpacket %d", t)
+			}
+		}
+	}
+	return nil
+}
+
+func parseSubpacketLength(b []byte) (length, n int, err error) {
+	switch {
+	case len(b) >= 1 && b[0] < 192:
+		return int(b[0]), 1, nil
+	case len(b) >= 2 && b[0] < 255:
+		return (int(b[0])-192)<<8 + int(b[1]) + 192, 2, nil
+	case len(b) >= 5 && b[0] == 255:
+		return int(binary.BigEndian.Uint32(b[1:5])), 5, nil
+	}
+	return 0, 0, errors.New("openpgp: truncated signature subpacket")
+}
+
+// trailer returns the hashed part of the signature, followed by the final
+// trailer of RFC 9580, section 5.2.4.
+func (sig *Signature) trailer() []byte {
+	b := append([]byte(nil), sig.hashed...)
+	b = append(b, signatureVersion, 0xff)
+	return binary.BigEndian.AppendUint32(b, uint32(len(sig.hashed)))
+}
+
+// signedMessage returns the bytes covered by the signature: the salt, the
+// data being signed and the trailer.
+func (sig *Signature) signedMessage(data []byte) []byte {
+	return append(append(append([]byte{}, sig.salt...), data...), sig.trailer()...)
+}
+
+// sign fills in the remaining fields of sig and signs data with priv.
+func (sig *Signature) sign(data []byte, priv *PrivateKey) error {
+	if priv.Encrypted {
+		return errors.New("openpgp: signing key is encrypted")
+	}
+	if !priv.CanSign() {
+		return errors.New("openpgp: key cannot sign")
+	}
+	sig.PubKeyAlgo = priv.PubKeyAlgo
+	if sig.CreationTime.IsZero() {
+		sig.CreationTime = time.Now()
+	}
+	sig.CreationTime = time.Unix(sig.CreationTime.Unix(), 0)
+	sig.IssuerFingerprint = priv.Fingerprint()
+
+	sig.salt = make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, sig.salt); err != nil {
+		return err
+	}
+
+	subpackets := sig.appendSubpackets(nil)
+	sig.hashed = []byte{signatureVersion, byte(sig.SigType), byte(sig.PubKeyAlgo), hashSHA512}
+	sig.hashed = binary.BigEndian.AppendUint32(sig.hashed, uint32(len(subpackets)))
+	sig.hashed = append(sig.hashed, subpackets...)
+	sig.unhashed = nil
+
+	message := sig.signedMessage(data)
+	digest := sha512.Sum512(message)
+	copy(sig.hashTag[:], digest[:2])
+
+	r, s, err := ecdsa.NewSign(sha512.New, priv.PrivateKey).Sign(message)
+	if err != nil {
+		return err
+	}
+	sig.r, sig.s = r, s
+	return nil
+}
+
+// verify checks that sig is a valid signature by pk over data.
+func (sig *Signature) verify(data []byte, pk *PublicKey) error {
+	if sig.PubKeyAlgo != pk.PubKeyAlgo || !pk.CanSign() {
+		return errors.New("openpgp: signature algorithm does not match the key")
+	}
+	if sig.IssuerFingerprint != nil && !bytes.Equal(sig.IssuerFingerprint, pk.Fingerprint()) {
+		return errors.New("openpgp: signature was made by a different key")
+	}
+
+	message := sig.signedMessage(data)
+	digest := sha512.Sum512(message)
+	if digest[0] != sig.hashTag[0] || digest[1] != sig.hashTag[1] {
+		return ErrSignatureInvalid
+	}
+
+	n := eccfrog512ck2.GeneratorOrder()
+	if sig.r.Sign() <= 0 || sig.r.Cmp(n) >= 0 || sig.s.Sign() <= 0 || sig.s.Cmp(n) >= 0 {
+		return ErrSignatureInvalid
+	}
+	ok, err := ecdsa.NewVerification(sha512.New, pk.PublicKey).Verify([2]*big.Int{sig.r, sig.s}, message)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (sig *Signature) serialize(w io.Writer) error {
+	if sig.r == nil {
+		return errors.New("openpgp: signature has not been signed")
+	}
+	b := append([]byte(nil), sig.hashed...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(sig.unhashed)))
+	b = append(b, sig.unhashed...)
+	b = append(b, sig.hashTag[:]...)
+	b = append(b, byte(len(sig.salt)))
+	b = append(b, sig.salt...)
+	b = appendMPI(b, sig.r)
+	b = appendMPI(b, sig.s)
+	return writePacket(w, packetTypeSignature, b)
+}
+
+func parseSignature(b []byte) (*Signature, error) {
+	if len(b) < 8 {
+		return nil, errors.New("openpgp: truncated signature packet")
+	}
+	if b[0] != signatureVersion {
+		return nil, fmt.Errorf("openpgp: unsupported signature version %d", b[0])
+	}
+	sig := &Signature{
+		SigType:    SignatureType(b[1]),
+		PubKeyAlgo: PublicKeyAlgorithm(b[2]),
+	}
+	if b[3] != hashSHA512 {
+		return nil, fmt.Errorf("openpgp: unsupported signature hash algorithm %d", b[3])
+	}
+
+	hashedLength := int(binary.BigEndian.Uint32(b[4:8]))
+	if len(b) < 8+hashedLength+4 {
+		return nil, errors.New("openpgp: truncated signature packet")
+	}
+	sig.hashed = b[:8+hashedLength]
+	rest := b[8+hashedLength:]
+	unhashedLength := int(binary.BigEndian.Uint32(rest))
+	if len(rest) < 4+unhashedLength+3 {
+		return nil, errors.New("openpgp: truncated signature packet")
+	}
+	sig.unhashed = rest[4 : 4+unhashedLength]
+	rest = rest[4+unhashedLength:]
+
+	if err := sig.parseSubpackets(sig.hashed[8:], true); err != nil {
+		return nil, err
+	}
+	if err := sig.parseSubpackets(sig.unhashed, false); err != nil {
+		return nil, err
+	}
+	if sig.CreationTime.IsZero() {
+		return nil, errors.New("openpgp: signature has no creation time")
+	}
+
+	copy(sig.hashTag[:], rest[:2])
+	saltLength := int(rest[2])
+	rest = rest[3:]
+	if saltLength != saltSize || len(rest) < saltLength {
+		return nil, errors.New("openpgp: invalid signature salt")
+	}
+	sig.salt = rest[:saltLength]
+	rest = rest[saltLength:]
+
+	var err error
+	if sig.r, rest, err = readMPI(rest); err != nil {
+		return nil, err
+	}
+	if sig.s, rest, err = readMPI(rest); err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("openpgp: trailing data in signature packet")
+	}
+	return sig, nil
+}
+
+// userIDSignedData returns the data covered by a certification of id on pk.
+func userIDSignedData(pk *PublicKey, id string) []byte {
+	var b bytes.Buffer
+	pk.hashFor(&b)
+	b.WriteByte(0xb4)
+	binary.Write(&b, binary.BigEndian, uint32(len(id)))
+	b.WriteString(id)
+	return b.Bytes()
+}
+
+// keySignedData returns the data covered by a direct key signature on pk,
+// or by a binding signature of subkey to pk if subkey is not nil.
+func keySignedData(pk, subkey *PublicKey) []byte {
+	var b bytes.Buffer
+	pk.hashFor(&b)
+	if subkey != nil {
+		subkey.hashFor(&b)
+	}
+	return b.Bytes()
+}
+
+// Sign signs data with priv, making sig a binary document signature.
+func (sig *Signature) Sign(data []byte, priv *PrivateKey) error {
+	sig.SigType = SigTypeBinary
+	return sig.sign(data, priv)
+}
+
+// SignUserId makes sig a certification of id on pub, made by priv.
+func (sig *Signature) SignUserId(id string, pub *PublicKey, priv *PrivateKey) error {
+	sig.SigType = SigTypePositiveCertification
+	return sig.sign(userIDSignedData(pub, id), priv)
+}
+
+// SignKey makes sig a binding of subkey pub to the primary key priv.
+func (sig *Signature) SignKey(pub *PublicKey, priv *PrivateKey) error {
+	sig.SigType = SigTypeSubkeyBinding
+	return sig.sign(keySignedData(&priv.PublicKey, pub), priv)
+}
+
+// SignDirectKey makes sig a direct key self-signature of priv, which holds
+// the key flags and other properties of the key itself.
+func (sig *Signature) SignDirectKey(priv *PrivateKey) error {
+	sig.SigType = SigTypeDirectKey
+	return sig.sign(keySignedData(&priv.PublicKey, nil), priv)
+}
+
+// VerifySignature checks that sig is a valid binary signature over data made
+// by pk.
+func (pk *PublicKey) VerifySignature(data []byte, sig *Signature) error {
+	if sig.SigType != SigTypeBinary {
+		return errors.New("openpgp: not a binary signature")
+	}
+	return sig.verify(data, pk)
+}
+
+// VerifyUserIdSignature checks that sig is a valid certification of id on
+// pub, made by pk.
+func (pk *PublicKey) VerifyUserIdSignature(id string, pub *PublicKey, sig *Signature) error {
+	if sig.SigType < 0x10 || sig.SigType > SigTypePositiveCertification {
+		return errors.New("openpgp: not a certification signature")
+	}
+	return sig.verify(userIDSignedData(pub, id), pk)
+}
+
+// VerifyKeySignature checks that sig is a valid binding of subkey signed by
+// the primary key pk.
+func (pk *PublicKey) VerifyKeySignature(subkey *PublicKey, sig *Signature) error {
+	if sig.SigType != SigTypeSubkeyBinding {
+		return errors.New("openpgp: not a subkey binding signature")
+	}
+	return sig.verify(keySignedData(pk, subkey), pk)
+}
+
+// VerifyDirectKeySignature checks that sig is a valid direct key signature
+// on pk, made by pk itself.
+func (pk *PublicKey) VerifyDirectKeySignature(sig *Signature) error {
+	if sig.SigType != SigTypeDirectKey {
+		return errors.New("openpgp: not a direct key signature")
+	}
+	return sig.verify(keySignedData(pk, nil), pk)
+}
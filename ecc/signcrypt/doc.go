@@ -0,0 +1,19 @@
+// Package signcrypt signs and encrypts a message in one step, producing a
+// single artifact that the recipient decrypts and verifies in one call.
+//
+// The construction is sign-then-encrypt with both identities bound in, as
+// recommended by Davis, "Defective Sign & Encrypt in S/MIME, PKCS#7, MOSS,
+// PEM, PGP, and XML" (USENIX 2001). The sender signs the message together
+// with its own public key and the recipient's, with ECDSA over SHA-512, and
+// the sender's public key, the signature and the message are then sealed to
+// the recipient with box.SealAnonymous:
+//
+//	magic "EFROGSCR" (8 bytes) || version (1 byte) ||
+//	    sealed box of (sender public key (65 bytes) || r || s (64 bytes each) || message)
+//
+// Signing the recipient's key stops the recipient from re-encrypting a
+// signed message to a third party, who would otherwise believe it was meant
+// for them. Encrypting the signature hides who sent the message from
+// everyone but the recipient, and stops anyone from replacing it with their
+// own.
+package signcrypt
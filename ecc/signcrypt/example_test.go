@@ -0,0 +1,30 @@
+package signcrypt_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/signcrypt"
+)
+
+func ExampleSeal() {
+	alicePrivateKey, _ := ecc.GeneratePrivateKey()
+	alicePublicKey, _ := alicePrivateKey.DerivePublicKey()
+	bobPrivateKey, _ := ecc.GeneratePrivateKey()
+	bobPublicKey, _ := bobPrivateKey.DerivePublicKey()
+
+	// Alice signs and encrypts a message to Bob in one step
+	sealed, err := signcrypt.Seal(alicePrivateKey, bobPublicKey, []byte("Hello, Bob!"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Bob decrypts it and checks that Alice signed it for him
+	message, err := signcrypt.Open(bobPrivateKey, alicePublicKey, sealed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(message))
+	// Output: Hello, Bob!
+}
@@ -0,0 +1,142 @@
+package signcrypt
+
+import (
+	"crypto/sha512"
+	"errors"
+	"math/big"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/box"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdsa"
+)
+
+const (
+	// Magic starts every signcrypted message.
+	Magic = "EFROGSCR"
+	// Version is the version of the format that Seal writes.
+	Version = 1
+
+	// Overhead is the number of bytes Seal adds to a message.
+	Overhead = len(Magic) + 1 + box.AnonymousOverhead + senderKeySize + signatureSize
+
+	senderKeySize = 65
+	scalarSize    = 64
+	signatureSize = 2 * scalarSize
+)
+
+// label separates signcryption signatures from signatures over other data.
+const label = "eccfrog512ck2 signcryption v1"
+
+// signedData returns the data the sender signs: the label, both public keys
+// and the message.
+func signedData(sender, recipient eccfrog512ck2.CurvePoint, message []byte) []byte {
+	data := append([]byte(label), sender.MarshalSEC1(false)...)
+	data = append(data, recipient.MarshalSEC1(false)...)
+	return append(data, message...)
+}
+
+// Seal signs message with senderPrivateKey and encrypts it, along with the
+// signature, to recipientPublicKey.
+//
+// Parameters:
+//   - senderPrivateKey: The sender's private key, which signs the message
+//   - recipientPublicKey: The public key of the recipient
+//   - message: The message to signcrypt
+//
+// Returns:
+//   - The signcrypted message, Overhead bytes longer than message
+//   - An error if either key is invalid, or signing or encryption fails
+func Seal(
+	senderPrivateKey ecc.PrivateKey,
+	recipientPublicKey eccfrog512ck2.CurvePoint,
+	message []byte,
+) ([]byte, error) {
+	if err := ecc.ValidatePublicKey(recipientPublicKey); err != nil {
+		return nil, err
+	}
+	senderPublicKey, err := senderPrivateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := ecdsa.NewSign(sha512.New, senderPrivateKey).
+		Sign(signedData(senderPublicKey, recipientPublicKey, message))
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, 0, senderKeySize+signatureSize+len(message))
+	payload = append(payload, senderPublicKey.MarshalSEC1(true)...)
+	payload = append(payload, r.FillBytes(make([]byte, scalarSize))...)
+	payload = append(payload, s.FillBytes(make([]byte, scalarSize))...)
+	payload = append(payload, message...)
+
+	out := append([]byte(Magic), Version)
+	return box.SealAnonymous(out, payload, recipientPublicKey, nil)
+}
+
+// Open decrypts a message signcrypted by Seal and verifies that
+// senderPublicKey signed it for the holder of recipientPrivateKey.
+//
+// Parameters:
+//   - recipientPrivateKey: The recipient's private key
+//   - senderPublicKey: The public key of the expected sender
+//   - sealed: The signcrypted message
+//
+// Returns:
+//   - The message
+//   - An error if the message is malformed, was not encrypted to the
+//     recipient, or was not signed by senderPublicKey for the recipient
+func Open(
+	recipientPrivateKey ecc.PrivateKey,
+	senderPublicKey eccfrog512ck2.CurvePoint,
+	sealed []byte,
+) ([]byte, error) {
+	if len(sealed) < len(Magic)+1 || string(sealed[:len(Magic)]) != Magic {
+		return nil, errors.New("signcrypt: not a signcrypted message")
+	}
+	if sealed[len(Magic)] != Version {
+		return nil, errors.New("signcrypt: unsupported version")
+	}
+	if err := ecc.ValidatePublicKey(senderPublicKey); err != nil {
+		return nil, err
+	}
+	recipientPublicKey, err := recipientPrivateKey.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, ok := box.OpenAnonymous(nil, sealed[len(Magic)+1:], recipientPublicKey, recipientPrivateKey)
+	if !ok {
+		return nil, errors.New("signcrypt: message authentication failed")
+	}
+	if len(payload) < senderKeySize+signatureSize {
+		return nil, errors.New("signcrypt: truncated message")
+	}
+	sender, err := ecc.ParsePublicKeySEC1(payload[:senderKeySize])
+	if err != nil {
+		return nil, err
+	}
+	if !sender.Equal(senderPublicKey) {
+		return nil, errors.New("signcrypt: message is from a different sender")
+	}
+	signature := payload[senderKeySize : senderKeySize+signatureSize]
+	r := new(big.Int).SetBytes(signature[:scalarSize])
+	s := new(big.Int).SetBytes(signature[scalarSize:])
+	message := payload[senderKeySize+signatureSize:]
+
+	// Verify operates on r and s, so reject those outside [1, n-1] first
+	if !inScalarRange(r) || !inScalarRange(s) {
+		return nil, errors.New("signcrypt: invalid signature")
+	}
+	valid, err := ecdsa.NewVerification(sha512.New, senderPublicKey).
+		Verify([2]*big.Int{r, s}, signedData(senderPublicKey, recipientPublicKey, message))
+	if err != nil || !valid {
+		return nil, errors.New("signcrypt: invalid signature")
+	}
+	return message, nil
+}
+
+func inScalarRange(value *big.Int) bool {
+	return value.Sign() > 0 && value.Cmp(eccfrog512ck2.GeneratorOrder()) < 0
+}
@@ -0,0 +1,112 @@
+package signcrypt_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/box"
+	"github.com/shovon/go-eccfrog512ck2/ecc/signcrypt"
+)
+
+func generateKey(t *testing.T) (eccfrog512ck2.CurvePoint, ecc.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := box.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKey, privateKey
+}
+
+// resealed opens sealed as its recipient and seals the payload unchanged to
+// another recipient, as a recipient forwarding a message would.
+func resealed(t *testing.T, sealed []byte, publicKey eccfrog512ck2.CurvePoint, privateKey ecc.PrivateKey, to eccfrog512ck2.CurvePoint) []byte {
+	t.Helper()
+	header := sealed[:len(signcrypt.Magic)+1]
+	payload, ok := box.OpenAnonymous(nil, sealed[len(header):], publicKey, privateKey)
+	if !ok {
+		t.Fatal("Failed to open box")
+	}
+	out, err := box.SealAnonymous(bytes.Clone(header), payload, to, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestSealOpen(t *testing.T) {
+	alicePublicKey, alicePrivateKey := generateKey(t)
+	bobPublicKey, bobPrivateKey := generateKey(t)
+	carolPublicKey, carolPrivateKey := generateKey(t)
+	message := []byte("Hello, World!")
+
+	sealed, err := signcrypt.Seal(alicePrivateKey, bobPublicKey, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sealed) != len(message)+signcrypt.Overhead {
+		t.Fatalf("Unexpected message of %d bytes", len(sealed))
+	}
+	if bytes.Contains(sealed, message) {
+		t.Error("Message is not encrypted")
+	}
+	opened, err := signcrypt.Open(bobPrivateKey, alicePublicKey, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, message) {
+		t.Fatal("Opened message does not match")
+	}
+
+	if _, err := signcrypt.Open(bobPrivateKey, carolPublicKey, sealed); err == nil {
+		t.Error("Opened a message from the wrong sender")
+	}
+	if _, err := signcrypt.Open(carolPrivateKey, alicePublicKey, sealed); err == nil {
+		t.Error("Opened a message with the wrong recipient key")
+	}
+
+	// Bob cannot forward Alice's signed message to Carol as if it were
+	// meant for her
+	forwarded := resealed(t, sealed, bobPublicKey, bobPrivateKey, carolPublicKey)
+	if _, err := signcrypt.Open(carolPrivateKey, alicePublicKey, forwarded); err == nil {
+		t.Error("Opened a forwarded message")
+	}
+
+	for _, i := range []int{0, len(signcrypt.Magic), len(signcrypt.Magic) + 1, len(sealed) / 2, len(sealed) - 1} {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 1
+		if _, err := signcrypt.Open(bobPrivateKey, alicePublicKey, tampered); err == nil {
+			t.Errorf("Opened a message modified at byte %d", i)
+		}
+	}
+	for _, n := range []int{0, len(signcrypt.Magic) + 1, signcrypt.Overhead - 1} {
+		if _, err := signcrypt.Open(bobPrivateKey, alicePublicKey, sealed[:n]); err == nil {
+			t.Errorf("Opened a message truncated to %d bytes", n)
+		}
+	}
+
+	if _, err := signcrypt.Seal(alicePrivateKey, eccfrog512ck2.PointAtInfinity(), message); err == nil {
+		t.Error("Expected error for the point at infinity")
+	}
+	if _, err := signcrypt.Open(bobPrivateKey, eccfrog512ck2.CurvePoint{}, sealed); err == nil {
+		t.Error("Expected error for the point at infinity")
+	}
+}
+
+func TestOpenForgedSignature(t *testing.T) {
+	alicePublicKey, _ := generateKey(t)
+	bobPublicKey, bobPrivateKey := generateKey(t)
+
+	// Anyone can seal a box to Bob, so the signature inside is untrusted
+	// input. A zero signature must be rejected rather than verified.
+	payload := append(alicePublicKey.MarshalSEC1(true), make([]byte, 128)...)
+	payload = append(payload, "Hello, World!"...)
+	forged, err := box.SealAnonymous(append([]byte(signcrypt.Magic), signcrypt.Version), payload, bobPublicKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signcrypt.Open(bobPrivateKey, alicePublicKey, forged); err == nil {
+		t.Error("Opened a message with a zero signature")
+	}
+}
@@ -0,0 +1,312 @@
+package x509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+)
+
+var (
+	oidExtensionCRLNumber  = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+)
+
+type revokedCertificate struct {
+	SerialNumber   *big.Int
+	RevocationTime asn1.RawValue
+	Extensions     []pkix.Extension `asn1:"optional"`
+}
+
+type tbsCertList struct {
+	Raw                 asn1.RawContent
+	Version             int
+	Signature           pkix.AlgorithmIdentifier
+	Issuer              asn1.RawValue
+	ThisUpdate          asn1.RawValue
+	NextUpdate          asn1.RawValue        `asn1:"optional"`
+	RevokedCertificates []revokedCertificate `asn1:"optional"`
+	Extensions          []pkix.Extension     `asn1:"tag:0,optional,explicit"`
+}
+
+// parsedRevokedCertificate and parsedTBSCertList mirror their counterparts
+// above, but use time.Time so that both UTCTime and GeneralizedTime are
+// accepted, and so that the optional nextUpdate can be told apart from the
+// list of revoked certificates.
+type parsedRevokedCertificate struct {
+	SerialNumber   *big.Int
+	RevocationTime time.Time
+	Extensions     []pkix.Extension `asn1:"optional"`
+}
+
+type parsedTBSCertList struct {
+	Raw                 asn1.RawContent
+	Version             int `asn1:"optional"`
+	Signature           pkix.AlgorithmIdentifier
+	Issuer              asn1.RawValue
+	ThisUpdate          time.Time
+	NextUpdate          time.Time                  `asn1:"optional"`
+	RevokedCertificates []parsedRevokedCertificate `asn1:"optional"`
+	Extensions          []pkix.Extension           `asn1:"tag:0,optional,explicit"`
+}
+
+type certificateList struct {
+	TBSCertList        asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// RevocationListEntry represents an entry in the revokedCertificates sequence
+// of a CRL.
+type RevocationListEntry struct {
+	SerialNumber   *big.Int
+	RevocationTime time.Time
+
+	// ReasonCode is the CRLReason from RFC 5280, section 5.3.1. Zero means
+	// that no reason was given.
+	ReasonCode int
+}
+
+// RevocationList represents a certificate revocation list as defined in RFC
+// 5280, signed by an EccFrog512ck2 CA.
+type RevocationList struct {
+	Raw                  []byte // Complete ASN.1 DER content.
+	RawTBSRevocationList []byte // The tbsCertList part of the ASN.1 DER content.
+	RawIssuer            []byte // DER encoded Issuer.
+
+	Issuer         pkix.Name
+	AuthorityKeyId []byte
+
+	Signature          []byte
+	SignatureAlgorithm asn1.ObjectIdentifier
+
+	RevokedCertificateEntries []RevocationListEntry
+
+	// Number is the value of the CRL number extension, which must be
+	// monotonically increasing for each list issued by a CA.
+	Number *big.Int
+
+	ThisUpdate time.Time
+	NextUpdate time.Time
+
+	// Extensions contains all the extensions found in a parsed list.
+	Extensions []pkix.Extension
+}
+
+// CreateRevocationList creates a new X.509 v2 certificate revocation list,
+// signed by priv on behalf of issuer, and returns it in DER form.
+//
+// The following members of template are used: RevokedCertificateEntries,
+// Number, ThisUpdate and NextUpdate. The issuer must be a CA allowed to sign
+// CRLs, and priv must be the private key of its public key.
+func CreateRevocationList(template *RevocationList, issuer *Certificate, priv ecc.PrivateKey) ([]byte, error) {
+	if template.Number == nil {
+		return nil, errors.New("x509: template contains nil Number field")
+	}
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&KeyUsageCRLSign == 0 {
+		return nil, errors.New("x509: issuer must have the CRL signing key usage")
+	}
+	if !template.NextUpdate.IsZero() && template.NextUpdate.Before(template.ThisUpdate) {
+		return nil, errors.New("x509: template.ThisUpdate is after template.NextUpdate")
+	}
+	if err := checkKeyPair(priv, issuer.PublicKey); err != nil {
+		return nil, err
+	}
+
+	issuerName, err := marshalName(issuer.RawSubject, issuer.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	thisUpdate, err := marshalTime(template.ThisUpdate)
+	if err != nil {
+		return nil, err
+	}
+	tbs := tbsCertList{
+		Version:    1,
+		Signature:  signatureAlgorithm(),
+		Issuer:     issuerName,
+		ThisUpdate: asn1.RawValue{FullBytes: thisUpdate},
+	}
+	if !template.NextUpdate.IsZero() {
+		nextUpdate, err := marshalTime(template.NextUpdate)
+		if err != nil {
+			return nil, err
+		}
+		tbs.NextUpdate = asn1.RawValue{FullBytes: nextUpdate}
+	}
+
+	for _, entry := range template.RevokedCertificateEntries {
+		revocationTime, err := marshalTime(entry.RevocationTime)
+		if err != nil {
+			return nil, err
+		}
+		revoked := revokedCertificate{
+			SerialNumber:   entry.SerialNumber,
+			RevocationTime: asn1.RawValue{FullBytes: revocationTime},
+		}
+		if entry.ReasonCode != 0 {
+			value, err := asn1.Marshal(asn1.Enumerated(entry.ReasonCode))
+			if err != nil {
+				return nil, err
+			}
+			revoked.Extensions = []pkix.Extension{{Id: oidExtensionReasonCode, Value: value}}
+		}
+		tbs.RevokedCertificates = append(tbs.RevokedCertificates, revoked)
+	}
+
+	number, err := asn1.Marshal(template.Number)
+	if err != nil {
+		return nil, err
+	}
+	tbs.Extensions = []pkix.Extension{{Id: oidExtensionCRLNumber, Value: number}}
+	if len(issuer.SubjectKeyId) > 0 {
+		value, err := asn1.Marshal(authKeyId{Id: issuer.SubjectKeyId})
+		if err != nil {
+			return nil, err
+		}
+		tbs.Extensions = append(tbs.Extensions, pkix.Extension{Id: oidExtensionAuthorityKeyId, Value: value})
+	}
+
+	tbsBytes, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signTBS(priv, tbsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(certificateList{
+		TBSCertList:        asn1.RawValue{FullBytes: tbsBytes},
+		SignatureAlgorithm: signatureAlgorithm(),
+		SignatureValue:     signature,
+	})
+}
+
+// ParseRevocationList parses a certificate revocation list from the given
+// ASN.1 DER data.
+//
+// The signature is not checked; call CheckSignatureFrom with the issuer's
+// certificate before trusting the list.
+func ParseRevocationList(der []byte) (*RevocationList, error) {
+	var list certificateList
+	rest, err := asn1.Unmarshal(der, &list)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after CRL")
+	}
+
+	var tbs parsedTBSCertList
+	if rest, err := asn1.Unmarshal(list.TBSCertList.FullBytes, &tbs); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after tbsCertList")
+	}
+	if !tbs.Signature.Algorithm.Equal(list.SignatureAlgorithm.Algorithm) {
+		return nil, errors.New("x509: inner and outer signature algorithms don't match")
+	}
+
+	issuer, err := parseName(tbs.Issuer.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rl := &RevocationList{
+		Raw:                  der,
+		RawTBSRevocationList: list.TBSCertList.FullBytes,
+		RawIssuer:            tbs.Issuer.FullBytes,
+		Issuer:               issuer,
+		Signature:            list.SignatureValue.RightAlign(),
+		SignatureAlgorithm:   list.SignatureAlgorithm.Algorithm,
+		ThisUpdate:           tbs.ThisUpdate,
+		NextUpdate:           tbs.NextUpdate,
+		Extensions:           tbs.Extensions,
+	}
+
+	for _, revoked := range tbs.RevokedCertificates {
+		entry := RevocationListEntry{
+			SerialNumber:   revoked.SerialNumber,
+			RevocationTime: revoked.RevocationTime,
+		}
+		for _, ext := range revoked.Extensions {
+			if ext.Id.Equal(oidExtensionReasonCode) {
+				var reason asn1.Enumerated
+				if _, err := asn1.Unmarshal(ext.Value, &reason); err != nil {
+					return nil, err
+				}
+				entry.ReasonCode = int(reason)
+			} else if ext.Critical {
+				return nil, errors.New("x509: unsupported critical CRL entry extension")
+			}
+		}
+		rl.RevokedCertificateEntries = append(rl.RevokedCertificateEntries, entry)
+	}
+
+	for _, ext := range tbs.Extensions {
+		switch {
+		case ext.Id.Equal(oidExtensionCRLNumber):
+			rl.Number = new(big.Int)
+			if _, err := asn1.Unmarshal(ext.Value, &rl.Number); err != nil {
+				return nil, err
+			}
+		case ext.Id.Equal(oidExtensionAuthorityKeyId):
+			var id authKeyId
+			if _, err := asn1.Unmarshal(ext.Value, &id); err != nil {
+				return nil, err
+			}
+			rl.AuthorityKeyId = id.Id
+		default:
+			if ext.Critical {
+				return nil, errors.New("x509: unsupported critical CRL extension")
+			}
+		}
+	}
+
+	return rl, nil
+}
+
+// CheckSignatureFrom verifies that the signature on rl is a valid signature
+// from issuer, and that issuer is allowed to sign revocation lists.
+func (rl *RevocationList) CheckSignatureFrom(issuer *Certificate) error {
+	if issuer.Version == 3 && !issuer.BasicConstraintsValid ||
+		issuer.BasicConstraintsValid && !issuer.IsCA {
+		return errors.New("x509: issuer of the revocation list is not a CA")
+	}
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&KeyUsageCRLSign == 0 {
+		return errors.New("x509: issuer cannot sign revocation lists")
+	}
+	return issuer.CheckSignature(rl.SignatureAlgorithm, rl.RawTBSRevocationList, rl.Signature)
+}
+
+// Lookup returns the entry for the certificate with the given serial number,
+// if it has been revoked.
+func (rl *RevocationList) Lookup(serialNumber *big.Int) (RevocationListEntry, bool) {
+	for _, entry := range rl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(serialNumber) == 0 {
+			return entry, true
+		}
+	}
+	return RevocationListEntry{}, false
+}
+
+// MarshalPEM encodes the revocation list as a PEM "X509 CRL" block.
+func (rl *RevocationList) MarshalPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: rl.Raw})
+}
+
+// ParseRevocationListPEM parses a PEM-encoded certificate revocation list.
+func ParseRevocationListPEM(pemBytes []byte) (*RevocationList, error) {
+	der, err := decodePEM(pemBytes, "X509 CRL")
+	if err != nil {
+		return nil, err
+	}
+	return ParseRevocationList(der)
+}
@@ -0,0 +1,140 @@
+package x509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+)
+
+type tbsCertificateRequest struct {
+	Raw           asn1.RawContent
+	Version       int
+	Subject       asn1.RawValue
+	PublicKey     asn1.RawValue
+	RawAttributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+type certificateRequest struct {
+	TBSCSR             tbsCertificateRequest
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// CertificateRequest represents a PKCS#10 certificate signing request for an
+// EccFrog512ck2 public key.
+type CertificateRequest struct {
+	Raw                      []byte // Complete ASN.1 DER content (CSR, signature algorithm and signature).
+	RawTBSCertificateRequest []byte // Certificate request info part of raw ASN.1 DER content.
+	RawSubjectPublicKeyInfo  []byte // DER encoded SubjectPublicKeyInfo.
+	RawSubject               []byte // DER encoded Subject.
+
+	Version            int
+	Signature          []byte
+	SignatureAlgorithm asn1.ObjectIdentifier
+
+	PublicKey eccfrog512ck2.CurvePoint
+
+	Subject pkix.Name
+
+	// RawAttributes contains the DER encoded attributes of the request. They
+	// are copied as-is when marshaling.
+	RawAttributes []asn1.RawValue
+}
+
+// CreateCertificateRequest creates a new certificate request based on a
+// template, and returns it in DER form. The request is signed by priv, whose
+// public key becomes the subject public key of the request.
+//
+// The following members of template are used: Subject, RawSubject and
+// RawAttributes.
+func CreateCertificateRequest(template *CertificateRequest, priv ecc.PrivateKey) ([]byte, error) {
+	pub, err := priv.DerivePublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := marshalName(template.RawSubject, template.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	tbs := tbsCertificateRequest{
+		Version:       0,
+		Subject:       subject,
+		PublicKey:     asn1.RawValue{FullBytes: publicKey},
+		RawAttributes: template.RawAttributes,
+	}
+	if tbs.RawAttributes == nil {
+		tbs.RawAttributes = []asn1.RawValue{}
+	}
+	tbsBytes, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	tbs.Raw = tbsBytes
+
+	signature, err := signTBS(priv, tbsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(certificateRequest{
+		TBSCSR:             tbs,
+		SignatureAlgorithm: signatureAlgorithm(),
+		SignatureValue:     signature,
+	})
+}
+
+// ParseCertificateRequest parses a single certificate request from the given
+// ASN.1 DER data.
+//
+// The signature is not checked; call CheckSignature to verify that the
+// requester possesses the private key.
+func ParseCertificateRequest(der []byte) (*CertificateRequest, error) {
+	var csr certificateRequest
+	rest, err := asn1.Unmarshal(der, &csr)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after certificate request")
+	}
+
+	publicKey, err := ParsePKIXPublicKey(csr.TBSCSR.PublicKey.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := parseName(csr.TBSCSR.Subject.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateRequest{
+		Raw:                      der,
+		RawTBSCertificateRequest: csr.TBSCSR.Raw,
+		RawSubjectPublicKeyInfo:  csr.TBSCSR.PublicKey.FullBytes,
+		RawSubject:               csr.TBSCSR.Subject.FullBytes,
+		Version:                  csr.TBSCSR.Version,
+		Signature:                csr.SignatureValue.RightAlign(),
+		SignatureAlgorithm:       csr.SignatureAlgorithm.Algorithm,
+		PublicKey:                publicKey,
+		Subject:                  subject,
+		RawAttributes:            csr.TBSCSR.RawAttributes,
+	}, nil
+}
+
+// CheckSignature verifies the proof-of-possession signature on the request,
+// that is, that the request was signed by the private key matching its
+// subject public key.
+func (c *CertificateRequest) CheckSignature() error {
+	return checkSignature(c.SignatureAlgorithm, c.RawTBSCertificateRequest, c.Signature, c.PublicKey)
+}
@@ -0,0 +1,12 @@
+// Package x509 provides X.509 certificates and PKCS#10 certificate signing
+// requests for keys on the EccFrog512ck2 elliptic curve.
+//
+// The standard library's crypto/x509 package only understands the NIST
+// curves, so this package implements the subset of RFC 5280 and RFC 2986
+// needed to issue, parse and verify certificates whose subject public keys
+// and signatures use EccFrog512ck2.
+//
+// Public keys are encoded as id-ecPublicKey with the named curve
+// OIDNamedCurveEccFrog512ck2, and signatures use ecdsa-with-SHA512 with the
+// ECDSA-Sig-Value encoding, exactly as for any other ECDSA curve.
+package x509
@@ -0,0 +1,90 @@
+package x509_test
+
+import (
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+func ExampleCreateCertificateRequest() {
+	// The requester generates a key pair and a signed request
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate private key:", err)
+		return
+	}
+	csrDER, err := x509.CreateCertificateRequest(&x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "alice"},
+	}, privateKey)
+	if err != nil {
+		fmt.Println("Failed to create request:", err)
+		return
+	}
+
+	// The CA checks the proof of possession before issuing a certificate
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		fmt.Println("Failed to parse request:", err)
+		return
+	}
+	if err := csr.CheckSignature(); err != nil {
+		fmt.Println("Invalid request:", err)
+		return
+	}
+
+	caKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		fmt.Println("Failed to generate CA key:", err)
+		return
+	}
+	caPublicKey, err := caKey.DerivePublicKey()
+	if err != nil {
+		fmt.Println("Failed to derive CA public key:", err)
+		return
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Example CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(caTemplate, caTemplate, caPublicKey, caKey)
+	if err != nil {
+		fmt.Println("Failed to create CA certificate:", err)
+		return
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		fmt.Println("Failed to parse CA certificate:", err)
+		return
+	}
+
+	certDER, err := x509.CreateCertificate(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		RawSubject:   csr.RawSubject,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, ca, csr.PublicKey, caKey)
+	if err != nil {
+		fmt.Println("Failed to issue certificate:", err)
+		return
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		fmt.Println("Failed to parse certificate:", err)
+		return
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{Roots: []*x509.Certificate{ca}})
+	fmt.Printf("Issued to %s, chain valid? %v\n", cert.Subject.CommonName, err == nil)
+	// Output:
+	// Issued to alice, chain valid? true
+}
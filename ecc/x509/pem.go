@@ -0,0 +1,48 @@
+package x509
+
+import (
+	"encoding/pem"
+	"fmt"
+)
+
+// MarshalPEM encodes the certificate as a PEM "CERTIFICATE" block.
+func (c *Certificate) MarshalPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+}
+
+// ParseCertificatePEM parses a PEM-encoded certificate.
+func ParseCertificatePEM(pemBytes []byte) (*Certificate, error) {
+	der, err := decodePEM(pemBytes, "CERTIFICATE")
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertificate(der)
+}
+
+// MarshalPEM encodes the certificate request as a PEM "CERTIFICATE REQUEST"
+// block.
+func (c *CertificateRequest) MarshalPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: c.Raw})
+}
+
+// ParseCertificateRequestPEM parses a PEM-encoded certificate request.
+func ParseCertificateRequestPEM(pemBytes []byte) (*CertificateRequest, error) {
+	der, err := decodePEM(pemBytes, "CERTIFICATE REQUEST")
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertificateRequest(der)
+}
+
+func decodePEM(pemBytes []byte, blockType string) ([]byte, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+
+	if block.Type != blockType {
+		return nil, fmt.Errorf("invalid PEM block type: %s", block.Type)
+	}
+
+	return block.Bytes, nil
+}
@@ -0,0 +1,762 @@
+package x509
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2"
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/ecdsa"
+)
+
+var (
+	// OIDPublicKeyEC is id-ecPublicKey, as defined in RFC 5480.
+	OIDPublicKeyEC = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+	// OIDNamedCurveEccFrog512ck2 identifies the EccFrog512ck2 curve in the
+	// parameters of an id-ecPublicKey algorithm identifier.
+	//
+	// The curve has no registered OID, so this one is allocated under the
+	// documentation arc reserved by RFC 5612.
+	OIDNamedCurveEccFrog512ck2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 32473, 1, 512, 2}
+
+	// OIDSignatureECDSAWithSHA512 is ecdsa-with-SHA512, as defined in RFC 5758.
+	OIDSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+
+	oidExtensionSubjectKeyId     = asn1.ObjectIdentifier{2, 5, 29, 14}
+	oidExtensionKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+	oidExtensionAuthorityKeyId   = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionAuthorityInfo    = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}
+
+	oidAuthorityInfoAccessOCSP = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1}
+)
+
+var (
+	// ErrUnknownAuthority is returned by Certificate.Verify when no chain to
+	// one of the roots could be built.
+	ErrUnknownAuthority = errors.New("x509: certificate signed by unknown authority")
+
+	// ErrExpired is returned by Certificate.Verify when a certificate in the
+	// chain is not valid at the verification time.
+	ErrExpired = errors.New("x509: certificate has expired or is not yet valid")
+
+	// ErrRevoked is returned by Certificate.Verify when a certificate in the
+	// chain has been revoked by its issuer.
+	ErrRevoked = errors.New("x509: certificate has been revoked")
+)
+
+const maxChainLength = 10
+
+// KeyUsage represents the set of actions that are valid for a given key. It's
+// a bitmap of the KeyUsage* constants.
+type KeyUsage int
+
+const (
+	KeyUsageDigitalSignature KeyUsage = 1 << iota
+	KeyUsageContentCommitment
+	KeyUsageKeyEncipherment
+	KeyUsageDataEncipherment
+	KeyUsageKeyAgreement
+	KeyUsageCertSign
+	KeyUsageCRLSign
+)
+
+type publicKeyInfo struct {
+	Raw       asn1.RawContent
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type tbsCertificate struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           asn1.RawValue
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	UniqueId           asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueId    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+type validity struct {
+	NotBefore, NotAfter time.Time
+}
+
+type certificate struct {
+	TBSCertificate     tbsCertificate
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type basicConstraints struct {
+	IsCA       bool `asn1:"optional"`
+	MaxPathLen int  `asn1:"optional,default:-1"`
+}
+
+type authKeyId struct {
+	Id []byte `asn1:"optional,tag:0"`
+}
+
+type authorityInfoAccess struct {
+	Method   asn1.ObjectIdentifier
+	Location asn1.RawValue
+}
+
+// Certificate represents an X.509 certificate whose subject public key is an
+// EccFrog512ck2 point.
+type Certificate struct {
+	Raw                     []byte // Complete ASN.1 DER content.
+	RawTBSCertificate       []byte // Certificate part of raw ASN.1 DER content.
+	RawSubjectPublicKeyInfo []byte // DER encoded SubjectPublicKeyInfo.
+	RawSubject              []byte // DER encoded Subject.
+	RawIssuer               []byte // DER encoded Issuer.
+
+	Signature          []byte
+	SignatureAlgorithm asn1.ObjectIdentifier
+
+	PublicKey eccfrog512ck2.CurvePoint
+
+	Version             int
+	SerialNumber        *big.Int
+	Issuer              pkix.Name
+	Subject             pkix.Name
+	NotBefore, NotAfter time.Time
+	KeyUsage            KeyUsage
+
+	// Extensions contains all the extensions found in a parsed certificate.
+	// It is ignored when marshaling; use ExtraExtensions instead.
+	Extensions []pkix.Extension
+
+	// ExtraExtensions contains extensions to be copied, raw, into any
+	// marshaled certificate.
+	ExtraExtensions []pkix.Extension
+
+	// UnhandledCriticalExtensions contains the OIDs of critical extensions
+	// that were not understood while parsing. Verify fails if it is not
+	// empty.
+	UnhandledCriticalExtensions []asn1.ObjectIdentifier
+
+	BasicConstraintsValid bool
+	IsCA                  bool
+
+	SubjectKeyId   []byte
+	AuthorityKeyId []byte
+
+	// OCSPServer contains the URLs of the OCSP responders for this
+	// certificate, taken from the authority information access extension.
+	OCSPServer []string
+}
+
+// MarshalPKIXPublicKey converts an EccFrog512ck2 public key to the PKIX,
+// ASN.1 DER SubjectPublicKeyInfo form.
+func MarshalPKIXPublicKey(pub eccfrog512ck2.CurvePoint) ([]byte, error) {
+	info, err := newPublicKeyInfo(pub)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(info)
+}
+
+// ParsePKIXPublicKey parses an EccFrog512ck2 public key in PKIX, ASN.1 DER
+// SubjectPublicKeyInfo form.
+func ParsePKIXPublicKey(der []byte) (eccfrog512ck2.CurvePoint, error) {
+	var info publicKeyInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, err
+	}
+	if len(rest) != 0 {
+		return eccfrog512ck2.CurvePoint{}, errors.New("x509: trailing data after public key")
+	}
+	return parsePublicKeyInfo(info)
+}
+
+func newPublicKeyInfo(pub eccfrog512ck2.CurvePoint) (publicKeyInfo, error) {
+	keyBytes := pub.MarshalSEC1(false)
+	if keyBytes == nil {
+		return publicKeyInfo{}, errors.New("x509: public key is the point at infinity")
+	}
+	paramBytes, err := asn1.Marshal(OIDNamedCurveEccFrog512ck2)
+	if err != nil {
+		return publicKeyInfo{}, err
+	}
+	return publicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  OIDPublicKeyEC,
+			Parameters: asn1.RawValue{FullBytes: paramBytes},
+		},
+		PublicKey: asn1.BitString{Bytes: keyBytes, BitLength: 8 * len(keyBytes)},
+	}, nil
+}
+
+func parsePublicKeyInfo(info publicKeyInfo) (eccfrog512ck2.CurvePoint, error) {
+	if !info.Algorithm.Algorithm.Equal(OIDPublicKeyEC) {
+		return eccfrog512ck2.CurvePoint{}, fmt.Errorf("x509: unsupported public key algorithm %v", info.Algorithm.Algorithm)
+	}
+	var namedCurve asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &namedCurve)
+	if err != nil {
+		return eccfrog512ck2.CurvePoint{}, errors.New("x509: failed to parse EC parameters")
+	}
+	if len(rest) != 0 || !namedCurve.Equal(OIDNamedCurveEccFrog512ck2) {
+		return eccfrog512ck2.CurvePoint{}, fmt.Errorf("x509: unsupported elliptic curve %v", namedCurve)
+	}
+	return ecc.ParsePublicKeySEC1(info.PublicKey.RightAlign())
+}
+
+func signatureAlgorithm() pkix.AlgorithmIdentifier {
+	return pkix.AlgorithmIdentifier{Algorithm: OIDSignatureECDSAWithSHA512}
+}
+
+func signTBS(priv ecc.PrivateKey, tbs []byte) (asn1.BitString, error) {
+	signature, err := ecdsa.NewSign(sha512.New, priv).SignASN1(tbs)
+	if err != nil {
+		return asn1.BitString{}, err
+	}
+	return asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)}, nil
+}
+
+// checkSignature verifies that signature is a valid signature over signed
+// from pub, using the given signature algorithm.
+func checkSignature(algorithm asn1.ObjectIdentifier, signed, signature []byte, pub eccfrog512ck2.CurvePoint) error {
+	if !algorithm.Equal(OIDSignatureECDSAWithSHA512) {
+		return fmt.Errorf("x509: unsupported signature algorithm %v", algorithm)
+	}
+	valid, err := ecdsa.NewVerification(sha512.New, pub).VerifyASN1(signature, signed)
+	if err != nil {
+		return fmt.Errorf("x509: malformed signature: %v", err)
+	}
+	if !valid {
+		return errors.New("x509: ECDSA verification failure")
+	}
+	return nil
+}
+
+// checkKeyPair returns an error if pub is not the public key of priv.
+func checkKeyPair(priv ecc.PrivateKey, pub eccfrog512ck2.CurvePoint) error {
+	derived, err := priv.DerivePublicKey()
+	if err != nil {
+		return err
+	}
+	if !derived.Equal(pub) {
+		return errors.New("x509: private key does not match the public key")
+	}
+	return nil
+}
+
+func marshalName(raw []byte, name pkix.Name) (asn1.RawValue, error) {
+	if len(raw) > 0 {
+		return asn1.RawValue{FullBytes: raw}, nil
+	}
+	der, err := asn1.Marshal(name.ToRDNSequence())
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{FullBytes: der}, nil
+}
+
+func parseName(raw []byte) (pkix.Name, error) {
+	var rdns pkix.RDNSequence
+	rest, err := asn1.Unmarshal(raw, &rdns)
+	if err != nil {
+		return pkix.Name{}, err
+	}
+	if len(rest) != 0 {
+		return pkix.Name{}, errors.New("x509: trailing data after name")
+	}
+	var name pkix.Name
+	name.FillFromRDNSequence(&rdns)
+	return name, nil
+}
+
+// marshalTime encodes t as a UTCTime if it lies between 1950 and 2049, and as
+// a GeneralizedTime otherwise, as RFC 5280 requires.
+func marshalTime(t time.Time) ([]byte, error) {
+	t = t.UTC().Truncate(time.Second)
+	if t.Year() < 1950 || t.Year() >= 2050 {
+		return asn1.MarshalWithParams(t, "generalized")
+	}
+	return asn1.MarshalWithParams(t, "utc")
+}
+
+func marshalValidity(notBefore, notAfter time.Time) (asn1.RawValue, error) {
+	before, err := marshalTime(notBefore)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	after, err := marshalTime(notAfter)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSequence,
+		IsCompound: true,
+		Bytes:      append(before, after...),
+	}, nil
+}
+
+func marshalKeyUsage(usage KeyUsage) (pkix.Extension, error) {
+	var bits [2]byte
+	bitLength := 0
+	for i := 0; i < 9; i++ {
+		if usage&(1<<i) != 0 {
+			bits[i/8] |= 0x80 >> (i % 8)
+			bitLength = i + 1
+		}
+	}
+	bitString := asn1.BitString{Bytes: bits[:(bitLength+7)/8], BitLength: bitLength}
+	value, err := asn1.Marshal(bitString)
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+	return pkix.Extension{Id: oidExtensionKeyUsage, Critical: true, Value: value}, nil
+}
+
+func parseKeyUsage(value []byte) (KeyUsage, error) {
+	var bits asn1.BitString
+	if _, err := asn1.Unmarshal(value, &bits); err != nil {
+		return 0, err
+	}
+	var usage KeyUsage
+	for i := 0; i < 9; i++ {
+		if bits.At(i) != 0 {
+			usage |= 1 << i
+		}
+	}
+	return usage, nil
+}
+
+// subjectKeyId derives a key identifier using method (1) of RFC 5280,
+// section 4.2.1.2.
+func subjectKeyId(pub eccfrog512ck2.CurvePoint) []byte {
+	sum := sha1.Sum(pub.MarshalSEC1(false))
+	return sum[:]
+}
+
+func buildCertificateExtensions(template *Certificate, authorityKeyId []byte) ([]pkix.Extension, error) {
+	var extensions []pkix.Extension
+
+	if template.KeyUsage != 0 {
+		ext, err := marshalKeyUsage(template.KeyUsage)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, ext)
+	}
+
+	if template.BasicConstraintsValid {
+		value, err := asn1.Marshal(basicConstraints{IsCA: template.IsCA, MaxPathLen: -1})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionBasicConstraints, Critical: true, Value: value})
+	}
+
+	if len(template.SubjectKeyId) > 0 {
+		value, err := asn1.Marshal(template.SubjectKeyId)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionSubjectKeyId, Value: value})
+	}
+
+	if len(authorityKeyId) > 0 {
+		value, err := asn1.Marshal(authKeyId{Id: authorityKeyId})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionAuthorityKeyId, Value: value})
+	}
+
+	if len(template.OCSPServer) > 0 {
+		var accessDescriptions []authorityInfoAccess
+		for _, url := range template.OCSPServer {
+			accessDescriptions = append(accessDescriptions, authorityInfoAccess{
+				Method:   oidAuthorityInfoAccessOCSP,
+				Location: asn1.RawValue{Tag: 6, Class: asn1.ClassContextSpecific, Bytes: []byte(url)},
+			})
+		}
+		value, err := asn1.Marshal(accessDescriptions)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionAuthorityInfo, Value: value})
+	}
+
+	return append(extensions, template.ExtraExtensions...), nil
+}
+
+// CreateCertificate creates a new X.509 v3 certificate based on a template,
+// and returns it in DER form.
+//
+// The certificate is signed by priv, whose public key must be the public key
+// of parent. If parent is equal to template the certificate is self-signed.
+// The pub parameter is the public key of the certificate being generated.
+//
+// The following members of template are used: SerialNumber, Subject,
+// RawSubject, NotBefore, NotAfter, KeyUsage, BasicConstraintsValid, IsCA,
+// SubjectKeyId, OCSPServer and ExtraExtensions.
+//
+// If SubjectKeyId is empty and the template is a CA, a key identifier is
+// derived from the public key. The AuthorityKeyId is taken from the
+// SubjectKeyId of parent, if any.
+func CreateCertificate(template, parent *Certificate, pub eccfrog512ck2.CurvePoint, priv ecc.PrivateKey) ([]byte, error) {
+	if template.SerialNumber == nil {
+		return nil, errors.New("x509: no SerialNumber given")
+	}
+	if template.SerialNumber.Sign() < 0 {
+		return nil, errors.New("x509: serial number must be positive")
+	}
+	if template.BasicConstraintsValid && !template.IsCA && template.KeyUsage&KeyUsageCertSign != 0 {
+		return nil, errors.New("x509: only CAs can have the certificate signing key usage")
+	}
+
+	issuerKey := parent.PublicKey
+	if parent == template {
+		issuerKey = pub
+	}
+	if err := checkKeyPair(priv, issuerKey); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := newPublicKeyInfo(pub)
+	if err != nil {
+		return nil, err
+	}
+	publicKeyBytes, err := asn1.Marshal(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := marshalName(template.RawSubject, template.Subject)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := marshalName(parent.RawSubject, parent.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	validity, err := marshalValidity(template.NotBefore, template.NotAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectKeyIdCopy := template.SubjectKeyId
+	if len(subjectKeyIdCopy) == 0 && template.IsCA {
+		subjectKeyIdCopy = subjectKeyId(pub)
+	}
+	authorityKeyId := parent.SubjectKeyId
+	if parent == template {
+		authorityKeyId = subjectKeyIdCopy
+	}
+
+	withKeyId := *template
+	withKeyId.SubjectKeyId = subjectKeyIdCopy
+	extensions, err := buildCertificateExtensions(&withKeyId, authorityKeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	tbs := tbsCertificate{
+		Version:            2,
+		SerialNumber:       template.SerialNumber,
+		SignatureAlgorithm: signatureAlgorithm(),
+		Issuer:             issuer,
+		Validity:           validity,
+		Subject:            subject,
+		PublicKey:          asn1.RawValue{FullBytes: publicKeyBytes},
+		Extensions:         extensions,
+	}
+	tbsBytes, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	tbs.Raw = tbsBytes
+
+	signature, err := signTBS(priv, tbsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(certificate{
+		TBSCertificate:     tbs,
+		SignatureAlgorithm: signatureAlgorithm(),
+		SignatureValue:     signature,
+	})
+}
+
+// ParseCertificate parses a single certificate from the given ASN.1 DER data.
+func ParseCertificate(der []byte) (*Certificate, error) {
+	var cert certificate
+	rest, err := asn1.Unmarshal(der, &cert)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after certificate")
+	}
+
+	tbs := cert.TBSCertificate
+	if !cert.SignatureAlgorithm.Algorithm.Equal(tbs.SignatureAlgorithm.Algorithm) {
+		return nil, errors.New("x509: inner and outer signature algorithms don't match")
+	}
+
+	var keyInfo publicKeyInfo
+	if _, err := asn1.Unmarshal(tbs.PublicKey.FullBytes, &keyInfo); err != nil {
+		return nil, err
+	}
+	publicKey, err := parsePublicKeyInfo(keyInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := parseName(tbs.Issuer.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := parseName(tbs.Subject.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var times validity
+	if _, err := asn1.Unmarshal(tbs.Validity.FullBytes, &times); err != nil {
+		return nil, err
+	}
+
+	c := &Certificate{
+		Raw:                     der,
+		RawTBSCertificate:       tbs.Raw,
+		RawSubjectPublicKeyInfo: tbs.PublicKey.FullBytes,
+		RawSubject:              tbs.Subject.FullBytes,
+		RawIssuer:               tbs.Issuer.FullBytes,
+		Signature:               cert.SignatureValue.RightAlign(),
+		SignatureAlgorithm:      cert.SignatureAlgorithm.Algorithm,
+		PublicKey:               publicKey,
+		Version:                 tbs.Version + 1,
+		SerialNumber:            tbs.SerialNumber,
+		Issuer:                  issuer,
+		Subject:                 subject,
+		NotBefore:               times.NotBefore,
+		NotAfter:                times.NotAfter,
+		Extensions:              tbs.Extensions,
+	}
+
+	for _, ext := range tbs.Extensions {
+		handled, err := c.parseExtension(ext)
+		if err != nil {
+			return nil, err
+		}
+		if !handled && ext.Critical {
+			c.UnhandledCriticalExtensions = append(c.UnhandledCriticalExtensions, ext.Id)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Certificate) parseExtension(ext pkix.Extension) (bool, error) {
+	switch {
+	case ext.Id.Equal(oidExtensionKeyUsage):
+		usage, err := parseKeyUsage(ext.Value)
+		if err != nil {
+			return false, err
+		}
+		c.KeyUsage = usage
+	case ext.Id.Equal(oidExtensionBasicConstraints):
+		var constraints basicConstraints
+		if _, err := asn1.Unmarshal(ext.Value, &constraints); err != nil {
+			return false, err
+		}
+		c.BasicConstraintsValid = true
+		c.IsCA = constraints.IsCA
+	case ext.Id.Equal(oidExtensionSubjectKeyId):
+		if _, err := asn1.Unmarshal(ext.Value, &c.SubjectKeyId); err != nil {
+			return false, err
+		}
+	case ext.Id.Equal(oidExtensionAuthorityKeyId):
+		var id authKeyId
+		if _, err := asn1.Unmarshal(ext.Value, &id); err != nil {
+			return false, err
+		}
+		c.AuthorityKeyId = id.Id
+	case ext.Id.Equal(oidExtensionAuthorityInfo):
+		var accessDescriptions []authorityInfoAccess
+		if _, err := asn1.Unmarshal(ext.Value, &accessDescriptions); err != nil {
+			return false, err
+		}
+		for _, description := range accessDescriptions {
+			// A URI is a GeneralName with the context-specific tag 6.
+			if description.Method.Equal(oidAuthorityInfoAccessOCSP) && description.Location.Tag == 6 {
+				c.OCSPServer = append(c.OCSPServer, string(description.Location.Bytes))
+			}
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// Equal reports whether c and other are the same certificate.
+func (c *Certificate) Equal(other *Certificate) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	return bytes.Equal(c.Raw, other.Raw)
+}
+
+// CheckSignatureFrom verifies that the signature on c is a valid signature
+// from parent, and that parent is allowed to issue certificates.
+func (c *Certificate) CheckSignatureFrom(parent *Certificate) error {
+	if parent.Version == 3 && !parent.BasicConstraintsValid ||
+		parent.BasicConstraintsValid && !parent.IsCA {
+		return errors.New("x509: parent certificate is not a CA")
+	}
+	if parent.KeyUsage != 0 && parent.KeyUsage&KeyUsageCertSign == 0 {
+		return errors.New("x509: parent certificate cannot sign certificates")
+	}
+	return parent.CheckSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature)
+}
+
+// CheckSignature verifies that signature is a valid signature over signed
+// from c's public key. It is used by formats such as CRLs and OCSP responses
+// that are signed by a certificate's key.
+func (c *Certificate) CheckSignature(algorithm asn1.ObjectIdentifier, signed, signature []byte) error {
+	return checkSignature(algorithm, signed, signature, c.PublicKey)
+}
+
+// VerifyOptions contains parameters for Certificate.Verify.
+type VerifyOptions struct {
+	// Roots is the set of trusted root certificates.
+	Roots []*Certificate
+
+	// Intermediates is an optional pool of certificates that are not trust
+	// anchors, but can be used to form a chain from the leaf certificate to
+	// a root certificate.
+	Intermediates []*Certificate
+
+	// CurrentTime is used to check the validity of all certificates in the
+	// chain. If zero, the current time is used.
+	CurrentTime time.Time
+
+	// RevocationLists are consulted for every certificate in a candidate
+	// chain. A list only applies to certificates issued by the list's issuer,
+	// and must be signed by that issuer and current at CurrentTime.
+	RevocationLists []*RevocationList
+
+	// CheckRevocation, if set, is called for every certificate in a
+	// candidate chain together with its issuer and CurrentTime, after any
+	// RevocationLists have been consulted. A non-nil error rejects the
+	// chain. The ocsp package provides a checker that queries OCSP
+	// responders.
+	CheckRevocation func(cert, issuer *Certificate, now time.Time) error
+}
+
+// Verify attempts to verify c by building a chain from c to a certificate in
+// opts.Roots, using certificates in opts.Intermediates if needed.
+//
+// On success it returns the chain, starting with c and ending with the root.
+func (c *Certificate) Verify(opts VerifyOptions) ([]*Certificate, error) {
+	if opts.CurrentTime.IsZero() {
+		opts.CurrentTime = time.Now()
+	}
+	return c.buildChain([]*Certificate{c}, &opts)
+}
+
+func (c *Certificate) isValidAt(now time.Time) error {
+	if now.Before(c.NotBefore) || now.After(c.NotAfter) {
+		return fmt.Errorf("%w: current time %s is outside %s - %s", ErrExpired,
+			now.UTC().Format(time.RFC3339),
+			c.NotBefore.UTC().Format(time.RFC3339),
+			c.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if len(c.UnhandledCriticalExtensions) > 0 {
+		return fmt.Errorf("x509: unhandled critical extension %v", c.UnhandledCriticalExtensions[0])
+	}
+	return nil
+}
+
+func (c *Certificate) buildChain(chain []*Certificate, opts *VerifyOptions) ([]*Certificate, error) {
+	if err := c.isValidAt(opts.CurrentTime); err != nil {
+		return nil, err
+	}
+
+	for _, root := range opts.Roots {
+		if root.Equal(c) {
+			return chain, nil
+		}
+	}
+
+	if len(chain) >= maxChainLength {
+		return nil, errors.New("x509: certificate chain is too long")
+	}
+
+	err := ErrUnknownAuthority
+	candidates := append(append([]*Certificate{}, opts.Roots...), opts.Intermediates...)
+	for _, candidate := range candidates {
+		if !bytes.Equal(candidate.RawSubject, c.RawIssuer) || inChain(chain, candidate) {
+			continue
+		}
+		if signatureErr := c.CheckSignatureFrom(candidate); signatureErr != nil {
+			err = signatureErr
+			continue
+		}
+		if revocationErr := opts.checkRevocation(c, candidate); revocationErr != nil {
+			return nil, revocationErr
+		}
+		result, chainErr := candidate.buildChain(append(chain, candidate), opts)
+		if chainErr == nil {
+			return result, nil
+		}
+		err = chainErr
+	}
+
+	return nil, err
+}
+
+func (opts *VerifyOptions) checkRevocation(cert, issuer *Certificate) error {
+	for _, list := range opts.RevocationLists {
+		if !bytes.Equal(list.RawIssuer, issuer.RawSubject) {
+			continue
+		}
+		if err := list.CheckSignatureFrom(issuer); err != nil {
+			return err
+		}
+		if opts.CurrentTime.Before(list.ThisUpdate) {
+			return errors.New("x509: revocation list is not yet valid")
+		}
+		if !list.NextUpdate.IsZero() && opts.CurrentTime.After(list.NextUpdate) {
+			return errors.New("x509: revocation list is out of date")
+		}
+		if entry, ok := list.Lookup(cert.SerialNumber); ok {
+			return fmt.Errorf("%w: serial number %v was revoked at %s", ErrRevoked,
+				cert.SerialNumber, entry.RevocationTime.UTC().Format(time.RFC3339))
+		}
+	}
+	if opts.CheckRevocation != nil {
+		return opts.CheckRevocation(cert, issuer, opts.CurrentTime)
+	}
+	return nil
+}
+
+func inChain(chain []*Certificate, cert *Certificate) bool {
+	for _, c := range chain {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package x509_test
+
+import (
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/shovon/go-eccfrog512ck2/ecc"
+	"github.com/shovon/go-eccfrog512ck2/ecc/x509"
+)
+
+// newCA creates a self-signed CA certificate, failing the test on error.
+func newCA(t *testing.T, name string) (*x509.Certificate, ecc.PrivateKey) {
+	t.Helper()
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(template, template, publicKey, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+	return cert, privateKey
+}
+
+func TestPKIXPublicKeyRoundTrip(t *testing.T) {
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := privateKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(publicKey) {
+		t.Error("Parsed public key does not match the original")
+	}
+}
+
+func TestCertificateRequest(t *testing.T) {
+	privateKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificateRequest(&x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "alice", Organization: []string{"Frog"}},
+	}, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate request: %v", err)
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate request: %v", err)
+	}
+	if csr.Subject.CommonName != "alice" {
+		t.Errorf("expected common name %q but got %q", "alice", csr.Subject.CommonName)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Errorf("Proof of possession failed: %v", err)
+	}
+
+	// Tamper with the subject; the proof of possession must no longer hold.
+	csr.RawTBSCertificateRequest = append([]byte{}, csr.RawTBSCertificateRequest...)
+	csr.RawTBSCertificateRequest[len(csr.RawTBSCertificateRequest)-3] ^= 0xff
+	if err := csr.CheckSignature(); err == nil {
+		t.Error("Expected the signature check to fail for a tampered request")
+	}
+
+	parsed, err := x509.ParseCertificateRequestPEM(csr.MarshalPEM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.PublicKey.Equal(csr.PublicKey) {
+		t.Error("PEM round trip changed the public key")
+	}
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	root, rootKey := newCA(t, "root")
+
+	leafKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(&x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "leaf"},
+	}, leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		RawSubject:   csr.RawSubject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	leafDER, err := x509.CreateCertificate(template, root, csr.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificatePEM((&x509.Certificate{Raw: leafDER}).MarshalPEM())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if leaf.Subject.CommonName != "leaf" || leaf.Issuer.CommonName != "root" {
+		t.Errorf("unexpected subject %q or issuer %q", leaf.Subject.CommonName, leaf.Issuer.CommonName)
+	}
+	if string(leaf.AuthorityKeyId) != string(root.SubjectKeyId) {
+		t.Error("authority key identifier does not match the issuer's subject key identifier")
+	}
+
+	chain, err := leaf.Verify(x509.VerifyOptions{Roots: []*x509.Certificate{root}})
+	if err != nil {
+		t.Fatalf("Failed to verify chain: %v", err)
+	}
+	if len(chain) != 2 || !chain[1].Equal(root) {
+		t.Errorf("unexpected chain of length %d", len(chain))
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:       []*x509.Certificate{root},
+			CurrentTime: time.Now().Add(2 * time.Hour),
+		})
+		if !errors.Is(err, x509.ErrExpired) {
+			t.Errorf("expected ErrExpired but got %v", err)
+		}
+	})
+
+	t.Run("unknown authority", func(t *testing.T) {
+		other, _ := newCA(t, "root")
+		if _, err := leaf.Verify(x509.VerifyOptions{Roots: []*x509.Certificate{other}}); err == nil {
+			t.Error("expected verification against an unrelated root to fail")
+		}
+	})
+
+	t.Run("wrong issuer key", func(t *testing.T) {
+		if _, err := x509.CreateCertificate(template, root, csr.PublicKey, leafKey); err == nil {
+			t.Error("expected an error when the signing key does not match the parent")
+		}
+	})
+}
+
+func TestRevocationList(t *testing.T) {
+	root, rootKey := newCA(t, "root")
+
+	leafKey, err := ecc.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafPublicKey, err := leafKey.DerivePublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafDER, err := x509.CreateCertificate(&x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, root, leafPublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revokedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	der, err := x509.CreateRevocationList(&x509.RevocationList{
+		Number:     big.NewInt(7),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: big.NewInt(42), RevocationTime: revokedAt, ReasonCode: 1},
+		},
+	}, root, rootKey)
+	if err != nil {
+		t.Fatalf("Failed to create revocation list: %v", err)
+	}
+
+	list, err := x509.ParseRevocationListPEM((&x509.RevocationList{Raw: der}).MarshalPEM())
+	if err != nil {
+		t.Fatalf("Failed to parse revocation list: %v", err)
+	}
+	if err := list.CheckSignatureFrom(root); err != nil {
+		t.Errorf("Failed to verify revocation list signature: %v", err)
+	}
+	if list.Number.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("expected CRL number 7 but got %v", list.Number)
+	}
+	entry, ok := list.Lookup(big.NewInt(42))
+	if !ok {
+		t.Fatal("expected serial number 42 to be revoked")
+	}
+	if entry.ReasonCode != 1 || !entry.RevocationTime.Equal(revokedAt) {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if _, ok := list.Lookup(big.NewInt(43)); ok {
+		t.Error("serial number 43 should not be revoked")
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: []*x509.Certificate{root}}); err != nil {
+		t.Fatalf("Failed to verify chain without revocation list: %v", err)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:           []*x509.Certificate{root},
+		RevocationLists: []*x509.RevocationList{list},
+	})
+	if !errors.Is(err, x509.ErrRevoked) {
+		t.Errorf("expected ErrRevoked but got %v", err)
+	}
+
+	t.Run("list from the future", func(t *testing.T) {
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:           []*x509.Certificate{root},
+			RevocationLists: []*x509.RevocationList{list},
+			CurrentTime:     time.Now().Add(-30 * time.Minute),
+		})
+		if err == nil || errors.Is(err, x509.ErrRevoked) {
+			t.Errorf("expected a list issued after the verification time to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("forged list", func(t *testing.T) {
+		other, otherKey := newCA(t, "root")
+		forgedDER, err := x509.CreateRevocationList(&x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: time.Now(),
+		}, other, otherKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forged, err := x509.ParseRevocationList(forgedDER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := forged.CheckSignatureFrom(root); err == nil {
+			t.Error("expected a list signed by another CA to be rejected")
+		}
+		if _, err := leaf.Verify(x509.VerifyOptions{
+			Roots:           []*x509.Certificate{root},
+			RevocationLists: []*x509.RevocationList{forged},
+		}); err == nil {
+			t.Error("expected verification with a forged revocation list to fail")
+		}
+	})
+}